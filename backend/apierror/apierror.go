@@ -0,0 +1,300 @@
+// Package apierror 统一API错误响应：机器可读的错误码、HTTP状态码映射、字段级校验详情，
+// 以及按Accept-Language本地化的错误信息
+//
+// 错误响应格式：
+//
+//	{"code": "validation_failed", "error": "请求参数校验失败", "details": [{"field": "options", "code": "min", "param": "2", "message": "至少需要2项"}]}
+//
+// error字段始终是本地化后的可读信息，客户端应根据code判断错误类型。
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code 机器可读的错误码
+type Code string
+
+// 错误码，对应的HTTP状态码见statuses，信息见catalogs
+const (
+	InvalidRequest     Code = "invalid_request"
+	ValidationFailed   Code = "validation_failed"
+	InvalidParameter   Code = "invalid_parameter"
+	InvalidTime        Code = "invalid_time"
+	InvalidTimeRange   Code = "invalid_time_range"
+	InvalidGranularity Code = "invalid_granularity"
+	TooManyBuckets     Code = "too_many_buckets"
+	UnsupportedFormat  Code = "unsupported_format"
+	InvalidDocument    Code = "invalid_document"
+	PlanRejected       Code = "plan_rejected"
+	UndefinedVariable  Code = "undefined_template_variable"
+	InvalidRoll        Code = "invalid_roll"
+	InvalidOption      Code = "invalid_option"
+	AlreadyVoted       Code = "already_voted"
+	WriteInRequired    Code = "write_in_required"
+	WriteInNotAllowed  Code = "write_in_not_allowed"
+	InvalidVote        Code = "invalid_vote"
+	InvalidQuestion    Code = "invalid_question"
+	InvalidAnswer      Code = "invalid_answer"
+	AnswerRequired     Code = "answer_required"
+	QuestionHidden     Code = "question_hidden"
+	AlreadyResponded   Code = "already_responded"
+	Unauthorized       Code = "unauthorized"
+	AdminDisabled      Code = "admin_disabled"
+	ResultsHidden      Code = "results_hidden"
+	ProposalsDisabled  Code = "proposals_disabled"
+	AccessCodeInvalid  Code = "invalid_access_code"
+	BallotInvalid      Code = "invalid_ballot_token"
+	NotEligible        Code = "not_eligible"
+	NoActivePoll       Code = "no_active_poll"
+	PollNotFound       Code = "poll_not_found"
+	VoteNotFound       Code = "vote_not_found"
+	TemplateNotFound   Code = "template_not_found"
+	WebhookNotFound    Code = "webhook_not_found"
+	DeliveryNotFound   Code = "delivery_not_found"
+	TokenNotFound      Code = "token_not_found"
+	ProposalNotFound   Code = "proposal_not_found"
+	SurveyNotFound     Code = "survey_not_found"
+	BallotNotFound     Code = "ballot_not_found"
+	RollNotFound       Code = "roll_not_found"
+	DelegationNotFound Code = "delegation_not_found"
+	PollClosed         Code = "poll_closed"
+	PollStateUnchanged Code = "poll_state_unchanged"
+	VisibilityLocked   Code = "visibility_locked"
+	ResultsSealed      Code = "results_sealed"
+	DeliveryPending    Code = "delivery_pending"
+	WebhookDeleted     Code = "webhook_deleted"
+	OptionExists       Code = "option_exists"
+	TooManyProposals   Code = "too_many_proposals"
+	ProposalResolved   Code = "proposal_resolved"
+	SurveyClosed       Code = "survey_closed"
+	SurveyUnchanged    Code = "survey_state_unchanged"
+	BallotUsed         Code = "ballot_token_used"
+	RollInUse          Code = "roll_in_use"
+	DelegationCycle    Code = "delegation_cycle"
+	TallyOverflow      Code = "tally_overflow"
+	Internal           Code = "internal_error"
+)
+
+// statuses 错误码对应的HTTP状态码
+var statuses = map[Code]int{
+	InvalidRequest:     http.StatusBadRequest,
+	ValidationFailed:   http.StatusBadRequest,
+	InvalidParameter:   http.StatusBadRequest,
+	InvalidTime:        http.StatusBadRequest,
+	InvalidTimeRange:   http.StatusBadRequest,
+	InvalidGranularity: http.StatusBadRequest,
+	TooManyBuckets:     http.StatusBadRequest,
+	UnsupportedFormat:  http.StatusBadRequest,
+	InvalidDocument:    http.StatusBadRequest,
+	PlanRejected:       http.StatusBadRequest,
+	UndefinedVariable:  http.StatusBadRequest,
+	InvalidRoll:        http.StatusBadRequest,
+	InvalidOption:      http.StatusBadRequest,
+	AlreadyVoted:       http.StatusBadRequest,
+	WriteInRequired:    http.StatusBadRequest,
+	WriteInNotAllowed:  http.StatusBadRequest,
+	InvalidVote:        http.StatusBadRequest,
+	InvalidQuestion:    http.StatusBadRequest,
+	InvalidAnswer:      http.StatusBadRequest,
+	AnswerRequired:     http.StatusBadRequest,
+	QuestionHidden:     http.StatusBadRequest,
+	AlreadyResponded:   http.StatusBadRequest,
+	Unauthorized:       http.StatusUnauthorized,
+	AdminDisabled:      http.StatusForbidden,
+	ResultsHidden:      http.StatusForbidden,
+	ProposalsDisabled:  http.StatusForbidden,
+	AccessCodeInvalid:  http.StatusForbidden,
+	BallotInvalid:      http.StatusForbidden,
+	NotEligible:        http.StatusForbidden,
+	NoActivePoll:       http.StatusNotFound,
+	PollNotFound:       http.StatusNotFound,
+	VoteNotFound:       http.StatusNotFound,
+	TemplateNotFound:   http.StatusNotFound,
+	WebhookNotFound:    http.StatusNotFound,
+	DeliveryNotFound:   http.StatusNotFound,
+	TokenNotFound:      http.StatusNotFound,
+	ProposalNotFound:   http.StatusNotFound,
+	SurveyNotFound:     http.StatusNotFound,
+	BallotNotFound:     http.StatusNotFound,
+	RollNotFound:       http.StatusNotFound,
+	DelegationNotFound: http.StatusNotFound,
+	PollClosed:         http.StatusConflict,
+	PollStateUnchanged: http.StatusConflict,
+	VisibilityLocked:   http.StatusConflict,
+	ResultsSealed:      http.StatusConflict,
+	DeliveryPending:    http.StatusConflict,
+	WebhookDeleted:     http.StatusConflict,
+	OptionExists:       http.StatusConflict,
+	TooManyProposals:   http.StatusConflict,
+	ProposalResolved:   http.StatusConflict,
+	SurveyClosed:       http.StatusConflict,
+	SurveyUnchanged:    http.StatusConflict,
+	BallotUsed:         http.StatusConflict,
+	RollInUse:          http.StatusConflict,
+	DelegationCycle:    http.StatusConflict,
+	TallyOverflow:      http.StatusConflict,
+	Internal:           http.StatusInternalServerError,
+}
+
+// Status 返回错误码对应的HTTP状态码，未知错误码视为服务器内部错误
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error 业务错误，由服务层返回，HTTP和gRPC接口按错误码转换为各自的响应
+type Error struct {
+	Code Code
+	// Params 成对的信息模板参数，同Abort
+	Params []string
+}
+
+// New 创建业务错误
+func New(code Code, params ...string) *Error {
+	return &Error{Code: code, Params: params}
+}
+
+func (e *Error) Error() string {
+	return Message(LanguageEnglish, e.Code, e.Params...)
+}
+
+// As 从错误链中取出业务错误
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// FieldError 字段级的校验错误
+type FieldError struct {
+	// Field 字段路径，使用JSON字段名，例如 options[1]、rules.win_percent
+	Field string `json:"field"`
+	// Code 校验规则，例如 required、max、oneof
+	Code string `json:"code"`
+	// Param 校验规则的参数，例如 max=255 中的255
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+
+	// key 信息在目录中的键，为空时使用Code
+	key string
+}
+
+// Response 错误响应体，部分错误会附带额外字段，例如导入失败时的plan和diff
+type Response struct {
+	Code Code `json:"code"`
+	// Error 本地化后的错误信息
+	Error   string       `json:"error"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Field 创建字段级的校验错误，信息在响应时按语言填充
+func Field(field, code, param string) FieldError {
+	return FieldError{Field: field, Code: code, Param: param}
+}
+
+// Abort 写入错误响应并中止后续处理，params为成对的信息模板参数，例如 "name", "limit"
+func Abort(c *gin.Context, code Code, params ...string) {
+	AbortWithData(c, code, nil, params...)
+}
+
+// AbortWithData 写入带附加字段的错误响应，例如导入失败时附带变更计划
+func AbortWithData(c *gin.Context, code Code, data gin.H, params ...string) {
+	respond(c, code, nil, data, params)
+}
+
+// Invalid 写入字段级的校验错误响应
+func Invalid(c *gin.Context, details ...FieldError) {
+	respond(c, ValidationFailed, details, nil, nil)
+}
+
+// InvalidWith 写入字段级的错误响应并使用指定的错误码
+func InvalidWith(c *gin.Context, code Code, details ...FieldError) {
+	respond(c, code, details, nil, nil)
+}
+
+// Fail 写入服务器内部错误响应，err记录到请求上下文中由日志输出，不返回给客户端
+func Fail(c *gin.Context, err error) {
+	if err != nil {
+		c.Error(err)
+	}
+	Abort(c, Internal)
+}
+
+// Respond 按服务层返回的错误写入响应，业务错误返回对应的错误码，其他错误视为服务器内部错误
+func Respond(c *gin.Context, err error) {
+	if e, ok := As(err); ok {
+		Abort(c, e.Code, e.Params...)
+		return
+	}
+	Fail(c, err)
+}
+
+func respond(c *gin.Context, code Code, details []FieldError, data gin.H, params []string) {
+	lang := Negotiate(c.GetHeader("Accept-Language"))
+
+	resp := Response{Code: code, Error: Message(lang, code, params...)}
+	for _, detail := range details {
+		if detail.Message == "" {
+			key := detail.key
+			if key == "" {
+				key = detail.Code
+			}
+			detail.Message = ruleMessage(lang, key, detail.Param)
+		}
+		resp.Details = append(resp.Details, detail)
+	}
+
+	c.Header("Content-Language", lang)
+	if data == nil {
+		c.AbortWithStatusJSON(code.Status(), resp)
+		return
+	}
+
+	body := gin.H{}
+	for key, value := range data {
+		body[key] = value
+	}
+	body["code"], body["error"] = resp.Code, resp.Error
+	if len(resp.Details) > 0 {
+		body["details"] = resp.Details
+	}
+	c.AbortWithStatusJSON(code.Status(), body)
+}
+
+// Message 返回错误码在指定语言下的信息
+func Message(lang string, code Code, params ...string) string {
+	text, ok := catalogs[lang].codes[code]
+	if !ok {
+		text = catalogs[DefaultLanguage].codes[code]
+	}
+	if text == "" {
+		text = string(code)
+	}
+	if len(params) >= 2 {
+		pairs := make([]string, 0, len(params))
+		for i := 0; i+1 < len(params); i += 2 {
+			pairs = append(pairs, "{"+params[i]+"}", params[i+1])
+		}
+		text = strings.NewReplacer(pairs...).Replace(text)
+	}
+	return text
+}
+
+// ruleMessage 返回校验规则在指定语言下的信息
+func ruleMessage(lang, key, param string) string {
+	text, ok := catalogs[lang].rules[key]
+	if !ok {
+		text, ok = catalogs[lang].rules["invalid"]
+	}
+	if !ok {
+		return key
+	}
+	return strings.ReplaceAll(text, "{param}", param)
+}
@@ -0,0 +1,150 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type body struct {
+	Code    Code         `json:"code"`
+	Error   string       `json:"error"`
+	Details []FieldError `json:"details"`
+}
+
+func request(handler gin.HandlerFunc, payload, language string) (*httptest.ResponseRecorder, body) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", handler)
+
+	req, _ := http.NewRequest("POST", "/", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp body
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          DefaultLanguage,
+		"en":                        LanguageEnglish,
+		"en-US,en;q=0.9":            LanguageEnglish,
+		"zh-CN,zh;q=0.9,en;q=0.8":   LanguageChinese,
+		"zh-TW":                     LanguageChinese,
+		"fr-FR,en;q=0.5,zh;q=0.8":   LanguageChinese,
+		"fr-FR,de;q=0.5":            DefaultLanguage,
+		"zh;q=0,en":                 LanguageEnglish,
+		" EN-gb ; q=0.7 , fr;q=0.9": LanguageEnglish,
+	}
+	for header, want := range cases {
+		if got := Negotiate(header); got != want {
+			t.Errorf("%q: 期望 %s, 得到 %s", header, want, got)
+		}
+	}
+}
+
+func TestCatalogsComplete(t *testing.T) {
+	for lang, cat := range catalogs {
+		for code := range statuses {
+			if cat.codes[code] == "" {
+				t.Errorf("%s: 错误码 %s 缺少信息", lang, code)
+			}
+		}
+		for key := range catalogs[DefaultLanguage].rules {
+			if cat.rules[key] == "" {
+				t.Errorf("%s: 校验规则 %s 缺少信息", lang, key)
+			}
+		}
+	}
+}
+
+func TestAbort(t *testing.T) {
+	w, resp := request(func(c *gin.Context) {
+		Abort(c, InvalidParameter, "name", "limit")
+	}, "", "en")
+
+	if w.Code != http.StatusBadRequest || resp.Code != InvalidParameter || resp.Error != "Invalid parameter: limit" {
+		t.Errorf("错误响应不正确: %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Language") != LanguageEnglish {
+		t.Errorf("期望 Content-Language: en, 得到 %q", w.Header().Get("Content-Language"))
+	}
+
+	_, resp = request(func(c *gin.Context) {
+		Abort(c, InvalidParameter, "name", "limit")
+	}, "", "")
+	if resp.Error != "参数 limit 无效" {
+		t.Errorf("默认应返回中文信息, 得到 %q", resp.Error)
+	}
+}
+
+func TestFail_HidesCause(t *testing.T) {
+	w, resp := request(func(c *gin.Context) {
+		Fail(c, errors.New("database is locked"))
+		if len(c.Errors) != 1 {
+			t.Error("内部错误应记录到请求上下文")
+		}
+	}, "", "en")
+
+	if w.Code != http.StatusInternalServerError || resp.Code != Internal || strings.Contains(w.Body.String(), "locked") {
+		t.Errorf("内部错误不应返回原因: %s", w.Body.String())
+	}
+}
+
+func TestBind(t *testing.T) {
+	type rules struct {
+		WinPercent int `json:"win_percent" binding:"max=100"`
+	}
+	type payload struct {
+		Title   string   `json:"title" binding:"required,max=5"`
+		Options []string `json:"options" binding:"required,min=2"`
+		Rules   rules    `json:"rules"`
+	}
+	handler := func(c *gin.Context) {
+		var req payload
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Bind(c, err)
+		}
+	}
+
+	w, resp := request(handler, `{"title": "too long", "options": ["a"], "rules": {"win_percent": 120}}`, "en")
+	if w.Code != http.StatusBadRequest || resp.Code != ValidationFailed || len(resp.Details) != 3 {
+		t.Fatalf("校验错误响应不正确: %d %s", w.Code, w.Body.String())
+	}
+	want := []FieldError{
+		{Field: "title", Code: "max", Param: "5", Message: "must be at most 5 characters long"},
+		{Field: "options", Code: "min", Param: "2", Message: "must contain at least 2 items"},
+		{Field: "rules.win_percent", Code: "max", Param: "100", Message: "must be at most 100"},
+	}
+	for i, detail := range resp.Details {
+		if detail != want[i] {
+			t.Errorf("字段详情 %d: 期望 %+v, 得到 %+v", i, want[i], detail)
+		}
+	}
+	if strings.Contains(w.Body.String(), "payload") || strings.Contains(w.Body.String(), "Field validation") {
+		t.Errorf("不应暴露校验器的内部信息: %s", w.Body.String())
+	}
+
+	// 类型错误
+	_, resp = request(handler, `{"title": 1}`, "zh-CN")
+	if len(resp.Details) != 1 || resp.Details[0].Field != "title" || resp.Details[0].Message != "类型不正确，应为string" {
+		t.Errorf("类型错误响应不正确: %+v", resp)
+	}
+
+	// 无法解析的请求体
+	_, resp = request(handler, `{`, "en")
+	if resp.Code != InvalidRequest || len(resp.Details) != 0 {
+		t.Errorf("期望 invalid_request, 得到 %+v", resp)
+	}
+}
@@ -0,0 +1,58 @@
+package apierror
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言
+const (
+	LanguageChinese = "zh-CN"
+	LanguageEnglish = "en"
+
+	// DefaultLanguage 未携带Accept-Language或没有支持的语言时使用的语言，与前端界面一致
+	DefaultLanguage = LanguageChinese
+)
+
+// Negotiate 根据Accept-Language请求头选择语言，按q值从高到低匹配zh*和en*
+func Negotiate(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		switch {
+		case c.tag == "zh" || strings.HasPrefix(c.tag, "zh-"):
+			return LanguageChinese
+		case c.tag == "en" || strings.HasPrefix(c.tag, "en-"):
+			return LanguageEnglish
+		}
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,170 @@
+package apierror
+
+// catalog 一种语言的错误信息
+type catalog struct {
+	// codes 错误码的信息，{name}形式的占位符由Abort的参数替换
+	codes map[Code]string
+	// rules 字段校验规则的信息，{param}替换为规则参数；带.len后缀的键用于字符串长度，带.items后缀的键用于列表长度
+	rules map[string]string
+}
+
+var catalogs = map[string]catalog{
+	LanguageChinese: {
+		codes: map[Code]string{
+			InvalidRequest:     "请求格式不正确",
+			ValidationFailed:   "请求参数校验失败",
+			InvalidParameter:   "参数 {name} 无效",
+			InvalidTime:        "参数 {name} 应为RFC3339格式的时间",
+			InvalidTimeRange:   "开始时间必须早于结束时间",
+			InvalidGranularity: "时间粒度只能是 minute、hour 或 day",
+			TooManyBuckets:     "时间范围内的数据点过多，请使用更粗的时间粒度",
+			UnsupportedFormat:  "不支持的格式: {format}",
+			InvalidDocument:    "文档格式不正确",
+			PlanRejected:       "文档无法应用，请查看变更计划中的错误",
+			UndefinedVariable:  "模板变量 {name} 未定义",
+			InvalidRoll:        "名册第 {line} 行的 {column} 无效",
+			InvalidOption:      "选项无效",
+			AlreadyVoted:       "您已经投过票了",
+			WriteInRequired:    "请填写自填选项的内容",
+			WriteInNotAllowed:  "只有自填选项可以填写内容",
+			InvalidVote:        "选票的 {field} 不符合投票方式 {mode} 的要求",
+			InvalidQuestion:    "第 {position} 个问题无效",
+			InvalidAnswer:      "问题 {question_id} 的回答无效",
+			AnswerRequired:     "问题 {question_id} 为必答题",
+			QuestionHidden:     "按之前的回答，问题 {question_id} 应被跳过",
+			AlreadyResponded:   "您已经提交过该调查问卷",
+			Unauthorized:       "管理令牌无效",
+			AdminDisabled:      "管理接口未启用",
+			ResultsHidden:      "该投票问卷的结果暂不公开",
+			ProposalsDisabled:  "该投票问卷不接受新选项提议",
+			AccessCodeInvalid:  "访问码不正确",
+			BallotInvalid:      "投票令牌无效或已被吊销",
+			NotEligible:        "您不在该投票问卷的投票人名册中",
+			NoActivePoll:       "当前没有进行中的投票问卷",
+			PollNotFound:       "投票问卷不存在",
+			VoteNotFound:       "没有找到您的投票记录",
+			TemplateNotFound:   "模板不存在",
+			WebhookNotFound:    "Webhook不存在",
+			DeliveryNotFound:   "投递记录不存在",
+			TokenNotFound:      "API令牌不存在",
+			ProposalNotFound:   "提议不存在",
+			SurveyNotFound:     "调查问卷不存在",
+			BallotNotFound:     "投票令牌不存在",
+			RollNotFound:       "投票人名册不存在",
+			DelegationNotFound: "没有找到您的委托",
+			PollClosed:         "投票问卷已关闭",
+			PollStateUnchanged: "投票问卷已经处于请求的状态",
+			VisibilityLocked:   "投票问卷开启期间不能放宽结果可见性",
+			ResultsSealed:      "投票问卷关闭前结果不公开",
+			DeliveryPending:    "该投递已在等待发送",
+			WebhookDeleted:     "Webhook已被删除",
+			OptionExists:       "已有相同的选项",
+			TooManyProposals:   "您的待审核提议过多，请等待管理员审核",
+			ProposalResolved:   "该提议已审核",
+			SurveyClosed:       "调查问卷已关闭",
+			SurveyUnchanged:    "调查问卷已经处于请求的状态",
+			BallotUsed:         "该投票令牌已被使用",
+			RollInUse:          "投票人名册正在被投票问卷使用",
+			DelegationCycle:    "该委托会形成循环：{delegate} 已直接或间接委托给您",
+			TallyOverflow:      "选项的加权票数或得分超出可以保存的范围",
+			Internal:           "服务器内部错误，请稍后重试",
+		},
+		rules: map[string]string{
+			"required":    "不能为空",
+			"min":         "不能小于{param}",
+			"min.len":     "长度不能少于{param}",
+			"min.items":   "至少需要{param}项",
+			"max":         "不能大于{param}",
+			"max.len":     "长度不能超过{param}",
+			"max.items":   "最多{param}项",
+			"len.items":   "必须为{param}项",
+			"url":         "必须是有效的URL",
+			"oneof":       "取值无效",
+			"type":        "类型不正确，应为{param}",
+			"not_found":   "引用的对象不存在",
+			"unsupported": "不支持该字段",
+			"duplicate":   "不能重复",
+			"invalid":     "取值无效",
+			"decimal":     "必须是正的十进制小数，最多9位整数和{param}位小数",
+		},
+	},
+	LanguageEnglish: {
+		codes: map[Code]string{
+			InvalidRequest:     "Malformed request body",
+			ValidationFailed:   "Request validation failed",
+			InvalidParameter:   "Invalid parameter: {name}",
+			InvalidTime:        "Parameter {name} must be an RFC3339 time",
+			InvalidTimeRange:   "from must be before to",
+			InvalidGranularity: "Granularity must be minute, hour or day",
+			TooManyBuckets:     "Too many buckets for the requested range, use a coarser granularity",
+			UnsupportedFormat:  "Unsupported format: {format}",
+			InvalidDocument:    "Invalid document",
+			PlanRejected:       "The document cannot be applied, see the errors in the plan",
+			UndefinedVariable:  "Undefined template variable: {name}",
+			InvalidRoll:        "Invalid {column} on line {line} of the voter roll",
+			InvalidOption:      "Invalid option",
+			AlreadyVoted:       "You have already voted",
+			WriteInRequired:    "Please fill in your write-in answer",
+			WriteInNotAllowed:  "Only the write-in option accepts text",
+			InvalidVote:        "The ballot's {field} does not fit the {mode} voting mode",
+			InvalidQuestion:    "Question {position} is invalid",
+			InvalidAnswer:      "Invalid answer to question {question_id}",
+			AnswerRequired:     "Question {question_id} requires an answer",
+			QuestionHidden:     "Question {question_id} is skipped by the earlier answers",
+			AlreadyResponded:   "You have already responded to this survey",
+			Unauthorized:       "Invalid admin token",
+			AdminDisabled:      "Admin API is disabled",
+			ResultsHidden:      "Results are hidden for this poll",
+			ProposalsDisabled:  "This poll does not accept option proposals",
+			AccessCodeInvalid:  "Invalid access code",
+			BallotInvalid:      "Invalid or revoked ballot token",
+			NotEligible:        "You are not on the voter roll for this poll",
+			NoActivePoll:       "No active poll found",
+			PollNotFound:       "Poll not found",
+			VoteNotFound:       "No vote found for this user",
+			TemplateNotFound:   "Template not found",
+			WebhookNotFound:    "Webhook not found",
+			DeliveryNotFound:   "Delivery not found",
+			TokenNotFound:      "Token not found",
+			ProposalNotFound:   "Proposal not found",
+			SurveyNotFound:     "Survey not found",
+			BallotNotFound:     "Ballot token not found",
+			RollNotFound:       "Voter roll not found",
+			DelegationNotFound: "No delegation found for this user",
+			PollClosed:         "Poll has closed",
+			PollStateUnchanged: "Poll is already in the requested state",
+			VisibilityLocked:   "Result visibility cannot be relaxed while the poll is open",
+			ResultsSealed:      "Results are hidden until the poll closes",
+			DeliveryPending:    "Delivery is already pending",
+			WebhookDeleted:     "Webhook has been deleted",
+			OptionExists:       "An option with the same text already exists",
+			TooManyProposals:   "You have too many proposals awaiting review",
+			ProposalResolved:   "This proposal has already been reviewed",
+			SurveyClosed:       "Survey has closed",
+			SurveyUnchanged:    "Survey is already in the requested state",
+			BallotUsed:         "This ballot token has already been used",
+			RollInUse:          "The voter roll is still attached to a poll",
+			DelegationCycle:    "This delegation would create a cycle: {delegate} already delegates to you directly or indirectly",
+			TallyOverflow:      "The option's weighted votes or points would exceed the supported range",
+			Internal:           "Internal server error, please try again later",
+		},
+		rules: map[string]string{
+			"required":    "is required",
+			"min":         "must be at least {param}",
+			"min.len":     "must be at least {param} characters long",
+			"min.items":   "must contain at least {param} items",
+			"max":         "must be at most {param}",
+			"max.len":     "must be at most {param} characters long",
+			"max.items":   "must contain at most {param} items",
+			"len.items":   "must contain exactly {param} items",
+			"url":         "must be a valid URL",
+			"oneof":       "has an invalid value",
+			"type":        "has the wrong type, expected {param}",
+			"not_found":   "refers to a record that does not exist",
+			"unsupported": "is not supported here",
+			"duplicate":   "must be unique",
+			"invalid":     "has an invalid value",
+			"decimal":     "must be a positive decimal with at most 9 integer and {param} fractional digits",
+		},
+	},
+}
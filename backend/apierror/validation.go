@@ -0,0 +1,98 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// 校验错误中的字段使用JSON字段名，与请求体保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// Bind 将请求体绑定错误转换为错误响应，校验失败时返回字段级详情，不暴露校验器的内部信息
+func Bind(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, fieldError(fe))
+		}
+		Invalid(c, details...)
+		return
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		Invalid(c, Field(typeErr.Field, "type", jsonType(typeErr.Type)))
+		return
+	}
+
+	Abort(c, InvalidRequest)
+}
+
+// Length 创建字符串长度相关的校验错误
+func Length(field, code, param string) FieldError {
+	return FieldError{Field: field, Code: code, Param: param, key: code + ".len"}
+}
+
+// Items 创建列表长度相关的校验错误
+func Items(field, code, param string) FieldError {
+	return FieldError{Field: field, Code: code, Param: param, key: code + ".items"}
+}
+
+// fieldError 转换单个校验错误，字段路径去掉最外层的结构体名
+func fieldError(fe validator.FieldError) FieldError {
+	field := fe.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	switch fe.Tag() {
+	case "min", "max", "len":
+		switch fe.Kind() {
+		case reflect.String:
+			return Length(field, fe.Tag(), fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return Items(field, fe.Tag(), fe.Param())
+		}
+	}
+	return Field(field, fe.Tag(), fe.Param())
+}
+
+// jsonType 返回Go类型对应的JSON类型名
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,90 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Prefix 令牌的固定前缀，便于在日志和密钥扫描中识别
+const Prefix = "vst_"
+
+// prefixLen 列表中展示的令牌前缀长度
+const prefixLen = 12
+
+// touchInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+const touchInterval = time.Minute
+
+// ErrInvalid 令牌不存在或已吊销
+var ErrInvalid = errors.New("invalid api token")
+
+// Hash 计算令牌的SHA-256，令牌本身是随机值，不需要加盐
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 生成新令牌并保存其哈希，明文只在此时返回
+func Create(tx *gorm.DB, name string) (string, models.APIToken, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", models.APIToken{}, err
+	}
+	raw := Prefix + hex.EncodeToString(buf)
+
+	token := models.APIToken{
+		Name:      name,
+		Prefix:    raw[:prefixLen],
+		TokenHash: Hash(raw),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return "", models.APIToken{}, err
+	}
+	return raw, token, nil
+}
+
+// Lookup 校验令牌并更新最近使用时间
+func Lookup(db *gorm.DB, raw string, now time.Time) (*models.APIToken, error) {
+	if !strings.HasPrefix(raw, Prefix) {
+		return nil, ErrInvalid
+	}
+
+	var token models.APIToken
+	if err := db.Where("token_hash = ?", Hash(raw)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalid
+		}
+		return nil, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= touchInterval {
+		token.LastUsedAt = &now
+		db.Model(&token).Update("last_used_at", now)
+	}
+	return &token, nil
+}
+
+// Authenticate 校验管理令牌或API令牌，返回审计日志中的操作人：配置的管理令牌记为admin，API令牌记为token:<名称>
+//
+// adminToken为空时只接受API令牌，db为nil时只接受管理令牌。
+func Authenticate(db *gorm.DB, adminToken, provided string) (string, bool) {
+	if provided == "" {
+		return "", false
+	}
+	if adminToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1 {
+		return "admin", true
+	}
+	if db != nil {
+		if t, err := Lookup(db, provided, time.Now()); err == nil {
+			return "token:" + t.Name, true
+		}
+	}
+	return "", false
+}
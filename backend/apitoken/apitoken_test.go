@@ -0,0 +1,53 @@
+package apitoken
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndLookup(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.APIToken{})
+
+	raw, token, err := Create(db, "ci")
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	if !strings.HasPrefix(raw, Prefix) || !strings.HasPrefix(raw, token.Prefix) {
+		t.Errorf("令牌前缀不正确: %s %s", raw, token.Prefix)
+	}
+	if token.TokenHash == raw || token.TokenHash != Hash(raw) {
+		t.Error("数据库中应只保存令牌的哈希")
+	}
+
+	now := time.Now()
+	found, err := Lookup(db, raw, now)
+	if err != nil || found.ID != token.ID {
+		t.Fatalf("期望找到令牌 %d, 得到 %+v %v", token.ID, found, err)
+	}
+	var stored models.APIToken
+	db.First(&stored, token.ID)
+	if stored.LastUsedAt == nil {
+		t.Error("应记录最近使用时间")
+	}
+
+	for _, invalid := range []string{"", "secret", Prefix + "0000"} {
+		if _, err := Lookup(db, invalid, now); err != ErrInvalid {
+			t.Errorf("%q: 期望 ErrInvalid, 得到 %v", invalid, err)
+		}
+	}
+
+	// 吊销后不可用
+	db.Delete(&stored)
+	if _, err := Lookup(db, raw, now); err != ErrInvalid {
+		t.Errorf("吊销的令牌期望 ErrInvalid, 得到 %v", err)
+	}
+}
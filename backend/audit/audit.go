@@ -0,0 +1,287 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// chainHeadID 链头记录固定使用的主键
+const chainHeadID = 1
+
+// Entry 待写入的审计事件
+type Entry struct {
+	Action    string
+	Actor     string
+	IP        string
+	RequestID string
+	PollID    *uint
+	Before    interface{}
+	After     interface{}
+}
+
+// Filter 审计日志查询条件
+type Filter struct {
+	Action   string
+	Actor    string
+	PollID   *uint
+	From     *time.Time
+	To       *time.Time
+	BeforeID uint
+	Limit    int
+}
+
+// VerifyResult 哈希链校验结果
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	BrokenAt uint64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PollSummary 生成用于审计前后对比的投票问卷摘要，结果对管理员不可见时不记录票数
+//
+// 单选以外的投票方式中各选项票数之和不是选票数，只记录各选项的票数和得分。
+func PollSummary(poll models.Poll) map[string]interface{} {
+	visible := poll.ResultsVisible(false, true)
+	options := make([]map[string]interface{}, 0, len(poll.Options))
+	total := 0
+	var totalWeight weight.Weight
+	for _, option := range poll.Options {
+		summary := map[string]interface{}{
+			"id":   option.ID,
+			"text": option.Text,
+		}
+		if option.WriteIn {
+			summary["write_in"] = true
+		}
+		if visible {
+			summary["vote_count"] = option.VoteCount
+			summary["weighted_votes"] = option.WeightedVotes
+			if !poll.Plurality() {
+				summary["points"] = option.Points
+			}
+		}
+		options = append(options, summary)
+		total += option.VoteCount
+		totalWeight += option.WeightedVotes
+	}
+
+	summary := map[string]interface{}{
+		"title":             poll.Title,
+		"description":       poll.Description,
+		"is_active":         poll.IsActive,
+		"result_visibility": poll.ResultVisibility,
+		"options":           options,
+	}
+	if visible && poll.Plurality() {
+		summary["total_votes"] = total
+		summary["total_weight"] = totalWeight
+	}
+	if len(poll.Tags) > 0 {
+		summary["tags"] = poll.Tags
+	}
+	if poll.AllowProposals {
+		summary["allow_proposals"] = true
+	}
+	if poll.Access != "" && poll.Access != models.AccessPublic {
+		summary["access"] = poll.Access
+	}
+	if poll.RollID != nil {
+		summary["roll_id"] = *poll.RollID
+	}
+	if !poll.Plurality() {
+		summary["mode"] = poll.Mode
+		if poll.MaxScore != 0 {
+			summary["max_score"] = poll.MaxScore
+		}
+	}
+	return summary
+}
+
+// Record 在给定事务中追加一条审计日志
+//
+// 先更新链头行取得行锁，再读取上一条记录的Hash，保证并发事务按顺序成链。
+func Record(tx *gorm.DB, entry Entry) error {
+	before, err := encode(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := encode(entry.After)
+	if err != nil {
+		return err
+	}
+
+	result := tx.Model(&models.AuditChainHead{}).Where("id = ?", chainHeadID).Update("seq", gorm.Expr("seq + ?", 1))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := tx.Create(&models.AuditChainHead{ID: chainHeadID, Seq: 1}).Error; err != nil {
+			return err
+		}
+	}
+
+	var head models.AuditChainHead
+	if err := tx.First(&head, chainHeadID).Error; err != nil {
+		return err
+	}
+
+	log := models.AuditLog{
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Seq:       head.Seq,
+		Action:    entry.Action,
+		Actor:     entry.Actor,
+		IP:        entry.IP,
+		RequestID: entry.RequestID,
+		PollID:    entry.PollID,
+		Before:    before,
+		After:     after,
+		PrevHash:  head.LastHash,
+	}
+	log.Hash = Hash(log)
+
+	if err := tx.Create(&log).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&head).Update("last_hash", log.Hash).Error
+}
+
+// Hash 计算审计日志的哈希值，覆盖除ID和Hash以外的所有字段
+func Hash(log models.AuditLog) string {
+	pollID := ""
+	if log.PollID != nil {
+		pollID = strconv.FormatUint(uint64(*log.PollID), 10)
+	}
+
+	fields := []string{
+		strconv.FormatUint(log.Seq, 10),
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		log.Action,
+		log.Actor,
+		log.IP,
+		log.RequestID,
+		pollID,
+		log.Before,
+		log.After,
+		log.PrevHash,
+	}
+
+	// 各字段先做长度前缀，避免拼接歧义
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(strconv.Itoa(len(f)))
+		b.WriteByte(':')
+		b.WriteString(f)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Query 按条件查询审计日志，按ID倒序分页
+func Query(db *gorm.DB, filter Filter) ([]models.AuditLog, error) {
+	query := db.Model(&models.AuditLog{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.PollID != nil {
+		query = query.Where("poll_id = ?", *filter.PollID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", filter.To.UTC())
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	var logs []models.AuditLog
+	err := query.Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// Verify 按顺序重新计算哈希链，返回第一处被篡改或断链的位置
+func Verify(db *gorm.DB) (*VerifyResult, error) {
+	result := &VerifyResult{Valid: true}
+	prevHash := ""
+	var expectedSeq uint64 = 1
+
+	rows, err := db.Model(&models.AuditLog{}).Order("seq").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() && result.Valid {
+		var log models.AuditLog
+		if err := db.ScanRows(rows, &log); err != nil {
+			return nil, err
+		}
+		result.Entries++
+
+		switch {
+		case log.Seq != expectedSeq:
+			result.fail(expectedSeq, "missing entry")
+		case log.PrevHash != prevHash:
+			result.fail(log.Seq, "previous hash mismatch")
+		case Hash(log) != log.Hash:
+			result.fail(log.Seq, "entry hash mismatch")
+		}
+
+		prevHash = log.Hash
+		expectedSeq = log.Seq + 1
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// 链头记录的最后一个Hash也必须与最后一条日志一致，防止截断末尾
+	if result.Valid {
+		var head models.AuditChainHead
+		if err := db.Where("id = ?", chainHeadID).Limit(1).Find(&head).Error; err != nil {
+			return nil, err
+		}
+		if head.LastHash != prevHash {
+			result.fail(expectedSeq, "chain head mismatch")
+		}
+	}
+
+	return result, nil
+}
+
+func (r *VerifyResult) fail(seq uint64, reason string) {
+	r.Valid = false
+	r.BrokenAt = seq
+	r.Reason = reason
+}
+
+func encode(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"testing"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.AuditLog{}, &models.AuditChainHead{})
+	return db
+}
+
+func record(t *testing.T, db *gorm.DB, action string, pollID uint) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return Record(tx, Entry{
+			Action: action,
+			Actor:  "admin",
+			IP:     "127.0.0.1",
+			PollID: &pollID,
+			Before: map[string]int{"total_votes": 3},
+			After:  map[string]int{"total_votes": 0},
+		})
+	})
+	if err != nil {
+		t.Fatalf("写入审计日志失败: %v", err)
+	}
+}
+
+func TestRecordBuildsChain(t *testing.T) {
+	db := setupTestDB()
+
+	record(t, db, models.AuditPollCreated, 1)
+	record(t, db, models.AuditPollReset, 1)
+	record(t, db, models.AuditPollClosed, 2)
+
+	var logs []models.AuditLog
+	db.Order("seq").Find(&logs)
+	if len(logs) != 3 {
+		t.Fatalf("期望3条审计日志, 得到 %d", len(logs))
+	}
+
+	if logs[0].PrevHash != "" || logs[0].Seq != 1 {
+		t.Errorf("第一条日志应为链首: %+v", logs[0])
+	}
+
+	for i := 1; i < len(logs); i++ {
+		if logs[i].PrevHash != logs[i-1].Hash {
+			t.Errorf("第 %d 条日志未链接到上一条", i+1)
+		}
+		if logs[i].Seq != logs[i-1].Seq+1 {
+			t.Errorf("序号不连续: %d -> %d", logs[i-1].Seq, logs[i].Seq)
+		}
+	}
+
+	if logs[1].Before != `{"total_votes":3}` || logs[1].After != `{"total_votes":0}` {
+		t.Errorf("前后摘要不正确: %s / %s", logs[1].Before, logs[1].After)
+	}
+
+	result, err := Verify(db)
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if !result.Valid || result.Entries != 3 {
+		t.Errorf("完整的哈希链应校验通过: %+v", result)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	db := setupTestDB()
+
+	record(t, db, models.AuditPollCreated, 1)
+	record(t, db, models.AuditPollReset, 1)
+	record(t, db, models.AuditPollClosed, 1)
+
+	// 篡改第二条日志的操作人
+	db.Model(&models.AuditLog{}).Where("seq = ?", 2).Update("actor", "someone-else")
+
+	result, err := Verify(db)
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if result.Valid || result.BrokenAt != 2 {
+		t.Errorf("期望在第2条检测到篡改, 得到 %+v", result)
+	}
+}
+
+func TestVerifyDetectsDeletion(t *testing.T) {
+	db := setupTestDB()
+
+	record(t, db, models.AuditPollCreated, 1)
+	record(t, db, models.AuditPollReset, 1)
+	record(t, db, models.AuditPollClosed, 1)
+
+	db.Where("seq = ?", 2).Delete(&models.AuditLog{})
+
+	result, _ := Verify(db)
+	if result.Valid || result.BrokenAt != 2 {
+		t.Errorf("期望检测到第2条被删除, 得到 %+v", result)
+	}
+
+	// 删除末尾的日志也应被发现
+	db2 := setupTestDB()
+	record(t, db2, models.AuditPollCreated, 1)
+	record(t, db2, models.AuditPollReset, 1)
+	db2.Where("seq = ?", 2).Delete(&models.AuditLog{})
+
+	result, _ = Verify(db2)
+	if result.Valid {
+		t.Error("截断末尾日志应校验失败")
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	db := setupTestDB()
+
+	record(t, db, models.AuditPollCreated, 1)
+	record(t, db, models.AuditPollReset, 1)
+	record(t, db, models.AuditPollReset, 2)
+
+	logs, err := Query(db, Filter{Action: models.AuditPollReset})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("期望2条重置日志, 得到 %d", len(logs))
+	}
+
+	pollID := uint(1)
+	logs, _ = Query(db, Filter{PollID: &pollID})
+	if len(logs) != 2 {
+		t.Errorf("期望投票问卷1有2条日志, 得到 %d", len(logs))
+	}
+
+	logs, _ = Query(db, Filter{Limit: 1})
+	if len(logs) != 1 || logs[0].Seq != 3 {
+		t.Fatalf("期望返回最新一条日志, 得到 %+v", logs)
+	}
+
+	logs, _ = Query(db, Filter{BeforeID: logs[0].ID})
+	if len(logs) != 2 {
+		t.Errorf("翻页后期望2条日志, 得到 %d", len(logs))
+	}
+}
@@ -0,0 +1,214 @@
+// Package ballot 私有投票问卷的访问码和邀请制投票问卷的一次性投票令牌
+//
+// 投票令牌为 "vbt_" 加随机数和用投票问卷密钥计算的HMAC签名，签名不符的令牌不查询数据库。
+// 数据库中只保存令牌的SHA-256，使用后只标记为已使用，不记录使用时间，投票记录中也不保存令牌，
+// 因此可以统计哪些受邀人已投票，但不能知道他们投给了哪个选项。
+package ballot
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"time"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// Prefix 投票令牌的固定前缀
+const Prefix = "vbt_"
+
+// prefixLen 列表中展示的令牌前缀长度
+const prefixLen = 12
+
+// signatureLen 令牌中签名的十六进制长度
+const signatureLen = 32
+
+var (
+	// ErrInvalid 令牌格式或签名不正确、不属于该投票问卷或已被吊销
+	ErrInvalid = errors.New("invalid ballot token")
+	// ErrUsed 令牌已被使用
+	ErrUsed = errors.New("ballot token already used")
+)
+
+// Hash 计算访问码或令牌的SHA-256
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckCode 判断访问码是否与投票问卷的访问码一致，投票问卷没有设置访问码时始终不一致
+func CheckCode(poll models.Poll, code string) bool {
+	if poll.AccessCodeHash == "" || code == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(Hash(code)), []byte(poll.AccessCodeHash)) == 1
+}
+
+// Issued 新生成的投票令牌，明文只在生成时返回
+type Issued struct {
+	models.BallotToken
+	Token string `json:"token"`
+	// URL 附加了令牌的投票页面地址
+	URL string `json:"url"`
+}
+
+// Issue 在事务中为投票问卷的每个受邀人生成一个令牌，labels为受邀人备注，weights为对应的投票权重
+//
+// 投票问卷还没有签名密钥时先生成并保存。
+func Issue(tx *gorm.DB, poll *models.Poll, labels []string, weights []weight.Weight) ([]Issued, error) {
+	if poll.BallotSecret == "" {
+		secret, err := random(32)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Model(poll).Update("ballot_secret", secret).Error; err != nil {
+			return nil, err
+		}
+		poll.BallotSecret = secret
+	}
+
+	issued := make([]Issued, 0, len(labels))
+	tokens := make([]models.BallotToken, 0, len(labels))
+	for i, label := range labels {
+		nonce, err := random(16)
+		if err != nil {
+			return nil, err
+		}
+		raw := Prefix + nonce + "." + sign(poll.BallotSecret, nonce)
+		issued = append(issued, Issued{Token: raw})
+		tokens = append(tokens, models.BallotToken{
+			PollID:    poll.ID,
+			Label:     strings.TrimSpace(label),
+			Prefix:    raw[:prefixLen],
+			TokenHash: Hash(raw),
+			Weight:    weights[i],
+		})
+	}
+	if err := tx.CreateInBatches(&tokens, 500).Error; err != nil {
+		return nil, err
+	}
+	for i := range issued {
+		issued[i].BallotToken = tokens[i]
+	}
+	return issued, nil
+}
+
+// Verify 校验令牌的签名并读取令牌记录，已使用的令牌也会返回，由调用方判断
+func Verify(db *gorm.DB, poll models.Poll, raw string) (*models.BallotToken, error) {
+	nonce, signature, ok := strings.Cut(strings.TrimPrefix(raw, Prefix), ".")
+	if !strings.HasPrefix(raw, Prefix) || !ok || poll.BallotSecret == "" ||
+		!hmac.Equal([]byte(signature), []byte(sign(poll.BallotSecret, nonce))) {
+		return nil, ErrInvalid
+	}
+
+	var token models.BallotToken
+	if err := db.Where("token_hash = ? AND poll_id = ?", Hash(raw), poll.ID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalid
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, ErrInvalid
+	}
+	return &token, nil
+}
+
+// Use 在投票的事务中把令牌标记为已使用，并发使用同一令牌时只有一方成功
+func Use(tx *gorm.DB, token *models.BallotToken) error {
+	result := tx.Model(&models.BallotToken{}).
+		Where("id = ? AND used = ? AND revoked_at IS NULL", token.ID, false).
+		Update("used", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUsed
+	}
+	token.Used = true
+	return nil
+}
+
+// Revoke 吊销未使用的令牌，已使用的令牌返回ErrUsed，已吊销的令牌保持不变
+func Revoke(tx *gorm.DB, token *models.BallotToken, now time.Time) error {
+	if token.Used {
+		return ErrUsed
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+	result := tx.Model(&models.BallotToken{}).
+		Where("id = ? AND used = ?", token.ID, false).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUsed
+	}
+	token.RevokedAt = &now
+	return nil
+}
+
+// Turnout 投票问卷按令牌统计的投票率，Percentage相对于未吊销的令牌数
+type Turnout struct {
+	Issued     int     `json:"issued"`
+	Used       int     `json:"used"`
+	Revoked    int     `json:"revoked"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Count 统计投票问卷的令牌数和投票率
+func Count(db *gorm.DB, pollID uint) (Turnout, error) {
+	var rows []struct {
+		Used    bool
+		Revoked bool
+		Count   int
+	}
+	err := db.Model(&models.BallotToken{}).
+		Select("used, revoked_at IS NOT NULL AS revoked, COUNT(*) AS count").
+		Where("poll_id = ?", pollID).
+		Group("used, revoked_at IS NOT NULL").
+		Scan(&rows).Error
+	if err != nil {
+		return Turnout{}, err
+	}
+
+	var turnout Turnout
+	for _, row := range rows {
+		turnout.Issued += row.Count
+		switch {
+		case row.Used:
+			turnout.Used += row.Count
+		case row.Revoked:
+			turnout.Revoked += row.Count
+		}
+	}
+	if valid := turnout.Issued - turnout.Revoked; valid > 0 {
+		turnout.Percentage = math.Round(float64(turnout.Used)*10000/float64(valid)) / 100
+	}
+	return turnout, nil
+}
+
+// sign 计算随机数的HMAC签名
+func sign(secret, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))[:signatureLen]
+}
+
+// random 生成n字节的随机数，以十六进制返回
+func random(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,107 @@
+package ballot
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.BallotToken{})
+	return db
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	db := setupTestDB(t)
+	poll := models.Poll{Title: "理事会选举", IsActive: true, Access: models.AccessInvite}
+	db.Create(&poll)
+
+	issued, err := Issue(db, &poll, []string{" 张三 ", "李四"}, []weight.Weight{weight.One, 2500000})
+	if err != nil || len(issued) != 2 {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	if poll.BallotSecret == "" {
+		t.Fatal("第一次生成令牌时应创建签名密钥")
+	}
+	first := issued[0]
+	if first.ID == 0 || first.Label != "张三" || !strings.HasPrefix(first.Token, Prefix) || !strings.HasPrefix(first.Token, first.Prefix) {
+		t.Errorf("令牌不正确: %+v", first)
+	}
+	if first.TokenHash != Hash(first.Token) {
+		t.Error("数据库中应只保存令牌的哈希")
+	}
+
+	// 再次生成时沿用已有的密钥
+	secret := poll.BallotSecret
+	if _, err := Issue(db, &poll, []string{""}, []weight.Weight{weight.One}); err != nil || poll.BallotSecret != secret {
+		t.Errorf("再次生成令牌不应更换密钥: %v", err)
+	}
+
+	token, err := Verify(db, poll, first.Token)
+	if err != nil || token.ID != first.ID {
+		t.Fatalf("期望校验通过, 得到 %+v %v", token, err)
+	}
+
+	other := models.Poll{Title: "其他投票", IsActive: true, Access: models.AccessInvite}
+	db.Create(&other)
+	Issue(db, &other, []string{"王五"}, []weight.Weight{weight.One})
+	forged := first.Token[:len(first.Token)-1] + "0"
+	if strings.HasSuffix(first.Token, "0") {
+		forged = first.Token[:len(first.Token)-1] + "1"
+	}
+	for _, raw := range []string{"", "vbt_", first.Token + "x", forged, strings.TrimPrefix(first.Token, Prefix)} {
+		if _, err := Verify(db, poll, raw); err != ErrInvalid {
+			t.Errorf("%q: 期望 ErrInvalid, 得到 %v", raw, err)
+		}
+	}
+	if _, err := Verify(db, other, first.Token); err != ErrInvalid {
+		t.Errorf("其他投票问卷的令牌期望 ErrInvalid, 得到 %v", err)
+	}
+
+	if err := Use(db, token); err != nil || !token.Used {
+		t.Fatalf("使用令牌失败: %v", err)
+	}
+	if err := Use(db, token); err != ErrUsed {
+		t.Errorf("重复使用期望 ErrUsed, 得到 %v", err)
+	}
+	if err := Revoke(db, token, time.Now()); err != ErrUsed {
+		t.Errorf("吊销已使用的令牌期望 ErrUsed, 得到 %v", err)
+	}
+
+	second, _ := Verify(db, poll, issued[1].Token)
+	if err := Revoke(db, second, time.Now()); err != nil {
+		t.Fatalf("吊销令牌失败: %v", err)
+	}
+	if _, err := Verify(db, poll, issued[1].Token); err != ErrInvalid {
+		t.Errorf("吊销的令牌期望 ErrInvalid, 得到 %v", err)
+	}
+
+	turnout, err := Count(db, poll.ID)
+	if err != nil || turnout != (Turnout{Issued: 3, Used: 1, Revoked: 1, Percentage: 50}) {
+		t.Errorf("投票率不正确: %+v %v", turnout, err)
+	}
+}
+
+func TestCheckCode(t *testing.T) {
+	poll := models.Poll{Access: models.AccessPrivate, AccessCodeHash: Hash("spring-2024")}
+	if !CheckCode(poll, "spring-2024") {
+		t.Error("正确的访问码应通过")
+	}
+	for _, code := range []string{"", "spring", "SPRING-2024"} {
+		if CheckCode(poll, code) {
+			t.Errorf("访问码 %q 不应通过", code)
+		}
+	}
+	if CheckCode(models.Poll{}, "") {
+		t.Error("没有设置访问码时不应通过")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client 管理接口的HTTP客户端
+type Client struct {
+	// Server 服务地址，例如 http://localhost:8080
+	Server string
+	Token  string
+	// Actor 通过X-Actor写入审计日志的操作人
+	Actor string
+	// Language 通过Accept-Language请求的错误信息语言
+	Language string
+	HTTP     *http.Client
+}
+
+// APIError 管理接口返回的错误
+type APIError struct {
+	Status int
+	// Code 机器可读的错误码，例如 poll_not_found
+	Code    string
+	Message string
+	Details []FieldError
+}
+
+// FieldError 字段级的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	for _, detail := range e.Details {
+		msg += fmt.Sprintf("\n  %s: %s", detail.Field, detail.Message)
+	}
+	return msg
+}
+
+// NewClient 创建HTTP客户端
+func NewClient(server, token, actor string) *Client {
+	return &Client{
+		Server: strings.TrimRight(server, "/"),
+		Token:  token,
+		Actor:  actor,
+		HTTP:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Do 调用管理接口，body不为nil时以JSON发送，out不为nil时解析JSON响应
+func (c *Client) Do(method, path string, body, out interface{}) error {
+	resp, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Stream 调用管理接口并将响应体原样写入w，用于导出文件
+func (c *Client) Stream(method, path string, w io.Writer) error {
+	resp, err := c.send(method, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// send 发送请求，非2xx响应转换为APIError
+func (c *Client) send(method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.Server+"/api/admin"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if c.Actor != "" {
+		req.Header.Set("X-Actor", c.Actor)
+	}
+	if c.Language != "" {
+		req.Header.Set("Accept-Language", c.Language)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	var payload struct {
+		Code    string       `json:"code"`
+		Error   string       `json:"error"`
+		Details []FieldError `json:"details"`
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(data, &payload) != nil || payload.Error == "" {
+		payload.Error = strings.TrimSpace(string(data))
+	}
+	return nil, &APIError{Status: resp.StatusCode, Code: payload.Code, Message: payload.Error, Details: payload.Details}
+}
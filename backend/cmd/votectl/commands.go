@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"vote-system/models"
+	"vote-system/stats"
+	"vote-system/weight"
+)
+
+// Run 执行子命令
+func (c *CLI) Run(name string, args []string) error {
+	switch name {
+	case "list":
+		return c.list(args)
+	case "show":
+		return c.show(args)
+	case "create":
+		return c.create(args)
+	case "open", "close":
+		return c.setState(name, args)
+	case "reset":
+		return c.reset(args)
+	case "watch":
+		return c.watch(args)
+	case "export":
+		return c.export(args)
+	case "reconcile":
+		return c.reconcile(args)
+	case "tokens":
+		return c.tokens(args)
+	}
+	return fmt.Errorf("unknown command: %s", name)
+}
+
+// stringList 可重复的字符串参数
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// parseArgs 解析子命令参数，允许位置参数和选项交替出现，返回位置参数
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// pollID 解析唯一的位置参数为投票问卷ID
+func pollID(name string, positional []string) (uint, error) {
+	if len(positional) != 1 {
+		return 0, fmt.Errorf("usage: votectl %s <id>", name)
+	}
+	id, err := strconv.ParseUint(positional[0], 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%s: invalid poll id %q", name, positional[0])
+	}
+	return uint(id), nil
+}
+
+// call 调用管理接口，-json时原样输出响应，否则解析到out交给print输出
+func (c *CLI) call(method, path string, body, out interface{}, print func()) error {
+	var raw json.RawMessage
+	if err := c.Client.Do(method, path, body, &raw); err != nil {
+		return err
+	}
+
+	if c.JSON {
+		var buf bytes.Buffer
+		json.Indent(&buf, raw, "", "  ")
+		buf.WriteByte('\n')
+		_, err := buf.WriteTo(c.Out)
+		return err
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return err
+		}
+	}
+	if print != nil {
+		print()
+	}
+	return nil
+}
+
+// pollList 管理接口的投票问卷列表响应
+type pollList struct {
+	Polls      []models.Poll `json:"polls"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// listPolls 按query中的条件读取投票问卷，沿next_cursor读取所有分页
+func (c *CLI) listPolls(query url.Values) ([]models.Poll, error) {
+	polls := []models.Poll{}
+	for {
+		var page pollList
+		if err := c.Client.Do("GET", "/polls?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		polls = append(polls, page.Polls...)
+		if page.NextCursor == "" {
+			return polls, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+func (c *CLI) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	query := url.Values{}
+	for _, name := range []string{"state", "tag", "owner", "q", "sort", "order"} {
+		fs.Func(name, "filter or sort polls by "+name+", see GET /api/admin/polls", func(v string) error {
+			query.Set(name, v)
+			return nil
+		})
+	}
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	polls, err := c.listPolls(query)
+	if err != nil {
+		return err
+	}
+
+	if c.JSON {
+		data, err := json.MarshalIndent(pollList{Polls: polls}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.Out, "%s\n", data)
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSLUG\tTITLE\tSTATUS\tVOTES\tVISIBILITY\tTAGS")
+	for _, poll := range polls {
+		slug := "-"
+		if poll.Slug != nil {
+			slug = *poll.Slug
+		}
+		tags := "-"
+		if len(poll.Tags) > 0 {
+			tags = strings.Join(poll.Tags, ",")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", poll.ID, slug, poll.Title, status(poll), totalVotes(poll), poll.ResultVisibility, tags)
+	}
+	return w.Flush()
+}
+
+func (c *CLI) show(args []string) error {
+	id, err := pollID("show", args)
+	if err != nil {
+		return err
+	}
+
+	var poll models.Poll
+	return c.call("GET", fmt.Sprintf("/polls/%d", id), nil, &poll, func() {
+		printResults(c.Out, poll)
+	})
+}
+
+func (c *CLI) create(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	title := fs.String("title", "", "poll title")
+	description := fs.String("description", "", "poll description")
+	visibility := fs.String("visibility", "", "result visibility: always, after_vote, after_close or admin_only")
+	inactive := fs.Bool("inactive", false, "create the poll closed")
+	var options stringList
+	fs.Var(&options, "option", "option text, repeat for each option")
+	var tags stringList
+	fs.Var(&tags, "tag", "poll tag, repeat for each tag")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	active := !*inactive
+	req := models.CreatePollRequest{
+		Title:            *title,
+		Description:      *description,
+		Options:          options,
+		Tags:             tags,
+		IsActive:         &active,
+		ResultVisibility: *visibility,
+	}
+
+	var poll models.Poll
+	return c.call("POST", "/polls", req, &poll, func() {
+		fmt.Fprintf(c.Out, "Created poll %d\n", poll.ID)
+	})
+}
+
+func (c *CLI) setState(name string, args []string) error {
+	id, err := pollID(name, args)
+	if err != nil {
+		return err
+	}
+
+	var poll models.Poll
+	return c.call("POST", fmt.Sprintf("/polls/%d/%s", id, name), nil, &poll, func() {
+		fmt.Fprintf(c.Out, "Poll %d is now %s\n", poll.ID, status(poll))
+	})
+}
+
+func (c *CLI) reset(args []string) error {
+	fs := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm deleting all votes")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := pollID("reset", positional)
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		return errors.New("reset deletes all votes of the poll, pass -yes to confirm")
+	}
+
+	return c.call("POST", fmt.Sprintf("/polls/%d/reset", id), nil, nil, func() {
+		fmt.Fprintf(c.Out, "Poll %d reset\n", id)
+	})
+}
+
+func (c *CLI) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "export format: csv, json or xlsx")
+	votes := fs.Bool("votes", false, "include per-vote raw records")
+	output := fs.String("o", "", "output file (default stdout)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := pollID("export", positional)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {*format}}
+	if *votes {
+		query.Set("votes", "true")
+	}
+
+	var w io.Writer = c.Out
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return c.Client.Stream("GET", fmt.Sprintf("/polls/%d/export?%s", id, query.Encode()), w)
+}
+
+// reconcileResult 修正接口的响应
+type reconcileResult struct {
+	PollID        uint          `json:"poll_id"`
+	DryRun        bool          `json:"dry_run"`
+	Drift         []stats.Drift `json:"drift"`
+	ResultsHidden bool          `json:"results_hidden"`
+	OptionIDs     []uint        `json:"option_ids"`
+}
+
+func (c *CLI) reconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only report mismatched vote counts")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// 未指定ID时修正所有投票问卷
+	var ids []uint
+	if len(positional) > 0 {
+		id, err := pollID("reconcile", positional)
+		if err != nil {
+			return err
+		}
+		ids = []uint{id}
+	} else {
+		polls, err := c.listPolls(url.Values{"sort": {"created_at"}, "order": {"asc"}})
+		if err != nil {
+			return err
+		}
+		for _, poll := range polls {
+			ids = append(ids, poll.ID)
+		}
+	}
+
+	for _, id := range ids {
+		path := fmt.Sprintf("/polls/%d/reconcile", id)
+		if *dryRun {
+			path += "?dry_run=true"
+		}
+
+		var result reconcileResult
+		err := c.call("POST", path, nil, &result, func() {
+			if len(result.Drift) == 0 && len(result.OptionIDs) == 0 {
+				fmt.Fprintf(c.Out, "Poll %d: ok\n", id)
+				return
+			}
+			verb := "fixed"
+			if result.DryRun {
+				verb = "mismatch"
+			}
+			// 结果对管理员隐藏时只有选项ID
+			if result.ResultsHidden {
+				for _, optionID := range result.OptionIDs {
+					fmt.Fprintf(c.Out, "Poll %d: %s option %d (counts hidden)\n", id, verb, optionID)
+				}
+				return
+			}
+			for _, d := range result.Drift {
+				fmt.Fprintf(c.Out, "Poll %d: %s option %d %q: %d -> %d\n", id, verb, d.OptionID, d.Text, d.Stored, d.Actual)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("poll %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (c *CLI) tokens(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: votectl tokens list|create <name>|revoke <id>")
+	}
+
+	switch args[0] {
+	case "list":
+		var resp struct {
+			Tokens []models.APIToken `json:"tokens"`
+		}
+		return c.call("GET", "/tokens", nil, &resp, func() {
+			w := tabwriter.NewWriter(c.Out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tPREFIX\tCREATED\tLAST USED")
+			for _, t := range resp.Tokens {
+				lastUsed := "never"
+				if t.LastUsedAt != nil {
+					lastUsed = t.LastUsedAt.Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Prefix, t.CreatedAt.Format("2006-01-02 15:04"), lastUsed)
+			}
+			w.Flush()
+		})
+
+	case "create":
+		if len(args) != 2 {
+			return errors.New("usage: votectl tokens create <name>")
+		}
+		var resp struct {
+			Token  models.APIToken `json:"token"`
+			Secret string          `json:"secret"`
+		}
+		return c.call("POST", "/tokens", map[string]string{"name": args[1]}, &resp, func() {
+			fmt.Fprintf(c.Out, "Created token %d (%s). It is shown only once:\n%s\n", resp.Token.ID, resp.Token.Name, resp.Secret)
+		})
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: votectl tokens revoke <id>")
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("tokens revoke: invalid token id %q", args[1])
+		}
+		return c.call("DELETE", fmt.Sprintf("/tokens/%d", id), nil, nil, func() {
+			fmt.Fprintf(c.Out, "Revoked token %d\n", id)
+		})
+	}
+	return fmt.Errorf("unknown tokens command: %s", args[0])
+}
+
+// status 投票问卷的状态，关闭时附带原因
+func status(poll models.Poll) string {
+	if poll.IsActive {
+		return "open"
+	}
+	if poll.CloseReason != "" {
+		return "closed (" + poll.CloseReason + ")"
+	}
+	return "closed"
+}
+
+// totalVotes 总票数，结果隐藏时返回hidden
+func totalVotes(poll models.Poll) string {
+	if poll.ResultsHidden {
+		return "hidden"
+	}
+	total := 0
+	for _, option := range poll.Options {
+		total += option.VoteCount
+	}
+	return strconv.Itoa(total)
+}
+
+// printResults 输出投票问卷的当前结果
+func printResults(w io.Writer, poll models.Poll) {
+	fmt.Fprintf(w, "#%d %s [%s]\n", poll.ID, poll.Title, status(poll))
+	if poll.ResultsHidden {
+		fmt.Fprintf(w, "  results hidden (%s)\n", poll.ResultVisibility)
+		return
+	}
+
+	// 评分投票和波达计数按得分排名，显示标记的选票数、得分和平均得分
+	if poll.Mode == models.ModeScore || poll.Mode == models.ModeBorda {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, option := range poll.Options {
+			average := "-"
+			if option.Average != nil {
+				average = fmt.Sprintf("%.2f", *option.Average)
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t%s pts\tavg %s\n", option.Text, option.VoteCount, option.Points, average)
+		}
+		tw.Flush()
+		fmt.Fprintf(w, "  mode: %s\n", poll.Mode)
+		return
+	}
+
+	total := 0
+	var totalWeight weight.Weight
+	for _, option := range poll.Options {
+		total += option.VoteCount
+		totalWeight += option.WeightedVotes
+	}
+	// 使用了权重时增加加权票数一列，百分比按加权票数计算；旧版本的服务没有加权票数
+	weighted := totalWeight != 0 && totalWeight != weight.Of(total)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, option := range poll.Options {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(option.VoteCount) * 100 / float64(total)
+		}
+		if weighted {
+			percent = weight.Percentage(option.WeightedVotes, totalWeight)
+			fmt.Fprintf(tw, "  %s\t%d\t%s\t%5.1f%%\t%s\n", option.Text, option.VoteCount, option.WeightedVotes, percent, strings.Repeat("#", int(percent/5)))
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%5.1f%%\t%s\n", option.Text, option.VoteCount, percent, strings.Repeat("#", int(percent/5)))
+	}
+	tw.Flush()
+	if weighted {
+		fmt.Fprintf(w, "  total: %d (weighted %s)\n", total, totalWeight)
+		return
+	}
+	fmt.Fprintf(w, "  total: %d\n", total)
+}
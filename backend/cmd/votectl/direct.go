@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"vote-system/config"
+	"vote-system/database"
+	"vote-system/handlers"
+	"vote-system/privacy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// handlerTransport 在进程内调用handler，不经过网络
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+// NewDirectClient 直连数据库的客户端，用于服务不可用时的紧急操作
+//
+// 请求在进程内由与服务相同的管理接口处理，校验、审计日志和outbox事件与通过服务操作一致；
+// 事件由服务的分发器在下一次轮询时广播。
+func NewDirectClient(cfg *config.Config, actor string) (*Client, error) {
+	db, err := database.Init(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect database: %w", err)
+	}
+
+	var hasher *privacy.Hasher
+	if cfg.PrivacyMode {
+		keys, err := privacy.ParseKeys(cfg.VoterKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VOTER_HMAC_KEYS: %w", err)
+		}
+		hasher = privacy.NewHasher(keys)
+	}
+
+	// 进程内使用一次性令牌，不依赖配置的管理令牌
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(buf)
+
+	client := NewClient("http://votectl.direct", token, actor)
+	client.HTTP = &http.Client{Transport: handlerTransport{handler: directRouter(db, hasher, token)}}
+	return client, nil
+}
+
+// directRouter 构建只包含管理接口的路由，没有WebSocket Hub和分发器
+func directRouter(db *gorm.DB, hasher *privacy.Hasher, token string) http.Handler {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(handlers.RequestID())
+
+	admin := r.Group("/api/admin", handlers.AdminAuth(token, nil))
+	handlers.RegisterAdminRoutes(admin,
+		handlers.NewPollHandler(db, nil, nil, hasher),
+		handlers.NewSurveyHandler(db, nil, hasher),
+		handlers.NewAuditHandler(db),
+		handlers.NewWebhookHandler(db, hasher),
+		handlers.NewTokenHandler(db, hasher),
+	)
+	return r
+}
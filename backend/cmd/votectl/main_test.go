@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupCLI 使用内存数据库和进程内路由的CLI
+func setupCLI(t *testing.T) (*CLI, *bytes.Buffer, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.PollTemplate{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.OptionProposal{}, &models.BallotToken{}, &models.VoterRoll{}, &models.RollMember{}, &models.Delegation{}, &models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{}, &models.VoteRollup{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookThreshold{}, &models.APIToken{})
+
+	client := NewClient("http://votectl.test", "secret", "alice")
+	client.HTTP = &http.Client{Transport: handlerTransport{handler: directRouter(db, nil, "secret")}}
+
+	var out bytes.Buffer
+	return &CLI{Client: client, Out: &out, Direct: true}, &out, db
+}
+
+func TestCLI_PollLifecycle(t *testing.T) {
+	cli, out, db := setupCLI(t)
+
+	steps := []struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{"create", []string{"-title", "午餐吃什么", "-option", "面", "-option", "饭"}, "Created poll 1"},
+		{"list", nil, "午餐吃什么"},
+		{"close", []string{"1"}, "Poll 1 is now closed (manual)"},
+		{"open", []string{"1"}, "Poll 1 is now open"},
+		{"show", []string{"1"}, "total: 0"},
+		{"reconcile", []string{"-dry-run"}, "Poll 1: ok"},
+		{"reset", []string{"1", "-yes"}, "Poll 1 reset"},
+	}
+	for _, step := range steps {
+		out.Reset()
+		if err := cli.Run(step.name, step.args); err != nil {
+			t.Fatalf("%s: 执行失败: %v", step.name, err)
+		}
+		if !strings.Contains(out.String(), step.expect) {
+			t.Errorf("%s: 期望输出包含 %q, 得到 %q", step.name, step.expect, out.String())
+		}
+	}
+
+	// 审计日志记录操作人
+	var entry models.AuditLog
+	db.Where("action = ?", models.AuditPollClosed).First(&entry)
+	if entry.Actor != "admin:alice" {
+		t.Errorf("期望操作人 admin:alice, 得到 %q", entry.Actor)
+	}
+
+	// 接口错误原样返回
+	err := cli.Run("close", []string{"42"})
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Status != http.StatusNotFound || apiErr.Code != "poll_not_found" {
+		t.Errorf("期望404错误, 得到 %v", err)
+	}
+	if err := cli.Run("reset", []string{"1"}); err == nil {
+		t.Error("未确认的重置应返回错误")
+	}
+	if err := cli.Run("watch", nil); err == nil {
+		t.Error("直连数据库模式不支持watch")
+	}
+}
+
+func TestCLI_Tokens(t *testing.T) {
+	cli, out, _ := setupCLI(t)
+
+	if err := cli.Run("tokens", []string{"create", "ci"}); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	if !strings.Contains(out.String(), "vst_") {
+		t.Errorf("应输出令牌明文, 得到 %q", out.String())
+	}
+
+	out.Reset()
+	cli.JSON = true
+	if err := cli.Run("tokens", []string{"list"}); err != nil {
+		t.Fatalf("列出令牌失败: %v", err)
+	}
+	if !strings.Contains(out.String(), `"name": "ci"`) || strings.Contains(out.String(), "token_hash") {
+		t.Errorf("JSON输出不正确: %s", out.String())
+	}
+}
+
+func TestWatchOutput(t *testing.T) {
+	target, err := wsURL("https://vote.example.com/", "a b")
+	if err != nil || target != "wss://vote.example.com/ws/poll?token=a+b" {
+		t.Errorf("WebSocket地址不正确: %s %v", target, err)
+	}
+
+	var out bytes.Buffer
+	cli := &CLI{Out: &out}
+	cli.printMessage([]byte(`{"type":"poll_update","data":{"id":2,"title":"B","is_active":true,"options":[{"text":"x","vote_count":1}]}}`), 1)
+	if out.Len() != 0 {
+		t.Errorf("应忽略其他投票问卷的更新, 得到 %q", out.String())
+	}
+	cli.printMessage([]byte(`{"type":"poll_update","data":{"id":1,"title":"A","is_active":true,"options":[{"text":"x","vote_count":3},{"text":"y","vote_count":1}]}}`), 1)
+	cli.printMessage([]byte(`{"type":"poll_closed","data":{"poll_id":1,"reason":"vote_target"}}`), 1)
+	for _, want := range []string{"#1 A [open]", "75.0%", "total: 4", "#1 closed: vote_target"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("输出中缺少 %q: %s", want, out.String())
+		}
+	}
+}
+
+func TestPrintWeightedResults(t *testing.T) {
+	var out bytes.Buffer
+	cli := &CLI{Out: &out}
+	cli.printMessage([]byte(`{"type":"poll_update","data":{"id":1,"title":"A","is_active":true,"options":[`+
+		`{"text":"x","vote_count":3,"weighted_votes":"3"},{"text":"y","vote_count":1,"weighted_votes":"5.5"}]}}`), 1)
+	for _, want := range []string{"5.5", "64.7%", "total: 4 (weighted 8.5)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("输出中缺少 %q: %s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"vote-system/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsMessage 服务推送的WebSocket消息
+type wsMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// wsURL 根据服务地址生成WebSocket地址，管理令牌通过token查询参数传入
+func wsURL(server, token string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported server scheme %q", u.Scheme)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/ws/poll"
+	u.RawQuery = url.Values{"token": {token}}.Encode()
+	return u.String(), nil
+}
+
+// watch 通过WebSocket持续输出投票问卷的实时结果，未指定ID时输出所有投票问卷的更新
+func (c *CLI) watch(args []string) error {
+	if c.Direct {
+		return errors.New("watch needs the running server and is not available with -direct")
+	}
+
+	var id uint
+	if len(args) > 0 {
+		var err error
+		if id, err = pollID("watch", args); err != nil {
+			return err
+		}
+		// 先输出当前结果
+		if err := c.show(args); err != nil {
+			return err
+		}
+	}
+
+	target, err := wsURL(c.Client.Server, c.Client.Token)
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(target, nil)
+	if err != nil {
+		return fmt.Errorf("connect websocket: %w", err)
+	}
+	defer conn.Close()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("websocket closed: %w", err)
+		}
+		c.printMessage(data, id)
+	}
+}
+
+// printMessage 输出一条推送消息，id不为0时只输出该投票问卷的消息
+func (c *CLI) printMessage(data []byte, id uint) {
+	if c.JSON {
+		fmt.Fprintln(c.Out, string(data))
+		return
+	}
+
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+
+	stamp := time.Now().Format("15:04:05")
+	switch msg.Type {
+	case "poll_update":
+		var poll models.Poll
+		if json.Unmarshal(msg.Data, &poll) != nil || (id != 0 && poll.ID != id) {
+			return
+		}
+		fmt.Fprintf(c.Out, "[%s] ", stamp)
+		printResults(c.Out, poll)
+
+	case "poll_closed":
+		var closed struct {
+			PollID uint   `json:"poll_id"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(msg.Data, &closed) != nil || (id != 0 && closed.PollID != id) {
+			return
+		}
+		fmt.Fprintf(c.Out, "[%s] #%d closed: %s\n", stamp, closed.PollID, closed.Reason)
+	}
+}
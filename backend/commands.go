@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"vote-system/audit"
+	"vote-system/export"
+	"vote-system/manifest"
+	"vote-system/privacy"
+	"vote-system/seed"
+
+	"gorm.io/gorm"
+)
+
+// runCommand 执行命令行子命令
+func runCommand(db *gorm.DB, hasher *privacy.Hasher, name string, args []string) error {
+	switch name {
+	case "seed":
+		return runSeed(db, hasher, args)
+	case "export":
+		return runExport(db, hasher, args)
+	case "purge-identifiers":
+		return runPurgeIdentifiers(db, args)
+	case "import-polls":
+		return runImportPolls(db, hasher, args)
+	case "export-polls":
+		return runExportPolls(db, args)
+	}
+	return fmt.Errorf("unknown command: %s", name)
+}
+
+// runExport 导出投票问卷结果，例如: vote-system export -poll 1 -format xlsx -votes -o results.xlsx
+func runExport(db *gorm.DB, hasher *privacy.Hasher, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	pollID := fs.Uint("poll", 0, "poll id to export")
+	format := fs.String("format", export.FormatCSV, "export format: csv, json or xlsx")
+	votes := fs.Bool("votes", false, "include per-vote raw records")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	if *pollID == 0 {
+		return fmt.Errorf("export: -poll is required")
+	}
+	if !export.ValidFormat(*format) {
+		return fmt.Errorf("export: unsupported format %q", *format)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return export.Write(w, db, *pollID, export.Options{
+		Format:       *format,
+		IncludeVotes: *votes,
+		Hasher:       hasher,
+	})
+}
+
+// runPurgeIdentifiers 立即清除已过保留期的投票人标识，例如: vote-system purge-identifiers -days 30
+func runPurgeIdentifiers(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("purge-identifiers", flag.ExitOnError)
+	days := fs.Int("days", 0, "purge identifiers of polls closed more than this many days ago")
+	fs.Parse(args)
+
+	if *days <= 0 {
+		return fmt.Errorf("purge-identifiers: -days must be positive")
+	}
+
+	purged, err := privacy.PurgeIdentifiers(db, *days, time.Now(), "cli")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Purged voter identifiers from %d votes\n", purged)
+	return nil
+}
+
+// runImportPolls 按文档创建、更新或归档投票问卷，例如: vote-system import-polls -f polls.yaml -dry-run
+func runImportPolls(db *gorm.DB, hasher *privacy.Hasher, args []string) error {
+	fs := flag.NewFlagSet("import-polls", flag.ExitOnError)
+	file := fs.String("f", "", "YAML or JSON document to import")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("import-polls: -f is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	doc, err := manifest.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		plan, err := manifest.Compute(db, doc)
+		if err != nil {
+			return err
+		}
+		fmt.Print(plan.Diff())
+		if len(plan.Errors) > 0 {
+			return fmt.Errorf("import-polls: document cannot be applied")
+		}
+		return nil
+	}
+
+	plan, err := manifest.Apply(db, doc, manifest.ApplyOptions{
+		Audit:  audit.Entry{Actor: "cli"},
+		Hasher: hasher,
+	})
+	if plan != nil {
+		fmt.Print(plan.Diff())
+	}
+	return err
+}
+
+// runExportPolls 将投票问卷导出为可再次导入的文档，例如: vote-system export-polls -format yaml -o polls.yaml
+func runExportPolls(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("export-polls", flag.ExitOnError)
+	format := fs.String("format", manifest.FormatYAML, "document format: yaml or json")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	doc, err := manifest.Export(db)
+	if err != nil {
+		return err
+	}
+	data, err := manifest.Encode(doc, *format)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
+
+// runSeed 初始化数据后退出，已存在的投票问卷不会被修改，例如: vote-system seed demo
+func runSeed(db *gorm.DB, hasher *privacy.Hasher, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: seed <%s|%s|%s|file>", seed.FixtureEmpty, seed.FixtureDemo, seed.FixtureLoadTest)
+	}
+
+	result, err := seed.Run(db, args[0], hasher)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeded fixture %s: %d created, %d skipped, %d votes\n", result.Fixture, result.Created, result.Skipped, result.Votes)
+	return nil
+}
@@ -2,11 +2,27 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
-	Port        string
+	Port string
+	// gRPC服务端口，设为off时不启动gRPC服务
+	GRPCPort    string
 	DatabaseURL string
+	AdminToken  string
+	// 隐私模式：投票人标识只以HMAC形式保存
+	PrivacyMode bool
+	// HMAC密钥，格式为 "id:secret,id:secret"，第一个为当前密钥
+	VoterKeys string
+	// 投票人的识别方式：ip（默认）或 "header:请求头名称"
+	VoterIdentity string
+	// 投票问卷关闭多少天后清除投票人标识，0表示不清除
+	RetentionDays int
+	// 运行环境，development 或 production，默认 production
+	AppEnv string
+	// 启动时初始化的数据集名称或文件路径，生产环境下忽略，需使用 --seed
+	Seed string
 }
 
 func Load() *Config {
@@ -15,13 +31,51 @@ func Load() *Config {
 		port = "8080"
 	}
 
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
 		dbURL = "root:password@tcp(localhost:3306)/vote_system?charset=utf8mb4&parseTime=True&loc=Local"
 	}
 
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" {
+		appEnv = "production"
+	}
+
 	return &Config{
 		Port:        port,
+		GRPCPort:    grpcPort,
 		DatabaseURL: dbURL,
+		// 管理接口令牌，为空时管理接口不可用
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		PrivacyMode:   os.Getenv("PRIVACY_MODE") == "true",
+		VoterKeys:     os.Getenv("VOTER_HMAC_KEYS"),
+		VoterIdentity: os.Getenv("VOTER_IDENTITY"),
+		RetentionDays: envInt("RETENTION_DAYS", 0),
+		AppEnv:        appEnv,
+		Seed:          os.Getenv("SEED"),
+	}
+}
+
+// GRPCEnabled 是否启动gRPC服务
+func (c *Config) GRPCEnabled() bool {
+	return c.GRPCPort != "off"
+}
+
+// Production 是否为生产环境
+func (c *Config) Production() bool {
+	return c.AppEnv != "development"
+}
+
+// envInt 读取整数环境变量，未设置或格式错误时返回默认值
+func envInt(name string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
 	}
+	return v
 }
@@ -113,3 +113,71 @@ func TestLoadConfigEmptyEnvironmentVariables(t *testing.T) {
 		t.Errorf("期望默认数据库URL %s, 得到 %s", expectedDBURL, cfg.DatabaseURL)
 	}
 }
+
+func TestLoadConfigAdminToken(t *testing.T) {
+	os.Unsetenv("ADMIN_TOKEN")
+	if cfg := Load(); cfg.AdminToken != "" {
+		t.Errorf("期望默认管理令牌为空, 得到 %s", cfg.AdminToken)
+	}
+
+	os.Setenv("ADMIN_TOKEN", "secret")
+	defer os.Unsetenv("ADMIN_TOKEN")
+
+	if cfg := Load(); cfg.AdminToken != "secret" {
+		t.Errorf("期望管理令牌 secret, 得到 %s", cfg.AdminToken)
+	}
+}
+
+func TestLoadConfigPrivacy(t *testing.T) {
+	os.Setenv("PRIVACY_MODE", "true")
+	os.Setenv("VOTER_HMAC_KEYS", "k1:secret")
+	os.Setenv("RETENTION_DAYS", "30")
+	defer func() {
+		os.Unsetenv("PRIVACY_MODE")
+		os.Unsetenv("VOTER_HMAC_KEYS")
+		os.Unsetenv("RETENTION_DAYS")
+	}()
+
+	cfg := Load()
+	if !cfg.PrivacyMode || cfg.VoterKeys != "k1:secret" || cfg.RetentionDays != 30 {
+		t.Errorf("隐私配置加载不正确: %+v", cfg)
+	}
+
+	// 格式错误的保留天数使用默认值
+	os.Setenv("RETENTION_DAYS", "thirty")
+	if cfg := Load(); cfg.RetentionDays != 0 {
+		t.Errorf("期望默认保留天数 0, 得到 %d", cfg.RetentionDays)
+	}
+}
+
+func TestLoadConfigSeed(t *testing.T) {
+	os.Unsetenv("APP_ENV")
+	os.Unsetenv("SEED")
+	if cfg := Load(); !cfg.Production() || cfg.Seed != "" {
+		t.Errorf("默认应为生产环境且不初始化数据: %+v", cfg)
+	}
+
+	os.Setenv("APP_ENV", "development")
+	os.Setenv("SEED", "demo")
+	defer func() {
+		os.Unsetenv("APP_ENV")
+		os.Unsetenv("SEED")
+	}()
+
+	if cfg := Load(); cfg.Production() || cfg.Seed != "demo" {
+		t.Errorf("开发环境配置加载不正确: %+v", cfg)
+	}
+}
+
+func TestLoadConfigGRPCPort(t *testing.T) {
+	os.Unsetenv("GRPC_PORT")
+	if cfg := Load(); cfg.GRPCPort != "9090" || !cfg.GRPCEnabled() {
+		t.Errorf("期望默认gRPC端口 9090, 得到 %s", cfg.GRPCPort)
+	}
+
+	os.Setenv("GRPC_PORT", "off")
+	defer os.Unsetenv("GRPC_PORT")
+	if Load().GRPCEnabled() {
+		t.Error("GRPC_PORT=off 时不应启动gRPC服务")
+	}
+}
@@ -1,14 +1,18 @@
 package database
 
 import (
+	"strings"
 	"vote-system/models"
+	"vote-system/search"
+	"vote-system/stats"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func Init(databaseURL string) (*gorm.DB, error) {
-	db, err := gorm.Open(mysql.Open(databaseURL), &gorm.Config{})
+	db, err := gorm.Open(dialector(databaseURL), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -16,49 +20,57 @@ func Init(databaseURL string) (*gorm.DB, error) {
 	// 自动迁移数据库表
 	err = db.AutoMigrate(
 		&models.Poll{},
+		&models.PollTemplate{},
 		&models.Option{},
 		&models.Vote{},
+		&models.VoteMark{},
+		&models.OptionProposal{},
+		&models.BallotToken{},
+		&models.VoterRoll{},
+		&models.RollMember{},
+		&models.Delegation{},
+		&models.Survey{},
+		&models.SurveyQuestion{},
+		&models.SurveyResponse{},
+		&models.SurveyAnswer{},
+		&models.VoteRollup{},
+		&models.OutboxEvent{},
+		&models.AuditLog{},
+		&models.AuditChainHead{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.WebhookThreshold{},
+		&models.APIToken{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// 初始化默认数据
-	initDefaultData(db)
-
-	return db, nil
-}
-
-func initDefaultData(db *gorm.DB) {
-	// 检查是否已有投票问卷
-	var count int64
-	db.Model(&models.Poll{}).Count(&count)
-	if count > 0 {
-		return
+	// 标题、描述和选项文本的全文索引
+	if err := search.Migrate(db); err != nil {
+		return nil, err
 	}
 
-	// 创建默认投票问卷
-	poll := models.Poll{
-		Title:       "您最喜欢的编程语言是什么？",
-		Description: "请选择您最喜欢的编程语言",
-		IsActive:    true,
+	// 为升级前已有的投票补建汇总数据
+	if err := stats.BackfillRollups(db); err != nil {
+		return nil, err
 	}
 
-	result := db.Create(&poll)
-	if result.Error != nil {
-		return
+	// 为升级前已有票数的选项补齐加权票数
+	if err := stats.BackfillWeights(db); err != nil {
+		return nil, err
 	}
 
-	// 创建选项
-	options := []models.Option{
-		{PollID: poll.ID, Text: "Go", VoteCount: 0},
-		{PollID: poll.ID, Text: "Python", VoteCount: 0},
-		{PollID: poll.ID, Text: "JavaScript", VoteCount: 0},
-		{PollID: poll.ID, Text: "Java", VoteCount: 0},
-		{PollID: poll.ID, Text: "TypeScript", VoteCount: 0},
-	}
+	return db, nil
+}
 
-	for _, option := range options {
-		db.Create(&option)
+// dialector 根据连接串选择数据库驱动：SQLite文件或内存库，其余按MySQL DSN处理
+func dialector(databaseURL string) gorm.Dialector {
+	if strings.HasPrefix(databaseURL, "file:") ||
+		databaseURL == ":memory:" ||
+		strings.HasSuffix(databaseURL, ".db") ||
+		strings.HasSuffix(databaseURL, ".sqlite") {
+		return sqlite.Open(databaseURL)
 	}
+	return mysql.Open(databaseURL)
 }
@@ -31,109 +31,23 @@ func TestInit(t *testing.T) {
 	if !db.Migrator().HasTable(&models.Vote{}) {
 		t.Error("Vote表应该已创建")
 	}
-}
 
-func TestInitDefaultData(t *testing.T) {
-	// 创建测试数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("创建测试数据库失败: %v", err)
-	}
-
-	// 迁移表结构
-	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{})
-
-	// 调用初始化默认数据函数
-	initDefaultData(db)
-
-	// 验证是否创建了默认投票问卷
-	var pollCount int64
-	db.Model(&models.Poll{}).Count(&pollCount)
-	if pollCount != 1 {
-		t.Errorf("期望1个投票问卷, 得到 %d", pollCount)
-	}
-
-	// 验证投票问卷内容
-	var poll models.Poll
-	db.First(&poll)
-
-	expectedTitle := "您最喜欢的编程语言是什么？"
-	if poll.Title != expectedTitle {
-		t.Errorf("期望标题 %s, 得到 %s", expectedTitle, poll.Title)
-	}
-
-	expectedDescription := "请选择您最喜欢的编程语言"
-	if poll.Description != expectedDescription {
-		t.Errorf("期望描述 %s, 得到 %s", expectedDescription, poll.Description)
-	}
-
-	if !poll.IsActive {
-		t.Error("投票问卷应该是活跃状态")
-	}
-
-	// 验证是否创建了选项
-	var optionCount int64
-	db.Model(&models.Option{}).Count(&optionCount)
-	if optionCount != 5 {
-		t.Errorf("期望5个选项, 得到 %d", optionCount)
-	}
-
-	// 验证选项内容
-	var options []models.Option
-	db.Where("poll_id = ?", poll.ID).Find(&options)
-
-	expectedOptions := []string{"Go", "Python", "JavaScript", "Java", "TypeScript"}
-	if len(options) != len(expectedOptions) {
-		t.Errorf("期望 %d 个选项, 得到 %d", len(expectedOptions), len(options))
-	}
-
-	for i, option := range options {
-		if i < len(expectedOptions) && option.Text != expectedOptions[i] {
-			t.Errorf("选项 %d: 期望 %s, 得到 %s", i, expectedOptions[i], option.Text)
-		}
-
-		if option.VoteCount != 0 {
-			t.Errorf("选项 %s: 期望投票数 0, 得到 %d", option.Text, option.VoteCount)
-		}
-
-		if option.PollID != poll.ID {
-			t.Errorf("选项 %s: 期望投票问卷ID %d, 得到 %d", option.Text, poll.ID, option.PollID)
-		}
+	if !db.Migrator().HasTable(&models.OutboxEvent{}) {
+		t.Error("OutboxEvent表应该已创建")
 	}
 }
 
-func TestInitDefaultDataWithExistingPoll(t *testing.T) {
-	// 创建测试数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+func TestInitDoesNotSeed(t *testing.T) {
+	// 初始化只迁移表结构，不会隐式写入任何投票问卷
+	db, err := Init("file:noseed?mode=memory&cache=shared")
 	if err != nil {
-		t.Fatalf("创建测试数据库失败: %v", err)
-	}
-
-	// 迁移表结构
-	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{})
-
-	// 先创建一个投票问卷
-	existingPoll := models.Poll{
-		Title:    "已存在的投票",
-		IsActive: true,
+		t.Fatalf("数据库初始化失败: %v", err)
 	}
-	db.Create(&existingPoll)
-
-	// 调用初始化默认数据函数
-	initDefaultData(db)
 
-	// 验证没有创建新的投票问卷
 	var pollCount int64
 	db.Model(&models.Poll{}).Count(&pollCount)
-	if pollCount != 1 {
-		t.Errorf("期望1个投票问卷, 得到 %d", pollCount)
-	}
-
-	// 验证投票问卷仍然是原来的
-	var poll models.Poll
-	db.First(&poll)
-	if poll.Title != "已存在的投票" {
-		t.Errorf("期望标题 '已存在的投票', 得到 %s", poll.Title)
+	if pollCount != 0 {
+		t.Errorf("期望0个投票问卷, 得到 %d", pollCount)
 	}
 }
 
@@ -0,0 +1,492 @@
+// Package delegation 委托投票：投票人可以把某个投票问卷或某个标签下所有投票问卷的投票委托给另一个投票人
+//
+// 委托可以传递（A委托B、B委托C时A的投票随C），投票人自己投票时不使用委托。
+// 委托只在有投票人名册的投票问卷中生效，委托人和受托人按名册中的标识比较，权重为委托人自己的权重。
+// 隐私模式下委托只保存标识的HMAC，解析时按名册成员标识在各密钥下的HMAC匹配。
+// 投票问卷开启期间只保存委托关系，关闭时沿委托链找到直接投票的受托人，把委托人的投票计入同一选项（其他投票方式中复制受托人的整张选票）；
+// 委托链形成循环、经过不在名册中的投票人或最终没有人投票时，委托人的投票不计入。
+package delegation
+
+import (
+	"errors"
+	"time"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/stats"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// 委托链的状态
+const (
+	StatusDirect     = "direct"     // 投票人自己投了票，不使用委托
+	StatusDelegated  = "delegated"  // 按委托链计入受托人的选项
+	StatusPending    = "pending"    // 委托链最后的受托人还没有投票，也没有再委托
+	StatusNone       = "none"       // 没有投票也没有委托
+	StatusCycle      = "cycle"      // 委托链形成循环，不计入
+	StatusIneligible = "ineligible" // 委托链经过不在名册中的投票人，不计入
+)
+
+// ErrCycle 新的委托与同一范围内已有的委托形成循环
+var ErrCycle = errors.New("delegation would create a cycle")
+
+// Link 委托链中的一次委托，委托人和受托人为名册中的标识，受托人不在名册中时为保存的标识
+type Link struct {
+	Delegator string `json:"delegator"`
+	Delegate  string `json:"delegate"`
+	// PollID 和 Topic 该委托的范围
+	PollID uint   `json:"poll_id,omitempty"`
+	Topic  string `json:"topic,omitempty"`
+}
+
+// Chain 投票人在投票问卷中实际生效的委托链
+//
+// 不包含受托人所投的选项，受托人的选择只体现在关闭后的票数中。
+type Chain struct {
+	Voter  string `json:"voter"`
+	Status string `json:"status"`
+	Links  []Link `json:"links"`
+	// Delegate 投票被计入的受托人，只在status为delegated时有值
+	Delegate string `json:"delegate,omitempty"`
+
+	member *models.RollMember
+	vote   *models.Vote
+}
+
+// resolver 沿委托链查找投票，preloaded为true时已读入全部数据，map中没有的键即不存在，否则按需查询
+//
+// members按委托中可能保存的标识索引名册成员，rollLoaded为true时已读入整个名册；
+// 其余map按名册成员ID索引。
+type resolver struct {
+	db          *gorm.DB
+	poll        models.Poll
+	hasher      *privacy.Hasher
+	preloaded   bool
+	rollLoaded  bool
+	members     map[string]*models.RollMember
+	votes       map[uint]*models.Vote
+	delegations map[uint]*models.Delegation
+	chains      map[uint]Chain
+}
+
+func newResolver(db *gorm.DB, poll models.Poll, hasher *privacy.Hasher) *resolver {
+	return &resolver{
+		db:          db,
+		poll:        poll,
+		hasher:      hasher,
+		members:     map[string]*models.RollMember{},
+		votes:       map[uint]*models.Vote{},
+		delegations: map[uint]*models.Delegation{},
+		chains:      map[uint]Chain{},
+	}
+}
+
+// scope 投票问卷中生效的委托：该投票问卷的委托，以及按标签的委托
+func (r *resolver) scope(db *gorm.DB) *gorm.DB {
+	if len(r.poll.Tags) == 0 {
+		return db.Where("poll_id = ?", r.poll.ID)
+	}
+	return db.Where("poll_id = ? OR (poll_id = 0 AND topic IN ?)", r.poll.ID, []string(r.poll.Tags))
+}
+
+// preferred 同一委托人有多个委托时，投票问卷的委托优先，其次是最近修改的按标签委托
+const preferred = "poll_id DESC, updated_at DESC, id DESC"
+
+// loadRoll 读入整个名册，按原始标识和隐私模式下各密钥的HMAC索引
+func (r *resolver) loadRoll() error {
+	var members []models.RollMember
+	if err := r.db.Where("roll_id = ?", *r.poll.RollID).Order("id").Find(&members).Error; err != nil {
+		return err
+	}
+	for i := range members {
+		for _, key := range r.hasher.Keys(members[i].Identifier) {
+			r.members[key] = &members[i]
+		}
+	}
+	r.rollLoaded = true
+	return nil
+}
+
+// preload 一次读入名册、直接投票和委托，用于统计整个投票问卷
+func (r *resolver) preload() error {
+	if err := r.loadRoll(); err != nil {
+		return err
+	}
+
+	var votes []models.Vote
+	if err := r.db.Where("poll_id = ? AND roll_member_id IS NOT NULL AND delegate_vote_id IS NULL", r.poll.ID).
+		Find(&votes).Error; err != nil {
+		return err
+	}
+	for i := range votes {
+		r.votes[*votes[i].RollMemberID] = &votes[i]
+	}
+
+	var delegations []models.Delegation
+	if err := r.scope(r.db).Order(preferred).Find(&delegations).Error; err != nil {
+		return err
+	}
+	for i := range delegations {
+		member := r.members[delegations[i].Delegator]
+		if member == nil {
+			continue
+		}
+		if _, ok := r.delegations[member.ID]; !ok {
+			r.delegations[member.ID] = &delegations[i]
+		}
+	}
+
+	r.preloaded = true
+	return nil
+}
+
+// member 按委托中保存的标识返回名册中的投票人，不在名册中时返回nil
+//
+// HMAC无法按标识查询，隐私模式下读入整个名册。
+func (r *resolver) member(identifier string) (*models.RollMember, error) {
+	if member, ok := r.members[identifier]; ok || r.rollLoaded {
+		return member, nil
+	}
+	if r.hasher.Enabled() {
+		if err := r.loadRoll(); err != nil {
+			return nil, err
+		}
+		return r.members[identifier], nil
+	}
+	var member models.RollMember
+	err := r.db.Where("roll_id = ? AND identifier = ?", *r.poll.RollID, identifier).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.members[identifier] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.members[identifier] = &member
+	return &member, nil
+}
+
+// vote 返回名册中投票人的直接投票，没有投票时返回nil
+func (r *resolver) vote(member *models.RollMember) (*models.Vote, error) {
+	if vote, ok := r.votes[member.ID]; ok || r.preloaded {
+		return vote, nil
+	}
+	var vote models.Vote
+	err := r.db.Where("poll_id = ? AND roll_member_id = ? AND delegate_vote_id IS NULL", r.poll.ID, member.ID).
+		First(&vote).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.votes[member.ID] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.votes[member.ID] = &vote
+	return &vote, nil
+}
+
+// delegation 返回名册中投票人在投票问卷中生效的委托，没有委托时返回nil
+func (r *resolver) delegation(member *models.RollMember) (*models.Delegation, error) {
+	if d, ok := r.delegations[member.ID]; ok || r.preloaded {
+		return d, nil
+	}
+	var d models.Delegation
+	err := r.scope(r.db.Where("delegator IN ?", r.hasher.Keys(member.Identifier))).Order(preferred).First(&d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.delegations[member.ID] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.delegations[member.ID] = &d
+	return &d, nil
+}
+
+// chain 沿委托链查找投票人的投票，遇到已解析过的投票人时直接接上其结果
+func (r *resolver) chain(identifier string) (Chain, error) {
+	chain := Chain{Voter: identifier, Links: []Link{}}
+	member, err := r.member(identifier)
+	if err != nil {
+		return chain, err
+	}
+	if member == nil {
+		chain.Status = StatusIneligible
+		return chain, nil
+	}
+	if resolved, ok := r.chains[member.ID]; ok {
+		return resolved, nil
+	}
+	chain.member = member
+
+	visited := map[uint]bool{member.ID: true}
+	current := member
+	for {
+		vote, err := r.vote(current)
+		if err != nil {
+			return chain, err
+		}
+		if vote != nil {
+			chain.vote = vote
+			chain.Status = StatusDirect
+			if current != member {
+				chain.Status, chain.Delegate = StatusDelegated, current.Identifier
+			}
+			break
+		}
+
+		d, err := r.delegation(current)
+		if err != nil {
+			return chain, err
+		}
+		if d == nil {
+			chain.Status = StatusNone
+			if current != member {
+				chain.Status = StatusPending
+			}
+			break
+		}
+		link := Link{Delegator: current.Identifier, Delegate: d.Delegate, PollID: d.PollID, Topic: d.Topic}
+		next, err := r.member(d.Delegate)
+		if err != nil {
+			return chain, err
+		}
+		if next == nil {
+			chain.Links = append(chain.Links, link)
+			chain.Status = StatusIneligible
+			break
+		}
+		link.Delegate = next.Identifier
+		chain.Links = append(chain.Links, link)
+		if visited[next.ID] {
+			chain.Status = StatusCycle
+			break
+		}
+		visited[next.ID] = true
+
+		// 受托人已解析过时沿用其结果，其委托链中的循环同样使本链无法计入
+		if resolved, ok := r.chains[next.ID]; ok {
+			chain.Links = append(chain.Links, resolved.Links...)
+			chain.Status, chain.vote = resolved.Status, resolved.vote
+			switch resolved.Status {
+			case StatusDirect:
+				chain.Status, chain.Delegate = StatusDelegated, next.Identifier
+			case StatusDelegated:
+				chain.Delegate = resolved.Delegate
+			case StatusNone:
+				chain.Status = StatusPending
+			}
+			break
+		}
+		current = next
+	}
+
+	r.chains[member.ID] = chain
+	return chain, nil
+}
+
+// For 返回投票人在有名册的投票问卷中的委托链，identifier为规范化后的投票人标识，hasher与保存委托时一致
+func For(db *gorm.DB, poll models.Poll, hasher *privacy.Hasher, identifier string) (Chain, error) {
+	return newResolver(db, poll, hasher).chain(identifier)
+}
+
+// Resolve 返回有名册的投票问卷中每个投票人的委托链，按名册顺序排列
+func Resolve(db *gorm.DB, poll models.Poll, hasher *privacy.Hasher) ([]Chain, error) {
+	r := newResolver(db, poll, hasher)
+	if err := r.preload(); err != nil {
+		return nil, err
+	}
+
+	var identifiers []string
+	if err := db.Model(&models.RollMember{}).Where("roll_id = ?", *poll.RollID).Order("id").
+		Pluck("identifier", &identifiers).Error; err != nil {
+		return nil, err
+	}
+	chains := make([]Chain, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		chain, err := r.chain(identifier)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+// Set 在事务中创建或替换委托人在同一范围内的委托，与同一范围内已有的委托形成循环时返回ErrCycle
+//
+// 委托人和受托人按保存的形式比较，隐私模式下调用方传入当前密钥的HMAC。
+// 不同范围的委托组合成的循环在关闭投票问卷时才能发现，届时相关投票人的投票不计入。
+func Set(tx *gorm.DB, d models.Delegation) (models.Delegation, error) {
+	visited := map[string]bool{}
+	for current := d.Delegate; !visited[current]; {
+		if current == d.Delegator {
+			return d, ErrCycle
+		}
+		visited[current] = true
+
+		var next models.Delegation
+		err := tx.Where("delegator = ? AND poll_id = ? AND topic = ?", current, d.PollID, d.Topic).First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return d, err
+		}
+		current = next.Delegate
+	}
+
+	var existing models.Delegation
+	err := tx.Where("delegator = ? AND poll_id = ? AND topic = ?", d.Delegator, d.PollID, d.Topic).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return d, tx.Create(&d).Error
+	}
+	if err != nil {
+		return d, err
+	}
+	if err := tx.Model(&existing).Update("delegate", d.Delegate).Error; err != nil {
+		return d, err
+	}
+	return existing, nil
+}
+
+// Apply 在关闭投票问卷的事务中为委托链可以计入的委托人创建投票，返回计入的票数
+//
+// 新投票沿用受托人投票的选项和自填内容，权重为委托人在名册中的权重，创建时间为关闭时间。
+// 完成后poll.Options为更新后的票数。没有名册的投票问卷不使用委托。
+func Apply(tx *gorm.DB, poll *models.Poll, hasher *privacy.Hasher, now time.Time) (int, error) {
+	if poll.RollID == nil {
+		return 0, nil
+	}
+	chains, err := Resolve(tx, *poll, hasher)
+	if err != nil {
+		return 0, err
+	}
+
+	var votes []models.Vote
+	for _, chain := range chains {
+		if chain.Status != StatusDelegated {
+			continue
+		}
+		memberID, sourceID := chain.member.ID, chain.vote.ID
+		votes = append(votes, models.Vote{
+			CreatedAt:      now,
+			PollID:         poll.ID,
+			OptionID:       chain.vote.OptionID,
+			WriteIn:        chain.vote.WriteIn,
+			ProposalID:     chain.vote.ProposalID,
+			RollMemberID:   &memberID,
+			Weight:         chain.member.Weight,
+			DelegateVoteID: &sourceID,
+		})
+	}
+	if len(votes) == 0 {
+		return 0, nil
+	}
+	if err := tx.CreateInBatches(&votes, 500).Error; err != nil {
+		return 0, err
+	}
+	if err := copyMarks(tx, votes); err != nil {
+		return 0, err
+	}
+	if err := adjust(tx, poll.ID, votes, 1); err != nil {
+		return 0, err
+	}
+	if err := tx.Where("poll_id = ?", poll.ID).Order("id").Find(&poll.Options).Error; err != nil {
+		return 0, err
+	}
+	return len(votes), nil
+}
+
+// Revert 在重新开启投票问卷的事务中删除关闭时按委托创建的投票，返回删除的票数
+func Revert(tx *gorm.DB, pollID uint) (int, error) {
+	var votes []models.Vote
+	if err := tx.Where("poll_id = ? AND delegate_vote_id IS NOT NULL", pollID).Find(&votes).Error; err != nil {
+		return 0, err
+	}
+	if len(votes) == 0 {
+		return 0, nil
+	}
+	ids := make([]uint, len(votes))
+	for i, vote := range votes {
+		ids[i] = vote.ID
+	}
+	if _, err := tally.Remove(tx, ids); err != nil {
+		return 0, err
+	}
+	if err := tx.Where("poll_id = ? AND delegate_vote_id IS NOT NULL", pollID).Delete(&models.Vote{}).Error; err != nil {
+		return 0, err
+	}
+	return len(votes), adjust(tx, pollID, votes, -1)
+}
+
+// copyMarks 为单选以外投票方式中按委托创建的投票复制受托人的选票标记，权重为委托人的权重
+func copyMarks(tx *gorm.DB, votes []models.Vote) error {
+	var sourceIDs []uint
+	for _, vote := range votes {
+		if vote.OptionID == 0 {
+			sourceIDs = append(sourceIDs, *vote.DelegateVoteID)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+	var marks []models.VoteMark
+	if err := tx.Where("vote_id IN ?", sourceIDs).Order("id").Find(&marks).Error; err != nil {
+		return err
+	}
+	byVote := map[uint][]models.VoteMark{}
+	for _, mark := range marks {
+		byVote[mark.VoteID] = append(byVote[mark.VoteID], mark)
+	}
+
+	for _, vote := range votes {
+		if vote.OptionID != 0 {
+			continue
+		}
+		copies := append([]models.VoteMark(nil), byVote[*vote.DelegateVoteID]...)
+		if err := tally.Record(tx, vote, copies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjust 按单选投票增加（sign为1）或减少（sign为-1）各选项的票数和加权票数，并重建汇总数据，溢出时返回TallyOverflow
+//
+// 其他投票方式的选票标记由tally.Record和tally.Remove更新选项。
+func adjust(tx *gorm.DB, pollID uint, votes []models.Vote, sign int) error {
+	type total struct {
+		votes  int
+		weight weight.Weight
+	}
+	totals := map[uint]*total{}
+	var order []uint
+	for _, vote := range votes {
+		if vote.OptionID == 0 {
+			continue
+		}
+		t, ok := totals[vote.OptionID]
+		if !ok {
+			t = &total{}
+			totals[vote.OptionID] = t
+			order = append(order, vote.OptionID)
+		}
+		t.votes++
+		var err error
+		if t.weight, err = t.weight.Add(vote.Weight); err != nil {
+			return apierror.New(apierror.TallyOverflow)
+		}
+	}
+
+	for _, optionID := range order {
+		t := totals[optionID]
+		if err := tally.Increment(tx, optionID, sign*t.votes, weight.Weight(sign)*t.weight, 0); err != nil {
+			return err
+		}
+	}
+	return stats.RebuildRollups(tx, pollID)
+}
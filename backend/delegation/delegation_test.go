@@ -0,0 +1,244 @@
+package delegation
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.VoterRoll{}, &models.RollMember{},
+		&models.Delegation{}, &models.VoteRollup{})
+	return db
+}
+
+// setupPoll 创建带名册和两个选项的投票问卷，返回投票问卷和按标识索引的名册成员
+func setupPoll(t *testing.T, db *gorm.DB, identifiers ...string) (models.Poll, map[string]models.RollMember) {
+	t.Helper()
+	roll := models.VoterRoll{Name: "理事会"}
+	db.Create(&roll)
+	members := map[string]models.RollMember{}
+	for i, identifier := range identifiers {
+		member := models.RollMember{RollID: roll.ID, Identifier: identifier, Weight: weight.Of(i + 1)}
+		db.Create(&member)
+		members[identifier] = member
+	}
+
+	poll := models.Poll{Title: "预算方案", IsActive: true, Tags: models.Tags{"finance"}, RollID: &roll.ID,
+		Options: []models.Option{{Text: "方案A"}, {Text: "方案B"}}}
+	if err := db.Create(&poll).Error; err != nil {
+		t.Fatalf("创建投票问卷失败: %v", err)
+	}
+	return poll, members
+}
+
+// castVote 直接投票并更新选项票数
+func castVote(db *gorm.DB, poll models.Poll, member models.RollMember, option models.Option) {
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: option.ID, UserIP: member.Identifier, RollMemberID: &member.ID, Weight: member.Weight})
+	db.Model(&option).Updates(map[string]interface{}{
+		"vote_count":     gorm.Expr("vote_count + ?", 1),
+		"weighted_votes": gorm.Expr("weighted_votes + ?", member.Weight),
+	})
+}
+
+func delegate(t *testing.T, db *gorm.DB, d models.Delegation) {
+	t.Helper()
+	if _, err := Set(db, d); err != nil {
+		t.Fatalf("创建委托 %s -> %s 失败: %v", d.Delegator, d.Delegate, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	db := setupTestDB(t)
+	poll, members := setupPoll(t, db, "alice", "bob", "carol", "dave", "erin", "frank", "grace")
+	castVote(db, poll, members["carol"], poll.Options[0])
+	castVote(db, poll, members["dave"], poll.Options[1])
+
+	// alice -> bob -> carol（已投票）
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "bob", PollID: poll.ID})
+	delegate(t, db, models.Delegation{Delegator: "bob", Delegate: "carol", Topic: "finance"})
+	// dave自己投了票，委托不生效
+	delegate(t, db, models.Delegation{Delegator: "dave", Delegate: "carol", PollID: poll.ID})
+	// erin和frank通过不同范围的委托形成循环
+	delegate(t, db, models.Delegation{Delegator: "erin", Delegate: "frank", PollID: poll.ID})
+	delegate(t, db, models.Delegation{Delegator: "frank", Delegate: "erin", Topic: "finance"})
+	// grace委托给不在名册中的投票人
+	delegate(t, db, models.Delegation{Delegator: "grace", Delegate: "mallory", PollID: poll.ID})
+
+	chains, err := Resolve(db, poll, nil)
+	if err != nil {
+		t.Fatalf("解析委托失败: %v", err)
+	}
+	want := map[string]string{
+		"alice": StatusDelegated,
+		"bob":   StatusDelegated,
+		"carol": StatusDirect,
+		"dave":  StatusDirect,
+		"erin":  StatusCycle,
+		"frank": StatusCycle,
+		"grace": StatusIneligible,
+	}
+	if len(chains) != len(want) {
+		t.Fatalf("期望 %d 条委托链, 得到 %d", len(want), len(chains))
+	}
+	for _, chain := range chains {
+		if chain.Status != want[chain.Voter] {
+			t.Errorf("%s: 期望 %s, 得到 %s", chain.Voter, want[chain.Voter], chain.Status)
+		}
+	}
+	alice := chains[0]
+	if alice.Delegate != "carol" || len(alice.Links) != 2 || alice.Links[1].Topic != "finance" {
+		t.Errorf("alice的委托链不正确: %+v", alice)
+	}
+
+	// 单个投票人按需查询的结果与整体解析一致
+	chain, err := For(db, poll, nil, "alice")
+	if err != nil || chain.Status != StatusDelegated || chain.Delegate != "carol" {
+		t.Errorf("期望 alice 计入 carol 的选项, 得到 %+v %v", chain, err)
+	}
+	if chain, _ := For(db, poll, nil, "frank"); chain.Status != StatusCycle {
+		t.Errorf("期望 %s, 得到 %s", StatusCycle, chain.Status)
+	}
+}
+
+func TestResolve_PollOverridesTopic(t *testing.T) {
+	db := setupTestDB(t)
+	poll, members := setupPoll(t, db, "alice", "bob", "carol")
+	castVote(db, poll, members["bob"], poll.Options[0])
+
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "bob", Topic: "finance"})
+	if chain, _ := For(db, poll, nil, "alice"); chain.Status != StatusDelegated {
+		t.Errorf("按标签的委托应生效, 得到 %s", chain.Status)
+	}
+
+	// 该投票问卷的委托优先于按标签的委托
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "carol", PollID: poll.ID})
+	chain, _ := For(db, poll, nil, "alice")
+	if chain.Status != StatusPending || chain.Links[0].Delegate != "carol" {
+		t.Errorf("期望委托给尚未投票的 carol, 得到 %+v", chain)
+	}
+
+	// 其他标签的委托不生效
+	delegate(t, db, models.Delegation{Delegator: "carol", Delegate: "bob", Topic: "hr"})
+	if chain, _ := For(db, poll, nil, "carol"); chain.Status != StatusNone {
+		t.Errorf("期望 %s, 得到 %s", StatusNone, chain.Status)
+	}
+}
+
+func TestSet(t *testing.T) {
+	db := setupTestDB(t)
+
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "bob", PollID: 1})
+	delegate(t, db, models.Delegation{Delegator: "bob", Delegate: "carol", PollID: 1})
+	if _, err := Set(db, models.Delegation{Delegator: "carol", Delegate: "alice", PollID: 1}); !errors.Is(err, ErrCycle) {
+		t.Errorf("期望 ErrCycle, 得到 %v", err)
+	}
+	// 不同范围的委托不在创建时检查
+	delegate(t, db, models.Delegation{Delegator: "carol", Delegate: "alice", PollID: 2})
+
+	// 同一范围内再次委托时替换受托人
+	updated, err := Set(db, models.Delegation{Delegator: "alice", Delegate: "dave", PollID: 1})
+	if err != nil || updated.Delegate != "dave" {
+		t.Fatalf("替换委托失败: %+v %v", updated, err)
+	}
+	var count int64
+	db.Model(&models.Delegation{}).Where("delegator = ?", "alice").Count(&count)
+	if count != 1 {
+		t.Errorf("期望 1 条委托, 得到 %d", count)
+	}
+}
+
+func TestApplyAndRevert(t *testing.T) {
+	db := setupTestDB(t)
+	poll, members := setupPoll(t, db, "alice", "bob", "carol")
+	castVote(db, poll, members["carol"], poll.Options[1])
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "bob", PollID: poll.ID})
+	delegate(t, db, models.Delegation{Delegator: "bob", Delegate: "carol", PollID: poll.ID})
+
+	closedAt := time.Now()
+	n, err := Apply(db, &poll, nil, closedAt)
+	if err != nil || n != 2 {
+		t.Fatalf("期望计入 2 票, 得到 %d %v", n, err)
+	}
+	// alice权重1、bob权重2、carol权重3
+	if option := poll.Options[1]; option.VoteCount != 3 || option.WeightedVotes != weight.Of(6) {
+		t.Errorf("期望 3 票、加权 6, 得到 %d、%s", option.VoteCount, option.WeightedVotes)
+	}
+	var delegated []models.Vote
+	db.Where("delegate_vote_id IS NOT NULL").Order("id").Find(&delegated)
+	if len(delegated) != 2 || *delegated[0].RollMemberID != members["alice"].ID || delegated[0].Weight != weight.One {
+		t.Errorf("委托的投票不正确: %+v", delegated)
+	}
+	var rollups int
+	db.Model(&models.VoteRollup{}).Where("option_id = ?", poll.Options[1].ID).Select("SUM(votes)").Scan(&rollups)
+	if rollups != 3 {
+		t.Errorf("期望汇总 3 票, 得到 %d", rollups)
+	}
+
+	// 关闭后alice的委托链仍按直接投票解析
+	if chain, _ := For(db, poll, nil, "alice"); chain.Status != StatusDelegated {
+		t.Errorf("期望 %s, 得到 %s", StatusDelegated, chain.Status)
+	}
+
+	n, err = Revert(db, poll.ID)
+	if err != nil || n != 2 {
+		t.Fatalf("期望删除 2 票, 得到 %d %v", n, err)
+	}
+	var option models.Option
+	db.First(&option, poll.Options[1].ID)
+	if option.VoteCount != 1 || option.WeightedVotes != weight.Of(3) {
+		t.Errorf("期望恢复为 1 票、加权 3, 得到 %d、%s", option.VoteCount, option.WeightedVotes)
+	}
+
+	open := models.Poll{Title: "没有名册", IsActive: true}
+	db.Create(&open)
+	if n, err := Apply(db, &open, nil, closedAt); n != 0 || err != nil {
+		t.Errorf("没有名册时不使用委托: %d %v", n, err)
+	}
+}
+
+func TestApplyAndRevert_Marks(t *testing.T) {
+	db := setupTestDB(t)
+	poll, members := setupPoll(t, db, "alice", "bob")
+	db.Model(&poll).Update("mode", models.ModeApproval)
+	poll.Mode = models.ModeApproval
+
+	// bob赞成两个选项，alice委托给bob
+	bob := members["bob"]
+	vote := models.Vote{PollID: poll.ID, UserIP: "bob", RollMemberID: &bob.ID, Weight: bob.Weight}
+	db.Create(&vote)
+	marks := []models.VoteMark{{OptionID: poll.Options[0].ID, Value: 1, Points: 1}, {OptionID: poll.Options[1].ID, Value: 1, Points: 1}}
+	if err := tally.Record(db, vote, marks); err != nil {
+		t.Fatalf("保存选票标记失败: %v", err)
+	}
+	delegate(t, db, models.Delegation{Delegator: "alice", Delegate: "bob", PollID: poll.ID})
+
+	if n, err := Apply(db, &poll, nil, time.Now()); err != nil || n != 1 {
+		t.Fatalf("期望计入 1 票, 得到 %d %v", n, err)
+	}
+	// alice权重1、bob权重2
+	for _, option := range poll.Options {
+		if option.VoteCount != 2 || option.Points != weight.Of(3) {
+			t.Errorf("期望 2 票、得分 3, 得到 %d、%s", option.VoteCount, option.Points)
+		}
+	}
+
+	if n, err := Revert(db, poll.ID); err != nil || n != 1 {
+		t.Fatalf("期望删除 1 票, 得到 %d %v", n, err)
+	}
+	var count int64
+	db.Model(&models.VoteMark{}).Count(&count)
+	if count != 2 {
+		t.Errorf("期望保留bob的 2 个标记, 得到 %d", count)
+	}
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+	"vote-system/ballot"
+	"vote-system/models"
+)
+
+// WriteIssuedBallots 输出新生成的投票令牌，每个受邀人一行，包含令牌明文和投票地址
+func WriteIssuedBallots(w io.Writer, issued []ballot.Issued) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ballot_id", "label", "token", "url", "weight"})
+	for _, item := range issued {
+		cw.Write([]string{
+			strconv.FormatUint(uint64(item.ID), 10),
+			item.Label,
+			item.Token,
+			item.URL,
+			item.Weight.String(),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteBallots 输出投票令牌的使用情况，只包含令牌前缀，不包含权重
+func WriteBallots(w io.Writer, tokens []models.BallotToken) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ballot_id", "label", "prefix", "used", "revoked_at"})
+	for _, token := range tokens {
+		revokedAt := ""
+		if token.RevokedAt != nil {
+			revokedAt = token.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			strconv.FormatUint(uint64(token.ID), 10),
+			token.Label,
+			token.Prefix,
+			strconv.FormatBool(token.Used),
+			revokedAt,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// writeCSV 先输出选项统计，需要时空一行后输出逐条投票记录
+//
+// 单选以外的投票方式在选项统计后追加points和average列，在投票记录后追加value列。
+func writeCSV(w io.Writer, db *gorm.DB, results *Results, opts Options) error {
+	cw := csv.NewWriter(w)
+	marked := results.Mode != ""
+
+	header := []string{"option_id", "option", "votes", "percentage", "weighted_votes", "weighted_percentage"}
+	if marked {
+		header = append(header, "points", "average")
+	}
+	cw.Write(header)
+	for _, option := range results.Options {
+		row := []string{
+			strconv.FormatUint(uint64(option.OptionID), 10),
+			option.Text,
+			strconv.Itoa(option.Votes),
+			strconv.FormatFloat(option.Percentage, 'f', 2, 64),
+			option.WeightedVotes.String(),
+			strconv.FormatFloat(option.WeightedPercentage, 'f', 2, 64),
+		}
+		if marked {
+			average := ""
+			if option.Average != nil {
+				average = strconv.FormatFloat(*option.Average, 'f', 2, 64)
+			}
+			row = append(row, option.Points.String(), average)
+		}
+		cw.Write(row)
+	}
+
+	if opts.IncludeVotes {
+		cw.Write(nil)
+		header := []string{"timestamp", "option_id", "option", "voter_hash", "weight"}
+		if marked {
+			header = append(header, "value")
+		}
+		cw.Write(header)
+
+		err := eachVote(db, results, opts.Hasher, func(record VoteRecord) error {
+			row := []string{
+				record.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.FormatUint(uint64(record.OptionID), 10),
+				record.OptionText,
+				record.VoterHash,
+				"",
+			}
+			if record.Weight != nil {
+				row[4] = record.Weight.String()
+			}
+			if record.Value != nil {
+				row = append(row, strconv.Itoa(*record.Value))
+			}
+			cw.Write(row)
+			return cw.Error()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,240 @@
+package export
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// 支持的导出格式
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatXLSX = "xlsx"
+)
+
+// ErrResultsHidden 投票问卷的结果可见性不允许导出
+var ErrResultsHidden = errors.New("results are hidden until the poll closes")
+
+// OptionResult 单个选项的统计结果，加权票数和百分比按投票人的权重计算
+type OptionResult struct {
+	OptionID           uint          `json:"option_id"`
+	Text               string        `json:"text"`
+	Votes              int           `json:"votes"`
+	Percentage         float64       `json:"percentage"`
+	WeightedVotes      weight.Weight `json:"weighted_votes"`
+	WeightedPercentage float64       `json:"weighted_percentage"`
+	// Points 和 Average 评分投票和波达计数中的得分和平均得分，见tally
+	Points  weight.Weight `json:"points,omitempty"`
+	Average *float64      `json:"average,omitempty"`
+}
+
+// Results 投票问卷的统计结果
+type Results struct {
+	PollID uint   `json:"poll_id"`
+	Title  string `json:"title"`
+	// Mode 单选以外的投票方式，此时TotalVotes为选票数，百分比为标记该选项的选票占比
+	Mode        string         `json:"mode,omitempty"`
+	TotalVotes  int            `json:"total_votes"`
+	TotalWeight weight.Weight  `json:"total_weight"`
+	Options     []OptionResult `json:"options"`
+
+	// invite 邀请制投票问卷，逐条投票记录不输出权重
+	invite bool
+}
+
+// VoteRecord 单条投票的原始记录，投票人身份只输出哈希值
+type VoteRecord struct {
+	CreatedAt  time.Time `json:"created_at"`
+	OptionID   uint      `json:"option_id"`
+	OptionText string    `json:"option_text"`
+	VoterHash  string    `json:"voter_hash"`
+	// Weight 投票的权重；邀请制投票问卷的权重来自受邀人的投票令牌，输出后可以据此把受邀人和所投的选项对应起来，因此为空
+	Weight *weight.Weight `json:"weight,omitempty"`
+	// Value 单选以外的投票方式中选票标记的值，每个标记输出一条记录，见models.VoteMark
+	Value *int `json:"value,omitempty"`
+}
+
+// Options 导出参数
+type Options struct {
+	Format       string
+	IncludeVotes bool
+	// Hasher 隐私模式的Hasher，为nil时投票人哈希使用进程内的随机密钥，见privacy.ExportHasher
+	Hasher *privacy.Hasher
+}
+
+// ContentType 返回导出格式对应的MIME类型
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return ""
+}
+
+// ValidFormat 判断是否为支持的导出格式
+func ValidFormat(format string) bool {
+	return ContentType(format) != ""
+}
+
+// LoadResults 读取投票问卷并计算各选项的票数和百分比
+func LoadResults(db *gorm.DB, pollID uint) (*Results, error) {
+	var poll models.Poll
+	if err := db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&poll, pollID).Error; err != nil {
+		return nil, err
+	}
+	// 导出只有管理员能操作，按管理员身份判断结果可见性
+	if !poll.ResultsVisible(false, true) {
+		return nil, ErrResultsHidden
+	}
+
+	results := &Results{
+		PollID: poll.ID,
+		Title:  poll.Title,
+		invite: poll.Access == models.AccessInvite,
+	}
+	if poll.Plurality() {
+		for _, option := range poll.Options {
+			results.TotalVotes += option.VoteCount
+			results.TotalWeight += option.WeightedVotes
+		}
+	} else {
+		results.Mode = poll.Mode
+		var err error
+		if results.TotalVotes, results.TotalWeight, err = tally.Ballots(db, poll.ID); err != nil {
+			return nil, err
+		}
+		if err := tally.Fill(db, &poll); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, option := range poll.Options {
+		results.Options = append(results.Options, OptionResult{
+			OptionID:   option.ID,
+			Text:       option.Text,
+			Votes:      option.VoteCount,
+			Percentage: percentage(option.VoteCount, results.TotalVotes),
+
+			WeightedVotes:      option.WeightedVotes,
+			WeightedPercentage: weight.Percentage(option.WeightedVotes, results.TotalWeight),
+
+			Points:  option.Points,
+			Average: option.Average,
+		})
+	}
+
+	return results, nil
+}
+
+// percentage 计算百分比并保留两位小数
+func percentage(votes, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(votes)*10000/float64(total)) / 100
+}
+
+// HashVoter 对投票人标识计算带密钥的HMAC，避免导出原始IP，也无法通过枚举IP还原
+func HashVoter(hasher *privacy.Hasher, identity string) string {
+	hash, _ := privacy.ExportHasher(hasher).Identify(identity)
+	return hash
+}
+
+// eachVote 以游标方式逐条读取投票记录，避免一次性加载到内存
+//
+// 单选以外的投票方式中投票记录的OptionID为0，按选票标记逐条输出。
+func eachVote(db *gorm.DB, results *Results, hasher *privacy.Hasher, fn func(VoteRecord) error) error {
+	pollID := results.PollID
+	votes := db.Model(&models.Vote{}).
+		Select("votes.created_at, votes.option_id, options.text, COALESCE(votes.user_ip, ''), COALESCE(votes.voter_hash, ''), votes.weight, NULL").
+		Joins("JOIN options ON options.id = votes.option_id").
+		Where("votes.poll_id = ?", pollID).
+		Order("votes.id")
+	marks := db.Model(&models.VoteMark{}).
+		Select("vote_marks.created_at, vote_marks.option_id, options.text, COALESCE(votes.user_ip, ''), COALESCE(votes.voter_hash, ''), vote_marks.weight, vote_marks.value").
+		Joins("JOIN votes ON votes.id = vote_marks.vote_id").
+		Joins("JOIN options ON options.id = vote_marks.option_id").
+		Where("vote_marks.poll_id = ?", pollID).
+		Order("vote_marks.id")
+
+	for _, q := range []*gorm.DB{votes, marks} {
+		if err := eachRecord(q, hasher, !results.invite, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachRecord 逐条读取eachVote的一个查询，withWeight为false时不输出权重
+func eachRecord(q *gorm.DB, hasher *privacy.Hasher, withWeight bool, fn func(VoteRecord) error) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record VoteRecord
+		var userIP, voterHash string
+		var w weight.Weight
+		var value sql.NullInt64
+		if err := rows.Scan(&record.CreatedAt, &record.OptionID, &record.OptionText, &userIP, &voterHash, &w, &value); err != nil {
+			return err
+		}
+		if withWeight {
+			record.Weight = &w
+		}
+		if value.Valid {
+			v := int(value.Int64)
+			record.Value = &v
+		}
+
+		// 隐私模式下已保存HMAC；标识被清除后导出为空
+		switch {
+		case voterHash != "":
+			record.VoterHash = voterHash
+		case userIP != "":
+			record.VoterHash = HashVoter(hasher, userIP)
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Write 按指定格式将投票问卷结果写入w
+func Write(w io.Writer, db *gorm.DB, pollID uint, opts Options) error {
+	results, err := LoadResults(db, pollID)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case FormatCSV:
+		return writeCSV(w, db, results, opts)
+	case FormatJSON:
+		return writeJSON(w, db, results, opts)
+	case FormatXLSX:
+		return writeXLSX(w, db, results, opts)
+	}
+	return fmt.Errorf("unsupported export format: %s", opts.Format)
+}
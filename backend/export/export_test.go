@@ -0,0 +1,213 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{})
+	return db
+}
+
+func setupTestData(db *gorm.DB) models.Poll {
+	poll := models.Poll{Title: "测试投票", IsActive: true}
+	db.Create(&poll)
+
+	options := []models.Option{
+		{PollID: poll.ID, Text: "Go", VoteCount: 3, WeightedVotes: weight.Of(3)},
+		{PollID: poll.ID, Text: "Rust, \"nightly\"", VoteCount: 1, WeightedVotes: 2500000},
+	}
+	for i := range options {
+		db.Create(&options[i])
+	}
+
+	for i, option := range []models.Option{options[0], options[0], options[0], options[1]} {
+		db.Create(&models.Vote{PollID: poll.ID, OptionID: option.ID, UserIP: "10.0.0." + string(rune('1'+i)), Weight: option.WeightedVotes / weight.Weight(option.VoteCount)})
+	}
+
+	return poll
+}
+
+func TestLoadResults(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+
+	results, err := LoadResults(db, poll.ID)
+	if err != nil {
+		t.Fatalf("读取结果失败: %v", err)
+	}
+
+	if results.TotalVotes != 4 {
+		t.Errorf("期望总票数 4, 得到 %d", results.TotalVotes)
+	}
+
+	if results.Options[0].Percentage != 75 || results.Options[1].Percentage != 25 {
+		t.Errorf("百分比不正确: %+v", results.Options)
+	}
+
+	if results.TotalWeight != 5500000 || results.Options[0].WeightedPercentage != 54.55 || results.Options[1].WeightedPercentage != 45.45 {
+		t.Errorf("加权票数不正确: %+v", results)
+	}
+}
+
+func TestLoadResults_SealedUntilClose(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+	db.Model(&poll).Update("result_visibility", models.VisibilityAfterClose)
+
+	if _, err := LoadResults(db, poll.ID); !errors.Is(err, ErrResultsHidden) {
+		t.Errorf("关闭前期望 ErrResultsHidden, 得到 %v", err)
+	}
+
+	db.Model(&poll).Update("is_active", false)
+	if _, err := LoadResults(db, poll.ID); err != nil {
+		t.Errorf("关闭后导出失败: %v", err)
+	}
+}
+
+func TestPercentageRounding(t *testing.T) {
+	if p := percentage(1, 3); p != 33.33 {
+		t.Errorf("期望 33.33, 得到 %v", p)
+	}
+
+	if p := percentage(0, 0); p != 0 {
+		t.Errorf("总票数为0时期望 0, 得到 %v", p)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+
+	hasher := privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("secret")}})
+	var buf bytes.Buffer
+	if err := Write(&buf, db, poll.ID, Options{Format: FormatCSV, IncludeVotes: true, Hasher: hasher}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "1,Go,3,75.00,3,54.55") || !strings.Contains(out, `,1,25.00,2.5,45.45`) {
+		t.Errorf("CSV缺少选项统计: %s", out)
+	}
+
+	if !strings.Contains(out, `"Rust, ""nightly"""`) {
+		t.Errorf("CSV未正确转义选项文本: %s", out)
+	}
+
+	if !strings.Contains(out, "timestamp,option_id,option,voter_hash,weight") {
+		t.Errorf("CSV缺少投票记录表头: %s", out)
+	}
+
+	if strings.Contains(out, "10.0.0.1") {
+		t.Error("导出不应包含原始IP")
+	}
+
+	// 投票人哈希使用隐私模式的密钥，与保存的HMAC一致
+	if hash, _ := hasher.Identify("10.0.0.1"); !strings.Contains(out, hash) {
+		t.Error("导出应包含投票人哈希")
+	}
+	if plain := sha256.Sum256([]byte("10.0.0.1")); strings.Contains(out, hex.EncodeToString(plain[:])) {
+		t.Error("投票人哈希不应是可枚举还原的无密钥哈希")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, db, poll.ID, Options{Format: FormatJSON, IncludeVotes: true}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	var decoded struct {
+		Results
+		Votes []VoteRecord `json:"votes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON格式不正确: %v\n%s", err, buf.String())
+	}
+
+	if decoded.TotalVotes != 4 || len(decoded.Options) != 2 {
+		t.Errorf("统计结果不正确: %+v", decoded.Results)
+	}
+
+	if len(decoded.Votes) != 4 {
+		t.Errorf("期望4条投票记录, 得到 %d", len(decoded.Votes))
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, db, poll.ID, Options{Format: FormatXLSX, IncludeVotes: true}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("xlsx不是有效的zip: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("xlsx缺少文件 %s", name)
+		}
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `name="Votes"`) {
+		t.Error("工作簿应包含Votes工作表")
+	}
+
+	if !strings.Contains(files["xl/worksheets/sheet1.xml"], "Rust, &#34;nightly&#34;") {
+		t.Errorf("单元格文本未正确转义: %s", files["xl/worksheets/sheet1.xml"])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for i, expected := range cases {
+		if name := columnName(i); name != expected {
+			t.Errorf("列 %d: 期望 %s, 得到 %s", i, expected, name)
+		}
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	db := setupTestDB()
+	poll := setupTestData(db)
+
+	if err := Write(io.Discard, db, poll.ID, Options{Format: "pdf"}); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+}
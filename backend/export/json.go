@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// writeJSON 输出统计结果，投票记录逐条编码写入votes数组
+func writeJSON(w io.Writer, db *gorm.DB, results *Results, opts Options) error {
+	summary, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	if !opts.IncludeVotes {
+		_, err = w.Write(append(summary, '\n'))
+		return err
+	}
+
+	// 去掉末尾的 '}'，在同一对象中追加votes数组
+	if _, err := w.Write(summary[:len(summary)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"votes":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = eachVote(db, results, opts.Hasher, func(record VoteRecord) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"vote-system/models"
+)
+
+// WriteRoll 输出投票人名册，格式与导入时相同，属性按列名排序，可以修改后重新导入
+func WriteRoll(w io.Writer, members []models.RollMember) error {
+	seen := map[string]bool{}
+	var attributes []string
+	for _, member := range members {
+		for name := range member.Attributes {
+			if !seen[name] {
+				seen[name] = true
+				attributes = append(attributes, name)
+			}
+		}
+	}
+	sort.Strings(attributes)
+
+	cw := csv.NewWriter(w)
+	cw.Write(append([]string{"identifier", "name", "weight"}, attributes...))
+	for _, member := range members {
+		record := []string{member.Identifier, member.Name, member.Weight.String()}
+		for _, name := range attributes {
+			record = append(record, member.Attributes[name])
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/survey"
+
+	"gorm.io/gorm"
+)
+
+// SurveyRecord 调查问卷单次作答的原始记录，Answers按问题顺序排列，未作答的问题为空字符串
+type SurveyRecord struct {
+	ResponseID uint      `json:"response_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	VoterHash  string    `json:"voter_hash"`
+	Answers    []string  `json:"answers"`
+}
+
+// surveyBatchSize 每批读取的作答数
+const surveyBatchSize = 500
+
+// WriteSurvey 按指定格式导出调查问卷的原始回答，每次作答一行，每个问题一列
+//
+// 选择题输出选项文本，多选以"; "分隔；投票人身份与投票记录一样只输出哈希值，hasher的含义见Options。
+func WriteSurvey(w io.Writer, db *gorm.DB, surveyID uint, format string, hasher *privacy.Hasher) error {
+	sv, err := survey.Load(db, surveyID)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatCSV:
+		return writeSurveyCSV(w, db, sv, hasher)
+	case FormatJSON:
+		return writeSurveyJSON(w, db, sv, hasher)
+	case FormatXLSX:
+		return writeSurveyXLSX(w, db, sv, hasher)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// eachResponse 分批读取调查问卷的作答，避免一次性加载到内存
+func eachResponse(db *gorm.DB, sv models.Survey, hasher *privacy.Hasher, fn func(SurveyRecord) error) error {
+	columns := map[uint]int{}
+	for i, question := range sv.Questions {
+		columns[question.ID] = i
+	}
+
+	var responses []models.SurveyResponse
+	return db.Preload("Answers").Where("survey_id = ?", sv.ID).Order("id").
+		FindInBatches(&responses, surveyBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, response := range responses {
+				record := SurveyRecord{
+					ResponseID: response.ID,
+					CreatedAt:  response.CreatedAt,
+					Answers:    make([]string, len(sv.Questions)),
+				}
+				// 隐私模式下已保存HMAC；标识被清除后导出为空
+				switch {
+				case response.VoterHash != "":
+					record.VoterHash = response.VoterHash
+				case response.UserIP != "":
+					record.VoterHash = HashVoter(hasher, response.UserIP)
+				}
+				for _, answer := range response.Answers {
+					if i, ok := columns[answer.QuestionID]; ok {
+						record.Answers[i] = answerText(sv.Questions[i], answer)
+					}
+				}
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// answerText 将回答转换为导出的单元格文本
+func answerText(question models.SurveyQuestion, answer models.SurveyAnswer) string {
+	switch {
+	case len(answer.Choices) > 0:
+		texts := make([]string, 0, len(answer.Choices))
+		for _, choice := range answer.Choices {
+			if choice >= 0 && choice < len(question.Choices) {
+				texts = append(texts, question.Choices[choice])
+			}
+		}
+		return strings.Join(texts, "; ")
+	case answer.Rating != nil:
+		return strconv.Itoa(*answer.Rating)
+	}
+	return answer.Text
+}
+
+// surveyHeader 导出的表头，问题列使用问题标题
+func surveyHeader(sv models.Survey) []string {
+	header := []string{"response_id", "timestamp", "voter_hash"}
+	for _, question := range sv.Questions {
+		header = append(header, question.Title)
+	}
+	return header
+}
+
+func writeSurveyCSV(w io.Writer, db *gorm.DB, sv models.Survey, hasher *privacy.Hasher) error {
+	cw := csv.NewWriter(w)
+	cw.Write(surveyHeader(sv))
+
+	err := eachResponse(db, sv, hasher, func(record SurveyRecord) error {
+		row := []string{
+			strconv.FormatUint(uint64(record.ResponseID), 10),
+			record.CreatedAt.UTC().Format(time.RFC3339),
+			record.VoterHash,
+		}
+		cw.Write(append(row, record.Answers...))
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSurveyJSON 输出问题列表，作答逐条编码写入responses数组
+func writeSurveyJSON(w io.Writer, db *gorm.DB, sv models.Survey, hasher *privacy.Hasher) error {
+	header, err := json.Marshal(map[string]interface{}{
+		"survey_id": sv.ID,
+		"title":     sv.Title,
+		"questions": sv.Questions,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 去掉末尾的 '}'，在同一对象中追加responses数组
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"responses":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = eachResponse(db, sv, hasher, func(record SurveyRecord) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func writeSurveyXLSX(w io.Writer, db *gorm.DB, sv models.Survey, hasher *privacy.Hasher) error {
+	xw := newXLSXWriter(w)
+
+	if err := xw.startSheet("Responses"); err != nil {
+		return err
+	}
+	header := []interface{}{}
+	for _, cell := range surveyHeader(sv) {
+		header = append(header, cell)
+	}
+	xw.writeRow(header...)
+
+	err := eachResponse(db, sv, hasher, func(record SurveyRecord) error {
+		row := []interface{}{record.ResponseID, record.CreatedAt.UTC().Format(time.RFC3339), record.VoterHash}
+		for _, answer := range record.Answers {
+			row = append(row, answer)
+		}
+		return xw.writeRow(row...)
+	})
+	if err != nil {
+		return err
+	}
+
+	return xw.close()
+}
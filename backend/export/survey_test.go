@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// setupSurvey 创建包含选择题、评分题和自由回答的调查问卷及两份作答
+func setupSurvey(db *gorm.DB) models.Survey {
+	db.AutoMigrate(&models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{})
+
+	sv := models.Survey{Title: "开发者调查", IsActive: true, Questions: []models.SurveyQuestion{
+		{Position: 1, Type: models.QuestionMultipleChoice, Title: "编辑器", Choices: []string{"Vim", "VS Code", "GoLand"}},
+		{Position: 2, Type: models.QuestionRating, Title: "满意度", MinRating: 1, MaxRating: 5},
+		{Position: 3, Type: models.QuestionText, Title: "建议"},
+	}}
+	db.Create(&sv)
+
+	rating := 4
+	db.Create(&models.SurveyResponse{SurveyID: sv.ID, UserIP: "10.0.0.1", Answers: []models.SurveyAnswer{
+		{QuestionID: sv.Questions[0].ID, Choices: []int{0, 2}},
+		{QuestionID: sv.Questions[1].ID, Rating: &rating},
+		{QuestionID: sv.Questions[2].ID, Text: "多写测试, 少开会"},
+	}})
+	db.Create(&models.SurveyResponse{SurveyID: sv.ID, UserIP: "10.0.0.2", Answers: []models.SurveyAnswer{
+		{QuestionID: sv.Questions[0].ID, Choices: []int{1}},
+	}})
+	return sv
+}
+
+func TestWriteSurveyCSV(t *testing.T) {
+	db := setupTestDB()
+	sv := setupSurvey(db)
+
+	var buf bytes.Buffer
+	if err := WriteSurvey(&buf, db, sv.ID, FormatCSV, nil); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "response_id,timestamp,voter_hash,编辑器,满意度,建议" {
+		t.Fatalf("CSV表头或行数不正确: %s", buf.String())
+	}
+	if !strings.HasSuffix(lines[1], `,Vim; GoLand,4,"多写测试, 少开会"`) {
+		t.Errorf("多选应以分号连接选项文本: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], ",VS Code,,") {
+		t.Errorf("未作答的问题应为空: %s", lines[2])
+	}
+	// 未开启隐私模式时使用进程内的随机密钥
+	if strings.Contains(buf.String(), "10.0.0.1") || !strings.Contains(buf.String(), HashVoter(nil, "10.0.0.1")) {
+		t.Error("导出应只包含投票人哈希")
+	}
+	if plain := sha256.Sum256([]byte("10.0.0.1")); strings.Contains(buf.String(), hex.EncodeToString(plain[:])) {
+		t.Error("投票人哈希不应是可枚举还原的无密钥哈希")
+	}
+}
+
+func TestWriteSurveyJSON(t *testing.T) {
+	db := setupTestDB()
+	sv := setupSurvey(db)
+
+	var buf bytes.Buffer
+	if err := WriteSurvey(&buf, db, sv.ID, FormatJSON, nil); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	var out struct {
+		Questions []models.SurveyQuestion `json:"questions"`
+		Responses []SurveyRecord          `json:"responses"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("JSON格式不正确: %v\n%s", err, buf.String())
+	}
+	if len(out.Questions) != 3 || len(out.Responses) != 2 {
+		t.Fatalf("期望3个问题2份作答, 得到 %d %d", len(out.Questions), len(out.Responses))
+	}
+	if out.Responses[0].Answers[1] != "4" || out.Responses[1].Answers[2] != "" {
+		t.Errorf("回答不正确: %+v", out.Responses)
+	}
+}
+
+func TestWriteSurveyXLSX(t *testing.T) {
+	db := setupTestDB()
+	sv := setupSurvey(db)
+
+	var buf bytes.Buffer
+	if err := WriteSurvey(&buf, db, sv.ID, FormatXLSX, nil); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("PK")) {
+		t.Error("xlsx应为zip格式")
+	}
+}
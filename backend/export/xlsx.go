@@ -0,0 +1,210 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// writeXLSX 输出包含"Results"和可选"Votes"两个工作表的xlsx文件
+func writeXLSX(w io.Writer, db *gorm.DB, results *Results, opts Options) error {
+	xw := newXLSXWriter(w)
+
+	if err := xw.startSheet("Results"); err != nil {
+		return err
+	}
+	marked := results.Mode != ""
+	header := []interface{}{"Option ID", "Option", "Votes", "Percentage", "Weighted Votes", "Weighted Percentage"}
+	if marked {
+		header = append(header, "Points", "Average")
+	}
+	xw.writeRow(header...)
+	for _, option := range results.Options {
+		row := []interface{}{option.OptionID, option.Text, option.Votes, option.Percentage, option.WeightedVotes, option.WeightedPercentage}
+		if marked {
+			var average interface{} = ""
+			if option.Average != nil {
+				average = *option.Average
+			}
+			row = append(row, option.Points, average)
+		}
+		xw.writeRow(row...)
+	}
+	xw.writeRow("", "Total", results.TotalVotes, "", results.TotalWeight, "")
+
+	if opts.IncludeVotes {
+		if err := xw.startSheet("Votes"); err != nil {
+			return err
+		}
+		header := []interface{}{"Timestamp", "Option ID", "Option", "Voter Hash", "Weight"}
+		if marked {
+			header = append(header, "Value")
+		}
+		xw.writeRow(header...)
+
+		err := eachVote(db, results, opts.Hasher, func(record VoteRecord) error {
+			var w interface{} = ""
+			if record.Weight != nil {
+				w = *record.Weight
+			}
+			row := []interface{}{record.CreatedAt.UTC().Format(time.RFC3339), record.OptionID, record.OptionText, record.VoterHash, w}
+			if record.Value != nil {
+				row = append(row, *record.Value)
+			}
+			return xw.writeRow(row...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return xw.close()
+}
+
+// xlsxWriter 纯Go实现的最小xlsx写入器，工作表按行流式写入zip，不依赖共享字符串表
+type xlsxWriter struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  io.Writer
+	row    int
+	err    error
+}
+
+func newXLSXWriter(w io.Writer) *xlsxWriter {
+	return &xlsxWriter{zw: zip.NewWriter(w)}
+}
+
+func (x *xlsxWriter) write(s string) {
+	if x.err != nil {
+		return
+	}
+	_, x.err = io.WriteString(x.sheet, s)
+}
+
+// startSheet 结束当前工作表并开始一个新的工作表
+func (x *xlsxWriter) startSheet(name string) error {
+	x.endSheet()
+	if x.err != nil {
+		return x.err
+	}
+
+	x.sheets = append(x.sheets, name)
+	x.sheet, x.err = x.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(x.sheets)))
+	x.row = 0
+	x.write(xml.Header)
+	x.write(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return x.err
+}
+
+func (x *xlsxWriter) endSheet() {
+	if x.sheet == nil {
+		return
+	}
+	x.write(`</sheetData></worksheet>`)
+	x.sheet = nil
+}
+
+// writeRow 写入一行，数字写为数值单元格，其余写为内联字符串
+func (x *xlsxWriter) writeRow(cells ...interface{}) error {
+	x.row++
+	x.write(`<row r="` + strconv.Itoa(x.row) + `">`)
+
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(x.row)
+		switch v := cell.(type) {
+		case int:
+			x.write(`<c r="` + ref + `"><v>` + strconv.Itoa(v) + `</v></c>`)
+		case uint:
+			x.write(`<c r="` + ref + `"><v>` + strconv.FormatUint(uint64(v), 10) + `</v></c>`)
+		case float64:
+			x.write(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+		case weight.Weight:
+			// 以十进制原文写入，不经过浮点数
+			x.write(`<c r="` + ref + `"><v>` + v.String() + `</v></c>`)
+		default:
+			x.write(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">` + escapeXML(fmt.Sprint(v)) + `</t></is></c>`)
+		}
+	}
+
+	x.write(`</row>`)
+	return x.err
+}
+
+// close 写入工作簿元数据并结束zip
+func (x *xlsxWriter) close() error {
+	x.endSheet()
+	if x.err != nil {
+		return x.err
+	}
+
+	var contentTypes, workbook, workbookRels strings.Builder
+
+	contentTypes.WriteString(xml.Header)
+	contentTypes.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	contentTypes.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	contentTypes.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	contentTypes.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+
+	workbook.WriteString(xml.Header)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+
+	workbookRels.WriteString(xml.Header)
+	workbookRels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i, name := range x.sheets {
+		n := strconv.Itoa(i + 1)
+		contentTypes.WriteString(`<Override PartName="/xl/worksheets/sheet` + n + `.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+		workbook.WriteString(`<sheet name="` + escapeXML(name) + `" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+		workbookRels.WriteString(`<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`)
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	workbookRels.WriteString(`</Relationships>`)
+
+	rootRels := xml.Header +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes.String()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+	}
+	for _, f := range files {
+		fw, err := x.zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+
+	return x.zw.Close()
+}
+
+// columnName 将从0开始的列号转换为A、B…Z、AA形式
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package grpcapi
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/pollpb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrorDomain 错误详情中ErrorInfo的domain
+const ErrorDomain = "vote-system"
+
+// grpcCodes HTTP状态码对应的gRPC状态码
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:   codes.InvalidArgument,
+	http.StatusUnauthorized: codes.Unauthenticated,
+	http.StatusForbidden:    codes.PermissionDenied,
+	http.StatusNotFound:     codes.NotFound,
+	http.StatusConflict:     codes.FailedPrecondition,
+}
+
+// codeOverrides 比按HTTP状态码映射更贴切的gRPC状态码
+var codeOverrides = map[apierror.Code]codes.Code{
+	apierror.AlreadyVoted: codes.AlreadyExists,
+}
+
+// toStatus 将服务层的错误转换为gRPC状态，业务错误在详情中附带错误码，信息按metadata中的accept-language本地化
+func toStatus(ctx context.Context, err error) error {
+	e, ok := apierror.As(err)
+	if !ok {
+		// 与HTTP接口一样不向调用方暴露内部错误的原因
+		method, _ := grpc.Method(ctx)
+		log.Printf("gRPC %s: %v", method, err)
+		e = apierror.New(apierror.Internal)
+	}
+
+	code, ok := codeOverrides[e.Code]
+	if !ok {
+		if code, ok = grpcCodes[e.Code.Status()]; !ok {
+			code = codes.Internal
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	lang := apierror.Negotiate(first(md.Get("accept-language")))
+
+	st := status.New(code, apierror.Message(lang, e.Code, e.Params...))
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(e.Code), Domain: ErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// first 取metadata中的第一个值
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// pollView 转换投票人视角的投票问卷
+func pollView(view models.PollResponse) *pollpb.PollView {
+	msg := &pollpb.PollView{
+		Poll:        pollMessage(view.Poll),
+		TotalVotes:  int64(view.TotalVotes),
+		UserVoted:   view.UserVoted,
+		TotalWeight: view.TotalWeight.String(),
+	}
+	if view.VotedOption != nil {
+		msg.VotedOption = uint64(*view.VotedOption)
+	}
+	if view.Turnout != nil {
+		msg.Turnout = turnoutMessage(*view.Turnout)
+	}
+	return msg
+}
+
+// turnoutMessage 转换按名册统计的投票率
+func turnoutMessage(turnout models.Turnout) *pollpb.Turnout {
+	return &pollpb.Turnout{
+		RollId:     uint64(turnout.RollID),
+		Eligible:   int64(turnout.Eligible),
+		Voted:      int64(turnout.Voted),
+		Percentage: turnout.Percentage,
+	}
+}
+
+// pollMessage 转换投票问卷，票数按传入数据是否已隐藏原样转换
+func pollMessage(poll models.Poll) *pollpb.Poll {
+	msg := &pollpb.Poll{
+		Id:               uint64(poll.ID),
+		Title:            poll.Title,
+		Description:      poll.Description,
+		IsActive:         poll.IsActive,
+		ResultVisibility: poll.ResultVisibility,
+		ResultsHidden:    poll.ResultsHidden,
+		CloseReason:      poll.CloseReason,
+		ClosedAt:         timestamp(poll.ClosedAt),
+		CreatedAt:        timestamp(&poll.CreatedAt),
+		UpdatedAt:        timestamp(&poll.UpdatedAt),
+		AllowProposals:   poll.AllowProposals,
+		Rules: &pollpb.PollRules{
+			TargetVotes:    int64(poll.Rules.TargetVotes),
+			WinPercent:     int64(poll.Rules.WinPercent),
+			WinMinVotes:    int64(poll.Rules.WinMinVotes),
+			EligibleVoters: int64(poll.Rules.EligibleVoters),
+			ClosesAt:       timestamp(poll.Rules.ClosesAt),
+		},
+	}
+	for _, option := range poll.Options {
+		msg.Options = append(msg.Options, &pollpb.Option{
+			Id:            uint64(option.ID),
+			Text:          option.Text,
+			VoteCount:     int64(option.VoteCount),
+			WriteIn:       option.WriteIn,
+			WeightedVotes: option.WeightedVotes.String(),
+		})
+	}
+	return msg
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,290 @@
+// Package grpcapi 投票问卷的gRPC接口，与HTTP接口共用service层
+//
+// 接口定义见pollpb/poll.proto。WatchPoll订阅WebSocket Hub的广播，与WebSocket客户端收到的更新相同。
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/apitoken"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/pollpb"
+	"vote-system/service"
+	"vote-system/websocket"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Server 实现pollpb.PollServiceServer
+type Server struct {
+	pollpb.UnimplementedPollServiceServer
+	polls *service.PollService
+	hub   *websocket.Hub
+}
+
+// NewServer 创建Server，hub为nil时WatchPoll只返回当前数据
+func NewServer(polls *service.PollService, hub *websocket.Hub) *Server {
+	return &Server{
+		polls: polls,
+		hub:   hub,
+	}
+}
+
+// NewGRPCServer 创建注册了PollService的grpc.Server，所有调用都需要管理令牌或API令牌
+//
+// 未配置管理令牌且db为nil时拒绝所有调用，与HTTP管理接口相同。
+func NewGRPCServer(srv *Server, token string, db *gorm.DB) *grpc.Server {
+	auth := authenticator{token: token, db: db}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.unary),
+		grpc.StreamInterceptor(auth.stream),
+	)
+	pollpb.RegisterPollServiceServer(s, srv)
+	return s
+}
+
+// GetPoll 按投票人的身份返回投票问卷和统计数据
+func (s *Server) GetPoll(ctx context.Context, req *pollpb.GetPollRequest) (*pollpb.PollView, error) {
+	view, err := s.polls.View(uint(req.PollId), req.Voter)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return pollView(view), nil
+}
+
+// ListPolls 按管理员身份列出所有投票问卷
+func (s *Server) ListPolls(ctx context.Context, req *pollpb.ListPollsRequest) (*pollpb.ListPollsResponse, error) {
+	polls, err := s.polls.List()
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	resp := &pollpb.ListPollsResponse{}
+	for _, poll := range polls {
+		resp.Polls = append(resp.Polls, pollMessage(poll))
+	}
+	return resp, nil
+}
+
+// Vote 代投票人投票，只支持单选投票问卷
+func (s *Server) Vote(ctx context.Context, req *pollpb.VoteRequest) (*pollpb.VoteResponse, error) {
+	if req.Voter == "" {
+		return nil, toStatus(ctx, apierror.New(apierror.InvalidParameter, "name", "voter"))
+	}
+	if req.OptionId == 0 {
+		return nil, toStatus(ctx, apierror.New(apierror.InvalidParameter, "name", "option_id"))
+	}
+	if err := s.polls.Vote(uint(req.PollId), uint(req.OptionId), req.Voter, req.WriteIn, service.Credentials{
+		AccessCode:  req.AccessCode,
+		BallotToken: req.BallotToken,
+	}); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &pollpb.VoteResponse{}, nil
+}
+
+// ResetPoll 清除投票问卷的所有投票
+func (s *Server) ResetPoll(ctx context.Context, req *pollpb.ResetPollRequest) (*pollpb.ResetPollResponse, error) {
+	var poll models.Poll
+	var err error
+	if req.PollId == 0 {
+		poll, err = s.polls.Active()
+	} else {
+		poll, err = s.polls.Load(uint(req.PollId))
+	}
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	if err := s.polls.Reset(poll, s.auditEntry(ctx)); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &pollpb.ResetPollResponse{}, nil
+}
+
+// WatchPoll 先发送当前数据，之后转发Hub中该投票问卷的广播，直到客户端取消或Hub因消费过慢断开订阅
+func (s *Server) WatchPoll(req *pollpb.WatchPollRequest, stream pollpb.PollService_WatchPollServer) error {
+	ctx := stream.Context()
+
+	// 先订阅再读取当前数据，避免丢失两者之间的更新
+	var messages <-chan []byte
+	if s.hub != nil {
+		var cancel func()
+		messages, cancel = s.hub.Subscribe(websocket.Audience{Voter: req.Voter})
+		defer cancel()
+	}
+
+	view, err := s.polls.View(uint(req.PollId), req.Voter)
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+	pollID := view.Poll.ID
+	if err := stream.Send(&pollpb.PollEvent{Type: "snapshot", Poll: pollMessage(view.Poll)}); err != nil {
+		return err
+	}
+	if messages == nil {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case data, ok := <-messages:
+			if !ok {
+				return status.Error(codes.Unavailable, "subscription dropped: client too slow")
+			}
+			event, err := pollEvent(data, pollID)
+			if err != nil {
+				log.Printf("Error decoding hub message for gRPC watcher: %v", err)
+				continue
+			}
+			if event == nil {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// hubMessage Hub广播的消息，data按type解析
+type hubMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// pollEvent 将Hub广播转换为指定投票问卷的事件，其他投票问卷的广播返回nil
+func pollEvent(data []byte, pollID uint) (*pollpb.PollEvent, error) {
+	var msg hubMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+
+	switch msg.Type {
+	case "poll_update":
+		var poll models.Poll
+		if err := json.Unmarshal(msg.Data, &poll); err != nil {
+			return nil, err
+		}
+		if poll.ID != pollID {
+			return nil, nil
+		}
+		return &pollpb.PollEvent{Type: msg.Type, Poll: pollMessage(poll)}, nil
+	case "poll_closed":
+		var closed struct {
+			PollID uint   `json:"poll_id"`
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(msg.Data, &closed); err != nil {
+			return nil, err
+		}
+		if closed.PollID != pollID {
+			return nil, nil
+		}
+		return &pollpb.PollEvent{Type: msg.Type, CloseReason: closed.Reason}, nil
+	case "turnout_update":
+		var turnout models.Turnout
+		if err := json.Unmarshal(msg.Data, &turnout); err != nil {
+			return nil, err
+		}
+		if turnout.PollID != pollID {
+			return nil, nil
+		}
+		return &pollpb.PollEvent{Type: msg.Type, Turnout: turnoutMessage(turnout)}, nil
+	}
+	return nil, nil
+}
+
+// auditEntry 根据调用上下文生成审计事件的操作人、IP和请求ID
+func (s *Server) auditEntry(ctx context.Context) audit.Entry {
+	entry := audit.Entry{Actor: actorFrom(ctx), RequestID: requestID(ctx)}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(entry.IP); err == nil {
+			entry.IP = host
+		}
+	}
+	return entry
+}
+
+// requestID 沿用调用方在metadata中传入的x-request-id，否则生成新的请求ID
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= 64 {
+			return ids[0]
+		}
+	}
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+type actorKey struct{}
+
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// authenticator 校验metadata中的Bearer令牌，通过后将操作人写入上下文
+type authenticator struct {
+	token string
+	db    *gorm.DB
+}
+
+func (a authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	if a.token == "" && a.db == nil {
+		return nil, toStatus(ctx, apierror.New(apierror.AdminDisabled))
+	}
+
+	var provided string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			provided = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	actor, ok := apitoken.Authenticate(a.db, a.token, provided)
+	if !ok {
+		return nil, toStatus(ctx, apierror.New(apierror.Unauthorized))
+	}
+	return context.WithValue(ctx, actorKey{}, actor), nil
+}
+
+func (a authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream 替换流的上下文，使处理函数能读取操作人
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,179 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/pollpb"
+	"vote-system/service"
+	"vote-system/websocket"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testToken = "secret"
+
+type testEnv struct {
+	db         *gorm.DB
+	client     pollpb.PollServiceClient
+	dispatcher *outbox.Dispatcher
+	poll       models.Poll
+}
+
+func setup(t *testing.T) *testEnv {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.OptionProposal{}, &models.BallotToken{}, &models.VoterRoll{}, &models.RollMember{}, &models.Delegation{}, &models.VoteRollup{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{}, &models.APIToken{})
+
+	poll := models.Poll{Title: "测试投票", IsActive: true, Options: []models.Option{{Text: "A"}, {Text: "B"}}}
+	db.Create(&poll)
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	dispatcher := outbox.NewDispatcher(db, outbox.NewHubPublisher(db, hub, nil))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := NewGRPCServer(NewServer(service.NewPollService(db, dispatcher, nil), hub), testToken, db)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testEnv{db: db, client: pollpb.NewPollServiceClient(conn), dispatcher: dispatcher, poll: poll}
+}
+
+func authorized(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+testToken, "accept-language", "en")
+}
+
+// errorReason 取出状态详情中的错误码
+func errorReason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestAuth(t *testing.T) {
+	env := setup(t)
+
+	_, err := env.client.GetPoll(context.Background(), &pollpb.GetPollRequest{})
+	if status.Code(err) != codes.Unauthenticated || errorReason(err) != "unauthorized" {
+		t.Errorf("未携带令牌时期望 Unauthenticated, 得到 %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	if _, err := env.client.ListPolls(ctx, &pollpb.ListPollsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("错误的令牌期望 Unauthenticated, 得到 %v", err)
+	}
+}
+
+func TestVote(t *testing.T) {
+	env := setup(t)
+	ctx := authorized(context.Background())
+	optionID := uint64(env.poll.Options[0].ID)
+
+	if _, err := env.client.Vote(ctx, &pollpb.VoteRequest{OptionId: optionID, Voter: "10.0.0.1"}); err != nil {
+		t.Fatalf("投票失败: %v", err)
+	}
+
+	// 与HTTP接口相同的重复投票检查
+	_, err := env.client.Vote(ctx, &pollpb.VoteRequest{PollId: uint64(env.poll.ID), OptionId: optionID, Voter: "10.0.0.1"})
+	if status.Code(err) != codes.AlreadyExists || errorReason(err) != "already_voted" {
+		t.Errorf("期望 already_voted, 得到 %v", err)
+	}
+	if msg := status.Convert(err).Message(); msg != "You have already voted" {
+		t.Errorf("错误信息应按accept-language本地化, 得到 %q", msg)
+	}
+
+	_, err = env.client.Vote(ctx, &pollpb.VoteRequest{OptionId: 9999, Voter: "10.0.0.2"})
+	if status.Code(err) != codes.InvalidArgument || errorReason(err) != "invalid_option" {
+		t.Errorf("期望 invalid_option, 得到 %v", err)
+	}
+
+	view, err := env.client.GetPoll(ctx, &pollpb.GetPollRequest{Voter: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !view.UserVoted || view.VotedOption != optionID || view.TotalVotes != 1 || view.TotalWeight != "1" {
+		t.Errorf("投票结果不正确: %+v", view)
+	}
+
+	// 已关闭的投票问卷
+	env.db.Model(&env.poll).Update("is_active", false)
+	_, err = env.client.Vote(ctx, &pollpb.VoteRequest{PollId: uint64(env.poll.ID), OptionId: optionID, Voter: "10.0.0.3"})
+	if status.Code(err) != codes.FailedPrecondition || errorReason(err) != "poll_closed" {
+		t.Errorf("期望 poll_closed, 得到 %v", err)
+	}
+}
+
+func TestResetPoll(t *testing.T) {
+	env := setup(t)
+	ctx := authorized(context.Background())
+
+	env.client.Vote(ctx, &pollpb.VoteRequest{OptionId: uint64(env.poll.Options[0].ID), Voter: "10.0.0.1"})
+	if _, err := env.client.ResetPoll(ctx, &pollpb.ResetPollRequest{PollId: uint64(env.poll.ID)}); err != nil {
+		t.Fatalf("重置失败: %v", err)
+	}
+
+	var log models.AuditLog
+	if err := env.db.Where("action = ?", models.AuditPollReset).First(&log).Error; err != nil || log.Actor != "admin" {
+		t.Errorf("重置应记录审计日志, 操作人为admin: %+v %v", log, err)
+	}
+
+	_, err := env.client.ResetPoll(ctx, &pollpb.ResetPollRequest{PollId: 9999})
+	if status.Code(err) != codes.NotFound || errorReason(err) != "poll_not_found" {
+		t.Errorf("期望 poll_not_found, 得到 %v", err)
+	}
+}
+
+func TestWatchPoll(t *testing.T) {
+	env := setup(t)
+	ctx, cancel := context.WithTimeout(authorized(context.Background()), 5*time.Second)
+	defer cancel()
+
+	stream, err := env.client.WatchPoll(ctx, &pollpb.WatchPollRequest{Voter: "10.0.0.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := stream.Recv()
+	if err != nil || event.Type != "snapshot" || event.Poll.Id != uint64(env.poll.ID) {
+		t.Fatalf("第一个事件应为当前数据: %+v %v", event, err)
+	}
+
+	if _, err := env.client.Vote(ctx, &pollpb.VoteRequest{OptionId: uint64(env.poll.Options[1].ID), Voter: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.dispatcher.DispatchPending(); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "poll_update" || event.Poll.Options[1].VoteCount != 1 || event.Poll.Options[1].WeightedVotes != "1" {
+		t.Errorf("期望推送投票更新, 得到 %+v", event)
+	}
+}
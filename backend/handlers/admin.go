@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/delegation"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/rules"
+	"vote-system/service"
+	"vote-system/stats"
+	"vote-system/tally"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errPollStateChanged 并发请求已先一步改变了投票问卷状态
+var errPollStateChanged = errors.New("poll state changed")
+
+// ListPolls 按条件分页列出投票问卷（管理接口），通过next_cursor翻页
+func (h *PollHandler) ListPolls(c *gin.Context) {
+	filter := service.PollFilter{
+		State:  c.Query("state"),
+		Tag:    c.Query("tag"),
+		Owner:  c.Query("owner"),
+		Query:  c.Query("q"),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+		Cursor: c.Query("cursor"),
+	}
+
+	for name, dst := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				apierror.Abort(c, apierror.InvalidTime, "name", name)
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			apierror.Abort(c, apierror.InvalidParameter, "name", "limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := h.polls.Search(filter)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, PollListResponse{Polls: page.Polls, NextCursor: page.NextCursor})
+}
+
+// GetPollByID 获取指定投票问卷（管理接口）
+func (h *PollHandler) GetPollByID(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+	if err := tally.Fill(h.db, &poll); err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+	service.AdminView(&poll)
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// CreatePoll 创建投票问卷（管理接口）
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	var req models.CreatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	visibility := req.ResultVisibility
+	if visibility == "" {
+		visibility = models.VisibilityAlways
+	}
+	if !models.ValidVisibility(visibility) {
+		apierror.Invalid(c, apierror.Field("result_visibility", "oneof", ""))
+		return
+	}
+	access := req.Access
+	if access == "" {
+		access = models.AccessPublic
+	}
+	if !models.ValidAccess(access) {
+		apierror.Invalid(c, apierror.Field("access", "oneof", ""))
+		return
+	}
+	if access == models.AccessPrivate && req.AccessCode == "" {
+		apierror.Invalid(c, apierror.Field("access_code", "required", ""))
+		return
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = models.ModePlurality
+	}
+	if !models.ValidMode(mode) {
+		apierror.Invalid(c, apierror.Field("mode", "oneof", ""))
+		return
+	}
+	maxScore := req.MaxScore
+	if mode == models.ModeScore && maxScore == 0 {
+		maxScore = models.DefaultMaxScore
+	}
+	var rules models.PollRules
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+	if field, rule, param := models.ModeConflict(mode, maxScore, rules, req.WriteInOption != "", req.AllowProposals); field != "" {
+		apierror.Invalid(c, apierror.Field(field, rule, param))
+		return
+	}
+	var rollID *uint
+	if req.RollID != nil && *req.RollID != 0 {
+		if !h.checkRoll(c, *req.RollID, access) {
+			return
+		}
+		rollID = req.RollID
+	}
+
+	active := req.IsActive == nil || *req.IsActive
+	poll := models.Poll{
+		Title:            req.Title,
+		Description:      req.Description,
+		Tags:             models.NormalizeTags(req.Tags),
+		IsActive:         active,
+		ResultVisibility: visibility,
+		AllowProposals:   req.AllowProposals,
+		Access:           access,
+		RollID:           rollID,
+		Mode:             mode,
+		MaxScore:         maxScore,
+		Rules:            rules,
+	}
+	if req.AccessCode != "" {
+		poll.AccessCodeHash = ballot.Hash(req.AccessCode)
+	}
+	for _, text := range req.Options {
+		poll.Options = append(poll.Options, models.Option{Text: text})
+	}
+	if req.WriteInOption != "" {
+		poll.Options = append(poll.Options, models.Option{Text: req.WriteInOption, WriteIn: true})
+	}
+
+	if err := h.insertPoll(c, &poll, active, nil); err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, poll)
+}
+
+// insertPoll 在事务中创建投票问卷及选项并写入事件和审计日志，source记录模板或复制来源
+//
+// 投票问卷的创建者为当前的管理操作人。
+func (h *PollHandler) insertPoll(c *gin.Context, poll *models.Poll, active bool, source gin.H) error {
+	poll.IsActive = active
+	poll.Owner = c.GetString(actorKey)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(poll).Error; err != nil {
+			return err
+		}
+		// IsActive带default标签，零值会被替换为默认值，需要单独更新
+		if !active {
+			if err := tx.Model(poll).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollCreated, gin.H{"title": poll.Title}); err != nil {
+			return err
+		}
+
+		after := audit.PollSummary(*poll)
+		if source != nil {
+			after["source"] = source
+		}
+		return audit.Record(tx, h.auditEntry(c, models.AuditPollCreated, poll.ID, nil, after))
+	})
+	if err != nil {
+		return err
+	}
+	h.dispatcher.Notify()
+	return nil
+}
+
+// UpdatePoll 编辑投票问卷的标题、描述、选项文本、自填选项和自动关闭规则（管理接口）
+func (h *PollHandler) UpdatePoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	existing := map[uint]bool{}
+	for _, option := range poll.Options {
+		existing[option.ID] = true
+	}
+	for _, input := range req.Options {
+		if input.ID != 0 && !existing[input.ID] {
+			apierror.Abort(c, apierror.InvalidOption)
+			return
+		}
+	}
+
+	// 投票方式创建后不能修改，新的规则、自填选项和选项提议需要与之兼容
+	if req.Rules != nil || req.WriteInOption != nil || req.AllowProposals != nil {
+		rules, allowProposals := poll.Rules, poll.AllowProposals
+		if req.Rules != nil {
+			rules = *req.Rules
+		}
+		if req.AllowProposals != nil {
+			allowProposals = *req.AllowProposals
+		}
+		if field, rule, param := models.ModeConflict(poll.Mode, poll.MaxScore, rules, req.WriteInOption != nil, allowProposals); field != "" {
+			apierror.Invalid(c, apierror.Field(field, rule, param))
+			return
+		}
+	}
+	// 波达计数的得分按创建时的选项数计算，不能再增加选项
+	if poll.Mode == models.ModeBorda {
+		for _, input := range req.Options {
+			if input.ID == 0 {
+				apierror.Invalid(c, apierror.Field("options", "unsupported", ""))
+				return
+			}
+		}
+	}
+
+	if req.ResultVisibility != nil {
+		if !models.ValidVisibility(*req.ResultVisibility) {
+			apierror.Invalid(c, apierror.Field("result_visibility", "oneof", ""))
+			return
+		}
+		// 关闭后可见的投票问卷在进行中不能放宽，否则等于提前公开结果
+		if poll.IsActive && poll.ResultVisibility == models.VisibilityAfterClose && *req.ResultVisibility != models.VisibilityAfterClose {
+			apierror.Abort(c, apierror.VisibilityLocked)
+			return
+		}
+	}
+
+	if req.Access != nil {
+		if !models.ValidAccess(*req.Access) {
+			apierror.Invalid(c, apierror.Field("access", "oneof", ""))
+			return
+		}
+		if *req.Access == models.AccessPrivate && poll.AccessCodeHash == "" && req.AccessCode == nil {
+			apierror.Invalid(c, apierror.Field("access_code", "required", ""))
+			return
+		}
+	}
+
+	// 按修改后的访问方式和名册检查，两者可能只修改其一
+	access, rollID := poll.Access, poll.RollID
+	if req.Access != nil {
+		access = *req.Access
+	}
+	if req.RollID != nil {
+		rollID = req.RollID
+		if *req.RollID == 0 {
+			rollID = nil
+		}
+	}
+	if rollID != nil && (req.RollID != nil || req.Access != nil) && !h.checkRoll(c, *rollID, access) {
+		return
+	}
+
+	before := audit.PollSummary(poll)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{}
+		if req.Title != nil {
+			updates["title"] = *req.Title
+		}
+		if req.Description != nil {
+			updates["description"] = *req.Description
+		}
+		if req.Tags != nil {
+			updates["tags"] = models.NormalizeTags(req.Tags)
+		}
+		if req.ResultVisibility != nil {
+			updates["result_visibility"] = *req.ResultVisibility
+		}
+		if req.AllowProposals != nil {
+			updates["allow_proposals"] = *req.AllowProposals
+		}
+		if req.Access != nil {
+			updates["access"] = *req.Access
+		}
+		if req.AccessCode != nil {
+			updates["access_code_hash"] = ballot.Hash(*req.AccessCode)
+		}
+		if req.RollID != nil {
+			updates["roll_id"] = rollID
+		}
+		if req.Rules != nil {
+			updates["target_votes"] = req.Rules.TargetVotes
+			updates["win_percent"] = req.Rules.WinPercent
+			updates["win_min_votes"] = req.Rules.WinMinVotes
+			updates["eligible_voters"] = req.Rules.EligibleVoters
+			updates["closes_at"] = req.Rules.ClosesAt
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&poll).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		// 已有选项只允许修改文本，不删除，避免丢失已投票数
+		for _, input := range req.Options {
+			if input.ID == 0 {
+				if err := tx.Create(&models.Option{PollID: poll.ID, Text: input.Text}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&models.Option{}).Where("id = ? AND poll_id = ?", input.ID, poll.ID).Update("text", input.Text).Error; err != nil {
+				return err
+			}
+		}
+
+		// 每个投票问卷只有一个自填选项，已有时修改文本
+		if req.WriteInOption != nil {
+			writeIn := models.Option{PollID: poll.ID, Text: *req.WriteInOption, WriteIn: true}
+			for _, option := range poll.Options {
+				if option.WriteIn {
+					writeIn.ID = option.ID
+				}
+			}
+			if writeIn.ID == 0 {
+				if err := tx.Create(&writeIn).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Model(&writeIn).Update("text", writeIn.Text).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Preload("Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).First(&poll, poll.ID).Error; err != nil {
+			return err
+		}
+
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollUpdated, gin.H{"title": poll.Title}); err != nil {
+			return err
+		}
+		after := audit.PollSummary(poll)
+		if req.AccessCode != nil {
+			after["access_code_changed"] = true
+		}
+		return audit.Record(tx, h.auditEntry(c, models.AuditPollUpdated, poll.ID, before, after))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+	h.dispatcher.Notify()
+	service.AdminView(&poll)
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// checkRoll 检查投票问卷要使用的名册，名册不存在或访问方式为邀请制时写入校验错误并返回false
+//
+// 邀请制投票问卷的投票记录不能与投票人对应，不能同时按名册统计投票率。
+func (h *PollHandler) checkRoll(c *gin.Context, rollID uint, access string) bool {
+	if access == models.AccessInvite {
+		apierror.Invalid(c, apierror.Field("roll_id", "unsupported", ""))
+		return false
+	}
+	var count int64
+	if err := h.db.Model(&models.VoterRoll{}).Where("id = ?", rollID).Count(&count).Error; err != nil {
+		apierror.Fail(c, err)
+		return false
+	}
+	if count == 0 {
+		apierror.Invalid(c, apierror.Field("roll_id", "not_found", ""))
+		return false
+	}
+	return true
+}
+
+// OpenPoll 开启投票问卷（管理接口）
+func (h *PollHandler) OpenPoll(c *gin.Context) {
+	h.setPollState(c, true)
+}
+
+// ClosePoll 关闭投票问卷（管理接口）
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	h.setPollState(c, false)
+}
+
+func (h *PollHandler) setPollState(c *gin.Context, active bool) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	if poll.IsActive == active {
+		apierror.Abort(c, apierror.PollStateUnchanged)
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if !active {
+			outcome, ok, err := rules.Close(tx, &poll, h.hasher, models.CloseReasonManual, time.Now())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errPollStateChanged
+			}
+			return audit.Record(tx, h.auditEntry(c, models.AuditPollClosed, poll.ID, gin.H{"is_active": true},
+				gin.H{"is_active": false, "close_reason": models.CloseReasonManual, "outcome": outcome}))
+		}
+
+		// 重新开启时删除关闭时按委托计入的投票，再次关闭时按当时的委托重新计入
+		reverted, err := delegation.Revert(tx, poll.ID)
+		if err != nil {
+			return err
+		}
+
+		// 重新开启时清除上一次的关闭时间和原因
+		if err := tx.Model(&poll).Updates(map[string]interface{}{
+			"is_active":    true,
+			"closed_at":    nil,
+			"close_reason": "",
+		}).Error; err != nil {
+			return err
+		}
+		poll.IsActive, poll.ClosedAt, poll.CloseReason = true, nil, ""
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollOpened, gin.H{}); err != nil {
+			return err
+		}
+		after := gin.H{"is_active": true}
+		if reverted > 0 {
+			after["delegated_votes_removed"] = reverted
+		}
+		return audit.Record(tx, h.auditEntry(c, models.AuditPollOpened, poll.ID, gin.H{"is_active": false}, after))
+	})
+	if errors.Is(err, errPollStateChanged) {
+		apierror.Abort(c, apierror.PollStateUnchanged)
+		return
+	}
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+	h.dispatcher.Notify()
+	service.AdminView(&poll)
+
+	c.JSON(http.StatusOK, poll)
+}
+
+// AdminResetPoll 重置指定投票问卷（管理接口）
+func (h *PollHandler) AdminResetPoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	h.resetPoll(c, poll)
+}
+
+// ReconcilePoll 按投票记录修正选项票数并重建汇总数据（管理接口），dry_run=true时只返回不一致的选项
+//
+// 结果对管理员隐藏时响应和审计日志只包含不一致的选项ID。
+func (h *PollHandler) ReconcilePoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		drift, err := stats.FindDrift(h.db, poll.ID)
+		if err != nil {
+			apierror.Fail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, newReconcileResponse(poll, true, drift))
+		return
+	}
+
+	var drift []stats.Drift
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if drift, err = stats.Reconcile(tx, poll.ID); err != nil {
+			return err
+		}
+		if len(drift) == 0 {
+			return nil
+		}
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollUpdated, gin.H{"title": poll.Title}); err != nil {
+			return err
+		}
+		// 结果对管理员隐藏时审计日志同样不记录票数
+		response := newReconcileResponse(poll, false, drift)
+		after := gin.H{"drift": drift}
+		if response.ResultsHidden {
+			after = gin.H{"option_ids": response.OptionIDs}
+		}
+		return audit.Record(tx, h.auditEntry(c, models.AuditPollReconciled, poll.ID, nil, after))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+	h.dispatcher.Notify()
+
+	c.JSON(http.StatusOK, newReconcileResponse(poll, false, drift))
+}
+
+// newReconcileResponse 创建修正票数响应，结果对管理员隐藏时只保留不一致的选项ID
+func newReconcileResponse(poll models.Poll, dryRun bool, drift []stats.Drift) ReconcileResponse {
+	response := ReconcileResponse{PollID: poll.ID, DryRun: dryRun, Drift: drift}
+	if poll.ResultsVisible(false, true) {
+		return response
+	}
+
+	response.Drift = []stats.Drift{}
+	response.ResultsHidden = true
+	response.OptionIDs = make([]uint, 0, len(drift))
+	for _, d := range drift {
+		response.OptionIDs = append(response.OptionIDs, d.OptionID)
+	}
+	return response
+}
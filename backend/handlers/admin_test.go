@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/models"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupPollAdminRouter(db *gorm.DB) *gin.Engine {
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	auditHandler := NewAuditHandler(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.DELETE("/reset", handler.ResetPoll)
+
+	admin := router.Group("/admin", AdminAuth("secret", nil))
+	admin.GET("/polls", handler.ListPolls)
+	admin.POST("/polls", handler.CreatePoll)
+	admin.POST("/polls/import", handler.ImportPolls)
+	admin.GET("/polls/manifest", handler.ExportManifest)
+	admin.PUT("/polls/:id", handler.UpdatePoll)
+	admin.POST("/polls/:id/open", handler.OpenPoll)
+	admin.POST("/polls/:id/close", handler.ClosePoll)
+	admin.POST("/polls/:id/reset", handler.AdminResetPoll)
+	admin.POST("/polls/:id/reconcile", handler.ReconcilePoll)
+	admin.GET("/audit", auditHandler.ListAuditLogs)
+	admin.GET("/audit/verify", auditHandler.VerifyAuditLog)
+	return router
+}
+
+func adminRequest(router *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req, _ := http.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Actor", "alice")
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreatePoll(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+
+	inactive := false
+	w := adminRequest(router, "POST", "/admin/polls", models.CreatePollRequest{
+		Title:    "午餐吃什么",
+		Options:  []string{"面", "饭"},
+		IsActive: &inactive,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var poll models.Poll
+	db.Preload("Options").First(&poll)
+	if poll.Title != "午餐吃什么" || len(poll.Options) != 2 {
+		t.Errorf("投票问卷创建不正确: %+v", poll)
+	}
+	if poll.IsActive {
+		t.Error("is_active=false 时投票问卷不应开启")
+	}
+
+	var log models.AuditLog
+	if err := db.Where("action = ?", models.AuditPollCreated).First(&log).Error; err != nil {
+		t.Fatal("创建投票问卷应写入审计日志")
+	}
+	if log.Actor != "admin:alice" || log.RequestID != "req-1" || log.PollID == nil || *log.PollID != poll.ID {
+		t.Errorf("审计日志内容不正确: %+v", log)
+	}
+}
+
+func TestCreatePoll_Validation(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+
+	w := adminRequest(router, "POST", "/admin/polls", gin.H{"title": "只有一个选项", "options": []string{"A", ""}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 字段级详情使用JSON字段名，信息默认为中文
+	var resp struct {
+		Code    string `json:"code"`
+		Details []struct {
+			Field   string `json:"field"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"details"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != "validation_failed" || len(resp.Details) != 1 {
+		t.Fatalf("错误响应不正确: %s", w.Body.String())
+	}
+	if detail := resp.Details[0]; detail.Field != "options[1]" || detail.Code != "required" || detail.Message != "不能为空" {
+		t.Errorf("字段详情不正确: %+v", detail)
+	}
+
+	w = adminRequest(router, "POST", "/admin/polls", gin.H{"title": "只有一个选项", "options": []string{"A"}})
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Details) != 1 || resp.Details[0].Field != "options" || resp.Details[0].Message != "至少需要2项" {
+		t.Errorf("字段详情不正确: %s", w.Body.String())
+	}
+}
+
+func TestListPolls(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+
+	for _, req := range []models.CreatePollRequest{
+		{Title: "午餐吃什么", Options: []string{"面", "饭"}, Tags: []string{" Food ", "food", "daily"}},
+		{Title: "团建地点", Options: []string{"海边", "山里"}, Tags: []string{"team"}},
+		{Title: "Team lunch", Options: []string{"Pizza", "Sushi"}, Tags: []string{"food", "team"}},
+	} {
+		if w := adminRequest(router, "POST", "/admin/polls", req); w.Code != http.StatusCreated {
+			t.Fatalf("创建投票问卷失败: %s", w.Body.String())
+		}
+	}
+
+	var created models.Poll
+	db.First(&created)
+	if created.Owner != "admin:alice" || len(created.Tags) != 2 || created.Tags[0] != "food" {
+		t.Errorf("创建者和标签不正确: owner=%q tags=%v", created.Owner, created.Tags)
+	}
+
+	list := func(query string) PollListResponse {
+		t.Helper()
+		w := adminRequest(router, "GET", "/admin/polls?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: 期望状态码 %d, 得到 %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+		}
+		var resp PollListResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	// 按标签筛选并分页
+	first := list("tag=food&order=asc&limit=1")
+	if len(first.Polls) != 1 || first.Polls[0].Title != "午餐吃什么" || first.NextCursor == "" {
+		t.Fatalf("第一页不正确: %+v", first)
+	}
+	second := list("tag=food&order=asc&limit=1&cursor=" + first.NextCursor)
+	if len(second.Polls) != 1 || second.Polls[0].Title != "Team lunch" || second.NextCursor != "" {
+		t.Errorf("第二页不正确: %+v", second)
+	}
+
+	// 全文检索选项文本
+	if resp := list("q=sushi"); len(resp.Polls) != 1 || resp.Polls[0].Title != "Team lunch" {
+		t.Errorf("检索选项文本不正确: %+v", resp.Polls)
+	}
+	if resp := list("owner=admin:alice&state=closed"); len(resp.Polls) != 0 {
+		t.Errorf("不应有已关闭的投票问卷: %+v", resp.Polls)
+	}
+
+	// 编辑时整体替换标签
+	w := adminRequest(router, "PUT", "/admin/polls/"+strconv.Itoa(int(created.ID)), gin.H{"tags": []string{}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("编辑标签失败: %s", w.Body.String())
+	}
+	if resp := list("tag=daily"); len(resp.Polls) != 0 {
+		t.Errorf("清除标签后不应再匹配: %+v", resp.Polls)
+	}
+
+	for query, code := range map[string]string{
+		"limit=0":              "invalid_parameter",
+		"sort=title":           "invalid_parameter",
+		"cursor=bad":           "invalid_parameter",
+		"created_from=2026-01": "invalid_time",
+	} {
+		w := adminRequest(router, "GET", "/admin/polls?"+query, nil)
+		var resp struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusBadRequest || resp.Code != code {
+			t.Errorf("%s: 期望 %s, 得到 %d %s", query, code, w.Code, w.Body.String())
+		}
+	}
+
+	w = adminRequest(router, "POST", "/admin/polls", gin.H{"title": "标签含逗号", "options": []string{"A", "B"}, "tags": []string{"a,b"}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("标签含逗号期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdatePoll(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, options := setupTestData(db)
+
+	title := "新标题"
+	w := adminRequest(router, "PUT", "/admin/polls/"+strconv.Itoa(int(poll.ID)), models.UpdatePollRequest{
+		Title: &title,
+		Options: []models.OptionInput{
+			{ID: options[0].ID, Text: "改名选项"},
+			{Text: "新增选项"},
+		},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var updated models.Poll
+	db.Preload("Options").First(&updated, poll.ID)
+	if updated.Title != title || len(updated.Options) != 4 || updated.Options[0].Text != "改名选项" {
+		t.Errorf("投票问卷编辑不正确: %+v", updated)
+	}
+
+	var log models.AuditLog
+	db.Where("action = ?", models.AuditPollUpdated).First(&log)
+	if log.Before == "" || log.After == "" {
+		t.Error("编辑投票问卷的审计日志应包含前后摘要")
+	}
+
+	// 追加自填选项，再次提供时修改其文本
+	for _, text := range []string{"其他", "其他（请填写）"} {
+		allow := true
+		w = adminRequest(router, "PUT", "/admin/polls/"+strconv.Itoa(int(poll.ID)), models.UpdatePollRequest{WriteInOption: &text, AllowProposals: &allow})
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&updated, poll.ID)
+	if len(updated.Options) != 5 || !updated.Options[4].WriteIn || updated.Options[4].Text != "其他（请填写）" || !updated.AllowProposals {
+		t.Errorf("自填选项编辑不正确: %+v", updated)
+	}
+
+	// 不属于该投票问卷的选项
+	w = adminRequest(router, "PUT", "/admin/polls/"+strconv.Itoa(int(poll.ID)), models.UpdatePollRequest{
+		Options: []models.OptionInput{{ID: 999, Text: "X"}},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestOpenClosePoll(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, _ := setupTestData(db)
+	path := "/admin/polls/" + strconv.Itoa(int(poll.ID))
+
+	w := adminRequest(router, "POST", path+"/close", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	var closed models.Poll
+	db.First(&closed, poll.ID)
+	if closed.IsActive || closed.ClosedAt == nil {
+		t.Errorf("投票问卷应已关闭: %+v", closed)
+	}
+
+	if w := adminRequest(router, "POST", path+"/close", nil); w.Code != http.StatusConflict {
+		t.Errorf("重复关闭期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+
+	if w := adminRequest(router, "POST", path+"/open", nil); w.Code != http.StatusOK {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	var events []models.OutboxEvent
+	db.Order("id").Find(&events)
+	if len(events) != 2 || events[0].Type != models.EventPollClosed || events[1].Type != models.EventPollOpened {
+		t.Errorf("状态变更应写入outbox事件: %+v", events)
+	}
+
+	var count int64
+	db.Model(&models.AuditLog{}).Where("action IN ?", []string{models.AuditPollClosed, models.AuditPollOpened}).Count(&count)
+	if count != 2 {
+		t.Errorf("期望2条状态变更审计日志, 得到 %d", count)
+	}
+}
+
+func TestResetPoll_WritesAuditLog(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, options := setupTestData(db)
+	db.Model(&options[0]).Update("vote_count", 2)
+
+	// 公开的重置接口记为anonymous
+	req, _ := http.NewRequest("DELETE", "/reset", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 管理接口重置记为管理员
+	adminRequest(router, "POST", "/admin/polls/"+strconv.Itoa(int(poll.ID))+"/reset", nil)
+
+	var logs []models.AuditLog
+	db.Where("action = ?", models.AuditPollReset).Order("seq").Find(&logs)
+	if len(logs) != 2 {
+		t.Fatalf("期望2条重置审计日志, 得到 %d", len(logs))
+	}
+
+	if logs[0].Actor != "anonymous" || logs[1].Actor != "admin:alice" {
+		t.Errorf("操作人不正确: %s, %s", logs[0].Actor, logs[1].Actor)
+	}
+
+	var before map[string]interface{}
+	json.Unmarshal([]byte(logs[0].Before), &before)
+	if before["total_votes"] != float64(2) {
+		t.Errorf("重置前摘要应记录总票数: %s", logs[0].Before)
+	}
+
+	w := adminRequest(router, "GET", "/admin/audit/verify", nil)
+	var result struct {
+		Valid   bool `json:"valid"`
+		Entries int  `json:"entries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if !result.Valid || result.Entries != 2 {
+		t.Errorf("哈希链应校验通过: %s", w.Body.String())
+	}
+}
+
+func TestReconcilePoll(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, options := setupTestData(db)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[1].ID, UserIP: "10.0.0.1"})
+	db.Model(&options[0]).Update("vote_count", 4)
+
+	path := "/admin/polls/" + strconv.Itoa(int(poll.ID)) + "/reconcile"
+	var result struct {
+		Drift []struct {
+			OptionID uint `json:"option_id"`
+			Stored   int  `json:"stored"`
+			Actual   int  `json:"actual"`
+		} `json:"drift"`
+	}
+
+	// dry-run不修改票数
+	w := adminRequest(router, "POST", path+"?dry_run=true", nil)
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || len(result.Drift) != 2 {
+		t.Fatalf("期望2个不一致的选项, 得到 %d %s", w.Code, w.Body.String())
+	}
+	var option models.Option
+	db.First(&option, options[0].ID)
+	if option.VoteCount != 4 {
+		t.Errorf("dry-run不应修改票数, 得到 %d", option.VoteCount)
+	}
+
+	adminRequest(router, "POST", path, nil)
+	db.Where("poll_id = ?", poll.ID).Order("id").Find(&options)
+	if options[0].VoteCount != 0 || options[1].VoteCount != 1 {
+		t.Errorf("修正后的票数不正确: %d, %d", options[0].VoteCount, options[1].VoteCount)
+	}
+
+	var logs int64
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditPollReconciled).Count(&logs)
+	if logs != 1 {
+		t.Errorf("期望1条修正审计日志, 得到 %d", logs)
+	}
+
+	// 没有不一致时不写审计日志
+	w = adminRequest(router, "POST", path, nil)
+	json.Unmarshal(w.Body.Bytes(), &result)
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditPollReconciled).Count(&logs)
+	if len(result.Drift) != 0 || logs != 1 {
+		t.Errorf("期望没有变更, 得到 %s", w.Body.String())
+	}
+}
+
+func TestReconcilePoll_HiddenResults(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("result_visibility", models.VisibilityAfterClose)
+	db.Model(&options[0]).Update("vote_count", 4)
+
+	// 关闭前结果对管理员同样隐藏，只返回不一致的选项ID
+	path := "/admin/polls/" + strconv.Itoa(int(poll.ID)) + "/reconcile"
+	w := adminRequest(router, "POST", path, nil)
+	want := `"option_ids":[` + strconv.Itoa(int(options[0].ID)) + `]`
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) || strings.Contains(w.Body.String(), "stored") {
+		t.Errorf("期望只返回选项ID, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	var log models.AuditLog
+	db.Where("action = ?", models.AuditPollReconciled).First(&log)
+	if !strings.Contains(log.After, want) || strings.Contains(log.After, "stored") {
+		t.Errorf("审计日志不应包含票数: %s", log.After)
+	}
+	var option models.Option
+	db.First(&option, options[0].ID)
+	if option.VoteCount != 0 {
+		t.Errorf("结果隐藏时仍应修正票数, 得到 %d", option.VoteCount)
+	}
+}
+
+func TestListAuditLogs(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	poll, _ := setupTestData(db)
+	path := "/admin/polls/" + strconv.Itoa(int(poll.ID))
+
+	adminRequest(router, "POST", path+"/close", nil)
+	adminRequest(router, "POST", path+"/open", nil)
+
+	w := adminRequest(router, "GET", "/admin/audit?action="+models.AuditPollClosed, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	var resp struct {
+		Entries []models.AuditLog `json:"entries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Entries) != 1 || resp.Entries[0].Action != models.AuditPollClosed {
+		t.Errorf("按动作过滤结果不正确: %+v", resp.Entries)
+	}
+
+	if w := adminRequest(router, "GET", "/admin/audit?from=yesterday", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// ListAuditLogs 按条件查询审计日志（管理接口），通过before_id向前翻页
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	filter := audit.Filter{
+		Action: c.Query("action"),
+		Actor:  c.Query("actor"),
+	}
+
+	if v := c.Query("poll_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter, "name", "poll_id")
+			return
+		}
+		pollID := uint(id)
+		filter.PollID = &pollID
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				apierror.Abort(c, apierror.InvalidTime, "name", name)
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter, "name", "before_id")
+			return
+		}
+		filter.BeforeID = uint(id)
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidParameter, "name", "limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	logs, err := audit.Query(h.db, filter)
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AuditLogListResponse{Entries: logs})
+}
+
+// VerifyAuditLog 校验审计日志哈希链是否完整（管理接口）
+func (h *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	result, err := audit.Verify(h.db)
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
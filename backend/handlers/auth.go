@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+	"vote-system/apierror"
+	"vote-system/apitoken"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminAuth 校验管理接口的Bearer令牌，可以是配置的管理令牌或db中的API令牌
+//
+// 未配置管理令牌且db为nil时拒绝所有管理请求。
+func AdminAuth(token string, db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" && db == nil {
+			apierror.Abort(c, apierror.AdminDisabled)
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		actor, ok := apitoken.Authenticate(db, token, provided)
+		if !ok {
+			apierror.Abort(c, apierror.Unauthorized)
+			return
+		}
+
+		// 共用令牌时可通过X-Actor标明操作人，写入审计日志
+		if name := c.GetHeader("X-Actor"); name != "" && len(name) <= 64 && actor == "admin" {
+			actor = "admin:" + name
+		}
+		c.Set(actorKey, actor)
+
+		c.Next()
+	}
+}
+
+// WebSocketAudience 识别WebSocket连接的身份，浏览器无法设置请求头，管理令牌通过token查询参数传入
+func WebSocketAudience(c *gin.Context, token string, db *gorm.DB) websocket.Audience {
+	_, admin := apitoken.Authenticate(db, token, c.Query("token"))
+	return websocket.Audience{
+		Voter: voterOf(c),
+		Admin: admin,
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"vote-system/apierror"
+	"vote-system/export"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueBallots 为邀请制投票问卷批量生成一次性投票令牌（管理接口），令牌明文只在此时返回
+//
+// format=csv时以CSV文件下载，否则返回JSON。
+func (h *PollHandler) IssueBallots(c *gin.Context) {
+	pollID, ok := pollIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.IssueBallotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	labels := req.Labels
+	if len(labels) == 0 {
+		labels = make([]string, req.Count)
+	}
+	if len(labels) == 0 {
+		apierror.Invalid(c, apierror.Field("count", "required", ""))
+		return
+	}
+	weights, ok := ballotWeights(c, req, len(labels))
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatJSON)
+	if format != export.FormatJSON && format != export.FormatCSV {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	base := req.BaseURL
+	if base == "" {
+		base = requestOrigin(c) + "/"
+	}
+	page, err := url.Parse(base)
+	if err != nil {
+		apierror.Invalid(c, apierror.Field("base_url", "url", ""))
+		return
+	}
+
+	issued, err := h.polls.IssueBallots(pollID, labels, weights, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	for i := range issued {
+		issued[i].URL = ballotURL(*page, issued[i].Token)
+	}
+
+	if format == export.FormatJSON {
+		c.JSON(http.StatusCreated, IssuedBallotsResponse{Ballots: issued})
+		return
+	}
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="poll-%d-ballots.csv"`, pollID))
+	c.Status(http.StatusCreated)
+	if err := export.WriteIssuedBallots(c.Writer, issued); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// ListBallots 列出投票问卷的投票令牌和投票率（管理接口），format=csv时以CSV文件下载
+func (h *PollHandler) ListBallots(c *gin.Context) {
+	pollID, ok := pollIDParam(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatJSON)
+	if format != export.FormatJSON && format != export.FormatCSV {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	tokens, turnout, err := h.polls.Ballots(pollID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	if format == export.FormatJSON {
+		c.JSON(http.StatusOK, BallotListResponse{Ballots: tokens, Turnout: turnout})
+		return
+	}
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="poll-%d-turnout.csv"`, pollID))
+	c.Status(http.StatusOK)
+	if err := export.WriteBallots(c.Writer, tokens); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// RevokeBallot 吊销未使用的投票令牌（管理接口）
+func (h *PollHandler) RevokeBallot(c *gin.Context) {
+	pollID, ok := pollIDParam(c)
+	if !ok {
+		return
+	}
+	ballotID, err := strconv.ParseUint(c.Param("ballot_id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "ballot_id")
+		return
+	}
+
+	token, err := h.polls.RevokeBallot(pollID, uint(ballotID), newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// ballotWeights 解析每个令牌的投票权重：提供Weights时必须与令牌一一对应，否则都使用Weight（默认为1）
+func ballotWeights(c *gin.Context, req models.IssueBallotsRequest, n int) ([]weight.Weight, bool) {
+	if len(req.Weights) > 0 && len(req.Weights) != n {
+		apierror.Invalid(c, apierror.Items("weights", "len", strconv.Itoa(n)))
+		return nil, false
+	}
+
+	base, err := weight.Parse(req.Weight)
+	if err != nil {
+		apierror.Invalid(c, apierror.Field("weight", "decimal", strconv.Itoa(weight.Decimals)))
+		return nil, false
+	}
+	weights := make([]weight.Weight, n)
+	for i := range weights {
+		weights[i] = base
+		if len(req.Weights) == 0 {
+			continue
+		}
+		if weights[i], err = weight.Parse(req.Weights[i]); err != nil {
+			apierror.Invalid(c, apierror.Field(fmt.Sprintf("weights[%d]", i), "decimal", strconv.Itoa(weight.Decimals)))
+			return nil, false
+		}
+	}
+	return weights, true
+}
+
+// pollIDParam 解析路径参数id，失败时写入错误响应并返回false
+func pollIDParam(c *gin.Context) (uint, bool) {
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return 0, false
+	}
+	return uint(pollID), true
+}
+
+// requestOrigin 请求的协议和主机，经反向代理时按X-Forwarded-Proto判断协议
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// ballotURL 在投票页面地址上附加ballot参数
+func ballotURL(page url.URL, token string) string {
+	query := page.Query()
+	query.Set("ballot", token)
+	page.RawQuery = query.Encode()
+	return page.String()
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInvitePollBallots(t *testing.T) {
+	router := setupFullRouter()
+
+	w := adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "私有投票", "options": []string{"甲", "乙"}, "access": "private"})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "access_code") {
+		t.Errorf("私有投票问卷缺少访问码应校验失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "理事会选举", "options": []string{"甲", "乙"}, "access": "invite"})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if w.Code != http.StatusCreated || poll.Access != models.AccessInvite {
+		t.Fatalf("创建邀请制投票问卷失败: %d %s", w.Code, w.Body.String())
+	}
+	path := "/api/admin/polls/" + strconv.Itoa(int(poll.ID)) + "/ballots"
+
+	w = adminRequest(router, "POST", path+"?format=csv", gin.H{"labels": []string{"张三", "李四"}, "weights": []string{"2.5", "1"}, "base_url": "https://vote.example.com/board"})
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if w.Code != http.StatusCreated || err != nil || len(records) != 3 {
+		t.Fatalf("生成令牌CSV失败: %d %s", w.Code, w.Body.String())
+	}
+	token := records[1][2]
+	if records[1][1] != "张三" || records[1][3] != "https://vote.example.com/board?ballot="+token || records[1][4] != "2.5" {
+		t.Errorf("令牌CSV内容不正确: %v", records[1])
+	}
+
+	w = adminRequest(router, "POST", path, gin.H{"count": 1})
+	var issued IssuedBallotsResponse
+	json.Unmarshal(w.Body.Bytes(), &issued)
+	if w.Code != http.StatusCreated || len(issued.Ballots) != 1 || !strings.HasPrefix(issued.Ballots[0].URL, "http://") {
+		t.Errorf("生成令牌失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", path, gin.H{})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("未指定数量期望400, 得到 %d", w.Code)
+	}
+	w = adminRequest(router, "POST", path, gin.H{"count": 2, "weights": []string{"1"}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"weights"`) {
+		t.Errorf("权重数量不一致期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", path, gin.H{"count": 1, "weight": "0.1234567"})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"weight"`) {
+		t.Errorf("权重无效期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": poll.Options[0].ID})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "invalid_ballot_token") {
+		t.Errorf("没有令牌时期望403, 得到 %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": poll.Options[0].ID, "ballot_token": token})
+	if w.Code != http.StatusOK {
+		t.Fatalf("使用令牌投票失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "GET", "/api/poll", nil)
+	if !strings.Contains(w.Body.String(), `"weighted_votes":"2.5"`) || !strings.Contains(w.Body.String(), `"total_weight":"2.5"`) {
+		t.Errorf("投票应使用令牌的权重: %s", w.Body.String())
+	}
+	w = adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": poll.Options[1].ID, "ballot_token": token})
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "ballot_token_used") {
+		t.Errorf("重复使用令牌期望409, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "DELETE", path+"/"+strconv.Itoa(int(issued.Ballots[0].ID)), nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "revoked_at") {
+		t.Errorf("吊销令牌失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", path, nil)
+	var list BallotListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Ballots) != 3 || list.Turnout.Used != 1 || list.Turnout.Percentage != 50 {
+		t.Errorf("投票率不正确: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), token) {
+		t.Error("令牌列表不应包含令牌明文")
+	}
+
+	w = adminRequest(router, "GET", path+"?format=csv", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "李四,"+records[2][2][:12]+",false,") {
+		t.Errorf("投票率CSV不正确: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestInviteBallotWeightsNotLinkable(t *testing.T) {
+	router := setupFullRouter()
+
+	w := adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "股东表决", "options": []string{"同意", "反对"}, "access": "invite"})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	path := "/api/admin/polls/" + strconv.Itoa(int(poll.ID))
+
+	w = adminRequest(router, "POST", path+"/ballots", gin.H{"labels": []string{"张三", "李四"}, "weights": []string{"7.25", "1"}})
+	var issued IssuedBallotsResponse
+	json.Unmarshal(w.Body.Bytes(), &issued)
+	if w.Code != http.StatusCreated || len(issued.Ballots) != 2 {
+		t.Fatalf("生成令牌失败: %d %s", w.Code, w.Body.String())
+	}
+	adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": poll.Options[1].ID, "ballot_token": issued.Ballots[0].Token})
+
+	// 令牌列表不包含权重，无法从备注得到权重
+	for _, format := range []string{"json", "csv"} {
+		w = adminRequest(router, "GET", path+"/ballots?format="+format, nil)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "张三") || strings.Contains(w.Body.String(), "7.25") {
+			t.Errorf("%s: 令牌列表不应包含权重: %s", format, w.Body.String())
+		}
+	}
+
+	// 逐条投票记录不包含权重，无法从权重得到所投的选项
+	w = adminRequest(router, "GET", path+"/export?format=json&votes=true", nil)
+	var exported struct {
+		Votes []map[string]interface{} `json:"votes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &exported); err != nil || len(exported.Votes) != 1 {
+		t.Fatalf("导出失败: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := exported.Votes[0]["weight"]; ok {
+		t.Errorf("邀请制投票问卷的投票记录不应包含权重: %v", exported.Votes[0])
+	}
+	w = adminRequest(router, "GET", path+"/export?format=csv&votes=true", nil)
+	reader := csv.NewReader(strings.NewReader(w.Body.String()))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		t.Fatalf("CSV导出失败: %v %s", err, w.Body.String())
+	}
+	last := records[len(records)-1]
+	if last[2] != "反对" || last[4] != "" {
+		t.Errorf("CSV投票记录的权重应为空, 得到 %v", last)
+	}
+}
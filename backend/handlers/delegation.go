@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/roll"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetDelegation 创建或替换当前投票人的委托，poll_id和topic都为空时委托进行中的投票问卷
+func (h *PollHandler) SetDelegation(c *gin.Context) {
+	var req models.DelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+	voter := voterOf(c)
+	if req.PollID != 0 && strings.TrimSpace(req.Topic) != "" {
+		apierror.Invalid(c, apierror.Field("topic", "unsupported", ""))
+		return
+	}
+	if roll.Normalize(req.Delegate) == "" {
+		apierror.Invalid(c, apierror.Field("delegate", "required", ""))
+		return
+	}
+	if roll.Normalize(req.Delegate) == roll.Normalize(voter) {
+		apierror.Invalid(c, apierror.Field("delegate", "invalid", ""))
+		return
+	}
+
+	delegation, err := h.polls.Delegate(voter, req)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delegation)
+}
+
+// RemoveDelegation 删除当前投票人在topic标签或poll_id投票问卷上的委托，都为空时为进行中的投票问卷
+func (h *PollHandler) RemoveDelegation(c *gin.Context) {
+	pollID, ok := pollIDQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.polls.RemoveDelegation(voterOf(c), pollID, c.Query("topic")); err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Delegation removed successfully"})
+}
+
+// GetDelegationChain 返回当前投票人在poll_id投票问卷（默认为进行中的投票问卷）中实际生效的委托链
+func (h *PollHandler) GetDelegationChain(c *gin.Context) {
+	pollID, ok := pollIDQuery(c)
+	if !ok {
+		return
+	}
+
+	chain, err := h.polls.DelegationChain(pollID, voterOf(c))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chain)
+}
+
+// ListDelegations 列出投票问卷名册中每个投票人的委托链和各状态人数（管理接口）
+func (h *PollHandler) ListDelegations(c *gin.Context) {
+	pollID, ok := pollIDParam(c)
+	if !ok {
+		return
+	}
+
+	chains, err := h.polls.Delegations(pollID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	statuses := map[string]int{}
+	for _, chain := range chains {
+		statuses[chain.Status]++
+	}
+	c.JSON(http.StatusOK, DelegationListResponse{Chains: chains, Statuses: statuses})
+}
+
+// pollIDQuery 解析可选的查询参数poll_id，未提供时为0，失败时写入错误响应并返回false
+func pollIDQuery(c *gin.Context) (uint, bool) {
+	v := c.Query("poll_id")
+	if v == "" {
+		return 0, true
+	}
+	pollID, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "poll_id")
+		return 0, false
+	}
+	return uint(pollID), true
+}
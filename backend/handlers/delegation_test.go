@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/delegation"
+	"vote-system/models"
+	"vote-system/privacy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// voterRequest 以X-Remote-User标识的投票人发送请求
+func voterRequest(router *gin.Engine, method, path, voter string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Remote-User", voter)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestDelegation(t *testing.T) {
+	router := setupRollRouter()
+
+	w := importRoll(router, "board", "identifier,weight\nalice,1\nbob,2\ncarol,3\ndave,1\n")
+	var roll models.VoterRoll
+	json.Unmarshal(w.Body.Bytes(), &roll)
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "年度预算", "options": []string{"通过", "否决"}, "tags": []string{"Finance"}, "roll_id": roll.ID})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建投票问卷失败: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "Alice"}); w.Code != http.StatusBadRequest {
+		t.Errorf("委托给自己期望400, 得到 %d", w.Code)
+	}
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "bob", "poll_id": poll.ID, "topic": "finance"}); w.Code != http.StatusBadRequest {
+		t.Errorf("同时提供poll_id和topic期望400, 得到 %d", w.Code)
+	}
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "mallory", gin.H{"delegate": "bob"}); w.Code != http.StatusForbidden {
+		t.Errorf("不在名册中期望403, 得到 %d", w.Code)
+	}
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "mallory"}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "delegate") {
+		t.Errorf("受托人不在名册中期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	// alice -> bob（进行中的投票问卷），bob -> carol（finance标签）
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "BOB"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"delegate":"bob"`) {
+		t.Fatalf("委托失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "bob", gin.H{"delegate": "carol", "topic": "FINANCE"}); w.Code != http.StatusOK {
+		t.Fatalf("按标签委托失败: %d %s", w.Code, w.Body.String())
+	}
+	w = voterRequest(router, "PUT", "/api/poll/delegation", "carol", gin.H{"delegate": "alice", "topic": "finance"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("委托失败: %d %s", w.Code, w.Body.String())
+	}
+	// 同一范围内形成循环时拒绝
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "bob", "topic": "finance"}); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "delegation_cycle") {
+		t.Errorf("期望409 delegation_cycle, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	// 跨范围的循环在解析时标记
+	var chain delegation.Chain
+	w = voterRequest(router, "GET", "/api/poll/delegation", "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &chain)
+	if w.Code != http.StatusOK || chain.Status != delegation.StatusCycle || len(chain.Links) != 3 {
+		t.Errorf("期望循环的委托链, 得到 %d %s", w.Code, w.Body.String())
+	}
+	if w := voterRequest(router, "DELETE", "/api/poll/delegation?topic=finance", "carol", nil); w.Code != http.StatusOK {
+		t.Fatalf("撤销委托失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := voterRequest(router, "DELETE", "/api/poll/delegation?topic=finance", "carol", nil); w.Code != http.StatusNotFound {
+		t.Errorf("重复撤销期望404, 得到 %d", w.Code)
+	}
+
+	// carol直接投票后alice和bob都随carol
+	if w := voteAs(router, "carol", poll.Options[1].ID); w.Code != http.StatusOK {
+		t.Fatalf("投票失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := voteAs(router, "dave", poll.Options[0].ID); w.Code != http.StatusOK {
+		t.Fatalf("投票失败: %d %s", w.Code, w.Body.String())
+	}
+	w = voterRequest(router, "GET", "/api/poll/delegation?poll_id="+strconv.Itoa(int(poll.ID)), "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &chain)
+	if chain.Status != delegation.StatusDelegated || chain.Delegate != "carol" || strings.Contains(w.Body.String(), "option") {
+		t.Errorf("期望计入carol的选项且不返回选项, 得到 %s", w.Body.String())
+	}
+
+	pollPath := "/api/admin/polls/" + strconv.Itoa(int(poll.ID))
+	w = adminRequest(router, "GET", pollPath+"/delegations", nil)
+	var list DelegationListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Statuses[delegation.StatusDelegated] != 2 || list.Statuses[delegation.StatusDirect] != 2 {
+		t.Errorf("委托链列表不正确: %d %s", w.Code, w.Body.String())
+	}
+
+	// 关闭时计入委托：否决 3 票（加权 6），通过 1 票
+	w = adminRequest(router, "POST", pollPath+"/close", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("关闭失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "GET", pollPath, nil)
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if poll.Options[1].VoteCount != 3 || poll.Options[1].WeightedVotes.String() != "6" || poll.Options[0].VoteCount != 1 {
+		t.Errorf("关闭后的票数不正确: %s", w.Body.String())
+	}
+	if w := adminRequest(router, "GET", "/api/admin/audit?action=poll.closed", nil); !strings.Contains(w.Body.String(), `delegated_votes`) {
+		t.Errorf("关闭结果应包含委托计入的票数: %s", w.Body.String())
+	}
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "dave", gin.H{"delegate": "carol", "poll_id": poll.ID}); w.Code != http.StatusConflict {
+		t.Errorf("关闭后委托期望409, 得到 %d", w.Code)
+	}
+
+	// 重新开启时删除委托计入的投票
+	if w := adminRequest(router, "POST", pollPath+"/open", nil); w.Code != http.StatusOK {
+		t.Fatalf("开启失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "GET", pollPath, nil)
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if poll.Options[1].VoteCount != 1 || poll.Options[1].WeightedVotes.String() != "3" {
+		t.Errorf("重新开启后的票数不正确: %s", w.Body.String())
+	}
+}
+
+func TestDelegation_PrivacyMode(t *testing.T) {
+	db := setupTestDB()
+	hasher := privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("secret")}})
+	router := rollRouter(db, hasher)
+
+	w := importRoll(router, "board", "identifier,weight\nalice,1\nbob,2\ncarol,3\n")
+	var roll models.VoterRoll
+	json.Unmarshal(w.Body.Bytes(), &roll)
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "年度预算", "options": []string{"通过", "否决"}, "tags": []string{"finance"}, "roll_id": roll.ID})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "bob"}); w.Code != http.StatusOK {
+		t.Fatalf("委托失败: %d %s", w.Code, w.Body.String())
+	}
+
+	// 委托人和受托人只保存HMAC
+	var stored models.Delegation
+	db.First(&stored)
+	if stored.Delegator != hasher.Pseudonymize("alice") || stored.Delegate != hasher.Pseudonymize("bob") {
+		t.Errorf("隐私模式下应只保存标识的HMAC, 得到 %+v", stored)
+	}
+
+	// 轮换密钥后按新密钥保存，旧密钥保存的委托仍能解析
+	rotated := privacy.NewHasher([]privacy.Key{{ID: "k2", Secret: []byte("new")}, {ID: "k1", Secret: []byte("secret")}})
+	router = rollRouter(db, rotated)
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "bob", gin.H{"delegate": "carol", "topic": "finance"}); w.Code != http.StatusOK {
+		t.Fatalf("按标签委托失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := voteAs(router, "carol", poll.Options[1].ID); w.Code != http.StatusOK {
+		t.Fatalf("投票失败: %d %s", w.Code, w.Body.String())
+	}
+	var chain delegation.Chain
+	w = voterRequest(router, "GET", "/api/poll/delegation", "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &chain)
+	if chain.Status != delegation.StatusDelegated || chain.Delegate != "carol" || len(chain.Links) != 2 || chain.Links[0].Delegate != "bob" {
+		t.Errorf("期望 alice 经 bob 计入 carol 的选项, 得到 %s", w.Body.String())
+	}
+
+	// 再次委托时替换旧密钥保存的委托
+	if w := voterRequest(router, "PUT", "/api/poll/delegation", "alice", gin.H{"delegate": "bob"}); w.Code != http.StatusOK {
+		t.Fatalf("再次委托失败: %d %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&models.Delegation{}).Where("poll_id = ?", poll.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("期望 1 条该投票问卷的委托, 得到 %d", count)
+	}
+
+	// 关闭时计入委托：否决 3 票（加权 6）
+	pollPath := "/api/admin/polls/" + strconv.Itoa(int(poll.ID))
+	if w := adminRequest(router, "POST", pollPath+"/close", nil); w.Code != http.StatusOK {
+		t.Fatalf("关闭失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "GET", pollPath, nil)
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if poll.Options[1].VoteCount != 3 || poll.Options[1].WeightedVotes.String() != "6" {
+		t.Errorf("关闭后的票数不正确: %s", w.Body.String())
+	}
+
+	if w := voterRequest(router, "DELETE", "/api/poll/delegation?topic=finance", "bob", nil); w.Code != http.StatusOK {
+		t.Errorf("撤销委托失败: %d %s", w.Code, w.Body.String())
+	}
+}
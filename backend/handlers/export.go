@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"vote-system/apierror"
+	"vote-system/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportResults 导出投票问卷结果（管理接口），支持csv、json、xlsx格式
+func (h *PollHandler) ExportResults(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	if !poll.ResultsVisible(false, true) {
+		apierror.Abort(c, apierror.ResultsSealed)
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidFormat(format) {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	opts := export.Options{
+		Format:       format,
+		IncludeVotes: c.Query("votes") == "true",
+		Hasher:       h.hasher,
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="poll-%d-results.%s"`, poll.ID, format))
+	c.Status(http.StatusOK)
+
+	// 结果直接流式写入响应，出错时响应头已发送，只能中断连接
+	if err := export.Write(c.Writer, h.db, poll.ID, opts); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupAdminRouter(handler *PollHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/admin", AdminAuth("secret", nil))
+	admin.GET("/polls/:id/export", handler.ExportResults)
+	return router
+}
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		token    string
+		header   string
+		expected int
+	}{
+		{"", "Bearer ", http.StatusForbidden},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		router := gin.New()
+		router.GET("/admin", AdminAuth(tc.token, nil), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", tc.header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tc.expected {
+			t.Errorf("令牌 %q 请求头 %q: 期望状态码 %d, 得到 %d", tc.token, tc.header, tc.expected, w.Code)
+		}
+	}
+}
+
+func TestExportResults_CSV(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, options := setupTestData(db)
+	db.Model(&options[0]).Update("vote_count", 2)
+
+	router := setupAdminRouter(handler)
+
+	req, _ := http.NewRequest("GET", "/admin/polls/"+strconv.Itoa(int(poll.ID))+"/export?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Content-Type不正确: %s", w.Header().Get("Content-Type"))
+	}
+
+	if !strings.Contains(w.Body.String(), "选项1,2,100.00") {
+		t.Errorf("导出内容不正确: %s", w.Body.String())
+	}
+}
+
+func TestExportResults_Errors(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, _ := setupTestData(db)
+
+	router := setupAdminRouter(handler)
+
+	cases := map[string]int{
+		"/admin/polls/abc/export": http.StatusBadRequest,
+		"/admin/polls/999/export": http.StatusNotFound,
+		"/admin/polls/" + strconv.Itoa(int(poll.ID)) + "/export?format=pdf": http.StatusBadRequest,
+	}
+
+	for path, expected := range cases {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != expected {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", path, expected, w.Code)
+		}
+	}
+}
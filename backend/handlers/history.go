@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/stats"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetHistory 获取投票问卷各选项随时间变化的票数
+func (h *PollHandler) GetHistory(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	// 趋势数据同样包含票数，需要遵守结果可见性
+	var voted int64
+	h.hasher.VoterScope(h.db.Model(&models.Vote{}), voterOf(c)).Where("poll_id = ?", poll.ID).Count(&voted)
+	if !poll.ResultsVisible(voted > 0, false) {
+		apierror.Abort(c, apierror.ResultsHidden)
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", stats.GranularityHour)
+	if !stats.ValidGranularity(granularity) {
+		apierror.Abort(c, apierror.InvalidGranularity)
+		return
+	}
+
+	// 默认范围为投票问卷创建至今
+	var err error
+	from, to := poll.CreatedAt, time.Now()
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			apierror.Abort(c, apierror.InvalidTime, "name", "from")
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			apierror.Abort(c, apierror.InvalidTime, "name", "to")
+			return
+		}
+	}
+	if !from.Before(to) {
+		apierror.Abort(c, apierror.InvalidTimeRange)
+		return
+	}
+
+	history, err := stats.LoadHistory(h.db, poll, granularity, from, to)
+	if err != nil {
+		if errors.Is(err, stats.ErrTooManyBuckets) {
+			apierror.Abort(c, apierror.TooManyBuckets)
+		} else {
+			apierror.Fail(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"vote-system/models"
+	"vote-system/stats"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetHistory_AfterVote(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, options := setupTestData(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/vote", handler.Vote)
+	router.GET("/polls/:id/history", handler.GetHistory)
+
+	jsonData, _ := json.Marshal(models.VoteRequest{OptionID: options[0].ID})
+	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/polls/"+strconv.Itoa(int(poll.ID))+"/history?granularity=day", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var history stats.History
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+
+	if len(history.Series) != len(options) {
+		t.Fatalf("期望 %d 个选项序列, 得到 %d", len(options), len(history.Series))
+	}
+
+	points := history.Series[0].Points
+	if len(points) == 0 || points[len(points)-1].Cumulative != 1 {
+		t.Errorf("期望累计票数 1, 得到 %+v", points)
+	}
+}
+
+func TestGetHistory_InvalidParams(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, _ := setupTestData(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/polls/:id/history", handler.GetHistory)
+
+	id := strconv.Itoa(int(poll.ID))
+	cases := map[string]int{
+		"/polls/" + id + "/history?granularity=week":                             http.StatusBadRequest,
+		"/polls/" + id + "/history?from=yesterday":                               http.StatusBadRequest,
+		"/polls/999/history":                                                     http.StatusNotFound,
+		"/polls/" + id + "/history?granularity=minute&from=2000-01-01T00:00:00Z": http.StatusBadRequest,
+	}
+
+	for path, expected := range cases {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != expected {
+			t.Errorf("%s: 期望状态码 %d, 得到 %d", path, expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"vote-system/apierror"
+	"vote-system/manifest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportPolls 按YAML或JSON文档创建、更新或归档投票问卷（管理接口），dry_run=true时只返回变更
+func (h *PollHandler) ImportPolls(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidRequest)
+		return
+	}
+
+	doc, err := manifest.Parse(data)
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		plan, err := manifest.Compute(h.db, doc)
+		if err != nil {
+			apierror.Fail(c, err)
+			return
+		}
+		if len(plan.Errors) > 0 {
+			apierror.AbortWithData(c, apierror.PlanRejected, gin.H{"dry_run": true, "plan": plan, "diff": plan.Diff()})
+			return
+		}
+		c.JSON(http.StatusOK, ImportResponse{DryRun: true, Plan: plan, Diff: plan.Diff()})
+		return
+	}
+
+	plan, err := manifest.Apply(h.db, doc, manifest.ApplyOptions{
+		Audit:  newAuditEntry(c, h.hasher, "", nil, nil, nil),
+		Hasher: h.hasher,
+	})
+	var planErr *manifest.PlanError
+	if errors.As(err, &planErr) {
+		apierror.AbortWithData(c, apierror.PlanRejected, gin.H{"dry_run": false, "plan": plan, "diff": plan.Diff()})
+		return
+	}
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	h.dispatcher.Notify()
+	c.JSON(http.StatusOK, ImportResponse{DryRun: false, Plan: plan, Diff: plan.Diff()})
+}
+
+// ExportManifest 将投票问卷导出为可再次导入的文档（管理接口），支持yaml、json格式
+func (h *PollHandler) ExportManifest(c *gin.Context) {
+	format := c.DefaultQuery("format", manifest.FormatYAML)
+
+	doc, err := manifest.Export(h.db)
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	data, err := manifest.Encode(doc, format)
+	if err != nil {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	contentType := "application/yaml"
+	if format == manifest.FormatJSON {
+		contentType = "application/json"
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// documentError 将文档解析错误转换为错误响应，字段校验失败时返回字段级详情
+func documentError(c *gin.Context, err error) {
+	var invalid *manifest.ValidationError
+	if !errors.As(err, &invalid) {
+		// YAML语法错误包含行号，原样返回便于定位
+		apierror.InvalidWith(c, apierror.InvalidDocument, apierror.FieldError{Code: "syntax", Message: err.Error()})
+		return
+	}
+
+	var detail apierror.FieldError
+	switch {
+	case invalid.Rule == "max":
+		detail = apierror.Length(invalid.Field, invalid.Rule, invalid.Param)
+	case invalid.Rule == "min":
+		detail = apierror.Items(invalid.Field, invalid.Rule, invalid.Param)
+	default:
+		detail = apierror.Field(invalid.Field, invalid.Rule, invalid.Param)
+	}
+	apierror.InvalidWith(c, apierror.InvalidDocument, detail)
+}
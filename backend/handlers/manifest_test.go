@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vote-system/models"
+)
+
+const importDocument = `
+polls:
+  - slug: lunch
+    title: 午餐吃什么
+    options: [面, 饭]
+`
+
+func TestImportPolls(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/yaml")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Actor", "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// dry-run只返回变更
+	w := send("/admin/polls/import?dry_run=true", importDocument)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"diff":"+ lunch\n`) {
+		t.Fatalf("dry-run响应不正确: %d %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&models.Poll{}).Count(&count)
+	if count != 0 {
+		t.Errorf("dry-run不应创建投票问卷, 得到 %d", count)
+	}
+
+	w = send("/admin/polls/import", importDocument)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	db.Model(&models.Poll{}).Where("slug = ?", "lunch").Count(&count)
+	if count != 1 {
+		t.Errorf("期望创建lunch, 得到 %d", count)
+	}
+
+	var entry models.AuditLog
+	db.Where("action = ?", models.AuditPollCreated).First(&entry)
+	if entry.Actor != "admin:alice" {
+		t.Errorf("期望操作人 admin:alice, 得到 %q", entry.Actor)
+	}
+
+	// 再次导入没有变更
+	w = send("/admin/polls/import", importDocument)
+	if !strings.Contains(w.Body.String(), `"diff":"no changes\n"`) {
+		t.Errorf("期望没有变更, 得到 %s", w.Body.String())
+	}
+
+	// 无效文档
+	w = send("/admin/polls/import", "polls: [{slug: lunch}]")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效文档期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestExportManifest(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	setupTestData(db)
+
+	w := adminRequest(router, "GET", "/admin/polls/manifest", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "slug: poll-1") {
+		t.Fatalf("YAML导出不正确: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", "/admin/polls/manifest?format=json", nil)
+	var doc struct {
+		Polls []struct {
+			Slug    string   `json:"slug"`
+			Options []string `json:"options"`
+		} `json:"polls"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || len(doc.Polls) != 1 || len(doc.Polls[0].Options) != 3 {
+		t.Errorf("JSON导出不正确: %s", w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", "/admin/polls/manifest?format=xml", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("不支持的格式期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"vote-system/audit"
+	"vote-system/identity"
+	"vote-system/privacy"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+	actorKey        = "actor"
+	voterKey        = "voter"
+)
+
+// RequestID 为每个请求分配请求ID，优先沿用客户端传入的X-Request-ID
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			buf := make([]byte, 16)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// VoterIdentity 按配置的身份识别方式识别投票人，resolver为nil或请求中没有身份信息时使用客户端IP
+func VoterIdentity(resolver identity.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if resolver != nil {
+			if voter := resolver.Resolve(c.Request); voter != "" {
+				c.Set(voterKey, voter)
+			}
+		}
+		c.Next()
+	}
+}
+
+// voterOf 返回VoterIdentity识别的投票人，未识别时为客户端IP
+func voterOf(c *gin.Context) string {
+	if voter := c.GetString(voterKey); voter != "" {
+		return voter
+	}
+	return c.ClientIP()
+}
+
+// auditEntry 根据请求上下文生成投票问卷相关的审计事件
+func (h *PollHandler) auditEntry(c *gin.Context, action string, pollID uint, before, after interface{}) audit.Entry {
+	return newAuditEntry(c, h.hasher, action, &pollID, before, after)
+}
+
+// newAuditEntry 根据请求上下文生成审计事件，未经过管理认证的请求记为anonymous
+//
+// 隐私模式下匿名请求的IP与投票人标识一样只记录HMAC。
+func newAuditEntry(c *gin.Context, hasher *privacy.Hasher, action string, pollID *uint, before, after interface{}) audit.Entry {
+	actor := c.GetString(actorKey)
+	ip := c.ClientIP()
+	if actor == "" {
+		actor = "anonymous"
+		ip = hasher.Pseudonymize(ip)
+	}
+
+	return audit.Entry{
+		Action:    action,
+		Actor:     actor,
+		IP:        ip,
+		RequestID: c.GetString(requestIDKey),
+		PollID:    pollID,
+		Before:    before,
+		After:     after,
+	}
+}
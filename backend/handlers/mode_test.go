@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createModePoll 创建指定投票方式的投票问卷
+func createModePoll(t *testing.T, router *gin.Engine, body gin.H) models.Poll {
+	t.Helper()
+	w := adminRequest(router, "POST", "/api/admin/polls", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("创建投票问卷失败: %d %s", w.Code, w.Body.String())
+	}
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	return poll
+}
+
+func TestCreatePoll_ModeValidation(t *testing.T) {
+	router := setupRollRouter()
+
+	cases := []struct {
+		field string
+		extra gin.H
+	}{
+		{"mode", gin.H{"mode": "ranked"}},
+		{"write_in_option", gin.H{"mode": models.ModeApproval, "write_in_option": "其他"}},
+		{"rules.target_votes", gin.H{"mode": models.ModeBorda, "rules": gin.H{"target_votes": 10}}},
+		{"allow_proposals", gin.H{"mode": models.ModeBorda, "allow_proposals": true}},
+		{"max_score", gin.H{"max_score": 5}},
+		{"max_score", gin.H{"mode": models.ModeScore, "max_score": 101}},
+	}
+	for _, tc := range cases {
+		body := gin.H{"title": "周末活动", "options": []string{"爬山", "看电影"}}
+		for k, v := range tc.extra {
+			body[k] = v
+		}
+		w := adminRequest(router, "POST", "/api/admin/polls", body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"`+tc.field+`"`) {
+			t.Errorf("%v: 期望400且字段为%s, 得到 %d %s", tc.extra, tc.field, w.Code, w.Body.String())
+		}
+	}
+
+	poll := createModePoll(t, router, gin.H{"title": "周末活动", "options": []string{"爬山", "看电影"}, "mode": models.ModeScore})
+	if poll.Mode != models.ModeScore || poll.MaxScore != models.DefaultMaxScore {
+		t.Errorf("期望评分投票、最高 %d 分, 得到 %s、%d", models.DefaultMaxScore, poll.Mode, poll.MaxScore)
+	}
+
+	// 投票方式创建后不能修改，新的规则需要与之兼容
+	w := adminRequest(router, "PUT", "/api/admin/polls/"+strconv.Itoa(int(poll.ID)), gin.H{"rules": gin.H{"win_percent": 60}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "rules.win_percent") {
+		t.Errorf("期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestVote_ScoreMode(t *testing.T) {
+	router := setupRollRouter()
+	poll := createModePoll(t, router, gin.H{"title": "餐厅评分", "options": []string{"川菜", "粤菜", "湘菜"}, "mode": models.ModeScore})
+	a, b, c := poll.Options[0].ID, poll.Options[1].ID, poll.Options[2].ID
+
+	invalid := map[string]gin.H{
+		"option_id": {"option_id": a},
+		"scores":    {"scores": []gin.H{{"option_id": a, "score": 6}}},
+		"duplicate": {"scores": []gin.H{{"option_id": a, "score": 1}, {"option_id": a, "score": 2}}},
+		"mixed":     {"scores": []gin.H{{"option_id": a, "score": 1}}, "option_ids": []uint{b}},
+	}
+	for name, body := range invalid {
+		if w := voterRequest(router, "POST", "/api/poll/vote", "carol", body); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_vote") {
+			t.Errorf("%s: 期望400 invalid_vote, 得到 %d %s", name, w.Code, w.Body.String())
+		}
+	}
+	if w := voterRequest(router, "POST", "/api/poll/vote", "carol", gin.H{}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "validation_failed") {
+		t.Errorf("空选票期望400 validation_failed, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	ballots := map[string][]gin.H{
+		"alice": {{"option_id": a, "score": 5}, {"option_id": b, "score": 3}},
+		"bob":   {{"option_id": a, "score": 4}, {"option_id": c, "score": 1}},
+	}
+	for voter, scores := range ballots {
+		if w := voterRequest(router, "POST", "/api/poll/vote", voter, gin.H{"scores": scores}); w.Code != http.StatusOK {
+			t.Fatalf("%s 投票失败: %d %s", voter, w.Code, w.Body.String())
+		}
+	}
+
+	var response models.PollResponse
+	w := voterRequest(router, "GET", "/api/poll", "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	options := response.Poll.Options
+	if response.TotalVotes != 2 || !response.UserVoted || response.VotedOption != nil {
+		t.Errorf("期望 2 张选票且没有单选选项, 得到 %s", w.Body.String())
+	}
+	if options[0].Points.String() != "9" || options[0].Average == nil || *options[0].Average != 4.5 {
+		t.Errorf("川菜期望得分 9、平均 4.5, 得到 %s", w.Body.String())
+	}
+	if options[0].Distribution[5] != 1 || options[0].Distribution[4] != 1 || options[2].VoteCount != 1 {
+		t.Errorf("分布不正确: %s", w.Body.String())
+	}
+
+	// 撤销投票时减去整张选票
+	if w := voterRequest(router, "DELETE", "/api/poll/clear-my-vote", "bob", nil); w.Code != http.StatusOK {
+		t.Fatalf("撤销投票失败: %d %s", w.Code, w.Body.String())
+	}
+	pollPath := "/api/admin/polls/" + strconv.Itoa(int(poll.ID))
+	w = adminRequest(router, "GET", pollPath, nil)
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if poll.Options[0].Points.String() != "5" || poll.Options[0].VoteCount != 1 || poll.Options[2].VoteCount != 0 {
+		t.Errorf("撤销后的得分不正确: %s", w.Body.String())
+	}
+	if w := adminRequest(router, "POST", pollPath+"/reconcile?dry_run=true", nil); !strings.Contains(w.Body.String(), `"drift":[]`) {
+		t.Errorf("选票标记与选项得分应一致: %s", w.Body.String())
+	}
+
+	// 按平均分决定领先者：川菜平均 5 分，粤菜得分更多但平均 4 分
+	voterRequest(router, "POST", "/api/poll/vote", "bob", gin.H{"scores": []gin.H{{"option_id": b, "score": 5}}})
+	adminRequest(router, "POST", pollPath+"/close", nil)
+	w = adminRequest(router, "GET", "/api/admin/audit?action=poll.closed", nil)
+	if !strings.Contains(w.Body.String(), `winner_text\":\"川菜`) || !strings.Contains(w.Body.String(), `winner_average\":5`) {
+		t.Errorf("期望川菜以平均 5 分领先: %s", w.Body.String())
+	}
+}
+
+func TestVote_ApprovalAndBorda(t *testing.T) {
+	router := setupRollRouter()
+	poll := createModePoll(t, router, gin.H{"title": "团建地点", "options": []string{"海边", "山里", "城里"}, "mode": models.ModeBorda})
+	a, b, c := poll.Options[0].ID, poll.Options[1].ID, poll.Options[2].ID
+
+	// 排名第k的选项得到 3-k 分
+	rankings := map[string][]uint{"alice": {b, a}, "bob": {a, b, c}, "carol": {b}}
+	for voter, ranking := range rankings {
+		if w := voterRequest(router, "POST", "/api/poll/vote", voter, gin.H{"option_ids": ranking}); w.Code != http.StatusOK {
+			t.Fatalf("%s 投票失败: %d %s", voter, w.Code, w.Body.String())
+		}
+	}
+	if w := voterRequest(router, "POST", "/api/poll/vote", "dave", gin.H{"option_ids": []uint{a, 9999}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_option") {
+		t.Errorf("期望400 invalid_option, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	var response models.PollResponse
+	w := voterRequest(router, "GET", "/api/poll", "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	options := response.Poll.Options
+	if options[0].Points.String() != "3" || options[1].Points.String() != "5" || options[2].Points.String() != "0" {
+		t.Errorf("波达计数得分不正确: %s", w.Body.String())
+	}
+	if options[1].Distribution[1] != 2 || options[1].Distribution[2] != 1 || response.TotalVotes != 3 {
+		t.Errorf("名次分布不正确: %s", w.Body.String())
+	}
+
+	// 得分取决于选项数，不能再增加选项或开启提议
+	pollPath := "/api/admin/polls/" + strconv.Itoa(int(poll.ID))
+	if w := adminRequest(router, "PUT", pollPath, gin.H{"options": []gin.H{{"text": "湖边"}}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"options"`) {
+		t.Errorf("增加选项期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(router, "PUT", pollPath, gin.H{"allow_proposals": true}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"allow_proposals"`) {
+		t.Errorf("开启提议期望400, 得到 %d %s", w.Code, w.Body.String())
+	}
+
+	adminRequest(router, "POST", pollPath+"/close", nil)
+	if w := adminRequest(router, "GET", "/api/admin/audit?action=poll.closed", nil); !strings.Contains(w.Body.String(), `winner_text\":\"山里`) {
+		t.Errorf("期望山里领先: %s", w.Body.String())
+	}
+
+	// 赞成投票：选任意多个选项，每个选项按赞成的选票计数
+	approval := createModePoll(t, router, gin.H{"title": "午餐", "options": []string{"面", "饭", "粉"}, "mode": models.ModeApproval})
+	voterRequest(router, "POST", "/api/poll/vote", "alice", gin.H{"option_ids": []uint{approval.Options[0].ID, approval.Options[1].ID}})
+	voterRequest(router, "POST", "/api/poll/vote", "bob", gin.H{"option_ids": []uint{approval.Options[1].ID}})
+	if w := voterRequest(router, "POST", "/api/poll/vote", "carol", gin.H{"option_ids": []uint{}}); w.Code != http.StatusBadRequest {
+		t.Errorf("空选票期望400, 得到 %d", w.Code)
+	}
+
+	var view models.PollResponse
+	w = voterRequest(router, "GET", "/api/poll", "alice", nil)
+	json.Unmarshal(w.Body.Bytes(), &view)
+	options = view.Poll.Options
+	if view.TotalVotes != 2 || options[1].VoteCount != 2 || options[0].VoteCount != 1 || options[1].Average != nil {
+		t.Errorf("赞成投票结果不正确: %s", w.Body.String())
+	}
+}
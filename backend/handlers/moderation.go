@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"vote-system/apierror"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListProposals 列出投票问卷的选项提议和自填内容（管理接口），status筛选审核状态
+func (h *PollHandler) ListProposals(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	proposals, err := h.polls.Proposals(poll.ID, c.Query("status"))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ProposalListResponse{Proposals: proposals})
+}
+
+// ApproveProposal 将提议作为新选项加入投票问卷（管理接口）
+func (h *PollHandler) ApproveProposal(c *gin.Context) {
+	h.resolveProposal(c, func(pollID, proposalID uint) (models.OptionProposal, error) {
+		return h.polls.ApproveProposal(pollID, proposalID, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	})
+}
+
+// MergeProposal 将提议并入已有选项（管理接口）
+func (h *PollHandler) MergeProposal(c *gin.Context) {
+	var req models.MergeProposalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	h.resolveProposal(c, func(pollID, proposalID uint) (models.OptionProposal, error) {
+		return h.polls.MergeProposal(pollID, proposalID, req.OptionID, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	})
+}
+
+// RejectProposal 拒绝提议（管理接口）
+func (h *PollHandler) RejectProposal(c *gin.Context) {
+	h.resolveProposal(c, func(pollID, proposalID uint) (models.OptionProposal, error) {
+		return h.polls.RejectProposal(pollID, proposalID, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	})
+}
+
+// resolveProposal 解析路径参数id和proposal_id，执行审核并写入响应
+func (h *PollHandler) resolveProposal(c *gin.Context, resolve func(pollID, proposalID uint) (models.OptionProposal, error)) {
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return
+	}
+	proposalID, err := strconv.ParseUint(c.Param("proposal_id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "proposal_id")
+		return
+	}
+
+	proposal, err := resolve(uint(pollID), uint(proposalID))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"vote-system/models"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProposalModeration(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterPublicRoutes(router.Group("/api"), handler, NewSurveyHandler(db, nil, nil))
+	admin := router.Group("/admin", AdminAuth("secret", nil))
+	admin.POST("/polls", handler.CreatePoll)
+	admin.GET("/polls/:id/proposals", handler.ListProposals)
+	admin.POST("/polls/:id/proposals/:proposal_id/approve", handler.ApproveProposal)
+	admin.POST("/polls/:id/proposals/:proposal_id/merge", handler.MergeProposal)
+
+	w := adminRequest(router, "POST", "/admin/polls", models.CreatePollRequest{
+		Title: "午餐吃什么", Options: []string{"面", "饭"}, WriteInOption: "其他", AllowProposals: true,
+	})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if w.Code != http.StatusCreated || len(poll.Options) != 3 || !poll.Options[2].WriteIn || !poll.AllowProposals {
+		t.Fatalf("创建带自填选项的投票问卷失败: %d %s", w.Code, w.Body.String())
+	}
+	other := poll.Options[2].ID
+
+	w = adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": other})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "write_in_required") {
+		t.Errorf("自填选项未填写内容应返回write_in_required: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", "/api/poll/vote", gin.H{"option_id": other, "write_in": "饺子"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("投给自填选项失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/poll/proposals", gin.H{"text": "米线"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w = adminRequest(router, "POST", "/api/poll/proposals", gin.H{"text": "米线"}); w.Code != http.StatusOK {
+		t.Errorf("重复提议期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	w = adminRequest(router, "GET", fmt.Sprintf("/admin/polls/%d/proposals?status=pending", poll.ID), nil)
+	var list ProposalListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Proposals) != 2 || list.Proposals[0].Votes != 1 {
+		t.Fatalf("待审核列表不正确: %s", w.Body.String())
+	}
+	writeIn, proposal := list.Proposals[0], list.Proposals[1]
+
+	w = adminRequest(router, "POST", fmt.Sprintf("/admin/polls/%d/proposals/%d/merge", poll.ID, writeIn.ID), gin.H{"option_id": poll.Options[0].ID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("合并失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", fmt.Sprintf("/admin/polls/%d/proposals/%d/approve", poll.ID, proposal.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("批准失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", fmt.Sprintf("/admin/polls/%d/proposals/%d/approve", poll.ID, proposal.ID), nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("重复审核期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+
+	var options []models.Option
+	db.Where("poll_id = ?", poll.ID).Order("id").Find(&options)
+	if len(options) != 4 || options[3].Text != "米线" || options[0].VoteCount != 1 || options[2].VoteCount != 0 {
+		t.Errorf("审核后选项不正确: %+v", options)
+	}
+}
@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"net/http"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/delegation"
+	"vote-system/export"
+	"vote-system/manifest"
+	"vote-system/models"
+	"vote-system/openapi"
+	"vote-system/service"
+	"vote-system/stats"
+	"vote-system/survey"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion 接口文档的版本
+const APIVersion = "1.0.0"
+
+// 常用的参数和错误码
+var (
+	dryRunParam = openapi.Param{Name: "dry_run", Type: "boolean", Description: "为true时只返回变更，不修改数据"}
+	limitParam  = openapi.Param{Name: "limit", Type: "integer", Description: "返回的最大条数"}
+
+	pollErrors    = []apierror.Code{apierror.InvalidParameter, apierror.PollNotFound}
+	bindErrors    = []apierror.Code{apierror.InvalidRequest, apierror.ValidationFailed}
+	templateErrs  = []apierror.Code{apierror.InvalidParameter, apierror.TemplateNotFound}
+	webhookErrors = []apierror.Code{apierror.InvalidParameter, apierror.WebhookNotFound}
+	proposalErrs  = []apierror.Code{apierror.InvalidParameter, apierror.PollNotFound, apierror.ProposalNotFound, apierror.ProposalResolved}
+	surveyErrors  = []apierror.Code{apierror.InvalidParameter, apierror.SurveyNotFound}
+)
+
+// errs 合并错误码列表
+func errs(lists ...[]apierror.Code) []apierror.Code {
+	var all []apierror.Code
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// OpenAPI 按路由表生成接口的OpenAPI 3文档，接口说明按处理函数登记，
+// 新增处理函数时需要在此补充说明，TestOpenAPICoversRoutes会检查每个路由都有说明
+func OpenAPI(routes gin.RoutesInfo) *openapi.Document {
+	b := openapi.New(openapi.Info{
+		Title:       "投票系统 API",
+		Description: "错误响应统一包含机器可读的code和按Accept-Language本地化的error信息（支持zh-CN、en）。",
+		Version:     APIVersion,
+	},
+		openapi.Tag{Name: "poll", Description: "投票页面使用的公开接口"},
+		openapi.Tag{Name: "admin", Description: "投票问卷管理"},
+		openapi.Tag{Name: "surveys", Description: "多问题调查问卷"},
+		openapi.Tag{Name: "templates", Description: "投票问卷模板"},
+		openapi.Tag{Name: "audit", Description: "审计日志"},
+		openapi.Tag{Name: "webhooks", Description: "外发webhook"},
+		openapi.Tag{Name: "tokens", Description: "管理接口API令牌"},
+		openapi.Tag{Name: "meta", Description: "实时推送和接口文档"},
+	)
+
+	b.Describe((*PollHandler).GetPoll, openapi.Op{
+		ID: "getPoll", Tag: "poll", Summary: "获取进行中的投票问卷和统计数据",
+		Response: models.PollResponse{},
+		Errors:   []apierror.Code{apierror.NoActivePoll},
+	})
+	b.Describe((*PollHandler).Vote, openapi.Op{
+		ID: "vote", Tag: "poll", Summary: "提交投票",
+		Description: "按投票问卷的mode提供选票：plurality为option_id，approval为赞成的option_ids，borda为按名次从高到低排列的option_ids（可以只排前几名），score为scores（每项0到max_score分）。" +
+			"私有投票问卷（access为private）需要access_code，邀请制投票问卷（access为invite）需要ballot_token；设置了投票人名册时只有名册中的投票人可以投票。投票的权重来自名册或投票令牌，其他情况为1。",
+		Request:  models.VoteRequest{},
+		Response: MessageResponse{},
+		Errors: errs(bindErrors, []apierror.Code{apierror.NoActivePoll, apierror.PollClosed, apierror.InvalidOption, apierror.InvalidVote,
+			apierror.WriteInRequired, apierror.WriteInNotAllowed, apierror.AlreadyVoted,
+			apierror.AccessCodeInvalid, apierror.BallotInvalid, apierror.BallotUsed, apierror.NotEligible}),
+	})
+	b.Describe((*PollHandler).ProposeOption, openapi.Op{
+		ID: "proposeOption", Tag: "poll", Summary: "提议新选项，经管理员审核后加入进行中的投票问卷",
+		Description: "相同内容（不区分大小写）已被提议时返回200和已有的提议。",
+		Request:     models.ProposeOptionRequest{}, Status: http.StatusCreated, Response: models.OptionProposal{},
+		Errors: errs(bindErrors, []apierror.Code{apierror.NoActivePoll, apierror.PollClosed, apierror.ProposalsDisabled,
+			apierror.OptionExists, apierror.TooManyProposals}),
+	})
+	b.Describe((*PollHandler).ClearVotes, openapi.Op{
+		ID: "clearMyVote", Tag: "poll", Summary: "清除当前用户的投票记录（仅开发模式）",
+		Response: MessageResponse{},
+		Errors:   []apierror.Code{apierror.NoActivePoll, apierror.VoteNotFound},
+	})
+	b.Describe((*PollHandler).ResetPoll, openapi.Op{
+		ID: "resetPoll", Tag: "poll", Summary: "重置进行中的投票问卷",
+		Response: MessageResponse{},
+		Errors:   []apierror.Code{apierror.NoActivePoll},
+	})
+	b.Describe((*PollHandler).GetDelegationChain, openapi.Op{
+		ID: "getDelegationChain", Tag: "poll", Summary: "获取当前投票人实际生效的委托链",
+		Description: "status为direct（自己已投票）、delegated（按委托链计入delegate的选项）、pending（最后的受托人尚未投票）、none、cycle（委托链形成循环，不计入）或ineligible（委托链经过不在名册中的投票人，不计入）。不返回受托人所投的选项。",
+		Query:       []openapi.Param{{Name: "poll_id", Type: "integer", Description: "投票问卷ID，默认为进行中的投票问卷"}},
+		Response:    delegation.Chain{},
+		Errors:      []apierror.Code{apierror.InvalidParameter, apierror.NoActivePoll, apierror.PollNotFound, apierror.RollNotFound, apierror.NotEligible},
+	})
+	b.Describe((*PollHandler).SetDelegation, openapi.Op{
+		ID: "setDelegation", Tag: "poll", Summary: "把投票委托给另一个投票人",
+		Description: "poll_id和topic只能提供一个，都为空时委托进行中的投票问卷；topic为投票问卷的标签，委托带有该标签的所有投票问卷。委托某个投票问卷时委托人和受托人都必须在其名册中。同一范围内已有委托时替换受托人，形成循环时返回delegation_cycle。投票问卷的委托优先于按标签的委托，自己投票时不使用委托；关闭投票问卷时按委托链计入委托人的投票。",
+		Request:     models.DelegationRequest{},
+		Response:    models.Delegation{},
+		Errors: errs(bindErrors, []apierror.Code{apierror.InvalidParameter, apierror.NoActivePoll, apierror.PollNotFound,
+			apierror.PollClosed, apierror.RollNotFound, apierror.NotEligible, apierror.DelegationCycle}),
+	})
+	b.Describe((*PollHandler).RemoveDelegation, openapi.Op{
+		ID: "removeDelegation", Tag: "poll", Summary: "撤销委托",
+		Query: []openapi.Param{
+			{Name: "poll_id", Type: "integer", Description: "投票问卷ID，默认为进行中的投票问卷"},
+			{Name: "topic", Description: "撤销按该标签的委托，提供时忽略poll_id"},
+		},
+		Response: MessageResponse{},
+		Errors:   []apierror.Code{apierror.InvalidParameter, apierror.NoActivePoll, apierror.PollNotFound, apierror.DelegationNotFound},
+	})
+	b.Describe((*PollHandler).GetHistory, openapi.Op{
+		ID: "getPollHistory", Tag: "poll", Summary: "获取各选项随时间变化的票数",
+		Query: []openapi.Param{
+			{Name: "granularity", Enum: []string{stats.GranularityMinute, stats.GranularityHour, stats.GranularityDay}, Description: "时间粒度，默认hour"},
+			{Name: "from", Format: "date-time", Description: "开始时间，默认为投票问卷创建时间"},
+			{Name: "to", Format: "date-time", Description: "结束时间，默认为当前时间"},
+		},
+		Response: stats.History{},
+		Errors: errs(pollErrors, []apierror.Code{apierror.ResultsHidden, apierror.InvalidGranularity,
+			apierror.InvalidTime, apierror.InvalidTimeRange, apierror.TooManyBuckets}),
+	})
+	b.Describe((*SurveyHandler).GetSurvey, openapi.Op{
+		ID: "getSurvey", Tag: "surveys", Summary: "获取调查问卷和当前投票人是否已提交",
+		Response: models.SurveyView{},
+		Errors:   surveyErrors,
+	})
+	b.Describe((*SurveyHandler).SubmitResponse, openapi.Op{
+		ID: "submitSurveyResponse", Tag: "surveys", Summary: "一次提交调查问卷所有问题的回答",
+		Description: "所有回答在一个事务中保存，任一回答无效时不保存任何回答。未作答的可选问题和按跳转规则被跳过的问题可以省略，回答被跳过的问题返回question_hidden。",
+		Request:     models.SubmitSurveyRequest{}, Status: http.StatusCreated, Response: models.SurveyResponse{},
+		Errors: errs(surveyErrors, bindErrors, []apierror.Code{apierror.SurveyClosed, apierror.InvalidAnswer,
+			apierror.AnswerRequired, apierror.QuestionHidden, apierror.AlreadyResponded}),
+	})
+	b.Describe((*SurveyHandler).NextQuestion, openapi.Op{
+		ID: "nextSurveyQuestion", Tag: "surveys", Summary: "按部分作答返回下一个要显示的问题",
+		Description: "请求体与提交作答相同，只包含已作答的问题。已作答部分按提交时的规则校验，到达问卷结尾时done为true。",
+		Request:     models.SubmitSurveyRequest{}, Response: survey.Progress{},
+		Errors: errs(surveyErrors, bindErrors, []apierror.Code{apierror.InvalidAnswer, apierror.AnswerRequired, apierror.QuestionHidden}),
+	})
+	b.Describe((*SurveyHandler).GetSurveyResults, openapi.Op{
+		ID: "getSurveyResults", Tag: "surveys", Summary: "获取按问题汇总的结果",
+		Description: "选择题返回各选项人数和百分比，评分题返回平均分和分布，自由回答只返回作答人数。",
+		Response:    survey.Results{},
+		Errors:      surveyErrors,
+	})
+	b.Describe((*SurveyHandler).GetSurveyCrosstab, openapi.Op{
+		ID: "getSurveyCrosstab", Tag: "surveys", Summary: "交叉分析问题的回答",
+		Description: "行为分组依据的选项或分值，列为问题的选项或分值，返回人数、行列百分比和卡方检验。人数大于0且小于min_cell_count的单元格及可用于反推的单元格不公开。",
+		Query: []openapi.Param{
+			{Name: "question", Type: "integer", Required: true, Description: "要分析的问题ID，只能是选择题或评分题"},
+			{Name: "pivot", Required: true, Description: "分组依据，格式为 question:问题ID"},
+		},
+		Response: survey.Crosstab{},
+		Errors:   surveyErrors,
+	})
+
+	b.BearerGroup("/api/admin", "配置的ADMIN_TOKEN或votectl创建的API令牌", apierror.Unauthorized, apierror.AdminDisabled)
+	b.Describe((*PollHandler).ListPolls, openapi.Op{
+		ID: "listPolls", Tag: "admin", Summary: "按条件分页列出投票问卷",
+		Description: "响应中的next_cursor不为空时，以相同的筛选和排序参数加上cursor获取下一页。",
+		Query: []openapi.Param{
+			{Name: "state", Enum: []string{service.StateActive, service.StateClosed}, Description: "按开启状态筛选"},
+			{Name: "tag", Description: "包含该标签，不区分大小写"},
+			{Name: "owner", Description: "创建者，例如 admin、token:ci"},
+			{Name: "created_from", Format: "date-time", Description: "创建时间不早于"},
+			{Name: "created_to", Format: "date-time", Description: "创建时间早于"},
+			{Name: "q", Description: "全文检索标题、描述和选项文本，多个关键词以空格分隔，需同时匹配"},
+			{Name: "sort", Enum: []string{service.SortCreatedAt, service.SortVotes, service.SortClosesAt}, Description: "排序字段，默认created_at"},
+			{Name: "order", Enum: []string{"asc", "desc"}, Description: "默认created_at和votes为desc，closes_at为asc"},
+			{Name: "limit", Type: "integer", Description: "每页条数，默认50，最大200"},
+			{Name: "cursor", Description: "上一页响应中的next_cursor"},
+		},
+		Response: PollListResponse{},
+		Errors:   []apierror.Code{apierror.InvalidParameter, apierror.InvalidTime, apierror.InvalidTimeRange},
+	})
+	b.Describe((*PollHandler).CreatePoll, openapi.Op{
+		ID: "createPoll", Tag: "admin", Summary: "创建投票问卷",
+		Description: "mode为plurality（默认）、approval、score或borda，创建后不能修改；score的max_score默认为5，最大100。" +
+			"plurality以外的投票方式不支持自填选项，自动关闭规则只支持closes_at。",
+		Request: models.CreatePollRequest{}, Status: http.StatusCreated, Response: models.Poll{},
+		Errors: bindErrors,
+	})
+	b.Describe((*PollHandler).ImportPolls, openapi.Op{
+		ID: "importPolls", Tag: "admin", Summary: "按声明式文档创建、更新或归档投票问卷",
+		Description:  "文档中没有的已管理投票问卷会被归档；无法应用时返回plan_rejected并附带plan和diff。",
+		Query:        []openapi.Param{dryRunParam},
+		Request:      manifest.Document{},
+		RequestTypes: []string{"application/yaml", "application/json"},
+		Response:     ImportResponse{},
+		Errors:       []apierror.Code{apierror.InvalidRequest, apierror.InvalidDocument, apierror.PlanRejected},
+	})
+	b.Describe((*PollHandler).ExportManifest, openapi.Op{
+		ID: "exportManifest", Tag: "admin", Summary: "导出可再次导入的声明式文档",
+		Query:         []openapi.Param{{Name: "format", Enum: []string{manifest.FormatYAML, manifest.FormatJSON}, Description: "默认yaml"}},
+		Response:      manifest.Document{},
+		ResponseTypes: []string{"application/yaml", "application/json"},
+		Errors:        []apierror.Code{apierror.UnsupportedFormat},
+	})
+	b.Describe((*PollHandler).GetPollByID, openapi.Op{
+		ID: "getPollByID", Tag: "admin", Summary: "获取指定投票问卷",
+		Response: models.Poll{},
+		Errors:   pollErrors,
+	})
+	b.Describe((*PollHandler).UpdatePoll, openapi.Op{
+		ID: "updatePoll", Tag: "admin", Summary: "编辑投票问卷，未提供的字段保持不变",
+		Request: models.UpdatePollRequest{}, Response: models.Poll{},
+		Errors: errs(pollErrors, bindErrors, []apierror.Code{apierror.InvalidOption, apierror.VisibilityLocked}),
+	})
+	b.Describe((*PollHandler).OpenPoll, openapi.Op{
+		ID: "openPoll", Tag: "admin", Summary: "开启投票问卷",
+		Response: models.Poll{},
+		Errors:   errs(pollErrors, []apierror.Code{apierror.PollStateUnchanged}),
+	})
+	b.Describe((*PollHandler).ClosePoll, openapi.Op{
+		ID: "closePoll", Tag: "admin", Summary: "关闭投票问卷",
+		Response: models.Poll{},
+		Errors:   errs(pollErrors, []apierror.Code{apierror.PollStateUnchanged}),
+	})
+	b.Describe((*PollHandler).AdminResetPoll, openapi.Op{
+		ID: "adminResetPoll", Tag: "admin", Summary: "清除投票问卷的所有投票",
+		Response: MessageResponse{},
+		Errors:   pollErrors,
+	})
+	b.Describe((*PollHandler).ReconcilePoll, openapi.Op{
+		ID: "reconcilePoll", Tag: "admin", Summary: "按投票记录修正选项票数",
+		Query:    []openapi.Param{dryRunParam},
+		Response: ReconcileResponse{},
+		Errors:   pollErrors,
+	})
+	b.Describe((*PollHandler).ExportResults, openapi.Op{
+		ID: "exportResults", Tag: "admin", Summary: "导出投票结果",
+		Query: []openapi.Param{
+			{Name: "format", Enum: []string{export.FormatCSV, export.FormatJSON, export.FormatXLSX}, Description: "默认csv"},
+			{Name: "votes", Type: "boolean", Description: "为true时附带逐条投票记录"},
+		},
+		Response:      struct{}{},
+		ResponseTypes: []string{export.ContentType(export.FormatCSV), export.ContentType(export.FormatJSON), export.ContentType(export.FormatXLSX)},
+		Errors:        errs(pollErrors, []apierror.Code{apierror.ResultsSealed, apierror.UnsupportedFormat}),
+	})
+	b.Describe((*PollHandler).ClonePoll, openapi.Op{
+		ID: "clonePoll", Tag: "admin", Summary: "复制投票问卷的选项和设置",
+		Request: models.ClonePollRequest{}, OptionalBody: true,
+		Status: http.StatusCreated, Response: models.Poll{},
+		Errors: errs(pollErrors, bindErrors),
+	})
+
+	b.Describe((*PollHandler).ListProposals, openapi.Op{
+		ID: "listProposals", Tag: "admin", Summary: "列出投票人提议的选项和自填内容",
+		Query: []openapi.Param{{Name: "status",
+			Enum:        []string{models.ProposalPending, models.ProposalApproved, models.ProposalMerged, models.ProposalRejected},
+			Description: "按审核状态筛选"}},
+		Response: ProposalListResponse{},
+		Errors:   pollErrors,
+	})
+	b.Describe((*PollHandler).ApproveProposal, openapi.Op{
+		ID: "approveProposal", Tag: "admin", Summary: "将提议作为新选项加入，自填内容的投票移到新选项",
+		Response: models.OptionProposal{},
+		Errors:   errs(proposalErrs, []apierror.Code{apierror.OptionExists, apierror.ProposalsDisabled}),
+	})
+	b.Describe((*PollHandler).MergeProposal, openapi.Op{
+		ID: "mergeProposal", Tag: "admin", Summary: "将提议并入已有选项，自填内容的投票移到该选项",
+		Request: models.MergeProposalRequest{}, Response: models.OptionProposal{},
+		Errors: errs(proposalErrs, bindErrors, []apierror.Code{apierror.InvalidOption}),
+	})
+	b.Describe((*PollHandler).RejectProposal, openapi.Op{
+		ID: "rejectProposal", Tag: "admin", Summary: "拒绝提议，自填内容的投票保留在自填选项中",
+		Response: models.OptionProposal{},
+		Errors:   proposalErrs,
+	})
+	b.Describe((*PollHandler).ListBallots, openapi.Op{
+		ID: "listBallots", Tag: "admin", Summary: "列出投票令牌和投票率",
+		Description:   "只返回令牌前缀和是否已使用，不记录令牌投给了哪个选项。",
+		Query:         []openapi.Param{{Name: "format", Enum: []string{export.FormatJSON, export.FormatCSV}, Description: "默认json"}},
+		Response:      BallotListResponse{},
+		ResponseTypes: []string{"application/json", export.ContentType(export.FormatCSV)},
+		Errors:        errs(pollErrors, []apierror.Code{apierror.UnsupportedFormat}),
+	})
+	b.Describe((*PollHandler).IssueBallots, openapi.Op{
+		ID: "issueBallots", Tag: "admin", Summary: "批量生成一次性投票令牌",
+		Description: "labels非空时为每个受邀人生成一个令牌，否则生成count个。weight为使用令牌投票时的权重（默认为1），weights与令牌一一对应时逐个指定。令牌明文和投票地址只在此时返回。",
+		Query:       []openapi.Param{{Name: "format", Enum: []string{export.FormatJSON, export.FormatCSV}, Description: "默认json"}},
+		Request:     models.IssueBallotsRequest{}, Status: http.StatusCreated, Response: IssuedBallotsResponse{},
+		ResponseTypes: []string{"application/json", export.ContentType(export.FormatCSV)},
+		Errors:        errs(pollErrors, bindErrors, []apierror.Code{apierror.UnsupportedFormat}),
+	})
+	b.Describe((*PollHandler).RevokeBallot, openapi.Op{
+		ID: "revokeBallot", Tag: "admin", Summary: "吊销未使用的投票令牌",
+		Response: models.BallotToken{},
+		Errors:   []apierror.Code{apierror.InvalidParameter, apierror.BallotNotFound, apierror.BallotUsed},
+	})
+	b.Describe((*PollHandler).GetTurnout, openapi.Op{
+		ID: "getTurnout", Tag: "admin", Summary: "按投票人名册统计投票率",
+		Response: models.Turnout{},
+		Errors:   errs(pollErrors, []apierror.Code{apierror.RollNotFound}),
+	})
+	b.Describe((*PollHandler).ListDelegations, openapi.Op{
+		ID: "listDelegations", Tag: "admin", Summary: "列出名册中每个投票人的委托链",
+		Description: "按当前的投票和委托解析，statuses为各状态的人数。关闭后delegated状态的投票人已按委托计入票数。",
+		Response:    DelegationListResponse{},
+		Errors:      errs(pollErrors, []apierror.Code{apierror.RollNotFound}),
+	})
+	b.Describe((*PollHandler).ListRolls, openapi.Op{
+		ID: "listRolls", Tag: "admin", Summary: "列出投票人名册",
+		Response: RollListResponse{},
+	})
+	b.Describe((*PollHandler).ImportRoll, openapi.Op{
+		ID: "importRoll", Tag: "admin", Summary: "从CSV导入投票人名册",
+		Description:  "第一行为表头，必须包含identifier列，name和weight列可选，其他列作为属性保存。任一行无效时返回invalid_roll并指出行号和列名。",
+		Query:        []openapi.Param{{Name: "name", Required: true, Description: "名册名称"}},
+		Request:      "",
+		RequestTypes: []string{export.ContentType(export.FormatCSV)},
+		Status:       http.StatusCreated, Response: models.VoterRoll{},
+		Errors: []apierror.Code{apierror.ValidationFailed, apierror.InvalidRoll},
+	})
+	b.Describe((*PollHandler).GetRoll, openapi.Op{
+		ID: "getRoll", Tag: "admin", Summary: "获取投票人名册",
+		Query:         []openapi.Param{{Name: "format", Enum: []string{export.FormatJSON, export.FormatCSV}, Description: "默认json，csv可以修改后重新导入"}},
+		Response:      models.VoterRoll{},
+		ResponseTypes: []string{"application/json", export.ContentType(export.FormatCSV)},
+		Errors:        []apierror.Code{apierror.InvalidParameter, apierror.RollNotFound, apierror.UnsupportedFormat},
+	})
+	b.Describe((*PollHandler).DeleteRoll, openapi.Op{
+		ID: "deleteRoll", Tag: "admin", Summary: "删除投票人名册",
+		Description: "仍有投票问卷（包括已归档的）使用时返回roll_in_use。",
+		Response:    MessageResponse{},
+		Errors:      []apierror.Code{apierror.InvalidParameter, apierror.RollNotFound, apierror.RollInUse},
+	})
+	b.Describe((*SurveyHandler).ListSurveys, openapi.Op{
+		ID: "listSurveys", Tag: "surveys", Summary: "列出调查问卷",
+		Response: SurveyListResponse{},
+	})
+	b.Describe((*SurveyHandler).CreateSurvey, openapi.Op{
+		ID: "createSurvey", Tag: "surveys", Summary: "创建调查问卷",
+		Description: "问题类型为single_choice、multiple_choice、rating或text，按请求中的顺序编号。",
+		Request:     models.CreateSurveyRequest{}, Status: http.StatusCreated, Response: models.Survey{},
+		Errors: errs(bindErrors, []apierror.Code{apierror.InvalidQuestion}),
+	})
+	b.Describe((*SurveyHandler).GetSurveyByID, openapi.Op{
+		ID: "getSurveyByID", Tag: "surveys", Summary: "获取指定调查问卷",
+		Response: models.Survey{},
+		Errors:   surveyErrors,
+	})
+	b.Describe((*SurveyHandler).OpenSurvey, openapi.Op{
+		ID: "openSurvey", Tag: "surveys", Summary: "开启调查问卷",
+		Response: models.Survey{},
+		Errors:   errs(surveyErrors, []apierror.Code{apierror.SurveyUnchanged}),
+	})
+	b.Describe((*SurveyHandler).CloseSurvey, openapi.Op{
+		ID: "closeSurvey", Tag: "surveys", Summary: "关闭调查问卷",
+		Response: models.Survey{},
+		Errors:   errs(surveyErrors, []apierror.Code{apierror.SurveyUnchanged}),
+	})
+	b.Describe((*SurveyHandler).ExportSurvey, openapi.Op{
+		ID: "exportSurvey", Tag: "surveys", Summary: "导出调查问卷的原始回答，每次作答一行、每个问题一列",
+		Query: []openapi.Param{
+			{Name: "format", Enum: []string{export.FormatCSV, export.FormatJSON, export.FormatXLSX}, Description: "默认csv"},
+		},
+		Response:      struct{}{},
+		ResponseTypes: []string{export.ContentType(export.FormatCSV), export.ContentType(export.FormatJSON), export.ContentType(export.FormatXLSX)},
+		Errors:        errs(surveyErrors, []apierror.Code{apierror.UnsupportedFormat}),
+	})
+	b.Describe((*PollHandler).ListTemplates, openapi.Op{
+		ID: "listTemplates", Tag: "templates", Summary: "列出模板",
+		Response: TemplateListResponse{},
+	})
+	b.Describe((*PollHandler).CreateTemplate, openapi.Op{
+		ID: "createTemplate", Tag: "templates", Summary: "创建模板",
+		Request: models.TemplateRequest{}, Status: http.StatusCreated, Response: models.PollTemplate{},
+		Errors: bindErrors,
+	})
+	b.Describe((*PollHandler).GetTemplate, openapi.Op{
+		ID: "getTemplate", Tag: "templates", Summary: "获取模板",
+		Response: models.PollTemplate{},
+		Errors:   templateErrs,
+	})
+	b.Describe((*PollHandler).UpdateTemplate, openapi.Op{
+		ID: "updateTemplate", Tag: "templates", Summary: "编辑模板",
+		Request: models.TemplateRequest{}, Response: models.PollTemplate{},
+		Errors: errs(templateErrs, bindErrors),
+	})
+	b.Describe((*PollHandler).DeleteTemplate, openapi.Op{
+		ID: "deleteTemplate", Tag: "templates", Summary: "删除模板",
+		Response: MessageResponse{},
+		Errors:   templateErrs,
+	})
+	b.Describe((*PollHandler).CreatePollFromTemplate, openapi.Op{
+		ID: "createPollFromTemplate", Tag: "templates", Summary: "根据模板创建投票问卷",
+		Request: models.InstantiateTemplateRequest{}, OptionalBody: true,
+		Status: http.StatusCreated, Response: models.Poll{},
+		Errors: errs(templateErrs, bindErrors, []apierror.Code{apierror.UndefinedVariable}),
+	})
+
+	b.Describe((*AuditHandler).ListAuditLogs, openapi.Op{
+		ID: "listAuditLogs", Tag: "audit", Summary: "查询审计日志，按ID倒序",
+		Query: []openapi.Param{
+			{Name: "action", Description: "动作，例如 poll.closed"},
+			{Name: "actor", Description: "操作人"},
+			{Name: "poll_id", Type: "integer"},
+			{Name: "from", Format: "date-time"},
+			{Name: "to", Format: "date-time"},
+			{Name: "before_id", Type: "integer", Description: "向前翻页，只返回ID小于该值的记录"},
+			limitParam,
+		},
+		Response: AuditLogListResponse{},
+		Errors:   []apierror.Code{apierror.InvalidParameter, apierror.InvalidTime},
+	})
+	b.Describe((*AuditHandler).VerifyAuditLog, openapi.Op{
+		ID: "verifyAuditLog", Tag: "audit", Summary: "校验审计日志哈希链",
+		Response: audit.VerifyResult{},
+	})
+
+	b.Describe((*WebhookHandler).ListWebhooks, openapi.Op{
+		ID: "listWebhooks", Tag: "webhooks", Summary: "列出webhook订阅",
+		Response: WebhookListResponse{},
+	})
+	b.Describe((*WebhookHandler).CreateWebhook, openapi.Op{
+		ID: "createWebhook", Tag: "webhooks", Summary: "创建webhook订阅，签名密钥只在创建时返回一次",
+		Request: models.CreateWebhookRequest{}, Status: http.StatusCreated, Response: CreateWebhookResponse{},
+		Errors: bindErrors,
+	})
+	b.Describe((*WebhookHandler).GetWebhook, openapi.Op{
+		ID: "getWebhook", Tag: "webhooks", Summary: "获取webhook订阅",
+		Response: models.Webhook{},
+		Errors:   webhookErrors,
+	})
+	b.Describe((*WebhookHandler).UpdateWebhook, openapi.Op{
+		ID: "updateWebhook", Tag: "webhooks", Summary: "编辑webhook订阅",
+		Request: models.UpdateWebhookRequest{}, Response: models.Webhook{},
+		Errors: errs(webhookErrors, bindErrors),
+	})
+	b.Describe((*WebhookHandler).DeleteWebhook, openapi.Op{
+		ID: "deleteWebhook", Tag: "webhooks", Summary: "删除webhook订阅",
+		Response: MessageResponse{},
+		Errors:   webhookErrors,
+	})
+	b.Describe((*WebhookHandler).ListDeliveries, openapi.Op{
+		ID: "listDeliveries", Tag: "webhooks", Summary: "查询webhook的投递记录",
+		Query: []openapi.Param{
+			{Name: "status", Enum: []string{models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead}},
+			limitParam,
+		},
+		Response: DeliveryListResponse{},
+		Errors:   webhookErrors,
+	})
+	b.Describe((*WebhookHandler).ListDeadDeliveries, openapi.Op{
+		ID: "listDeadDeliveries", Tag: "webhooks", Summary: "列出进入死信状态的投递记录",
+		Query:    []openapi.Param{limitParam},
+		Response: DeliveryListResponse{},
+		Errors:   []apierror.Code{apierror.InvalidParameter},
+	})
+	b.Describe((*WebhookHandler).RedeliverDelivery, openapi.Op{
+		ID: "redeliverDelivery", Tag: "webhooks", Summary: "重新投递",
+		Response: MessageResponse{},
+		Errors: []apierror.Code{apierror.InvalidParameter, apierror.DeliveryNotFound,
+			apierror.DeliveryPending, apierror.WebhookDeleted},
+	})
+
+	b.Describe((*TokenHandler).ListTokens, openapi.Op{
+		ID: "listTokens", Tag: "tokens", Summary: "列出API令牌",
+		Response: TokenListResponse{},
+	})
+	b.Describe((*TokenHandler).CreateToken, openapi.Op{
+		ID: "createToken", Tag: "tokens", Summary: "创建API令牌，令牌只在创建时返回一次",
+		Request: models.CreateTokenRequest{}, Status: http.StatusCreated, Response: CreateTokenResponse{},
+		Errors: bindErrors,
+	})
+	b.Describe((*TokenHandler).RevokeToken, openapi.Op{
+		ID: "revokeToken", Tag: "tokens", Summary: "吊销API令牌",
+		Response: MessageResponse{},
+		Errors:   []apierror.Code{apierror.InvalidParameter, apierror.TokenNotFound},
+	})
+
+	b.Describe((*webSocketHandler).serve, openapi.Op{
+		ID: "pollWebSocket", Tag: "meta", Summary: "实时推送投票结果的WebSocket连接",
+		Description: "浏览器无法设置请求头，管理员通过token查询参数传入管理令牌以接收隐藏的票数。",
+		Query:       []openapi.Param{{Name: "token", Description: "管理令牌或API令牌"}},
+		Status:      http.StatusSwitchingProtocols,
+	})
+	b.Describe((*openapi.Docs).Spec, openapi.Op{
+		ID: "getOpenAPI", Tag: "meta", Summary: "OpenAPI 3文档",
+		Response: map[string]interface{}{},
+	})
+	b.Describe((*openapi.Docs).UI, openapi.Op{
+		ID: "getDocs", Tag: "meta", Summary: "Swagger UI接口文档页面，可离线使用",
+		Response:      "",
+		ResponseTypes: []string{"text/html"},
+	})
+	b.Describe((*openapi.Docs).Asset, openapi.Op{
+		ID: "getDocsAsset", Tag: "meta", Summary: "接口文档页面内嵌的Swagger UI脚本和样式",
+		Response:      "",
+		ResponseTypes: []string{"application/octet-stream"},
+	})
+
+	b.AddRoutes(routes)
+	return b.Document()
+}
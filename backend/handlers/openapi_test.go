@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vote-system/openapi"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupFullRouter 按main.go的方式注册全部路由
+func setupFullRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB()
+	router := gin.New()
+	hub := websocket.NewHub()
+
+	polls := NewPollHandler(db, hub, nil, nil)
+	surveys := NewSurveyHandler(db, nil, nil)
+	RegisterPublicRoutes(router.Group("/api"), polls, surveys)
+	admin := router.Group("/api/admin", AdminAuth("secret", db))
+	RegisterAdminRoutes(admin, polls, surveys, NewAuditHandler(db), NewWebhookHandler(db, nil), NewTokenHandler(db, nil))
+	RegisterWebSocketRoute(router, hub, "secret", db)
+	RegisterDocsRoutes(router)
+	return router
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	router := setupFullRouter()
+	doc := OpenAPI(router.Routes())
+
+	ids := map[string]string{}
+	for _, route := range router.Routes() {
+		key := route.Method + " " + openapi.Path(route.Path)
+		item, ok := doc.Paths[openapi.Path(route.Path)]
+		if !ok || (*item)[strings.ToLower(route.Method)] == nil {
+			t.Errorf("路由 %s 没有写入OpenAPI文档", key)
+			continue
+		}
+		op := (*item)[strings.ToLower(route.Method)]
+		if op.Summary == "" {
+			t.Errorf("路由 %s 的处理函数 %s 没有接口说明，请在handlers.OpenAPI中补充", key, route.Handler)
+		}
+		if other, dup := ids[op.OperationID]; dup {
+			t.Errorf("operationId %s 重复: %s 和 %s", op.OperationID, other, key)
+		}
+		ids[op.OperationID] = key
+	}
+
+	// 认证方式取自路径前缀
+	if op := (*doc.Paths["/api/admin/polls"])["get"]; len(op.Security) != 1 {
+		t.Errorf("管理接口应需要认证, 得到 %+v", op.Security)
+	}
+	if op := (*doc.Paths["/api/poll"])["get"]; len(op.Security) != 0 {
+		t.Errorf("公开接口不应需要认证, 得到 %+v", op.Security)
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	doc := OpenAPI(setupFullRouter().Routes())
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("序列化文档失败: %v", err)
+	}
+
+	var raw interface{}
+	json.Unmarshal(data, &raw)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Components.Schemas[name]; !ok {
+					t.Errorf("无法解析引用 %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(raw)
+}
+
+func TestDocsRoutes(t *testing.T) {
+	router := setupFullRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 200, 得到 %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc.OpenAPI != openapi.Version {
+		t.Fatalf("文档格式不正确: %v", err)
+	}
+	if _, ok := doc.Paths["/api/admin/polls/{id}"]; !ok {
+		t.Error("路径参数应转换为 {id} 的写法")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/docs", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/openapi.json") || !strings.Contains(w.Body.String(), "SwaggerUIBundle") {
+		t.Errorf("文档页面不正确: %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), `src="http`) || strings.Contains(w.Body.String(), `href="http`) {
+		t.Error("文档页面不应依赖外部资源")
+	}
+
+	// Swagger UI的脚本和样式内嵌在服务中
+	for _, path := range []string{"/docs/swagger-ui-bundle.js", "/docs/swagger-ui.css"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%s: 期望状态码 200, 得到 %d", path, w.Code)
+		}
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/docs/index.html", nil)
+	router.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Error("/docs/index.html 应返回本服务的文档页面")
+	}
+}
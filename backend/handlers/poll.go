@@ -2,7 +2,13 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"vote-system/apierror"
 	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/service"
+	"vote-system/tally"
 	"vote-system/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -10,204 +16,124 @@ import (
 )
 
 type PollHandler struct {
-	db  *gorm.DB
-	hub *websocket.Hub
+	db         *gorm.DB
+	hub        *websocket.Hub
+	dispatcher *outbox.Dispatcher
+	hasher     *privacy.Hasher
+	polls      *service.PollService
 }
 
-func NewPollHandler(db *gorm.DB, hub *websocket.Hub) *PollHandler {
+// NewPollHandler 创建PollHandler，hasher为nil时投票人标识按原样保存
+func NewPollHandler(db *gorm.DB, hub *websocket.Hub, dispatcher *outbox.Dispatcher, hasher *privacy.Hasher) *PollHandler {
 	return &PollHandler{
-		db:  db,
-		hub: hub,
+		db:         db,
+		hub:        hub,
+		dispatcher: dispatcher,
+		hasher:     hasher,
+		polls:      service.NewPollService(db, dispatcher, hasher),
 	}
 }
 
 // GetPoll 获取投票问卷和统计数据
 func (h *PollHandler) GetPoll(c *gin.Context) {
-	userIP := c.ClientIP()
-
-	// 获取活跃的投票问卷
-	var poll models.Poll
-	if err := h.db.Where("is_active = ?", true).Preload("Options").First(&poll).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No active poll found"})
+	response, err := h.polls.View(0, voterOf(c))
+	if err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 计算总票数
-	totalVotes := 0
-	for _, option := range poll.Options {
-		totalVotes += option.VoteCount
-	}
-
-	// 检查用户是否已投票
-	var vote models.Vote
-	userVoted := false
-	var votedOption *uint
-
-	if err := h.db.Where("poll_id = ? AND user_ip = ?", poll.ID, userIP).First(&vote).Error; err == nil {
-		userVoted = true
-		votedOption = &vote.OptionID
-	}
-
-	response := models.PollResponse{
-		Poll:        poll,
-		TotalVotes:  totalVotes,
-		UserVoted:   userVoted,
-		VotedOption: votedOption,
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
-// Vote 提交投票
+// Vote 提交投票，按投票问卷的投票方式提供option_id、option_ids或scores
 func (h *PollHandler) Vote(c *gin.Context) {
-	userIP := c.ClientIP()
-
 	var req models.VoteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	// 获取活跃的投票问卷
-	var poll models.Poll
-	if err := h.db.Where("is_active = ?", true).First(&poll).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No active poll found"})
+		apierror.Bind(c, err)
 		return
 	}
 
-	// 检查选项是否存在
-	var option models.Option
-	if err := h.db.Where("id = ? AND poll_id = ?", req.OptionID, poll.ID).First(&option).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid option"})
+	b := tally.Ballot{OptionID: req.OptionID, OptionIDs: req.OptionIDs, Scores: req.Scores}
+	if b.Empty() {
+		apierror.Invalid(c, apierror.Field("option_id", "required", ""))
 		return
 	}
 
-	// 检查用户是否已投票
-	var existingVote models.Vote
-	if err := h.db.Where("poll_id = ? AND user_ip = ?", poll.ID, userIP).First(&existingVote).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You have already voted"})
+	if err := h.polls.Cast(0, b, voterOf(c), req.WriteIn, service.Credentials{
+		AccessCode:  req.AccessCode,
+		BallotToken: req.BallotToken,
+	}); err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 开始事务
-	tx := h.db.Begin()
-
-	// 创建投票记录
-	vote := models.Vote{
-		PollID:   poll.ID,
-		OptionID: req.OptionID,
-		UserIP:   userIP,
-	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Vote submitted successfully"})
+}
 
-	if err := tx.Create(&vote).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create vote"})
+// ProposeOption 提议新选项，经管理员审核后加入进行中的投票问卷，相同内容已被提议时返回已有的提议
+func (h *PollHandler) ProposeOption(c *gin.Context) {
+	var req models.ProposeOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
 		return
 	}
 
-	// 增加选项投票数
-	if err := tx.Model(&option).Update("vote_count", gorm.Expr("vote_count + ?", 1)).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote count"})
+	proposal, created, err := h.polls.Propose(0, req.Text, voterOf(c))
+	if err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 提交事务
-	tx.Commit()
-
-	// 获取更新后的数据
-	h.db.Where("is_active = ?", true).Preload("Options").First(&poll)
-
-	// 广播更新
-	h.hub.BroadcastPollUpdate(poll)
-
-	c.JSON(http.StatusOK, gin.H{"message": "Vote submitted successfully"})
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, proposal)
 }
 
 // ClearVotes 清除当前用户的投票记录（仅开发模式）
 func (h *PollHandler) ClearVotes(c *gin.Context) {
-	userIP := c.ClientIP()
-
-	// 获取活跃的投票问卷
-	var poll models.Poll
-	if err := h.db.Where("is_active = ?", true).First(&poll).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No active poll found"})
+	if err := h.polls.ClearVote(voterOf(c), newAuditEntry(c, h.hasher, "", nil, nil, nil)); err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 查找用户的投票记录
-	var vote models.Vote
-	if err := h.db.Where("poll_id = ? AND user_ip = ?", poll.ID, userIP).First(&vote).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No vote found for this user"})
-		return
-	}
-
-	// 开始事务
-	tx := h.db.Begin()
-
-	// 减少选项的投票数
-	var option models.Option
-	if err := tx.Where("id = ?", vote.OptionID).First(&option).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find option"})
-		return
-	}
-
-	if err := tx.Model(&option).Update("vote_count", gorm.Expr("vote_count - ?", 1)).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote count"})
-		return
-	}
-
-	// 删除投票记录
-	if err := tx.Delete(&vote).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vote"})
-		return
-	}
-
-	// 提交事务
-	tx.Commit()
-
-	// 获取更新后的数据并广播
-	h.db.Where("is_active = ?", true).Preload("Options").First(&poll)
-	h.hub.BroadcastPollUpdate(poll)
-
-	c.JSON(http.StatusOK, gin.H{"message": "Vote cleared successfully"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "Vote cleared successfully"})
 }
 
 // ResetPoll 重置投票（清除所有投票记录）
 func (h *PollHandler) ResetPoll(c *gin.Context) {
-	// 获取活跃的投票问卷
-	var poll models.Poll
-	if err := h.db.Where("is_active = ?", true).Preload("Options").First(&poll).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No active poll found"})
+	poll, err := h.polls.Active()
+	if err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 开始事务
-	tx := h.db.Begin()
-
-	// 删除所有投票记录
-	if err := tx.Where("poll_id = ?", poll.ID).Delete(&models.Vote{}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear votes"})
-		return
-	}
+	h.resetPoll(c, poll)
+}
 
-	// 重置所有选项的投票数
-	if err := tx.Model(&models.Option{}).Where("poll_id = ?", poll.ID).Update("vote_count", 0).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset vote counts"})
+// resetPoll 清除投票问卷的所有投票并写入响应
+func (h *PollHandler) resetPoll(c *gin.Context, poll models.Poll) {
+	if err := h.polls.Reset(poll, newAuditEntry(c, h.hasher, "", nil, nil, nil)); err != nil {
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 提交事务
-	tx.Commit()
+	c.JSON(http.StatusOK, MessageResponse{Message: "Poll reset successfully"})
+}
 
-	// 获取更新后的数据并广播
-	h.db.Where("is_active = ?", true).Preload("Options").First(&poll)
-	h.hub.BroadcastPollUpdate(poll)
+// loadPoll 根据路径参数id读取投票问卷（含选项），失败时写入错误响应并返回false
+func (h *PollHandler) loadPoll(c *gin.Context) (models.Poll, bool) {
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return models.Poll{}, false
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Poll reset successfully"})
+	poll, err := h.polls.Load(uint(pollID))
+	if err != nil {
+		apierror.Respond(c, err)
+		return poll, false
+	}
+	return poll, true
 }
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"vote-system/models"
+	"vote-system/privacy"
 	"vote-system/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +23,7 @@ func setupTestDB() *gorm.DB {
 	}
 
 	// 自动迁移测试表
-	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{})
+	db.AutoMigrate(&models.Poll{}, &models.PollTemplate{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.OptionProposal{}, &models.BallotToken{}, &models.VoterRoll{}, &models.RollMember{}, &models.Delegation{}, &models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{}, &models.VoteRollup{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookThreshold{}, &models.APIToken{})
 	return db
 }
 
@@ -52,7 +54,7 @@ func TestNewPollHandler(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
 
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	if handler == nil {
 		t.Fatal("PollHandler不应该为nil")
@@ -70,7 +72,7 @@ func TestNewPollHandler(t *testing.T) {
 func TestGetPoll_Success(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	poll, _ := setupTestData(db)
@@ -121,7 +123,7 @@ func TestGetPoll_Success(t *testing.T) {
 func TestGetPoll_NoPollFound(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -140,7 +142,7 @@ func TestGetPoll_NoPollFound(t *testing.T) {
 func TestVote_Success(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	_, options := setupTestData(db)
@@ -180,10 +182,115 @@ func TestVote_Success(t *testing.T) {
 	}
 }
 
+func TestVote_WritesOutboxEvent(t *testing.T) {
+	db := setupTestDB()
+	hub := websocket.NewHub()
+	handler := NewPollHandler(db, hub, nil, nil)
+
+	poll, options := setupTestData(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/vote", handler.Vote)
+
+	jsonData, _ := json.Marshal(models.VoteRequest{OptionID: options[1].ID})
+	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+
+	// 投票与事件应在同一事务中写入，且尚未投递
+	var events []models.OutboxEvent
+	db.Find(&events)
+	if len(events) != 1 {
+		t.Fatalf("期望1条outbox事件, 得到 %d", len(events))
+	}
+
+	if events[0].Type != models.EventVoteCast || events[0].PollID != poll.ID {
+		t.Errorf("事件内容不正确: %+v", events[0])
+	}
+
+	if events[0].DeliveredAt != nil {
+		t.Error("事件不应在处理请求时被标记为已投递")
+	}
+}
+
+func TestVote_AutoClose(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("target_votes", 2)
+	db.Model(&options[0]).Update("vote_count", 1)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/vote", handler.Vote)
+
+	jsonData, _ := json.Marshal(models.VoteRequest{OptionID: options[0].ID})
+	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	db.First(&poll, poll.ID)
+	if poll.IsActive || poll.CloseReason != models.CloseReasonTarget {
+		t.Errorf("达到总票数后应自动关闭, 得到 %+v", poll)
+	}
+
+	var closedEvents int64
+	db.Model(&models.OutboxEvent{}).Where("type = ?", models.EventPollClosed).Count(&closedEvents)
+	if closedEvents != 1 {
+		t.Errorf("期望1条poll_closed事件, 得到 %d", closedEvents)
+	}
+}
+
+func TestVote_AfterDeadline(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("closes_at", time.Now().Add(-time.Minute))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/vote", handler.Vote)
+
+	jsonData, _ := json.Marshal(models.VoteRequest{OptionID: options[0].ID})
+	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+
+	var votes int64
+	db.Model(&models.Vote{}).Count(&votes)
+	if votes != 0 {
+		t.Errorf("截止后不应记录投票, 得到 %d", votes)
+	}
+
+	db.First(&poll, poll.ID)
+	if poll.IsActive || poll.CloseReason != models.CloseReasonDeadline {
+		t.Errorf("截止后应关闭投票问卷, 得到 %+v", poll)
+	}
+}
+
 func TestVote_InvalidJSON(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -203,7 +310,7 @@ func TestVote_InvalidJSON(t *testing.T) {
 func TestVote_NoPollFound(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -228,7 +335,7 @@ func TestVote_NoPollFound(t *testing.T) {
 func TestVote_InvalidOption(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	setupTestData(db)
@@ -256,7 +363,7 @@ func TestVote_InvalidOption(t *testing.T) {
 func TestVote_AlreadyVoted(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	poll, options := setupTestData(db)
@@ -280,6 +387,7 @@ func TestVote_AlreadyVoted(t *testing.T) {
 
 	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.RemoteAddr = "127.0.0.1:12345"
 	w := httptest.NewRecorder()
 
@@ -288,12 +396,21 @@ func TestVote_AlreadyVoted(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
 	}
+
+	var resp struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Code != "already_voted" || resp.Error != "You have already voted" {
+		t.Errorf("错误响应不正确: %s", w.Body.String())
+	}
 }
 
 func TestClearVotes_Success(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	poll, options := setupTestData(db)
@@ -341,7 +458,7 @@ func TestClearVotes_Success(t *testing.T) {
 func TestClearVotes_NoVoteFound(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据但不创建投票记录
 	setupTestData(db)
@@ -363,7 +480,7 @@ func TestClearVotes_NoVoteFound(t *testing.T) {
 func TestResetPoll_Success(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	// 设置测试数据
 	poll, options := setupTestData(db)
@@ -417,7 +534,7 @@ func TestResetPoll_Success(t *testing.T) {
 func TestResetPoll_NoPollFound(t *testing.T) {
 	db := setupTestDB()
 	hub := websocket.NewHub()
-	handler := NewPollHandler(db, hub)
+	handler := NewPollHandler(db, hub, nil, nil)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -432,3 +549,59 @@ func TestResetPoll_NoPollFound(t *testing.T) {
 		t.Errorf("期望状态码 %d, 得到 %d", http.StatusNotFound, w.Code)
 	}
 }
+
+func TestVote_PrivacyMode(t *testing.T) {
+	db := setupTestDB()
+	hasher := privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("secret")}})
+	handler := NewPollHandler(db, websocket.NewHub(), nil, hasher)
+
+	_, options := setupTestData(db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/vote", handler.Vote)
+	router.GET("/poll", handler.GetPoll)
+
+	vote := func(h *PollHandler, optionID uint) int {
+		router := gin.New()
+		router.POST("/vote", h.Vote)
+		jsonData, _ := json.Marshal(models.VoteRequest{OptionID: optionID})
+		req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.1.2.3:4567"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := vote(handler, options[0].ID); code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d", http.StatusOK, code)
+	}
+
+	var stored models.Vote
+	db.First(&stored)
+	if stored.UserIP != "" {
+		t.Errorf("隐私模式下不应保存原始IP, 得到 %s", stored.UserIP)
+	}
+	if stored.VoterHash == "" || stored.VoterKeyID != "k1" {
+		t.Errorf("隐私模式下应保存HMAC和密钥ID: %+v", stored)
+	}
+
+	// 轮换密钥后仍能识别重复投票
+	rotated := privacy.NewHasher([]privacy.Key{{ID: "k2", Secret: []byte("new")}, {ID: "k1", Secret: []byte("secret")}})
+	if code := vote(NewPollHandler(db, websocket.NewHub(), nil, rotated), options[1].ID); code != http.StatusBadRequest {
+		t.Errorf("轮换密钥后重复投票期望状态码 %d, 得到 %d", http.StatusBadRequest, code)
+	}
+
+	// GetPoll 能识别已投票
+	req, _ := http.NewRequest("GET", "/poll", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response models.PollResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.UserVoted || response.VotedOption == nil || *response.VotedOption != options[0].ID {
+		t.Errorf("隐私模式下应能识别用户已投票: %+v", response)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"vote-system/ballot"
+	"vote-system/delegation"
+	"vote-system/manifest"
+	"vote-system/models"
+	"vote-system/stats"
+)
+
+// MessageResponse 只包含提示信息的响应
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// PollListResponse 投票问卷列表响应
+type PollListResponse struct {
+	Polls []models.Poll `json:"polls"`
+	// NextCursor 下一页的游标，最后一页为空
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ProposalListResponse 选项提议列表响应
+type ProposalListResponse struct {
+	Proposals []models.OptionProposal `json:"proposals"`
+}
+
+// IssuedBallotsResponse 新生成的投票令牌响应，令牌明文只在此时返回
+type IssuedBallotsResponse struct {
+	Ballots []ballot.Issued `json:"ballots"`
+}
+
+// BallotListResponse 投票令牌列表和投票率响应
+type BallotListResponse struct {
+	Ballots []models.BallotToken `json:"ballots"`
+	Turnout ballot.Turnout       `json:"turnout"`
+}
+
+// RollListResponse 投票人名册列表响应，不含名册中的投票人
+type RollListResponse struct {
+	Rolls []models.VoterRoll `json:"rolls"`
+}
+
+// DelegationListResponse 投票问卷的委托链列表响应，Statuses为各状态的人数
+type DelegationListResponse struct {
+	Chains   []delegation.Chain `json:"chains"`
+	Statuses map[string]int     `json:"statuses"`
+}
+
+// SurveyListResponse 调查问卷列表响应
+type SurveyListResponse struct {
+	Surveys []models.Survey `json:"surveys"`
+}
+
+// TemplateListResponse 模板列表响应
+type TemplateListResponse struct {
+	Templates []models.PollTemplate `json:"templates"`
+}
+
+// TokenListResponse API令牌列表响应
+type TokenListResponse struct {
+	Tokens []models.APIToken `json:"tokens"`
+}
+
+// CreateTokenResponse 创建API令牌响应，Secret只在创建时返回一次
+type CreateTokenResponse struct {
+	Token  models.APIToken `json:"token"`
+	Secret string          `json:"secret"`
+}
+
+// WebhookListResponse webhook订阅列表响应
+type WebhookListResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+// CreateWebhookResponse 创建webhook订阅响应，Secret只在创建时返回一次
+type CreateWebhookResponse struct {
+	Webhook models.Webhook `json:"webhook"`
+	Secret  string         `json:"secret"`
+}
+
+// DeliveryListResponse webhook投递列表响应
+type DeliveryListResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+// AuditLogListResponse 审计日志列表响应
+type AuditLogListResponse struct {
+	Entries []models.AuditLog `json:"entries"`
+}
+
+// ReconcileResponse 修正票数响应，Drift为修正前不一致的选项
+//
+// 结果对管理员隐藏时不返回Drift中的票数，只在OptionIDs中返回不一致的选项ID。
+type ReconcileResponse struct {
+	PollID        uint          `json:"poll_id"`
+	DryRun        bool          `json:"dry_run"`
+	Drift         []stats.Drift `json:"drift"`
+	ResultsHidden bool          `json:"results_hidden,omitempty"`
+	OptionIDs     []uint        `json:"option_ids,omitempty"`
+}
+
+// ImportResponse 导入文档响应
+type ImportResponse struct {
+	DryRun bool           `json:"dry_run"`
+	Plan   *manifest.Plan `json:"plan"`
+	Diff   string         `json:"diff"`
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/export"
+	"vote-system/roll"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRolls 列出投票人名册及其人数（管理接口）
+func (h *PollHandler) ListRolls(c *gin.Context) {
+	rolls, err := h.polls.Rolls()
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RollListResponse{Rolls: rolls})
+}
+
+// ImportRoll 从请求体中的CSV导入投票人名册（管理接口），name查询参数为名册名称
+//
+// 任一行无效时整个名册都不导入，错误信息中指出行号和列名。
+func (h *PollHandler) ImportRoll(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		apierror.Invalid(c, apierror.Field("name", "required", ""))
+		return
+	}
+	if len(name) > 255 {
+		apierror.Invalid(c, apierror.Field("name", "max", "255"))
+		return
+	}
+
+	members, err := roll.Parse(c.Request.Body)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	imported, err := h.polls.ImportRoll(name, members, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, imported)
+}
+
+// GetRoll 获取投票人名册及其中的投票人（管理接口），format=csv时以可重新导入的CSV文件下载
+func (h *PollHandler) GetRoll(c *gin.Context) {
+	rollID, ok := rollIDParam(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatJSON)
+	if format != export.FormatJSON && format != export.FormatCSV {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	result, err := h.polls.Roll(rollID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	if format == export.FormatJSON {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="roll-%d.csv"`, rollID))
+	c.Status(http.StatusOK)
+	if err := export.WriteRoll(c.Writer, result.Members); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// DeleteRoll 删除没有被投票问卷使用的投票人名册（管理接口）
+func (h *PollHandler) DeleteRoll(c *gin.Context) {
+	rollID, ok := rollIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.polls.DeleteRoll(rollID, newAuditEntry(c, h.hasher, "", nil, nil, nil)); err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Voter roll deleted successfully"})
+}
+
+// GetTurnout 按名册统计投票问卷的投票率（管理接口），投票问卷没有名册时返回RollNotFound
+func (h *PollHandler) GetTurnout(c *gin.Context) {
+	pollID, ok := pollIDParam(c)
+	if !ok {
+		return
+	}
+
+	turnout, err := h.polls.Turnout(pollID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	if turnout == nil {
+		apierror.Abort(c, apierror.RollNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, turnout)
+}
+
+// rollIDParam 解析路径参数id，失败时写入错误响应并返回false
+func rollIDParam(c *gin.Context) (uint, bool) {
+	rollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return 0, false
+	}
+	return uint(rollID), true
+}
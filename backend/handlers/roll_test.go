@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/identity"
+	"vote-system/models"
+	"vote-system/privacy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// setupRollRouter 按请求头X-Remote-User识别投票人的路由
+func setupRollRouter() *gin.Engine {
+	return rollRouter(setupTestDB(), nil)
+}
+
+// rollRouter 使用给定数据库和hasher的setupRollRouter，用于隐私模式和轮换密钥
+func rollRouter(db *gorm.DB, hasher *privacy.Hasher) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(VoterIdentity(identity.Header("X-Remote-User")))
+
+	polls := NewPollHandler(db, nil, nil, hasher)
+	RegisterPublicRoutes(router.Group("/api"), polls, NewSurveyHandler(db, nil, hasher))
+	RegisterAdminRoutes(router.Group("/api/admin", AdminAuth("secret", db)), polls, NewSurveyHandler(db, nil, hasher),
+		NewAuditHandler(db), NewWebhookHandler(db, hasher), NewTokenHandler(db, hasher))
+	return router
+}
+
+// importRoll 以CSV请求体导入名册
+func importRoll(router *gin.Engine, name, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/admin/rolls?name="+name, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// voteAs 以X-Remote-User标识的投票人投票
+func voteAs(router *gin.Engine, voter string, optionID uint) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/poll/vote", strings.NewReader(`{"option_id": `+strconv.Itoa(int(optionID))+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	if voter != "" {
+		req.Header.Set("X-Remote-User", voter)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVoterRoll(t *testing.T) {
+	router := setupRollRouter()
+
+	w := importRoll(router, "board", "identifier,name,department\nalice@example.com,张三,研发\nalice@example.com,李四,市场\n")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"invalid_roll"`) || !strings.Contains(w.Body.String(), "3") {
+		t.Errorf("重复的标识应指出行号: %d %s", w.Code, w.Body.String())
+	}
+	if w := importRoll(router, "", "identifier\na\n"); w.Code != http.StatusBadRequest {
+		t.Errorf("缺少名称期望400, 得到 %d", w.Code)
+	}
+
+	w = importRoll(router, "board", "identifier,name,department,weight\nalice@example.com,张三,研发,2\nbob@example.com,李四,市场,\ncarol@example.com,王五,研发,\n")
+	var roll models.VoterRoll
+	json.Unmarshal(w.Body.Bytes(), &roll)
+	if w.Code != http.StatusCreated || roll.MemberCount != 3 {
+		t.Fatalf("导入名册失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "理事会", "options": []string{"甲", "乙"}, "access": "invite", "roll_id": roll.ID})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "roll_id") {
+		t.Errorf("邀请制投票问卷不能使用名册: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "理事会", "options": []string{"甲", "乙"}, "roll_id": roll.ID + 1})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("名册不存在期望400, 得到 %d", w.Code)
+	}
+	w = adminRequest(router, "POST", "/api/admin/polls", gin.H{"title": "董事会决议", "options": []string{"同意", "反对"}, "roll_id": roll.ID})
+	var poll models.Poll
+	json.Unmarshal(w.Body.Bytes(), &poll)
+	if w.Code != http.StatusCreated || poll.RollID == nil || *poll.RollID != roll.ID {
+		t.Fatalf("创建使用名册的投票问卷失败: %d %s", w.Code, w.Body.String())
+	}
+
+	// 没有身份请求头时按客户端IP识别，不在名册中
+	for _, voter := range []string{"", "dave@example.com"} {
+		if w := voteAs(router, voter, poll.Options[0].ID); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "not_eligible") {
+			t.Errorf("%q 期望403 not_eligible, 得到 %d %s", voter, w.Code, w.Body.String())
+		}
+	}
+	for _, voter := range []string{"Alice@Example.com", "bob@example.com"} {
+		if w := voteAs(router, voter, poll.Options[0].ID); w.Code != http.StatusOK {
+			t.Fatalf("%s 投票失败: %d %s", voter, w.Code, w.Body.String())
+		}
+	}
+	if w := voteAs(router, "alice@example.com", poll.Options[1].ID); w.Code != http.StatusBadRequest {
+		t.Errorf("重复投票期望400, 得到 %d", w.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/poll", nil)
+	req.Header.Set("X-Remote-User", "alice@example.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var view models.PollResponse
+	json.Unmarshal(w.Body.Bytes(), &view)
+	if !view.UserVoted || view.Turnout == nil || view.Turnout.Voted != 2 || view.Turnout.Eligible != 3 || view.Turnout.Percentage != 66.67 {
+		t.Errorf("投票率不正确: %s", w.Body.String())
+	}
+
+	path := "/api/admin/polls/" + strconv.Itoa(int(poll.ID)) + "/turnout"
+	if w := adminRequest(router, "GET", path, nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"voted":2`) {
+		t.Errorf("管理接口投票率不正确: %d %s", w.Code, w.Body.String())
+	}
+
+	rollPath := "/api/admin/rolls/" + strconv.Itoa(int(roll.ID))
+	w = adminRequest(router, "GET", rollPath+"?format=csv", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "identifier,name,weight,department\nalice@example.com,张三,2,研发\n") {
+		t.Errorf("导出名册不正确: %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(router, "DELETE", rollPath, nil); w.Code != http.StatusConflict {
+		t.Errorf("名册被使用时期望409, 得到 %d", w.Code)
+	}
+
+	// 取消名册限制后可以删除名册
+	if w := adminRequest(router, "PUT", "/api/admin/polls/"+strconv.Itoa(int(poll.ID)), gin.H{"roll_id": 0}); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "roll_id") {
+		t.Fatalf("取消名册失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(router, "GET", path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("没有名册时期望404, 得到 %d", w.Code)
+	}
+	if w := adminRequest(router, "DELETE", rollPath, nil); w.Code != http.StatusOK {
+		t.Errorf("删除名册失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(router, "GET", "/api/admin/rolls", nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"rolls":[]`) {
+		t.Errorf("删除后名册列表应为空: %d %s", w.Code, w.Body.String())
+	}
+}
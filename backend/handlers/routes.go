@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"vote-system/openapi"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterPublicRoutes 注册投票页面使用的公开接口
+func RegisterPublicRoutes(api gin.IRoutes, polls *PollHandler, surveys *SurveyHandler) {
+	api.GET("/poll", polls.GetPoll)
+	api.POST("/poll/vote", polls.Vote)
+	api.POST("/poll/proposals", polls.ProposeOption)
+	api.DELETE("/poll/clear-my-vote", polls.ClearVotes)
+	api.DELETE("/poll/reset", polls.ResetPoll)
+	api.GET("/poll/delegation", polls.GetDelegationChain)
+	api.PUT("/poll/delegation", polls.SetDelegation)
+	api.DELETE("/poll/delegation", polls.RemoveDelegation)
+	api.GET("/polls/:id/history", polls.GetHistory)
+	api.GET("/surveys/:id", surveys.GetSurvey)
+	api.POST("/surveys/:id/responses", surveys.SubmitResponse)
+	api.POST("/surveys/:id/next", surveys.NextQuestion)
+	api.GET("/surveys/:id/results", surveys.GetSurveyResults)
+	api.GET("/surveys/:id/crosstab", surveys.GetSurveyCrosstab)
+}
+
+// webSocketHandler 实时推送的WebSocket连接，token和db用于识别管理员连接
+type webSocketHandler struct {
+	hub   *websocket.Hub
+	token string
+	db    *gorm.DB
+}
+
+func (h *webSocketHandler) serve(c *gin.Context) {
+	websocket.ServeWS(h.hub, c.Writer, c.Request, WebSocketAudience(c, h.token, h.db))
+}
+
+// RegisterWebSocketRoute 注册实时推送的WebSocket路由，token和db用于识别管理员连接
+func RegisterWebSocketRoute(r gin.IRoutes, hub *websocket.Hub, token string, db *gorm.DB) {
+	ws := &webSocketHandler{hub: hub, token: token, db: db}
+	r.GET("/ws/poll", ws.serve)
+}
+
+// RegisterDocsRoutes 注册OpenAPI文档和内嵌的Swagger UI页面，文档按r在第一次请求时的路由表生成
+func RegisterDocsRoutes(r *gin.Engine) {
+	docs := openapi.NewDocs(func() *openapi.Document { return OpenAPI(r.Routes()) })
+	r.GET("/openapi.json", docs.Spec)
+	r.GET(openapi.UIPath, docs.UI)
+	r.GET(openapi.UIPath+"/*filepath", docs.Asset)
+}
+
+// RegisterAdminRoutes 注册管理接口路由，服务本身和votectl的直连数据库模式共用
+func RegisterAdminRoutes(admin gin.IRoutes, polls *PollHandler, surveys *SurveyHandler, audits *AuditHandler, webhooks *WebhookHandler, tokens *TokenHandler) {
+	admin.GET("/polls", polls.ListPolls)
+	admin.POST("/polls", polls.CreatePoll)
+	admin.POST("/polls/import", polls.ImportPolls)
+	admin.GET("/polls/manifest", polls.ExportManifest)
+	admin.GET("/polls/:id", polls.GetPollByID)
+	admin.PUT("/polls/:id", polls.UpdatePoll)
+	admin.POST("/polls/:id/open", polls.OpenPoll)
+	admin.POST("/polls/:id/close", polls.ClosePoll)
+	admin.POST("/polls/:id/reset", polls.AdminResetPoll)
+	admin.POST("/polls/:id/reconcile", polls.ReconcilePoll)
+	admin.GET("/polls/:id/export", polls.ExportResults)
+	admin.POST("/polls/:id/clone", polls.ClonePoll)
+	admin.GET("/polls/:id/proposals", polls.ListProposals)
+	admin.POST("/polls/:id/proposals/:proposal_id/approve", polls.ApproveProposal)
+	admin.POST("/polls/:id/proposals/:proposal_id/merge", polls.MergeProposal)
+	admin.POST("/polls/:id/proposals/:proposal_id/reject", polls.RejectProposal)
+	admin.GET("/polls/:id/ballots", polls.ListBallots)
+	admin.POST("/polls/:id/ballots", polls.IssueBallots)
+	admin.DELETE("/polls/:id/ballots/:ballot_id", polls.RevokeBallot)
+	admin.GET("/polls/:id/turnout", polls.GetTurnout)
+	admin.GET("/polls/:id/delegations", polls.ListDelegations)
+	admin.GET("/rolls", polls.ListRolls)
+	admin.POST("/rolls", polls.ImportRoll)
+	admin.GET("/rolls/:id", polls.GetRoll)
+	admin.DELETE("/rolls/:id", polls.DeleteRoll)
+	admin.GET("/surveys", surveys.ListSurveys)
+	admin.POST("/surveys", surveys.CreateSurvey)
+	admin.GET("/surveys/:id", surveys.GetSurveyByID)
+	admin.POST("/surveys/:id/open", surveys.OpenSurvey)
+	admin.POST("/surveys/:id/close", surveys.CloseSurvey)
+	admin.GET("/surveys/:id/export", surveys.ExportSurvey)
+	admin.GET("/templates", polls.ListTemplates)
+	admin.POST("/templates", polls.CreateTemplate)
+	admin.GET("/templates/:id", polls.GetTemplate)
+	admin.PUT("/templates/:id", polls.UpdateTemplate)
+	admin.DELETE("/templates/:id", polls.DeleteTemplate)
+	admin.POST("/templates/:id/polls", polls.CreatePollFromTemplate)
+	admin.GET("/audit", audits.ListAuditLogs)
+	admin.GET("/audit/verify", audits.VerifyAuditLog)
+	admin.GET("/webhooks", webhooks.ListWebhooks)
+	admin.POST("/webhooks", webhooks.CreateWebhook)
+	admin.GET("/webhooks/:id", webhooks.GetWebhook)
+	admin.PUT("/webhooks/:id", webhooks.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", webhooks.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", webhooks.ListDeliveries)
+	admin.GET("/webhook-deliveries/dead", webhooks.ListDeadDeliveries)
+	admin.POST("/webhook-deliveries/:id/redeliver", webhooks.RedeliverDelivery)
+	admin.GET("/tokens", tokens.ListTokens)
+	admin.POST("/tokens", tokens.CreateToken)
+	admin.DELETE("/tokens/:id", tokens.RevokeToken)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"vote-system/apierror"
+	"vote-system/export"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SurveyHandler struct {
+	db      *gorm.DB
+	hasher  *privacy.Hasher
+	surveys *service.SurveyService
+}
+
+// NewSurveyHandler 创建SurveyHandler，hasher和dispatcher的含义与NewPollHandler相同
+func NewSurveyHandler(db *gorm.DB, dispatcher *outbox.Dispatcher, hasher *privacy.Hasher) *SurveyHandler {
+	return &SurveyHandler{
+		db:      db,
+		hasher:  hasher,
+		surveys: service.NewSurveyService(db, dispatcher, hasher),
+	}
+}
+
+// GetSurvey 获取调查问卷和当前投票人是否已提交
+func (h *SurveyHandler) GetSurvey(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.surveys.View(surveyID, voterOf(c))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// SubmitResponse 一次提交调查问卷所有问题的回答
+func (h *SurveyHandler) SubmitResponse(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.SubmitSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	response, err := h.surveys.Submit(surveyID, req.Answers, voterOf(c))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// NextQuestion 按部分作答返回下一个要显示的问题，跳转规则只在服务端计算
+func (h *SurveyHandler) NextQuestion(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.SubmitSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	progress, err := h.surveys.Next(surveyID, req.Answers)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// GetSurveyResults 获取调查问卷按问题汇总的结果，自由回答只返回作答人数
+func (h *SurveyHandler) GetSurveyResults(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.surveys.Results(surveyID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// GetSurveyCrosstab 按另一个问题交叉分析问题的回答，返回列联表和卡方检验
+func (h *SurveyHandler) GetSurveyCrosstab(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+	questionID, err := strconv.ParseUint(c.Query("question"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "question")
+		return
+	}
+
+	table, err := h.surveys.Crosstab(surveyID, uint(questionID), c.Query("pivot"))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, table)
+}
+
+// ListSurveys 列出所有调查问卷（管理接口）
+func (h *SurveyHandler) ListSurveys(c *gin.Context) {
+	surveys, err := h.surveys.List()
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SurveyListResponse{Surveys: surveys})
+}
+
+// CreateSurvey 创建调查问卷（管理接口）
+func (h *SurveyHandler) CreateSurvey(c *gin.Context) {
+	var req models.CreateSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	survey, err := h.surveys.Create(req, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, survey)
+}
+
+// GetSurveyByID 获取指定调查问卷（管理接口）
+func (h *SurveyHandler) GetSurveyByID(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	survey, err := h.surveys.Load(surveyID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+// OpenSurvey 开启调查问卷（管理接口）
+func (h *SurveyHandler) OpenSurvey(c *gin.Context) {
+	h.setSurveyState(c, true)
+}
+
+// CloseSurvey 关闭调查问卷（管理接口）
+func (h *SurveyHandler) CloseSurvey(c *gin.Context) {
+	h.setSurveyState(c, false)
+}
+
+func (h *SurveyHandler) setSurveyState(c *gin.Context, active bool) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+
+	survey, err := h.surveys.SetState(surveyID, active, newAuditEntry(c, h.hasher, "", nil, nil, nil))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, survey)
+}
+
+// ExportSurvey 导出调查问卷的原始回答（管理接口），每次作答一行
+func (h *SurveyHandler) ExportSurvey(c *gin.Context) {
+	surveyID, ok := surveyParam(c)
+	if !ok {
+		return
+	}
+	if _, err := h.surveys.Load(surveyID); err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", export.FormatCSV)
+	if !export.ValidFormat(format) {
+		apierror.Abort(c, apierror.UnsupportedFormat, "format", format)
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="survey-%d-responses.%s"`, surveyID, format))
+	c.Status(http.StatusOK)
+
+	// 回答直接流式写入响应，出错时响应头已发送，只能中断连接
+	if err := export.WriteSurvey(c.Writer, h.db, surveyID, format, h.hasher); err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// surveyParam 解析路径参数id，失败时写入错误响应并返回false
+func surveyParam(c *gin.Context) (uint, bool) {
+	surveyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return 0, false
+	}
+	return uint(surveyID), true
+}
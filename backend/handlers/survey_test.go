@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"vote-system/models"
+	"vote-system/survey"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSurveyRoundTrip(t *testing.T) {
+	router := setupFullRouter()
+
+	w := adminRequest(router, "POST", "/api/admin/surveys", gin.H{
+		"title": "开发者调查",
+		"questions": []gin.H{
+			{"type": "single_choice", "title": "常用的语言", "required": true, "choices": []string{"Go", "Rust"}},
+			{"type": "rating", "title": "满意度", "min_rating": 1, "max_rating": 10},
+			{"type": "text", "title": "建议"},
+		},
+	})
+	var sv models.Survey
+	json.Unmarshal(w.Body.Bytes(), &sv)
+	if w.Code != http.StatusCreated || len(sv.Questions) != 3 || sv.Owner != "admin:alice" {
+		t.Fatalf("创建调查问卷失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/admin/surveys", gin.H{
+		"title":     "缺少选项",
+		"questions": []gin.H{{"type": "multiple_choice", "title": "选哪些"}},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_question") {
+		t.Errorf("选择题缺少选项应返回invalid_question: %d %s", w.Code, w.Body.String())
+	}
+
+	path := "/api/surveys/" + strconv.Itoa(int(sv.ID))
+	w = adminRequest(router, "POST", path+"/responses", gin.H{"answers": []gin.H{
+		{"question_id": sv.Questions[1].ID, "rating": 8},
+	}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "answer_required") {
+		t.Errorf("缺少必答题应返回answer_required: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", path+"/responses", gin.H{"answers": []gin.H{
+		{"question_id": sv.Questions[0].ID, "choices": []int{1}},
+		{"question_id": sv.Questions[1].ID, "rating": 8},
+		{"question_id": sv.Questions[2].ID, "text": "文档再多一点"},
+	}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("提交失败: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", path, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"responded":true`) {
+		t.Errorf("提交后应标记为已提交: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", path+"/results", nil)
+	var results survey.Results
+	json.Unmarshal(w.Body.Bytes(), &results)
+	if w.Code != http.StatusOK || results.Responses != 1 || results.Questions[0].Choices[1].Count != 1 {
+		t.Errorf("汇总结果不正确: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "文档再多一点") {
+		t.Error("汇总结果不应包含自由回答的内容")
+	}
+
+	crosstab := path + "/crosstab?question=" + strconv.Itoa(int(sv.Questions[1].ID))
+	w = adminRequest(router, "GET", crosstab+"&pivot=question:"+strconv.Itoa(int(sv.Questions[0].ID)), nil)
+	var table survey.Crosstab
+	json.Unmarshal(w.Body.Bytes(), &table)
+	if w.Code != http.StatusOK || table.Responses != 1 || table.Suppressed == 0 || table.Cells[1][7].Count != nil {
+		t.Errorf("只有1人作答时交叉表的单元格应被隐藏: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "GET", crosstab+"&pivot=question:"+strconv.Itoa(int(sv.Questions[2].ID)), nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_parameter") {
+		t.Errorf("按自由回答交叉分析应返回invalid_parameter: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "GET", "/api/admin/surveys/"+strconv.Itoa(int(sv.ID))+"/export", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), ",Rust,8,文档再多一点") {
+		t.Errorf("导出的原始回答不正确: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", "/api/admin/surveys/"+strconv.Itoa(int(sv.ID))+"/close", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("关闭调查问卷失败: %d %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(router, "POST", "/api/admin/surveys/"+strconv.Itoa(int(sv.ID))+"/close", nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("重复关闭期望409, 得到 %d", w.Code)
+	}
+
+	w = adminRequest(router, "GET", "/api/surveys/999", nil)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "survey_not_found") {
+		t.Errorf("不存在的调查问卷期望404, 得到 %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSurveyBranching(t *testing.T) {
+	router := setupFullRouter()
+
+	w := adminRequest(router, "POST", "/api/admin/surveys", gin.H{
+		"title": "入职调查",
+		"questions": []gin.H{
+			{"type": "single_choice", "title": "用过类似产品吗", "required": true, "choices": []string{"是", "否"},
+				"branches": []gin.H{{"choice": 1, "goto": 3}}},
+			{"type": "text", "title": "之前用的是哪个", "required": true},
+			{"type": "text", "title": "对我们有什么期待"},
+		},
+	})
+	var sv models.Survey
+	json.Unmarshal(w.Body.Bytes(), &sv)
+	if w.Code != http.StatusCreated || len(sv.Questions[0].Branches) != 1 {
+		t.Fatalf("创建带跳转规则的调查问卷失败: %d %s", w.Code, w.Body.String())
+	}
+
+	path := "/api/surveys/" + strconv.Itoa(int(sv.ID))
+	w = adminRequest(router, "POST", path+"/next", gin.H{"answers": []gin.H{
+		{"question_id": sv.Questions[0].ID, "choices": []int{1}},
+	}})
+	var progress survey.Progress
+	json.Unmarshal(w.Body.Bytes(), &progress)
+	if w.Code != http.StatusOK || progress.Next == nil || progress.Next.ID != sv.Questions[2].ID {
+		t.Fatalf("选“否”后下一题应为第3题: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", path+"/responses", gin.H{"answers": []gin.H{
+		{"question_id": sv.Questions[0].ID, "choices": []int{1}},
+		{"question_id": sv.Questions[1].ID, "text": "某产品"},
+	}})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "question_hidden") {
+		t.Errorf("回答被跳过的问题应返回question_hidden: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(router, "POST", path+"/responses", gin.H{"answers": []gin.H{
+		{"question_id": sv.Questions[0].ID, "choices": []int{1}},
+	}})
+	if w.Code != http.StatusCreated {
+		t.Errorf("被跳过的必答题不应要求作答: %d %s", w.Code, w.Body.String())
+	}
+}
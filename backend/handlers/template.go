@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/templates"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListTemplates 列出所有投票问卷模板（管理接口）
+func (h *PollHandler) ListTemplates(c *gin.Context) {
+	var tpls []models.PollTemplate
+	if err := h.db.Order("id").Find(&tpls).Error; err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TemplateListResponse{Templates: tpls})
+}
+
+// GetTemplate 获取指定投票问卷模板（管理接口）
+func (h *PollHandler) GetTemplate(c *gin.Context) {
+	tpl, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// CreateTemplate 创建投票问卷模板（管理接口）
+func (h *PollHandler) CreateTemplate(c *gin.Context) {
+	var tpl models.PollTemplate
+	if !bindTemplate(c, &tpl) {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditTemplateCreated, nil, nil, tpl))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// UpdateTemplate 整体替换投票问卷模板的内容（管理接口）
+func (h *PollHandler) UpdateTemplate(c *gin.Context) {
+	tpl, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	before := tpl
+	if !bindTemplate(c, &tpl) {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// Select("*")保证零值字段也会被更新
+		if err := tx.Model(&tpl).Select("*").Omit("id", "created_at", "deleted_at").Updates(&tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditTemplateUpdated, nil, before, tpl))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DeleteTemplate 删除投票问卷模板（管理接口），已创建的投票问卷不受影响
+func (h *PollHandler) DeleteTemplate(c *gin.Context) {
+	tpl, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&tpl).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditTemplateDeleted, nil, tpl, nil))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Template deleted successfully"})
+}
+
+// CreatePollFromTemplate 根据模板创建投票问卷并填充模板变量（管理接口）
+func (h *PollHandler) CreatePollFromTemplate(c *gin.Context) {
+	tpl, ok := h.loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可以为空
+	var req models.InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Bind(c, err)
+		return
+	}
+
+	poll, err := templates.Instantiate(tpl, req.Variables, time.Now())
+	var undefined *templates.UndefinedVariableError
+	if errors.As(err, &undefined) {
+		apierror.Abort(c, apierror.UndefinedVariable, "name", undefined.Name)
+		return
+	}
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	active := req.IsActive == nil || *req.IsActive
+	if err := h.insertPoll(c, &poll, active, gin.H{"template_id": tpl.ID}); err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, poll)
+}
+
+// ClonePoll 复制投票问卷的选项和设置，不复制投票记录（管理接口）
+func (h *PollHandler) ClonePoll(c *gin.Context) {
+	source, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	var req models.ClonePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Bind(c, err)
+		return
+	}
+
+	poll := templates.Clone(source)
+	if req.Title != nil {
+		poll.Title = *req.Title
+	}
+	// 已过期的截止时间没有意义，需要重新设置
+	if poll.Rules.ClosesAt != nil && !poll.Rules.ClosesAt.After(time.Now()) {
+		poll.Rules.ClosesAt = nil
+	}
+
+	active := req.IsActive == nil || *req.IsActive
+	if err := h.insertPoll(c, &poll, active, gin.H{"poll_id": source.ID}); err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, poll)
+}
+
+// bindTemplate 解析并校验模板请求，写入tpl，失败时写入错误响应并返回false
+func bindTemplate(c *gin.Context, tpl *models.PollTemplate) bool {
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return false
+	}
+
+	visibility := req.ResultVisibility
+	if visibility == "" {
+		visibility = models.VisibilityAlways
+	}
+	if !models.ValidVisibility(visibility) {
+		apierror.Invalid(c, apierror.Field("result_visibility", "oneof", ""))
+		return false
+	}
+
+	var rules models.PollRules
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+	if rules.ClosesAt != nil {
+		// 模板使用close_after_minutes代替closes_at
+		apierror.Invalid(c, apierror.Field("rules.closes_at", "unsupported", ""))
+		return false
+	}
+
+	tpl.Name = req.Name
+	tpl.Title = req.Title
+	tpl.Description = req.Description
+	tpl.Options = req.Options
+	tpl.ResultVisibility = visibility
+	tpl.Rules = rules
+	tpl.CloseAfterMinutes = req.CloseAfterMinutes
+	return true
+}
+
+// loadTemplate 根据路径参数id读取模板，失败时写入错误响应并返回false
+func (h *PollHandler) loadTemplate(c *gin.Context) (models.PollTemplate, bool) {
+	var tpl models.PollTemplate
+
+	tplID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return tpl, false
+	}
+
+	if err := h.db.First(&tpl, tplID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Abort(c, apierror.TemplateNotFound)
+		} else {
+			apierror.Fail(c, err)
+		}
+		return tpl, false
+	}
+
+	return tpl, true
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreatePollFromTemplate(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	handler := NewPollHandler(db, nil, nil, nil)
+	router.POST("/admin/templates", handler.CreateTemplate)
+	router.POST("/admin/templates/:id/polls", handler.CreatePollFromTemplate)
+
+	w := adminRequest(router, "POST", "/admin/templates", models.TemplateRequest{
+		Name:              "每周午餐",
+		Title:             "{{date}} {{team}}午餐吃什么",
+		Options:           []string{"面", "饭"},
+		ResultVisibility:  models.VisibilityAfterVote,
+		CloseAfterMinutes: 60,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var tpl models.PollTemplate
+	json.Unmarshal(w.Body.Bytes(), &tpl)
+
+	path := "/admin/templates/" + strconv.Itoa(int(tpl.ID)) + "/polls"
+
+	// 缺少自定义变量
+	w = adminRequest(router, "POST", path, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("缺少变量期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = adminRequest(router, "POST", path, gin.H{"variables": gin.H{"team": "后端组"}, "is_active": false})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var poll models.Poll
+	db.Preload("Options").Order("id desc").First(&poll)
+	expected := time.Now().Format("2006-01-02") + " 后端组午餐吃什么"
+	if poll.Title != expected {
+		t.Errorf("期望标题 %q, 得到 %q", expected, poll.Title)
+	}
+	if poll.IsActive || len(poll.Options) != 2 || poll.ResultVisibility != models.VisibilityAfterVote {
+		t.Errorf("投票问卷设置不正确: %+v", poll)
+	}
+	if poll.Rules.ClosesAt == nil || poll.Rules.ClosesAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("期望约1小时后截止, 得到 %v", poll.Rules.ClosesAt)
+	}
+
+	var logEntry models.AuditLog
+	db.Where("action = ?", models.AuditPollCreated).Last(&logEntry)
+	if !strings.Contains(logEntry.After, `"template_id"`) {
+		t.Errorf("审计日志应记录模板来源, 得到 %s", logEntry.After)
+	}
+}
+
+func TestCreateTemplate_RejectsAbsoluteDeadline(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	handler := NewPollHandler(db, nil, nil, nil)
+	router.POST("/admin/templates", handler.CreateTemplate)
+
+	deadline := time.Now().Add(time.Hour)
+	w := adminRequest(router, "POST", "/admin/templates", models.TemplateRequest{
+		Name:    "午餐",
+		Title:   "午餐",
+		Options: []string{"面", "饭"},
+		Rules:   &models.PollRules{ClosesAt: &deadline},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestClonePoll(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+	handler := NewPollHandler(db, nil, nil, nil)
+	router.POST("/admin/polls/:id/clone", handler.ClonePoll)
+
+	poll, options := setupTestData(db)
+	db.Model(&poll).Updates(map[string]interface{}{"result_visibility": models.VisibilityAfterClose, "target_votes": 5})
+	db.Model(&options[0]).Update("vote_count", 4)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[0].ID, UserIP: "192.0.2.1"})
+
+	w := adminRequest(router, "POST", "/admin/polls/"+strconv.Itoa(int(poll.ID))+"/clone", gin.H{"title": "第二轮"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var clone models.Poll
+	json.Unmarshal(w.Body.Bytes(), &clone)
+	db.Preload("Options").First(&clone, clone.ID)
+
+	if clone.ID == poll.ID || clone.Title != "第二轮" {
+		t.Errorf("复制结果不正确: %+v", clone)
+	}
+	if clone.ResultVisibility != models.VisibilityAfterClose || clone.Rules.TargetVotes != 5 {
+		t.Errorf("应复制设置, 得到 %+v", clone)
+	}
+	if len(clone.Options) != len(options) {
+		t.Fatalf("期望 %d 个选项, 得到 %d", len(options), len(clone.Options))
+	}
+	for _, option := range clone.Options {
+		if option.VoteCount != 0 {
+			t.Errorf("复制的选项不应包含票数: %+v", option)
+		}
+	}
+
+	var votes int64
+	db.Model(&models.Vote{}).Where("poll_id = ?", clone.ID).Count(&votes)
+	if votes != 0 {
+		t.Errorf("不应复制投票记录, 得到 %d", votes)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"vote-system/apierror"
+	"vote-system/apitoken"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/privacy"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	db     *gorm.DB
+	hasher *privacy.Hasher
+}
+
+func NewTokenHandler(db *gorm.DB, hasher *privacy.Hasher) *TokenHandler {
+	return &TokenHandler{db: db, hasher: hasher}
+}
+
+// ListTokens 列出未吊销的API令牌（管理接口），不返回令牌本身
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	var tokens []models.APIToken
+	if err := h.db.Order("id").Find(&tokens).Error; err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenListResponse{Tokens: tokens})
+}
+
+// CreateToken 创建API令牌（管理接口），令牌只在创建时返回一次
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var req models.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	var raw string
+	var token models.APIToken
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if raw, token, err = apitoken.Create(tx, req.Name); err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditTokenCreated, nil, nil, token))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateTokenResponse{Token: token, Secret: raw})
+}
+
+// RevokeToken 吊销API令牌（管理接口）
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return
+	}
+
+	var token models.APIToken
+	if err := h.db.First(&token, tokenID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Abort(c, apierror.TokenNotFound)
+		} else {
+			apierror.Fail(c, err)
+		}
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&token).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditTokenRevoked, nil, token, nil))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Token revoked successfully"})
+}
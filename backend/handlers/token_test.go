@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPITokens(t *testing.T) {
+	db := setupTestDB()
+	handler := NewTokenHandler(db, nil)
+	polls := NewPollHandler(db, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/admin", AdminAuth("secret", db))
+	admin.GET("/tokens", handler.ListTokens)
+	admin.POST("/tokens", handler.CreateToken)
+	admin.DELETE("/tokens/:id", handler.RevokeToken)
+	admin.GET("/polls", polls.ListPolls)
+
+	w := adminRequest(router, "POST", "/admin/tokens", gin.H{"name": "ci"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct {
+		Token  models.APIToken `json:"token"`
+		Secret string          `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	withToken := func(method, path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 使用API令牌访问管理接口，审计日志记为token:<名称>
+	if w := withToken("GET", "/admin/polls", created.Secret); w.Code != http.StatusOK {
+		t.Errorf("API令牌期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	path := "/admin/tokens/" + strconv.Itoa(int(created.Token.ID))
+	if w := withToken("DELETE", path, created.Secret); w.Code != http.StatusOK {
+		t.Fatalf("吊销期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+	var entry models.AuditLog
+	db.Where("action = ?", models.AuditTokenRevoked).First(&entry)
+	if entry.Actor != "token:ci" {
+		t.Errorf("期望操作人 token:ci, 得到 %q", entry.Actor)
+	}
+
+	if w := withToken("GET", "/admin/polls", created.Secret); w.Code != http.StatusUnauthorized {
+		t.Errorf("吊销后期望状态码 %d, 得到 %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := withToken("GET", "/admin/polls", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("空令牌期望状态码 %d, 得到 %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// 列表不包含已吊销的令牌和令牌哈希
+	w = adminRequest(router, "GET", "/admin/tokens", nil)
+	if w.Body.String() != `{"tokens":[]}` {
+		t.Errorf("期望空列表, 得到 %s", w.Body.String())
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"vote-system/models"
+	"vote-system/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetPoll_HiddenUntilVote(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("result_visibility", models.VisibilityAfterVote)
+	db.Model(&options[0]).Update("vote_count", 3)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/poll", handler.GetPoll)
+	router.POST("/vote", handler.Vote)
+
+	getPoll := func() models.PollResponse {
+		req, _ := http.NewRequest("GET", "/poll", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response models.PollResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	response := getPoll()
+	if !response.Poll.ResultsHidden || response.TotalVotes != 0 || response.Poll.Options[0].VoteCount != 0 {
+		t.Errorf("投票前不应返回票数, 得到 %+v", response)
+	}
+
+	jsonData, _ := json.Marshal(models.VoteRequest{OptionID: options[1].ID})
+	req, _ := http.NewRequest("POST", "/vote", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	response = getPoll()
+	if response.Poll.ResultsHidden || response.TotalVotes != 4 {
+		t.Errorf("投票后期望总票数 4, 得到 %+v", response)
+	}
+}
+
+func TestGetPoll_AdminOnly(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("result_visibility", models.VisibilityAdminOnly)
+	db.Model(&options[0]).Update("vote_count", 2)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[0].ID, UserIP: "192.0.2.1"})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/poll", handler.GetPoll)
+
+	// 已投票的用户同样看不到结果
+	req, _ := http.NewRequest("GET", "/poll", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response models.PollResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.UserVoted {
+		t.Fatal("用户应该已投票")
+	}
+	if !response.Poll.ResultsHidden || response.TotalVotes != 0 {
+		t.Errorf("仅管理员可见时不应返回票数, 得到 %+v", response)
+	}
+}
+
+func TestSealedUntilClose(t *testing.T) {
+	db := setupTestDB()
+	handler := NewPollHandler(db, websocket.NewHub(), nil, nil)
+	poll, options := setupTestData(db)
+	db.Model(&poll).Update("result_visibility", models.VisibilityAfterClose)
+	db.Model(&options[0]).Update("vote_count", 2)
+
+	router := setupPollAdminRouter(db)
+	router.GET("/admin/polls/:id", handler.GetPollByID)
+	router.GET("/admin/polls/:id/export", handler.ExportResults)
+	router.GET("/polls/:id/history", handler.GetHistory)
+	id := strconv.Itoa(int(poll.ID))
+
+	// 关闭前管理员也看不到票数，也不能导出
+	w := adminRequest(router, "GET", "/admin/polls/"+id, nil)
+	var got models.Poll
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if !got.ResultsHidden || got.Options[0].VoteCount != 0 {
+		t.Errorf("关闭前不应返回票数, 得到 %+v", got)
+	}
+
+	w = adminRequest(router, "GET", "/admin/polls/"+id+"/export", nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("关闭前导出期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/polls/"+id+"/history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("关闭前趋势期望状态码 %d, 得到 %d", http.StatusForbidden, w.Code)
+	}
+
+	// 进行中不能放宽可见性
+	w = adminRequest(router, "PUT", "/admin/polls/"+id, gin.H{"result_visibility": models.VisibilityAlways})
+	if w.Code != http.StatusConflict {
+		t.Errorf("放宽可见性期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+
+	w = adminRequest(router, "POST", "/admin/polls/"+id+"/close", nil)
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got.ResultsHidden || got.Options[0].VoteCount != 2 {
+		t.Errorf("关闭后期望返回票数, 得到 %+v", got)
+	}
+
+	w = adminRequest(router, "GET", "/admin/polls/"+id+"/export", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("关闭后导出期望状态码 %d, 得到 %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestCreatePoll_InvalidVisibility(t *testing.T) {
+	db := setupTestDB()
+	router := setupPollAdminRouter(db)
+
+	w := adminRequest(router, "POST", "/admin/polls", models.CreatePollRequest{
+		Title:            "午餐吃什么",
+		Options:          []string{"面", "饭"},
+		ResultVisibility: "never",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
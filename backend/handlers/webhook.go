@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	db     *gorm.DB
+	hasher *privacy.Hasher
+}
+
+func NewWebhookHandler(db *gorm.DB, hasher *privacy.Hasher) *WebhookHandler {
+	return &WebhookHandler{db: db, hasher: hasher}
+}
+
+// ListWebhooks 列出所有webhook订阅（管理接口）
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	var hooks []models.Webhook
+	if err := h.db.Order("id").Find(&hooks).Error; err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookListResponse{Webhooks: hooks})
+}
+
+// GetWebhook 获取指定webhook订阅（管理接口）
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+// CreateWebhook 创建webhook订阅（管理接口），签名密钥只在创建时返回一次
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	events := strings.Join(req.Events, ",")
+	if !webhook.ValidEvents(events) {
+		apierror.Invalid(c, apierror.Field("events", "oneof", ""))
+		return
+	}
+
+	if req.PollID != nil {
+		if err := h.db.First(&models.Poll{}, *req.PollID).Error; err != nil {
+			apierror.Invalid(c, apierror.Field("poll_id", "not_found", ""))
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		secret = hex.EncodeToString(buf)
+	}
+
+	active := req.Active == nil || *req.Active
+	hook := models.Webhook{
+		PollID:    req.PollID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		Threshold: req.Threshold,
+		Active:    active,
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&hook).Error; err != nil {
+			return err
+		}
+		// Active带default标签，零值会被替换为默认值，需要单独更新
+		if !active {
+			if err := tx.Model(&hook).Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditWebhookCreated, hook.PollID, nil, hook))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateWebhookResponse{Webhook: hook, Secret: secret})
+}
+
+// UpdateWebhook 编辑webhook订阅（管理接口）
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Bind(c, err)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		updates["url"] = *req.URL
+	}
+	if req.Secret != nil {
+		updates["secret"] = *req.Secret
+	}
+	if req.Events != nil {
+		events := strings.Join(req.Events, ",")
+		if !webhook.ValidEvents(events) {
+			apierror.Invalid(c, apierror.Field("events", "oneof", ""))
+			return
+		}
+		updates["events"] = events
+	}
+	if req.Threshold != nil {
+		updates["threshold"] = *req.Threshold
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	before := hook
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&hook).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.First(&hook, hook.ID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditWebhookUpdated, hook.PollID, before, hook))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook 删除webhook订阅（管理接口），投递记录保留
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&hook).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditWebhookDeleted, hook.PollID, hook, nil))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Webhook deleted successfully"})
+}
+
+// ListDeliveries 查询webhook的投递记录（管理接口），可按status过滤
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	h.listDeliveries(c, query)
+}
+
+// ListDeadDeliveries 列出所有进入死信状态的投递记录（管理接口）
+func (h *WebhookHandler) ListDeadDeliveries(c *gin.Context) {
+	h.listDeliveries(c, h.db.Where("status = ?", models.DeliveryDead))
+}
+
+func (h *WebhookHandler) listDeliveries(c *gin.Context, query *gorm.DB) {
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			apierror.Abort(c, apierror.InvalidParameter, "name", "limit")
+			return
+		}
+		limit = n
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DeliveryListResponse{Deliveries: deliveries})
+}
+
+// RedeliverDelivery 重新投递一条投递记录（管理接口），通常用于死信
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	deliveryID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := h.db.First(&delivery, deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Abort(c, apierror.DeliveryNotFound)
+		} else {
+			apierror.Fail(c, err)
+		}
+		return
+	}
+
+	if delivery.Status == models.DeliveryPending {
+		apierror.Abort(c, apierror.DeliveryPending)
+		return
+	}
+
+	var hook models.Webhook
+	if err := h.db.First(&hook, delivery.WebhookID).Error; err != nil {
+		apierror.Abort(c, apierror.WebhookDeleted)
+		return
+	}
+
+	previous := delivery.Status
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := webhook.Redeliver(tx, &delivery); err != nil {
+			return err
+		}
+		return audit.Record(tx, newAuditEntry(c, h.hasher, models.AuditWebhookRedelivered, hook.PollID,
+			gin.H{"delivery_id": delivery.ID, "status": previous}, gin.H{"delivery_id": delivery.ID, "status": models.DeliveryPending}))
+	})
+	if err != nil {
+		apierror.Fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Delivery scheduled"})
+}
+
+// loadWebhook 根据路径参数id读取webhook订阅，失败时写入错误响应并返回false
+func (h *WebhookHandler) loadWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+
+	hookID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidParameter, "name", "id")
+		return hook, false
+	}
+
+	if err := h.db.First(&hook, hookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Abort(c, apierror.WebhookNotFound)
+		} else {
+			apierror.Fail(c, err)
+		}
+		return hook, false
+	}
+
+	return hook, true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"vote-system/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupWebhookRouter(db *gorm.DB) *gin.Engine {
+	handler := NewWebhookHandler(db, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/admin", AdminAuth("secret", nil))
+	admin.POST("/webhooks", handler.CreateWebhook)
+	admin.PUT("/webhooks/:id", handler.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", handler.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", handler.ListDeliveries)
+	admin.GET("/webhook-deliveries/dead", handler.ListDeadDeliveries)
+	admin.POST("/webhook-deliveries/:id/redeliver", handler.RedeliverDelivery)
+	return router
+}
+
+func TestCreateWebhook(t *testing.T) {
+	db := setupTestDB()
+	router := setupWebhookRouter(db)
+
+	w := adminRequest(router, "POST", "/admin/webhooks", gin.H{
+		"url":    "https://example.com/hook",
+		"events": []string{"vote_cast", "poll_closed"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Webhook map[string]interface{} `json:"webhook"`
+		Secret  string                 `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Secret) != 64 {
+		t.Errorf("期望自动生成64位密钥, 得到 %q", resp.Secret)
+	}
+	if _, ok := resp.Webhook["secret"]; ok {
+		t.Error("webhook对象不应包含密钥")
+	}
+
+	var hook models.Webhook
+	db.First(&hook)
+	if hook.Events != "vote_cast,poll_closed" || hook.Secret != resp.Secret || !hook.Active {
+		t.Errorf("保存的订阅不正确: %+v", hook)
+	}
+
+	var audits int64
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditWebhookCreated).Count(&audits)
+	if audits != 1 {
+		t.Errorf("期望1条审计记录, 得到 %d", audits)
+	}
+}
+
+func TestCreateWebhook_InvalidEvent(t *testing.T) {
+	db := setupTestDB()
+	router := setupWebhookRouter(db)
+
+	w := adminRequest(router, "POST", "/admin/webhooks", gin.H{
+		"url":    "https://example.com/hook",
+		"events": []string{"vote_casted"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateWebhook_Deactivate(t *testing.T) {
+	db := setupTestDB()
+	router := setupWebhookRouter(db)
+
+	hook := models.Webhook{URL: "https://example.com/hook", Secret: "s"}
+	db.Create(&hook)
+
+	w := adminRequest(router, "PUT", "/admin/webhooks/"+strconv.Itoa(int(hook.ID)), gin.H{"active": false, "threshold": 10})
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	db.First(&hook, hook.ID)
+	if hook.Active || hook.Threshold != 10 {
+		t.Errorf("更新未生效: %+v", hook)
+	}
+}
+
+func TestRedeliverDeadDelivery(t *testing.T) {
+	db := setupTestDB()
+	router := setupWebhookRouter(db)
+
+	hook := models.Webhook{URL: "https://example.com/hook", Secret: "s"}
+	db.Create(&hook)
+	delivery := models.WebhookDelivery{WebhookID: hook.ID, EventID: 1, EventType: models.EventVoteCast, Status: models.DeliveryDead, Attempts: 8}
+	db.Create(&delivery)
+
+	w := adminRequest(router, "GET", "/admin/webhook-deliveries/dead", nil)
+	var list struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Deliveries) != 1 {
+		t.Fatalf("期望1条死信, 得到 %d", len(list.Deliveries))
+	}
+
+	path := "/admin/webhook-deliveries/" + strconv.Itoa(int(delivery.ID)) + "/redeliver"
+	w = adminRequest(router, "POST", path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("期望状态码 %d, 得到 %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	db.First(&delivery, delivery.ID)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 0 {
+		t.Errorf("期望重新进入待投递, 得到 %+v", delivery)
+	}
+
+	// 已在待投递状态时不能重复触发
+	w = adminRequest(router, "POST", path, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("期望状态码 %d, 得到 %d", http.StatusConflict, w.Code)
+	}
+}
@@ -0,0 +1,38 @@
+// Package identity 识别HTTP请求的投票人
+//
+// 默认以客户端IP作为投票人标识；正式投票通常部署在统一认证的反向代理之后，
+// 可以配置为读取代理写入的请求头（例如 X-Remote-User），再与投票人名册中的标识比较。
+package identity
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Resolver 从请求中识别投票人，返回空字符串表示请求中没有身份信息
+type Resolver interface {
+	Resolve(r *http.Request) string
+}
+
+// Header 读取受信任的反向代理写入的请求头，代理必须覆盖客户端自己发送的同名请求头
+type Header string
+
+// Resolve 返回请求头的值，转换为小写，使同一投票人的标识与名册中的标识一致
+func (h Header) Resolve(r *http.Request) string {
+	return strings.ToLower(strings.TrimSpace(r.Header.Get(string(h))))
+}
+
+// Parse 解析VOTER_IDENTITY配置："ip"或空为客户端IP，返回nil；"header:请求头名称"为Header
+func Parse(spec string) (Resolver, error) {
+	kind, value, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	switch kind {
+	case "", "ip":
+		return nil, nil
+	case "header":
+		if name := strings.TrimSpace(value); name != "" {
+			return Header(http.CanonicalHeaderKey(name)), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported voter identity %q, expected ip or header:<name>", spec)
+}
@@ -0,0 +1,30 @@
+package identity
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"", "ip", " ip "} {
+		if resolver, err := Parse(spec); err != nil || resolver != nil {
+			t.Errorf("%q 应使用客户端IP: %v %v", spec, resolver, err)
+		}
+	}
+
+	resolver, err := Parse("header:x-remote-user")
+	if err != nil || resolver != Header("X-Remote-User") {
+		t.Fatalf("解析请求头配置失败: %v %v", resolver, err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Remote-User", " Alice@Example.com ")
+	if got := resolver.Resolve(req); got != "alice@example.com" {
+		t.Errorf("期望 alice@example.com, 得到 %q", got)
+	}
+
+	for _, spec := range []string{"header:", "cookie:sid", "session"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("期望 %q 解析失败", spec)
+		}
+	}
+}
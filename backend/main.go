@@ -1,11 +1,22 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"net"
 	"net/http"
+	"time"
 	"vote-system/config"
 	"vote-system/database"
+	"vote-system/grpcapi"
 	"vote-system/handlers"
+	"vote-system/identity"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/rules"
+	"vote-system/seed"
+	"vote-system/service"
+	"vote-system/webhook"
 	"vote-system/websocket"
 
 	"github.com/gin-contrib/cors"
@@ -13,6 +24,9 @@ import (
 )
 
 func main() {
+	seedFlag := flag.String("seed", "", "seed fixture before starting: empty, demo, load-test or a YAML/JSON file")
+	flag.Parse()
+
 	// 初始化配置
 	cfg := config.Load()
 
@@ -22,37 +36,105 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// 隐私模式下投票人标识只保存HMAC
+	var hasher *privacy.Hasher
+	if cfg.PrivacyMode {
+		keys, err := privacy.ParseKeys(cfg.VoterKeys)
+		if err != nil {
+			log.Fatal("Invalid VOTER_HMAC_KEYS:", err)
+		}
+		if len(keys) == 0 {
+			log.Fatal("PRIVACY_MODE requires VOTER_HMAC_KEYS")
+		}
+		hasher = privacy.NewHasher(keys)
+	}
+
+	// 投票人默认按客户端IP识别，部署在统一认证代理之后时可以读取代理写入的请求头
+	resolver, err := identity.Parse(cfg.VoterIdentity)
+	if err != nil {
+		log.Fatal("Invalid VOTER_IDENTITY:", err)
+	}
+
+	// 命令行子命令
+	if flag.NArg() > 0 {
+		if err := runCommand(db, hasher, flag.Arg(0), flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// 启动服务前只在明确指定时初始化数据，生产环境下只接受 --seed
+	fixture, err := seed.Select(*seedFlag, cfg.Seed, cfg.Production())
+	if err != nil {
+		log.Printf("SEED=%s ignored: %v", cfg.Seed, err)
+	}
+	if fixture != "" {
+		result, err := seed.Run(db, fixture, hasher)
+		if err != nil {
+			log.Fatal("Failed to seed data:", err)
+		}
+		log.Printf("Seeded fixture %s: %d created, %d skipped, %d votes", result.Fixture, result.Created, result.Skipped, result.Votes)
+	}
+
+	go privacy.RunRetention(db, cfg.RetentionDays, time.Hour)
+
 	// 初始化WebSocket Hub
 	hub := websocket.NewHub()
 	go hub.Run()
 
+	// 初始化outbox分发器，事务提交后再向客户端广播并生成webhook投递
+	dispatcher := outbox.NewDispatcher(db, outbox.NewHubPublisher(db, hub, hasher), webhook.NewPublisher(db))
+	go dispatcher.Run()
+	go webhook.NewWorker(db).Run()
+	go rules.RunDeadlines(db, dispatcher, hasher, 10*time.Second)
+
 	// 设置Gin路由
 	r := gin.Default()
+	r.Use(handlers.RequestID())
+	r.Use(handlers.VoterIdentity(resolver))
 
 	// CORS配置
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Session-ID"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Session-ID", "X-Request-ID", "X-Actor"},
+		ExposeHeaders:    []string{"X-Request-ID"},
 		AllowCredentials: true,
 	}))
 
 	// 创建handlers
-	pollHandler := handlers.NewPollHandler(db, hub)
+	pollHandler := handlers.NewPollHandler(db, hub, dispatcher, hasher)
+	surveyHandler := handlers.NewSurveyHandler(db, dispatcher, hasher)
+	auditHandler := handlers.NewAuditHandler(db)
+	webhookHandler := handlers.NewWebhookHandler(db, hasher)
+	tokenHandler := handlers.NewTokenHandler(db, hasher)
 
 	// API路由
-	api := r.Group("/api")
-	{
-		api.GET("/poll", pollHandler.GetPoll)
-		api.POST("/poll/vote", pollHandler.Vote)
-		api.DELETE("/poll/clear-my-vote", pollHandler.ClearVotes)
-		api.DELETE("/poll/reset", pollHandler.ResetPoll)
-	}
+	handlers.RegisterPublicRoutes(r.Group("/api"), pollHandler, surveyHandler)
+
+	// 管理接口，可使用配置的管理令牌或API令牌
+	admin := r.Group("/api/admin", handlers.AdminAuth(cfg.AdminToken, db))
+	handlers.RegisterAdminRoutes(admin, pollHandler, surveyHandler, auditHandler, webhookHandler, tokenHandler)
 
 	// WebSocket路由
-	r.GET("/ws/poll", func(c *gin.Context) {
-		websocket.ServeWS(hub, c.Writer, c.Request)
-	})
+	handlers.RegisterWebSocketRoute(r, hub, cfg.AdminToken, db)
+
+	// OpenAPI文档和Swagger UI，文档按路由表生成，新增处理函数时需要在handlers.OpenAPI中补充说明
+	handlers.RegisterDocsRoutes(r)
+
+	// gRPC服务与HTTP接口共用服务层，WatchPoll订阅同一个Hub
+	if cfg.GRPCEnabled() {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
+		}
+		polls := service.NewPollService(db, dispatcher, hasher)
+		grpcServer := grpcapi.NewGRPCServer(grpcapi.NewServer(polls, hub), cfg.AdminToken, db)
+		go func() {
+			log.Printf("gRPC server starting on port %s", cfg.GRPCPort)
+			log.Fatal(grpcServer.Serve(lis))
+		}()
+	}
 
 	// 启动服务器
 	log.Printf("Server starting on port %s", cfg.Port)
@@ -0,0 +1,236 @@
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/delegation"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/rules"
+
+	"gorm.io/gorm"
+)
+
+// ApplyOptions 应用文档的参数
+type ApplyOptions struct {
+	// Audit 审计日志的操作人、IP和请求ID，Action等字段由Apply填写
+	Audit audit.Entry
+	// Seed 为true时只创建尚不存在的投票问卷，不修改或归档已有的投票问卷，新建的投票问卷记为poll.seeded
+	Seed bool
+	// Hasher 关闭投票问卷时解析委托，与服务的隐私模式一致，nil表示未开启
+	Hasher *privacy.Hasher
+}
+
+// PlanError 文档无法应用，Plan中包含具体原因
+type PlanError struct {
+	Plan *Plan
+}
+
+func (e *PlanError) Error() string {
+	return "cannot apply document: " + strings.Join(e.Plan.Errors, "; ")
+}
+
+// Apply 在一个事务中将文档应用到数据库并返回执行的变更，重复应用同一文档不会产生变更
+func Apply(db *gorm.DB, doc *Document, opts ApplyOptions) (*Plan, error) {
+	var plan *Plan
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if plan, err = compute(tx, doc, opts.Seed); err != nil {
+			return err
+		}
+		if len(plan.Errors) > 0 {
+			return &PlanError{Plan: plan}
+		}
+
+		now := time.Now()
+		for i := range plan.Changes {
+			change := &plan.Changes[i]
+			switch change.Action {
+			case ActionCreate:
+				err = create(tx, change, opts)
+			case ActionUpdate, ActionRestore:
+				err = update(tx, change, opts, now)
+			case ActionArchive:
+				err = archive(tx, change, opts, now)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var planErr *PlanError
+	if errors.As(err, &planErr) {
+		return planErr.Plan, err
+	}
+	return plan, err
+}
+
+// entry 生成一条审计日志
+func entry(opts ApplyOptions, action string, pollID uint, before, after interface{}) audit.Entry {
+	e := opts.Audit
+	e.Action = action
+	e.PollID = &pollID
+	e.Before = before
+	e.After = after
+	return e
+}
+
+func create(tx *gorm.DB, change *Change, opts ApplyOptions) error {
+	spec := change.spec
+	slug := spec.Slug
+	poll := models.Poll{
+		Slug:             &slug,
+		Title:            spec.Title,
+		Description:      spec.Description,
+		Owner:            opts.Audit.Actor,
+		Tags:             spec.tags(),
+		IsActive:         spec.active(),
+		ResultVisibility: spec.visibility(),
+		Rules:            spec.rules(),
+		AllowProposals:   spec.AllowProposals,
+		Mode:             spec.mode(),
+		MaxScore:         spec.maxScore(),
+		Access:           spec.access(),
+		RollID:           spec.rollID(),
+	}
+	if spec.AccessCode != "" {
+		poll.AccessCodeHash = ballot.Hash(spec.AccessCode)
+	}
+	for _, text := range spec.Options {
+		poll.Options = append(poll.Options, models.Option{Text: text, WriteIn: text == spec.WriteInOption})
+	}
+
+	if err := tx.Create(&poll).Error; err != nil {
+		return err
+	}
+	// IsActive带default标签，零值会被替换为默认值，需要单独更新
+	if !spec.active() {
+		if err := tx.Model(&poll).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+	change.PollID = poll.ID
+
+	if err := outbox.Enqueue(tx, poll.ID, models.EventPollCreated, map[string]string{"title": poll.Title}); err != nil {
+		return err
+	}
+
+	action := models.AuditPollCreated
+	if opts.Seed {
+		action = models.AuditPollSeeded
+	}
+	after := audit.PollSummary(poll)
+	after["source"] = map[string]string{"slug": slug}
+	return audit.Record(tx, entry(opts, action, poll.ID, nil, after))
+}
+
+func update(tx *gorm.DB, change *Change, opts ApplyOptions, now time.Time) error {
+	spec, poll := change.spec, change.poll
+	before := audit.PollSummary(*poll)
+
+	if change.Action == ActionRestore {
+		if err := tx.Unscoped().Model(poll).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	declared := spec.rules()
+	updates := map[string]interface{}{
+		"slug":              spec.Slug,
+		"title":             spec.Title,
+		"description":       spec.Description,
+		"tags":              spec.tags(),
+		"result_visibility": spec.visibility(),
+		"target_votes":      declared.TargetVotes,
+		"win_percent":       declared.WinPercent,
+		"win_min_votes":     declared.WinMinVotes,
+		"eligible_voters":   declared.EligibleVoters,
+		"closes_at":         declared.ClosesAt,
+		"allow_proposals":   spec.AllowProposals,
+		"access":            spec.access(),
+		"roll_id":           spec.rollID(),
+	}
+	if spec.AccessCode != "" {
+		updates["access_code_hash"] = ballot.Hash(spec.AccessCode)
+	}
+	if err := tx.Model(poll).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	for _, text := range change.AddOptions {
+		if err := tx.Create(&models.Option{PollID: poll.ID, Text: text}).Error; err != nil {
+			return err
+		}
+	}
+	if len(change.RemoveOptions) > 0 {
+		if err := tx.Where("poll_id = ? AND text IN ? AND vote_count = 0", poll.ID, change.RemoveOptions).
+			Delete(&models.Option{}).Error; err != nil {
+			return err
+		}
+	}
+	// 自填选项按文本指定，其余选项取消自填
+	if err := tx.Model(&models.Option{}).Where("poll_id = ?", poll.ID).
+		Update("write_in", gorm.Expr("text = ?", spec.WriteInOption)).Error; err != nil {
+		return err
+	}
+
+	if err := outbox.Enqueue(tx, poll.ID, models.EventPollUpdated, map[string]string{"title": spec.Title}); err != nil {
+		return err
+	}
+
+	// 开启状态变化与管理接口的开启、关闭一致
+	if poll.IsActive && !spec.active() {
+		if _, _, err := rules.Close(tx, poll, opts.Hasher, models.CloseReasonManual, now); err != nil {
+			return err
+		}
+	} else if !poll.IsActive && spec.active() {
+		if _, err := delegation.Revert(tx, poll.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(poll).Updates(map[string]interface{}{
+			"is_active":    true,
+			"closed_at":    nil,
+			"close_reason": "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollOpened, map[string]string{}); err != nil {
+			return err
+		}
+	}
+
+	var updated models.Poll
+	if err := tx.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&updated, poll.ID).Error; err != nil {
+		return err
+	}
+
+	action := models.AuditPollUpdated
+	if change.Action == ActionRestore {
+		action = models.AuditPollRestored
+	}
+	return audit.Record(tx, entry(opts, action, poll.ID, before, audit.PollSummary(updated)))
+}
+
+func archive(tx *gorm.DB, change *Change, opts ApplyOptions, now time.Time) error {
+	poll := change.poll
+	before := audit.PollSummary(*poll)
+
+	if poll.IsActive {
+		if _, _, err := rules.Close(tx, poll, opts.Hasher, models.CloseReasonArchived, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.Delete(poll).Error; err != nil {
+		return err
+	}
+
+	return audit.Record(tx, entry(opts, models.AuditPollArchived, poll.ID, before, map[string]interface{}{"archived": true}))
+}
@@ -0,0 +1,324 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"vote-system/models"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// 支持的文档格式
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// slugPattern slug只允许小写字母、数字和连字符
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,127}$`)
+
+// adoptPattern 导出时为没有slug的投票问卷生成的slug，导入时按ID认领
+var adoptPattern = regexp.MustCompile(`^poll-([0-9]+)$`)
+
+// Document 声明式投票问卷文档，文档中没有的已管理投票问卷会被归档
+type Document struct {
+	Polls []PollSpec `yaml:"polls" json:"polls"`
+}
+
+// PollSpec 单个投票问卷的声明
+type PollSpec struct {
+	Slug        string `yaml:"slug" json:"slug"`
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Tags 标签，按models.NormalizeTags规范化后比较
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Active 为空时默认开启
+	Active           *bool             `yaml:"active,omitempty" json:"active,omitempty"`
+	ResultVisibility string            `yaml:"result_visibility,omitempty" json:"result_visibility,omitempty"`
+	Rules            *models.PollRules `yaml:"rules,omitempty" json:"rules,omitempty"`
+	Options          []string          `yaml:"options" json:"options"`
+	// WriteInOption 作为自填选项的选项文本，需为Options之一
+	WriteInOption  string `yaml:"write_in_option,omitempty" json:"write_in_option,omitempty"`
+	AllowProposals bool   `yaml:"allow_proposals,omitempty" json:"allow_proposals,omitempty"`
+	// Mode 投票方式，为空时为单选，创建后不能修改
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// MaxScore 评分投票的最高分，为空时为models.DefaultMaxScore
+	MaxScore int `yaml:"max_score,omitempty" json:"max_score,omitempty"`
+	// Access 谁可以投票，为空时为public
+	Access string `yaml:"access,omitempty" json:"access,omitempty"`
+	// AccessCode 私有投票问卷的访问码，只保存其SHA-256，导出时不包含；已有的私有投票问卷为空时保持原访问码
+	AccessCode string `yaml:"access_code,omitempty" json:"access_code,omitempty"`
+	// RollID 投票人名册，为空时不限制投票人
+	RollID uint `yaml:"roll_id,omitempty" json:"roll_id,omitempty"`
+}
+
+// active 返回声明的开启状态
+func (s PollSpec) active() bool {
+	return s.Active == nil || *s.Active
+}
+
+// visibility 返回声明的结果可见性
+func (s PollSpec) visibility() string {
+	if s.ResultVisibility == "" {
+		return models.VisibilityAlways
+	}
+	return s.ResultVisibility
+}
+
+// tags 返回规范化后的标签
+func (s PollSpec) tags() models.Tags {
+	return models.NormalizeTags(s.Tags)
+}
+
+// mode 返回声明的投票方式
+func (s PollSpec) mode() string {
+	if s.Mode == "" {
+		return models.ModePlurality
+	}
+	return s.Mode
+}
+
+// maxScore 返回声明的评分投票最高分
+func (s PollSpec) maxScore() int {
+	if s.mode() == models.ModeScore && s.MaxScore == 0 {
+		return models.DefaultMaxScore
+	}
+	return s.MaxScore
+}
+
+// access 返回声明的访问方式
+func (s PollSpec) access() string {
+	if s.Access == "" {
+		return models.AccessPublic
+	}
+	return s.Access
+}
+
+// rollID 返回声明的投票人名册，没有时为nil
+func (s PollSpec) rollID() *uint {
+	if s.RollID == 0 {
+		return nil
+	}
+	id := s.RollID
+	return &id
+}
+
+// rules 返回声明的自动关闭规则
+func (s PollSpec) rules() models.PollRules {
+	if s.Rules == nil {
+		return models.PollRules{}
+	}
+	return *s.Rules
+}
+
+// ValidationError 文档中某个字段校验失败
+type ValidationError struct {
+	// Field 字段路径，例如 polls[0].options[1]
+	Field string
+	// Rule 违反的规则：required、max、min、duplicate、oneof、invalid、unsupported
+	Rule string
+	// Param 规则参数，例如max的上限
+	Param string
+	// Value 导致错误的取值
+	Value string
+}
+
+func (e *ValidationError) Error() string {
+	msg := e.Field + ": " + e.Rule
+	if e.Param != "" {
+		msg += "=" + e.Param
+	}
+	if e.Value != "" {
+		msg += fmt.Sprintf(" (%q)", e.Value)
+	}
+	return msg
+}
+
+// Parse 解析YAML或JSON文档，JSON是YAML的子集，两种格式使用同一个解析器
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate 校验文档内容，返回第一个*ValidationError
+func (d *Document) Validate() error {
+	seen := map[string]bool{}
+	for i, spec := range d.Polls {
+		path := fmt.Sprintf("polls[%d]", i)
+		invalid := func(field, rule, param, value string) error {
+			return &ValidationError{Field: path + "." + field, Rule: rule, Param: param, Value: value}
+		}
+
+		if !slugPattern.MatchString(spec.Slug) {
+			return invalid("slug", "invalid", "", spec.Slug)
+		}
+		if seen[spec.Slug] {
+			return invalid("slug", "duplicate", "", spec.Slug)
+		}
+		seen[spec.Slug] = true
+
+		if spec.Title == "" {
+			return invalid("title", "required", "", "")
+		}
+		if len(spec.Title) > 255 {
+			return invalid("title", "max", "255", "")
+		}
+		if len(spec.Options) < 2 {
+			return invalid("options", "min", "2", "")
+		}
+		texts := map[string]bool{}
+		for j, text := range spec.Options {
+			option := fmt.Sprintf("options[%d]", j)
+			if text == "" {
+				return invalid(option, "required", "", "")
+			}
+			if len(text) > 255 {
+				return invalid(option, "max", "255", "")
+			}
+			if texts[text] {
+				return invalid(option, "duplicate", "", text)
+			}
+			texts[text] = true
+		}
+		if spec.WriteInOption != "" && !texts[spec.WriteInOption] {
+			return invalid("write_in_option", "oneof", "", spec.WriteInOption)
+		}
+		if len(spec.Tags) > models.MaxTags {
+			return invalid("tags", "max", strconv.Itoa(models.MaxTags), "")
+		}
+		for j, tag := range spec.Tags {
+			field := fmt.Sprintf("tags[%d]", j)
+			if strings.TrimSpace(tag) == "" {
+				return invalid(field, "required", "", "")
+			}
+			if len(tag) > models.MaxTagLength {
+				return invalid(field, "max", strconv.Itoa(models.MaxTagLength), "")
+			}
+			if strings.Contains(tag, ",") {
+				return invalid(field, "invalid", "", tag)
+			}
+		}
+		if !models.ValidVisibility(spec.visibility()) {
+			return invalid("result_visibility", "oneof", "", spec.ResultVisibility)
+		}
+		rules := spec.rules()
+		if rules.TargetVotes < 0 || rules.WinPercent < 0 || rules.WinPercent > 100 || rules.WinMinVotes < 0 || rules.EligibleVoters < 0 {
+			return invalid("rules", "invalid", "", "")
+		}
+		if !models.ValidMode(spec.mode()) {
+			return invalid("mode", "oneof", "", spec.Mode)
+		}
+		if field, rule, param := models.ModeConflict(spec.mode(), spec.maxScore(), rules, spec.WriteInOption != "", spec.AllowProposals); field != "" {
+			return invalid(field, rule, param, "")
+		}
+		if !models.ValidAccess(spec.access()) {
+			return invalid("access", "oneof", "", spec.Access)
+		}
+		if spec.AccessCode != "" {
+			if spec.access() != models.AccessPrivate {
+				return invalid("access_code", "unsupported", "", "")
+			}
+			if len(spec.AccessCode) < 4 {
+				return invalid("access_code", "min", "4", "")
+			}
+			if len(spec.AccessCode) > 64 {
+				return invalid("access_code", "max", "64", "")
+			}
+		}
+		// 邀请制投票问卷按投票令牌识别投票人，不能再限定名册
+		if spec.RollID != 0 && spec.access() == models.AccessInvite {
+			return invalid("roll_id", "unsupported", "", "")
+		}
+	}
+	return nil
+}
+
+// Export 将所有未归档的投票问卷导出为文档，没有slug的投票问卷使用poll-<id>
+//
+// 私有投票问卷只导出访问方式，不导出访问码及其哈希；导入到其他实例时需要补充access_code。
+func Export(db *gorm.DB) (*Document, error) {
+	var polls []models.Poll
+	if err := db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("id").Find(&polls).Error; err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Polls: []PollSpec{}}
+	for _, poll := range polls {
+		doc.Polls = append(doc.Polls, specOf(poll))
+	}
+	return doc, nil
+}
+
+// specOf 生成投票问卷的声明
+func specOf(poll models.Poll) PollSpec {
+	slug := "poll-" + strconv.FormatUint(uint64(poll.ID), 10)
+	if poll.Slug != nil {
+		slug = *poll.Slug
+	}
+
+	active := poll.IsActive
+	spec := PollSpec{
+		Slug:             slug,
+		Title:            poll.Title,
+		Description:      poll.Description,
+		Active:           &active,
+		ResultVisibility: poll.ResultVisibility,
+		Options:          []string{},
+		WriteInOption:    writeInText(poll),
+		AllowProposals:   poll.AllowProposals,
+		MaxScore:         poll.MaxScore,
+	}
+	if poll.Access != "" && poll.Access != models.AccessPublic {
+		spec.Access = poll.Access
+	}
+	if poll.RollID != nil {
+		spec.RollID = *poll.RollID
+	}
+	if !poll.Plurality() {
+		spec.Mode = poll.Mode
+	}
+	if len(poll.Tags) > 0 {
+		spec.Tags = poll.Tags
+	}
+	if poll.Rules != (models.PollRules{}) {
+		rules := poll.Rules
+		spec.Rules = &rules
+	}
+	for _, option := range poll.Options {
+		spec.Options = append(spec.Options, option.Text)
+	}
+	return spec
+}
+
+// writeInText 返回投票问卷自填选项的文本，没有时为空
+func writeInText(poll models.Poll) string {
+	for _, option := range poll.Options {
+		if option.WriteIn {
+			return option.Text
+		}
+	}
+	return ""
+}
+
+// Encode 按格式编码文档
+func Encode(doc *Document, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(doc)
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
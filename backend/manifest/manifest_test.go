@@ -0,0 +1,390 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.VoterRoll{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{})
+	return db
+}
+
+const lunchDocument = `
+polls:
+  - slug: lunch
+    title: 午餐吃什么
+    result_visibility: after_vote
+    tags: [Food, daily]
+    rules:
+      target_votes: 20
+    options: [面, 饭]
+  - slug: retro
+    title: 回顾会时间
+    active: false
+    options: [周一, 周五, 其他]
+    write_in_option: 其他
+    allow_proposals: true
+`
+
+func mustParse(t *testing.T, data string) *Document {
+	t.Helper()
+	doc, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("解析文档失败: %v", err)
+	}
+	return doc
+}
+
+func TestParse_Validation(t *testing.T) {
+	cases := map[string]string{
+		"invalid slug":     `polls: [{slug: "Lunch!", title: a, options: [x, y]}]`,
+		"duplicate slug":   `polls: [{slug: a, title: a, options: [x, y]}, {slug: a, title: b, options: [x, y]}]`,
+		"missing title":    `polls: [{slug: a, options: [x, y]}]`,
+		"too few options":  `polls: [{slug: a, title: a, options: [x]}]`,
+		"dup option":       `polls: [{slug: a, title: a, options: [x, x]}]`,
+		"bad visibility":   `polls: [{slug: a, title: a, result_visibility: never, options: [x, y]}]`,
+		"bad rules":        `polls: [{slug: a, title: a, rules: {win_percent: 120}, options: [x, y]}]`,
+		"comma in tag":     `polls: [{slug: a, title: a, tags: ["a,b"], options: [x, y]}]`,
+		"unknown write-in": `polls: [{slug: a, title: a, write_in_option: z, options: [x, y]}]`,
+		"bad mode":         `polls: [{slug: a, title: a, mode: ranked, options: [x, y]}]`,
+		"max score":        `polls: [{slug: a, title: a, mode: score, max_score: 101, options: [x, y]}]`,
+		"mode with target": `polls: [{slug: a, title: a, mode: borda, rules: {target_votes: 5}, options: [x, y]}]`,
+		"borda proposals":  `polls: [{slug: a, title: a, mode: borda, allow_proposals: true, options: [x, y]}]`,
+		"bad access":       `polls: [{slug: a, title: a, access: secret, options: [x, y]}]`,
+		"short code":       `polls: [{slug: a, title: a, access: private, access_code: abc, options: [x, y]}]`,
+		"public code":      `polls: [{slug: a, title: a, access_code: abcd, options: [x, y]}]`,
+		"invite roll":      `polls: [{slug: a, title: a, access: invite, roll_id: 1, options: [x, y]}]`,
+		"not a document":   `polls: 1`,
+	}
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: 期望返回错误", name)
+		}
+	}
+
+	// JSON文档使用同一个解析器
+	doc := mustParse(t, `{"polls": [{"slug": "a", "title": "A", "options": ["x", "y"]}]}`)
+	if len(doc.Polls) != 1 || doc.Polls[0].Slug != "a" {
+		t.Errorf("JSON文档解析不正确: %+v", doc)
+	}
+}
+
+func TestApply_CreateAndIdempotent(t *testing.T) {
+	db := setupTestDB()
+	doc := mustParse(t, lunchDocument)
+
+	plan, err := Apply(db, doc, ApplyOptions{Audit: audit.Entry{Actor: "alice"}})
+	if err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Action != ActionCreate || plan.Changes[0].PollID == 0 {
+		t.Fatalf("期望创建2个投票问卷, 得到 %+v", plan.Changes)
+	}
+
+	var lunch models.Poll
+	db.Preload("Options").Where("slug = ?", "lunch").First(&lunch)
+	if lunch.ResultVisibility != models.VisibilityAfterVote || lunch.Rules.TargetVotes != 20 || len(lunch.Options) != 2 {
+		t.Errorf("投票问卷内容不正确: %+v", lunch)
+	}
+	if !lunch.Tags.Equal(models.Tags{"food", "daily"}) || lunch.Owner != "alice" {
+		t.Errorf("标签应规范化, 创建者为操作人: tags=%v owner=%q", lunch.Tags, lunch.Owner)
+	}
+	var retro models.Poll
+	db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("slug = ?", "retro").First(&retro)
+	if retro.IsActive {
+		t.Error("active: false 的投票问卷应该是关闭状态")
+	}
+	if !retro.AllowProposals || len(retro.Options) != 3 || retro.Options[0].WriteIn || !retro.Options[2].WriteIn {
+		t.Errorf("自填选项和提议设置不正确: %+v", retro)
+	}
+
+	// 再次应用不产生变更
+	plan, err = Apply(db, doc, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("再次应用文档失败: %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("期望没有变更, 得到 %s", plan.Diff())
+	}
+
+	var logs int64
+	db.Model(&models.AuditLog{}).Where("action = ? AND actor = ?", models.AuditPollCreated, "alice").Count(&logs)
+	if logs != 2 {
+		t.Errorf("期望2条创建审计日志, 得到 %d", logs)
+	}
+}
+
+func TestApply_UpdateArchiveRestore(t *testing.T) {
+	db := setupTestDB()
+	if _, err := Apply(db, mustParse(t, lunchDocument), ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+
+	// 修改标题、增删选项、开启retro，删除lunch
+	updated := mustParse(t, `
+polls:
+  - slug: retro
+    title: 回顾会安排
+    options: [周一, 周三]
+`)
+	plan, err := Compute(db, updated)
+	if err != nil {
+		t.Fatalf("计算变更失败: %v", err)
+	}
+	diff := plan.Diff()
+	for _, want := range []string{"~ retro", `title: "回顾会时间" -> "回顾会安排"`, "active: false -> true", `+ option "周三"`, `- option "周五"`,
+		`write_in_option: "其他" -> ""`, "allow_proposals: true -> false", "- lunch (archive)"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("变更中缺少 %q:\n%s", want, diff)
+		}
+	}
+
+	// dry-run不修改数据库
+	var count int64
+	db.Model(&models.Poll{}).Count(&count)
+	if count != 2 {
+		t.Errorf("计算变更后期望2个投票问卷, 得到 %d", count)
+	}
+
+	if _, err := Apply(db, updated, ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+
+	var retro models.Poll
+	db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("slug = ?", "retro").First(&retro)
+	if retro.Title != "回顾会安排" || !retro.IsActive || len(retro.Options) != 2 || retro.Options[1].Text != "周三" {
+		t.Errorf("retro更新不正确: %+v", retro)
+	}
+
+	var lunch models.Poll
+	db.Unscoped().Where("slug = ?", "lunch").First(&lunch)
+	if !lunch.DeletedAt.Valid || lunch.IsActive || lunch.CloseReason != models.CloseReasonArchived {
+		t.Errorf("lunch应该被关闭并归档: %+v", lunch)
+	}
+
+	// 重新声明已归档的投票问卷会恢复它
+	plan, err = Apply(db, mustParse(t, lunchDocument), ApplyOptions{})
+	if err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	if plan.Changes[0].Action != ActionRestore || plan.Changes[0].PollID != lunch.ID {
+		t.Errorf("期望恢复lunch, 得到 %+v", plan.Changes[0])
+	}
+	var restored models.Poll
+	if err := db.Where("slug = ?", "lunch").First(&restored).Error; err != nil || !restored.IsActive || restored.ClosedAt != nil {
+		t.Errorf("lunch应该恢复并开启: %+v %v", restored, err)
+	}
+}
+
+func TestApply_RejectsRemovingVotedOption(t *testing.T) {
+	db := setupTestDB()
+	if _, err := Apply(db, mustParse(t, lunchDocument), ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	db.Model(&models.Option{}).Where("text = ?", "饭").Update("vote_count", 3)
+
+	doc := mustParse(t, strings.Replace(lunchDocument, "[面, 饭]", "[面, 粉]", 1))
+	plan, err := Apply(db, doc, ApplyOptions{})
+	if err == nil {
+		t.Fatal("删除有票数的选项应返回错误")
+	}
+	if len(plan.Errors) != 1 || !strings.Contains(plan.Diff(), "! lunch") {
+		t.Errorf("变更中应包含错误: %s", plan.Diff())
+	}
+
+	var count int64
+	db.Model(&models.Option{}).Where("text = ?", "粉").Count(&count)
+	if count != 0 {
+		t.Error("有错误时不应修改数据库")
+	}
+}
+
+func TestApply_RejectsChangingMode(t *testing.T) {
+	db := setupTestDB()
+	const document = `polls: [{slug: dinner, title: 晚餐评分, mode: score, options: [面, 饭]}]`
+	if _, err := Apply(db, mustParse(t, document), ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	var poll models.Poll
+	db.Where("slug = ?", "dinner").First(&poll)
+	if poll.Mode != models.ModeScore || poll.MaxScore != models.DefaultMaxScore {
+		t.Errorf("期望评分投票、最高 %d 分, 得到 %s、%d", models.DefaultMaxScore, poll.Mode, poll.MaxScore)
+	}
+
+	plan, err := Apply(db, mustParse(t, strings.Replace(document, "score", "borda", 1)), ApplyOptions{})
+	if err == nil || len(plan.Errors) != 1 || !strings.Contains(plan.Errors[0], "voting mode") {
+		t.Errorf("修改投票方式应返回错误: %v %+v", err, plan)
+	}
+
+	// 波达计数的得分取决于选项数，创建后不能增删选项
+	const borda = `polls: [{slug: trip, title: 团建, mode: borda, options: [海边, 山里]}]`
+	if _, err := Apply(db, mustParse(t, borda), ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	plan, err = Apply(db, mustParse(t, strings.Replace(borda, "山里]", "山里, 城里]", 1)), ApplyOptions{})
+	if err == nil || len(plan.Errors) != 1 || !strings.Contains(plan.Errors[0], "options of a borda poll") {
+		t.Errorf("增加波达计数的选项应返回错误: %v %+v", err, plan)
+	}
+}
+
+func TestExport_RoundTripAndAdopt(t *testing.T) {
+	db := setupTestDB()
+
+	// 没有slug的投票问卷导出为poll-<id>
+	poll := models.Poll{Title: "旧投票", IsActive: true, Options: []models.Option{{Text: "A"}, {Text: "B"}}}
+	db.Create(&poll)
+
+	doc, err := Export(db)
+	if err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Encode(doc, format)
+		if err != nil {
+			t.Fatalf("%s: 编码失败: %v", format, err)
+		}
+		parsed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: 导出的文档无法解析: %v", format, err)
+		}
+		if parsed.Polls[0].Slug != "poll-1" || parsed.Polls[0].Title != "旧投票" {
+			t.Errorf("%s: 导出内容不正确: %+v", format, parsed.Polls[0])
+		}
+	}
+
+	// 导入导出的文档会认领原投票问卷并写入slug
+	plan, err := Apply(db, doc, ApplyOptions{})
+	if err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	if plan.Changes[0].Action != ActionUpdate || plan.Changes[0].PollID != poll.ID {
+		t.Errorf("期望认领投票问卷 %d, 得到 %+v", poll.ID, plan.Changes[0])
+	}
+
+	var count int64
+	db.Model(&models.Poll{}).Where("slug = ?", "poll-1").Count(&count)
+	if count != 1 {
+		t.Errorf("期望1个slug为poll-1的投票问卷, 得到 %d", count)
+	}
+}
+
+func TestExport_RoundTripAccess(t *testing.T) {
+	db := setupTestDB()
+	roll := models.VoterRoll{Name: "董事会"}
+	db.Create(&roll)
+
+	doc := mustParse(t, fmt.Sprintf(`
+polls:
+  - {slug: board, title: 董事会决议, access: private, access_code: s3cret, roll_id: %d, options: [同意, 反对]}
+  - {slug: invited, title: 邀请投票, access: invite, options: [A, B]}
+`, roll.ID))
+	if _, err := Apply(db, doc, ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	var board models.Poll
+	db.Where("slug = ?", "board").First(&board)
+	if board.Access != models.AccessPrivate || board.AccessCodeHash != ballot.Hash("s3cret") || board.RollID == nil || *board.RollID != roll.ID {
+		t.Fatalf("访问设置不正确: %+v", board)
+	}
+
+	// 导出包含访问方式和名册，但不包含访问码
+	exported, err := Export(db)
+	if err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	data, _ := Encode(exported, FormatYAML)
+	if strings.Contains(string(data), "access_code") || strings.Contains(string(data), board.AccessCodeHash) {
+		t.Errorf("导出的文档不应包含访问码: %s", data)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("导出的文档无法解析: %v", err)
+	}
+	if parsed.Polls[0].Access != models.AccessPrivate || parsed.Polls[0].RollID != roll.ID || parsed.Polls[1].Access != models.AccessInvite {
+		t.Errorf("导出的访问设置不正确: %+v", parsed.Polls)
+	}
+
+	// 重新导入导出的文档没有变更，访问码保持不变
+	plan, err := Apply(db, parsed, ApplyOptions{})
+	if err != nil || plan.HasChanges() {
+		t.Fatalf("往返导入期望没有变更, 得到 %s %v", plan.Diff(), err)
+	}
+	db.First(&board, board.ID)
+	if board.AccessCodeHash != ballot.Hash("s3cret") {
+		t.Error("省略access_code时应保留原访问码")
+	}
+
+	// 访问方式和名册的修改可以检测到
+	parsed.Polls[0].Access = models.AccessPublic
+	parsed.Polls[0].RollID = 0
+	plan, err = Compute(db, parsed)
+	if err != nil {
+		t.Fatalf("计算变更失败: %v", err)
+	}
+	diff := plan.Diff()
+	if !strings.Contains(diff, `access: "private" -> "public"`) || !strings.Contains(diff, "roll_id:") {
+		t.Errorf("期望显示访问方式和名册的变更, 得到 %s", diff)
+	}
+
+	// 导入到没有该投票问卷的实例时，私有投票问卷必须提供访问码，不能静默变为公开
+	fresh := setupTestDB()
+	fresh.Create(&models.VoterRoll{Name: "董事会"})
+	if _, err := Apply(fresh, mustParse(t, string(data)), ApplyOptions{}); err == nil || !strings.Contains(err.Error(), "access_code is required") {
+		t.Errorf("期望要求提供访问码, 得到 %v", err)
+	}
+}
+
+func TestApply_SeedOnlyCreatesMissing(t *testing.T) {
+	db := setupTestDB()
+	if _, err := Apply(db, mustParse(t, lunchDocument), ApplyOptions{}); err != nil {
+		t.Fatalf("应用文档失败: %v", err)
+	}
+	db.Model(&models.Poll{}).Where("slug = ?", "lunch").Update("title", "管理员修改过的标题")
+
+	seed := mustParse(t, `
+polls:
+  - slug: lunch
+    title: 午餐吃什么
+    options: [面, 饭]
+  - slug: dinner
+    title: 晚餐吃什么
+    options: [面, 饭]
+`)
+	plan, err := Apply(db, seed, ApplyOptions{Seed: true})
+	if err != nil {
+		t.Fatalf("应用种子文档失败: %v", err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Action != ActionUnchanged || plan.Changes[1].Action != ActionCreate {
+		t.Errorf("期望只创建dinner, 得到 %+v", plan.Changes)
+	}
+
+	// 已有的投票问卷不被修改，文档中没有的retro不被归档
+	var lunch models.Poll
+	db.Where("slug = ?", "lunch").First(&lunch)
+	if lunch.Title != "管理员修改过的标题" {
+		t.Errorf("种子数据不应修改已有投票问卷, 得到 %q", lunch.Title)
+	}
+	var count int64
+	db.Model(&models.Poll{}).Count(&count)
+	if count != 3 {
+		t.Errorf("期望3个投票问卷, 得到 %d", count)
+	}
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditPollSeeded).Count(&count)
+	if count != 1 {
+		t.Errorf("期望1条poll.seeded审计日志, 得到 %d", count)
+	}
+}
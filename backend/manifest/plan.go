@@ -0,0 +1,339 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"vote-system/ballot"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// 变更类型
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionRestore   = "restore"
+	ActionArchive   = "archive"
+	ActionUnchanged = "unchanged"
+)
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Change 单个投票问卷的变更
+type Change struct {
+	Slug          string        `json:"slug"`
+	Action        string        `json:"action"`
+	PollID        uint          `json:"poll_id,omitempty"`
+	Fields        []FieldChange `json:"fields,omitempty"`
+	AddOptions    []string      `json:"add_options,omitempty"`
+	RemoveOptions []string      `json:"remove_options,omitempty"`
+
+	spec *PollSpec
+	poll *models.Poll
+}
+
+// Plan 将文档应用到数据库所需的变更
+type Plan struct {
+	Changes []Change `json:"changes"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// HasChanges 是否有需要执行的变更
+func (p *Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != ActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff 以文本形式输出变更，用于dry-run
+func (p *Plan) Diff() string {
+	var b strings.Builder
+	for _, change := range p.Changes {
+		switch change.Action {
+		case ActionCreate:
+			fmt.Fprintf(&b, "+ %s\n", change.Slug)
+		case ActionArchive:
+			fmt.Fprintf(&b, "- %s (archive)\n", change.Slug)
+		case ActionRestore:
+			fmt.Fprintf(&b, "~ %s (restore)\n", change.Slug)
+		case ActionUpdate:
+			fmt.Fprintf(&b, "~ %s\n", change.Slug)
+		default:
+			continue
+		}
+
+		for _, field := range change.Fields {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", field.Field, formatValue(field.Old), formatValue(field.New))
+		}
+		for _, text := range change.AddOptions {
+			fmt.Fprintf(&b, "    + option %q\n", text)
+		}
+		for _, text := range change.RemoveOptions {
+			fmt.Fprintf(&b, "    - option %q\n", text)
+		}
+	}
+	for _, err := range p.Errors {
+		fmt.Fprintf(&b, "! %s\n", err)
+	}
+	if b.Len() == 0 {
+		return "no changes\n"
+	}
+	return b.String()
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		return strconv.Quote(v)
+	case *time.Time:
+		if v == nil {
+			return "(none)"
+		}
+		return v.Format(time.RFC3339)
+	case *uint:
+		if v == nil {
+			return "(none)"
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	}
+	return fmt.Sprint(v)
+}
+
+// Compute 对比文档与数据库，计算变更
+func Compute(db *gorm.DB, doc *Document) (*Plan, error) {
+	return compute(db, doc, false)
+}
+
+// compute 计算变更，seed为true时只创建文档中尚不存在的投票问卷，不修改或归档已有的投票问卷
+func compute(db *gorm.DB, doc *Document, seed bool) (*Plan, error) {
+	var polls []models.Poll
+	if err := db.Unscoped().Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("id").Find(&polls).Error; err != nil {
+		return nil, err
+	}
+
+	var rollIDs []uint
+	if err := db.Model(&models.VoterRoll{}).Pluck("id", &rollIDs).Error; err != nil {
+		return nil, err
+	}
+	rolls := map[uint]bool{}
+	for _, id := range rollIDs {
+		rolls[id] = true
+	}
+
+	bySlug := map[string]*models.Poll{}
+	byID := map[uint]*models.Poll{}
+	for i := range polls {
+		if polls[i].Slug != nil {
+			bySlug[*polls[i].Slug] = &polls[i]
+		}
+		byID[polls[i].ID] = &polls[i]
+	}
+
+	plan := &Plan{Changes: []Change{}}
+	declared := map[uint]bool{}
+
+	for i := range doc.Polls {
+		spec := &doc.Polls[i]
+		poll := bySlug[spec.Slug]
+
+		// 认领导出时生成的poll-<id>
+		if poll == nil && !seed {
+			if m := adoptPattern.FindStringSubmatch(spec.Slug); m != nil {
+				id, _ := strconv.ParseUint(m[1], 10, 64)
+				if candidate := byID[uint(id)]; candidate != nil && candidate.Slug == nil {
+					poll = candidate
+				}
+			}
+		}
+
+		if spec.RollID != 0 && !rolls[spec.RollID] && (poll == nil || !seed) {
+			plan.Errors = append(plan.Errors, fmt.Sprintf("%s: voter roll %d not found", spec.Slug, spec.RollID))
+		}
+
+		if poll == nil {
+			if spec.access() == models.AccessPrivate && spec.AccessCode == "" {
+				plan.Errors = append(plan.Errors, spec.Slug+": access_code is required for a private poll")
+			}
+			plan.Changes = append(plan.Changes, Change{Slug: spec.Slug, Action: ActionCreate, AddOptions: spec.Options, spec: spec})
+			continue
+		}
+
+		declared[poll.ID] = true
+		if seed {
+			plan.Changes = append(plan.Changes, Change{Slug: spec.Slug, Action: ActionUnchanged, PollID: poll.ID})
+			continue
+		}
+		plan.Changes = append(plan.Changes, diffPoll(plan, spec, poll))
+	}
+	if seed {
+		return plan, nil
+	}
+
+	// 文档中没有的已管理投票问卷归档
+	for i := range polls {
+		poll := &polls[i]
+		if poll.Slug == nil || declared[poll.ID] || poll.DeletedAt.Valid {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Slug: *poll.Slug, Action: ActionArchive, PollID: poll.ID, poll: poll})
+	}
+
+	return plan, nil
+}
+
+// diffPoll 对比单个投票问卷
+func diffPoll(plan *Plan, spec *PollSpec, poll *models.Poll) Change {
+	change := Change{Slug: spec.Slug, Action: ActionUnchanged, PollID: poll.ID, spec: spec, poll: poll}
+
+	field := func(name string, old, new interface{}) {
+		change.Fields = append(change.Fields, FieldChange{Field: name, Old: old, New: new})
+	}
+
+	if poll.Slug == nil {
+		field("slug", nil, spec.Slug)
+	}
+	if poll.Title != spec.Title {
+		field("title", poll.Title, spec.Title)
+	}
+	if poll.Description != spec.Description {
+		field("description", poll.Description, spec.Description)
+	}
+	if !poll.Tags.Equal(spec.tags()) {
+		field("tags", poll.Tags, spec.tags())
+	}
+	if poll.IsActive != spec.active() {
+		field("active", poll.IsActive, spec.active())
+	}
+	if poll.ResultVisibility != spec.visibility() {
+		field("result_visibility", poll.ResultVisibility, spec.visibility())
+		if poll.IsActive && spec.active() && poll.ResultVisibility == models.VisibilityAfterClose {
+			plan.Errors = append(plan.Errors, spec.Slug+": result visibility cannot be relaxed while the poll is open")
+		}
+	}
+
+	rules := spec.rules()
+	if poll.Rules.TargetVotes != rules.TargetVotes {
+		field("rules.target_votes", poll.Rules.TargetVotes, rules.TargetVotes)
+	}
+	if poll.Rules.WinPercent != rules.WinPercent {
+		field("rules.win_percent", poll.Rules.WinPercent, rules.WinPercent)
+	}
+	if poll.Rules.WinMinVotes != rules.WinMinVotes {
+		field("rules.win_min_votes", poll.Rules.WinMinVotes, rules.WinMinVotes)
+	}
+	if poll.Rules.EligibleVoters != rules.EligibleVoters {
+		field("rules.eligible_voters", poll.Rules.EligibleVoters, rules.EligibleVoters)
+	}
+	if !sameTime(poll.Rules.ClosesAt, rules.ClosesAt) {
+		field("rules.closes_at", poll.Rules.ClosesAt, rules.ClosesAt)
+	}
+	if current := writeInText(*poll); current != spec.WriteInOption {
+		field("write_in_option", current, spec.WriteInOption)
+	}
+	if poll.AllowProposals != spec.AllowProposals {
+		field("allow_proposals", poll.AllowProposals, spec.AllowProposals)
+	}
+	access := poll.Access
+	if access == "" {
+		access = models.AccessPublic
+	}
+	if access != spec.access() {
+		field("access", access, spec.access())
+	}
+	// 访问码只比较哈希，差异中不显示访问码
+	if spec.AccessCode != "" && ballot.Hash(spec.AccessCode) != poll.AccessCodeHash {
+		field("access_code", redactedCode(poll.AccessCodeHash), redactedCode(spec.AccessCode))
+	}
+	if spec.access() == models.AccessPrivate && spec.AccessCode == "" && poll.AccessCodeHash == "" {
+		plan.Errors = append(plan.Errors, spec.Slug+": access_code is required for a private poll")
+	}
+	if !sameRoll(poll.RollID, spec.rollID()) {
+		field("roll_id", poll.RollID, spec.rollID())
+	}
+	// 已有选票按创建时的投票方式计分，不能修改
+	mode := poll.Mode
+	if poll.Plurality() {
+		mode = models.ModePlurality
+	}
+	if mode != spec.mode() {
+		field("mode", mode, spec.mode())
+	}
+	if poll.MaxScore != spec.maxScore() {
+		field("max_score", poll.MaxScore, spec.maxScore())
+	}
+	if mode != spec.mode() || poll.MaxScore != spec.maxScore() {
+		plan.Errors = append(plan.Errors, spec.Slug+": voting mode cannot be changed after creation")
+	}
+
+	// 选项按文本匹配，已有票数的选项不能删除
+	wanted := map[string]bool{}
+	for _, text := range spec.Options {
+		wanted[text] = true
+	}
+	existing := map[string]bool{}
+	for _, option := range poll.Options {
+		existing[option.Text] = true
+		if !wanted[option.Text] {
+			change.RemoveOptions = append(change.RemoveOptions, option.Text)
+			if option.VoteCount > 0 {
+				plan.Errors = append(plan.Errors, fmt.Sprintf("%s: option %q has votes and cannot be removed", spec.Slug, option.Text))
+			}
+		}
+	}
+	for _, text := range spec.Options {
+		if !existing[text] {
+			change.AddOptions = append(change.AddOptions, text)
+		}
+	}
+	sort.Strings(change.RemoveOptions)
+	// 波达计数的得分取决于选项数，增删选项会使之前的选票与之后的选票分值不同
+	if spec.mode() == models.ModeBorda && (len(change.AddOptions) > 0 || len(change.RemoveOptions) > 0) {
+		plan.Errors = append(plan.Errors, spec.Slug+": options of a borda poll cannot be changed after creation")
+	}
+
+	switch {
+	case poll.DeletedAt.Valid:
+		change.Action = ActionRestore
+	case len(change.Fields) > 0 || len(change.AddOptions) > 0 || len(change.RemoveOptions) > 0:
+		change.Action = ActionUpdate
+	}
+	return change
+}
+
+// redactedCode 在差异中代替访问码，没有访问码时为nil
+func redactedCode(code string) interface{} {
+	if code == "" {
+		return nil
+	}
+	return "***"
+}
+
+func sameRoll(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
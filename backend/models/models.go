@@ -1,21 +1,209 @@
 package models
 
 import (
+	"strconv"
 	"time"
+	"vote-system/weight"
 
 	"gorm.io/gorm"
 )
 
 // Poll 投票问卷模型
 type Poll struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	Title       string         `gorm:"size:255;not null" json:"title"`
-	Description string         `gorm:"type:text" json:"description"`
-	IsActive    bool           `gorm:"default:true" json:"is_active"`
-	Options     []Option       `gorm:"foreignKey:PollID" json:"options"`
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// Slug 声明式导入导出时用于匹配投票问卷的稳定标识，未通过导入管理的投票问卷为空
+	Slug        *string `gorm:"size:128;uniqueIndex" json:"slug,omitempty"`
+	Title       string  `gorm:"size:255;not null" json:"title"`
+	Description string  `gorm:"type:text" json:"description"`
+	// Owner 创建者，即创建时审计日志中的操作人，例如 admin、token:ci
+	Owner string `gorm:"size:128;index" json:"owner,omitempty"`
+	// Tags 标签，用于在列表中筛选
+	Tags     Tags       `gorm:"size:512" json:"tags"`
+	IsActive bool       `gorm:"default:true" json:"is_active"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	// CloseReason 关闭原因，取值见CloseReason*常量
+	CloseReason string    `gorm:"size:32" json:"close_reason,omitempty"`
+	Rules       PollRules `gorm:"embedded" json:"rules"`
+	// ResultVisibility 结果可见性，取值见Visibility*常量
+	ResultVisibility string `gorm:"size:16;default:always" json:"result_visibility"`
+	// AllowProposals 投票人可以提议新选项，经管理员审核后加入
+	AllowProposals bool `gorm:"default:false" json:"allow_proposals"`
+	// Access 谁可以投票，取值见Access*常量
+	Access string `gorm:"size:16;default:public" json:"access"`
+	// AccessCodeHash 私有投票问卷访问码的SHA-256
+	AccessCodeHash string `gorm:"size:64" json:"-"`
+	// BallotSecret 签名投票令牌的密钥，第一次生成令牌时创建
+	BallotSecret string `gorm:"size:64" json:"-"`
+	// RollID 投票人名册，设置后只有名册中的投票人可以投票
+	RollID *uint `gorm:"index" json:"roll_id,omitempty"`
+	// Mode 投票方式，取值见Mode*常量，创建后不能修改
+	Mode string `gorm:"size:16;default:plurality" json:"mode"`
+	// MaxScore 评分投票的最高分，每个选项可以评0到MaxScore分
+	MaxScore int      `gorm:"default:0" json:"max_score,omitempty"`
+	Options  []Option `gorm:"foreignKey:PollID" json:"options"`
+	// ResultsHidden 响应中的票数已被隐藏，不入库
+	ResultsHidden bool `gorm:"-" json:"results_hidden"`
+}
+
+// PollRules 投票问卷的自动关闭规则，零值表示不启用该规则
+type PollRules struct {
+	// TargetVotes 总票数达到该值时关闭
+	TargetVotes int `gorm:"default:0" json:"target_votes" yaml:"target_votes,omitempty" binding:"min=0"`
+	// WinPercent 某个选项得票率达到该百分比且票数不少于WinMinVotes时关闭
+	WinPercent  int `gorm:"default:0" json:"win_percent" yaml:"win_percent,omitempty" binding:"min=0,max=100"`
+	WinMinVotes int `gorm:"default:0" json:"win_min_votes" yaml:"win_min_votes,omitempty" binding:"min=0"`
+	// EligibleVoters 有投票资格的人数，剩余票数无法改变领先者时关闭
+	EligibleVoters int `gorm:"default:0" json:"eligible_voters" yaml:"eligible_voters,omitempty" binding:"min=0"`
+	// ClosesAt 截止时间
+	ClosesAt *time.Time `json:"closes_at,omitempty" yaml:"closes_at,omitempty"`
+}
+
+// 投票问卷关闭原因
+const (
+	CloseReasonManual       = "manual"
+	CloseReasonTarget       = "vote_target"
+	CloseReasonThreshold    = "option_threshold"
+	CloseReasonUnassailable = "unassailable_lead"
+	CloseReasonDeadline     = "deadline"
+	CloseReasonArchived     = "archived"
+)
+
+// 投票问卷结果可见性
+const (
+	VisibilityAlways     = "always"      // 始终可见
+	VisibilityAfterVote  = "after_vote"  // 投票后可见
+	VisibilityAfterClose = "after_close" // 关闭后可见，管理员也不例外
+	VisibilityAdminOnly  = "admin_only"  // 仅管理员可见
+)
+
+// 投票问卷的访问方式
+const (
+	AccessPublic  = "public"  // 任何人都可以投票
+	AccessPrivate = "private" // 需要访问码
+	AccessInvite  = "invite"  // 需要受邀人的一次性投票令牌
+)
+
+// 投票方式
+const (
+	ModePlurality = "plurality" // 单选，每人投一个选项
+	ModeApproval  = "approval"  // 赞成投票，每人可以选任意多个选项
+	ModeScore     = "score"     // 评分投票，每人为选项评0到MaxScore分，按平均分排名
+	ModeBorda     = "borda"     // 波达计数，每人对选项排序，按名次计分
+)
+
+// 评分投票的最高分
+const (
+	DefaultMaxScore = 5
+	MaxScoreLimit   = 100
+)
+
+// ValidMode 判断是否为支持的投票方式
+func ValidMode(v string) bool {
+	switch v {
+	case ModePlurality, ModeApproval, ModeScore, ModeBorda:
+		return true
+	}
+	return false
+}
+
+// ModeConflict 检查投票方式与最高分、自动关闭规则、自填选项和选项提议是否兼容，返回冲突的字段、校验规则和参数，兼容时field为空
+//
+// 单选以外的投票方式中一张选票包含多个选项，票数规则无法按人数判断，只支持截止时间；自填内容也只能投给一个选项。
+// 波达计数的得分取决于选项数，创建后不能再加入新选项，因此不接受选项提议。
+func ModeConflict(mode string, maxScore int, rules PollRules, writeIn, allowProposals bool) (field, rule, param string) {
+	if mode == "" {
+		mode = ModePlurality
+	}
+	switch {
+	case mode == ModeScore && maxScore < 1:
+		return "max_score", "min", "1"
+	case mode == ModeScore && maxScore > MaxScoreLimit:
+		return "max_score", "max", strconv.Itoa(MaxScoreLimit)
+	case mode != ModeScore && maxScore != 0:
+		return "max_score", "unsupported", ""
+	case mode == ModePlurality:
+		return "", "", ""
+	case writeIn:
+		return "write_in_option", "unsupported", ""
+	case mode == ModeBorda && allowProposals:
+		return "allow_proposals", "unsupported", ""
+	case rules.TargetVotes != 0:
+		return "rules.target_votes", "unsupported", ""
+	case rules.WinPercent != 0:
+		return "rules.win_percent", "unsupported", ""
+	case rules.WinMinVotes != 0:
+		return "rules.win_min_votes", "unsupported", ""
+	case rules.EligibleVoters != 0:
+		return "rules.eligible_voters", "unsupported", ""
+	}
+	return "", "", ""
+}
+
+// Plurality 判断是否为单选投票，升级前的投票问卷没有投票方式，视为单选
+func (p *Poll) Plurality() bool {
+	return p.Mode == "" || p.Mode == ModePlurality
+}
+
+// ValidAccess 判断是否为支持的访问方式
+func ValidAccess(v string) bool {
+	switch v {
+	case AccessPublic, AccessPrivate, AccessInvite:
+		return true
+	}
+	return false
+}
+
+// ValidVisibility 判断是否为支持的结果可见性
+func ValidVisibility(v string) bool {
+	switch v {
+	case VisibilityAlways, VisibilityAfterVote, VisibilityAfterClose, VisibilityAdminOnly:
+		return true
+	}
+	return false
+}
+
+// ResultsVisible 判断结果对访问者是否可见，voted表示访问者已投票，admin表示管理员
+func (p *Poll) ResultsVisible(voted, admin bool) bool {
+	switch p.ResultVisibility {
+	case VisibilityAfterVote:
+		return voted || admin || !p.IsActive
+	case VisibilityAfterClose:
+		return !p.IsActive
+	case VisibilityAdminOnly:
+		return admin
+	}
+	return true
+}
+
+// HideResults 清空各选项票数、加权票数和得分并标记结果已隐藏
+func (p *Poll) HideResults() {
+	for i := range p.Options {
+		p.Options[i].VoteCount = 0
+		p.Options[i].WeightedVotes = 0
+		p.Options[i].Points = 0
+		p.Options[i].Average = nil
+		p.Options[i].Distribution = nil
+	}
+	p.ResultsHidden = true
+}
+
+// PollTemplate 投票问卷模板，标题、描述和选项中可使用{{date}}等模板变量
+type PollTemplate struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	Name             string         `gorm:"size:128;not null" json:"name"`
+	Title            string         `gorm:"size:255;not null" json:"title"`
+	Description      string         `gorm:"type:text" json:"description"`
+	Options          []string       `gorm:"type:text;serializer:json" json:"options"`
+	ResultVisibility string         `gorm:"size:16;default:always" json:"result_visibility"`
+	// Rules 中的ClosesAt不使用，截止时间由CloseAfterMinutes在创建时计算
+	Rules             PollRules `gorm:"embedded" json:"rules"`
+	CloseAfterMinutes int       `gorm:"default:0" json:"close_after_minutes"`
 }
 
 // Option 选项模型
@@ -27,6 +215,16 @@ type Option struct {
 	PollID    uint           `gorm:"not null" json:"poll_id"`
 	Text      string         `gorm:"size:255;not null" json:"text"`
 	VoteCount int            `gorm:"default:0" json:"vote_count"`
+	// WeightedVotes 按投票人权重累计的票数，没有设置权重时与VoteCount相等
+	WeightedVotes weight.Weight `gorm:"not null;default:0" json:"weighted_votes"`
+	// WriteIn 自填选项（例如“其他”），投给该选项时需填写内容，内容经审核后可并入其他选项
+	WriteIn bool `gorm:"default:false" json:"write_in,omitempty"`
+	// Points 评分和波达计数中按投票人权重累计的得分，赞成投票中与WeightedVotes相等，单选投票中为0
+	Points weight.Weight `gorm:"not null;default:0" json:"points"`
+	// Average 每个加权票的平均得分，只用于评分和波达计数，不入库
+	Average *float64 `gorm:"-" json:"average,omitempty"`
+	// Distribution 评分投票中各分值、波达计数中各名次的人数，不入库
+	Distribution map[int]int `gorm:"-" json:"distribution,omitempty"`
 }
 
 // Vote 投票记录模型
@@ -37,18 +235,491 @@ type Vote struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	PollID    uint           `gorm:"not null" json:"poll_id"`
 	OptionID  uint           `gorm:"not null" json:"option_id"`
-	UserIP    string         `gorm:"size:45" json:"user_ip"`
+	// UserIP 投票人标识，默认为客户端IP，按请求头识别投票人时为请求头的值
+	UserIP string `gorm:"size:255" json:"user_ip"`
+	// 隐私模式下只保存投票人标识的HMAC，UserIP留空
+	VoterHash  string `gorm:"size:64;index" json:"-"`
+	VoterKeyID string `gorm:"size:16" json:"-"`
+	// WriteIn 投给自填选项时填写的内容，ProposalID为按内容归并的审核记录
+	WriteIn    string `gorm:"size:255" json:"write_in,omitempty"`
+	ProposalID *uint  `gorm:"index" json:"proposal_id,omitempty"`
+	// RollMemberID 有投票人名册的投票问卷中投票人在名册中的记录，用于统计投票率和解析委托，
+	// 与投票人标识一样能识别投票人，按保留期限清除标识时一并清除，见privacy.PurgeIdentifiers
+	RollMemberID *uint `gorm:"index" json:"-"`
+	// Weight 投票时投票人的权重，来自名册或投票令牌，之后修改名册不影响已投的票
+	Weight weight.Weight `gorm:"not null;default:1000000" json:"weight"`
+	// DelegateVoteID 关闭时按委托计入的投票所沿用的受托人投票，直接投票为空；重新开启时删除
+	DelegateVoteID *uint `gorm:"index" json:"delegate_vote_id,omitempty"`
+}
+
+// VoteMark 单选以外的投票方式中选票上的一个选项，所属投票记录的OptionID为0
+//
+// 选项的VoteCount为标记该选项的选票数，WeightedVotes和Points按投票记录的权重累计。
+type VoteMark struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	VoteID    uint      `gorm:"not null;index" json:"vote_id"`
+	PollID    uint      `gorm:"not null;index" json:"poll_id"`
+	OptionID  uint      `gorm:"not null;index" json:"option_id"`
+	// Value 评分投票中为分数，波达计数中为名次（从1开始），赞成投票中为1
+	Value int `gorm:"not null" json:"value"`
+	// Points 该选项得到的分数，波达计数中为选项数减名次
+	Points int `gorm:"not null" json:"points"`
+	// Weight 与投票记录的权重相同，便于按选项求和
+	Weight weight.Weight `gorm:"not null;default:1000000" json:"weight"`
+}
+
+// 审核记录的类型
+const (
+	ProposalKindOption  = "option"   // 投票人提议的新选项
+	ProposalKindWriteIn = "write_in" // 自填选项中相同内容的投票
+)
+
+// 审核记录的状态
+const (
+	ProposalPending  = "pending"
+	ProposalApproved = "approved" // 已作为新选项加入
+	ProposalMerged   = "merged"   // 已并入已有选项
+	ProposalRejected = "rejected"
+)
+
+// OptionProposal 待管理员审核的新选项，同一投票问卷中同类型、内容相同（不区分大小写）的只有一条
+//
+// 自填内容批准或合并后，相同内容的投票移到OptionID指向的选项，之后相同内容的新投票直接计入该选项。
+type OptionProposal struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	PollID    uint      `gorm:"not null;uniqueIndex:idx_proposal_key,priority:1" json:"poll_id"`
+	// Kind 取值见ProposalKind*常量
+	Kind string `gorm:"size:16;not null;uniqueIndex:idx_proposal_key,priority:2" json:"kind"`
+	Text string `gorm:"size:255;not null" json:"text"`
+	// Key 规范化后的内容，用于归并相同的提议
+	Key string `gorm:"size:255;not null;uniqueIndex:idx_proposal_key,priority:3" json:"-"`
+	// Status 取值见Proposal*常量
+	Status string `gorm:"size:16;not null;index" json:"status"`
+	// OptionID 批准后新建的选项或合并的目标选项
+	OptionID *uint `json:"option_id,omitempty"`
+	// ProposedBy 第一个提议的投票人，隐私模式下为HMAC
+	ProposedBy string     `gorm:"size:255;index" json:"-"`
+	ResolvedBy string     `gorm:"size:128" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	// Votes 填写该自填内容的票数，不入库
+	Votes int `gorm:"-" json:"votes"`
+}
+
+// BallotToken 邀请制投票问卷中受邀人的一次性投票令牌，只保存令牌的SHA-256
+//
+// 为了不能把令牌和所投的选项对应起来，令牌只记录是否已使用而不记录使用时间，投票记录中也不保存令牌和投票人标识。
+type BallotToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	PollID    uint      `gorm:"not null;index" json:"poll_id"`
+	// Label 受邀人备注，例如姓名或邮箱，可以为空
+	Label string `gorm:"size:255" json:"label"`
+	// Prefix 令牌的前几位，用于在列表中辨认令牌
+	Prefix    string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Used      bool       `gorm:"default:false" json:"used"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Weight 使用该令牌投票时的权重，只在生成令牌时返回；列表中同时给出备注和权重会把受邀人与投票记录的权重对应起来
+	Weight weight.Weight `gorm:"not null;default:1000000" json:"-"`
+}
+
+// VoterRoll 投票人名册，从CSV导入，关联到投票问卷后限定有投票资格的投票人
+type VoterRoll struct {
+	ID        uint         `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	Name      string       `gorm:"size:255;not null" json:"name"`
+	Members   []RollMember `gorm:"foreignKey:RollID" json:"members,omitempty"`
+	// MemberCount 名册人数，不入库
+	MemberCount int `gorm:"-" json:"member_count"`
+}
+
+// RollMember 名册中的一个投票人，Identifier与身份识别方式得到的投票人标识比较，不区分大小写
+type RollMember struct {
+	ID         uint   `gorm:"primarykey" json:"id"`
+	RollID     uint   `gorm:"not null;uniqueIndex:idx_roll_member,priority:1" json:"roll_id"`
+	Identifier string `gorm:"size:255;not null;uniqueIndex:idx_roll_member,priority:2" json:"identifier"`
+	Name       string `gorm:"size:255" json:"name,omitempty"`
+	// Weight 投票权重，默认为1
+	Weight weight.Weight `gorm:"not null;default:1000000" json:"weight"`
+	// Attributes 其他属性，例如部门
+	Attributes map[string]string `gorm:"type:text;serializer:json" json:"attributes,omitempty"`
+}
+
+// Delegation 投票人把投票委托给另一个投票人，PollID和Topic只有一个有效
+//
+// PollID不为0时只委托该投票问卷，否则委托带有Topic标签的所有投票问卷。委托可以传递，
+// 投票人自己投票时不使用委托；关闭投票问卷时按名册把委托人的投票计入受托人所选的选项。
+type Delegation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Delegator 和 Delegate 规范化后的投票人标识，隐私模式下为标识的HMAC，与名册中的标识比较
+	Delegator string `gorm:"size:255;not null;uniqueIndex:idx_delegation_scope,priority:1" json:"delegator"`
+	Delegate  string `gorm:"size:255;not null;index" json:"delegate"`
+	PollID    uint   `gorm:"not null;default:0;uniqueIndex:idx_delegation_scope,priority:2" json:"poll_id,omitempty"`
+	Topic     string `gorm:"size:32;not null;default:'';uniqueIndex:idx_delegation_scope,priority:3" json:"topic,omitempty"`
+}
+
+// Turnout 有投票人名册的投票问卷按名册统计的投票率
+type Turnout struct {
+	PollID     uint    `json:"poll_id"`
+	RollID     uint    `json:"roll_id"`
+	Eligible   int     `json:"eligible"`
+	Voted      int     `json:"voted"`
+	Percentage float64 `json:"percentage"`
 }
 
-// VoteRequest 投票请求结构
+// VoteRollup 按分钟汇总的选项票数，用于快速查询投票趋势
+type VoteRollup struct {
+	ID          uint      `gorm:"primarykey" json:"-"`
+	PollID      uint      `gorm:"not null;uniqueIndex:idx_rollup_bucket,priority:1" json:"poll_id"`
+	OptionID    uint      `gorm:"not null;uniqueIndex:idx_rollup_bucket,priority:2" json:"option_id"`
+	BucketStart time.Time `gorm:"not null;uniqueIndex:idx_rollup_bucket,priority:3" json:"bucket_start"`
+	Votes       int       `gorm:"not null;default:0" json:"votes"`
+}
+
+// Outbox事件类型
+const (
+	EventVoteCast    = "vote_cast"
+	EventVoteCleared = "vote_cleared"
+	EventPollReset   = "poll_reset"
+	EventPollCreated = "poll_created"
+	EventPollUpdated = "poll_updated"
+	EventPollOpened  = "poll_opened"
+	EventPollClosed  = "poll_closed"
+	// 选项提议和审核
+	EventOptionProposed   = "option_proposed"
+	EventOptionApproved   = "option_approved"
+	EventProposalMerged   = "proposal_merged"
+	EventProposalRejected = "proposal_rejected"
+	// EventThresholdCrossed 只用于webhook，在投票问卷总票数达到订阅的阈值时发送
+	EventThresholdCrossed = "threshold_crossed"
+	// 调查问卷
+	EventSurveyCreated  = "survey_created"
+	EventSurveyOpened   = "survey_opened"
+	EventSurveyClosed   = "survey_closed"
+	EventSurveyResponse = "survey_response"
+)
+
+// WebhookEvents 可订阅的webhook事件
+var WebhookEvents = []string{
+	EventVoteCast,
+	EventVoteCleared,
+	EventPollCreated,
+	EventPollUpdated,
+	EventPollOpened,
+	EventPollClosed,
+	EventPollReset,
+	EventOptionProposed,
+	EventOptionApproved,
+	EventProposalMerged,
+	EventProposalRejected,
+	EventThresholdCrossed,
+	EventSurveyCreated,
+	EventSurveyOpened,
+	EventSurveyClosed,
+	EventSurveyResponse,
+}
+
+// Webhook 外发webhook订阅，PollID为空表示订阅所有投票问卷
+type Webhook struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	PollID    *uint          `gorm:"index" json:"poll_id"`
+	URL       string         `gorm:"size:2048;not null" json:"url"`
+	Secret    string         `gorm:"size:128;not null" json:"-"`
+	// 逗号分隔的事件类型，为空表示订阅所有事件
+	Events    string `gorm:"size:512" json:"events"`
+	Threshold int    `gorm:"default:0" json:"threshold"`
+	Active    bool   `gorm:"default:true" json:"active"`
+}
+
+// WebhookThreshold 订阅的阈值事件已在投票问卷上发送过的标记，重置投票时清除
+type WebhookThreshold struct {
+	WebhookID uint      `gorm:"primaryKey;autoIncrement:false" json:"webhook_id"`
+	PollID    uint      `gorm:"primaryKey;autoIncrement:false" json:"poll_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIToken 管理接口的个人令牌，只保存令牌的SHA-256，吊销后软删除
+type APIToken struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Name      string         `gorm:"size:64;not null" json:"name"`
+	// Prefix 令牌的前几位，用于在列表中辨认令牌
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Webhook投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery 单次webhook投递及其重试状态，超过最大重试次数后进入死信状态
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	WebhookID      uint       `gorm:"not null;uniqueIndex:idx_delivery_event,priority:1" json:"webhook_id"`
+	EventID        uint       `gorm:"not null;uniqueIndex:idx_delivery_event,priority:2" json:"event_id"`
+	EventType      string     `gorm:"size:64;not null;uniqueIndex:idx_delivery_event,priority:3" json:"event_type"`
+	Payload        string     `gorm:"type:text" json:"payload"`
+	Status         string     `gorm:"size:16;index;not null" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// OutboxEvent 事务性发件箱事件，与业务数据在同一事务中写入，提交后由分发器投递
+type OutboxEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	PollID    uint      `gorm:"index;not null" json:"poll_id"`
+	// SurveyID 调查问卷的事件不属于投票问卷，PollID为0
+	SurveyID    uint       `gorm:"index;default:0" json:"survey_id,omitempty"`
+	Type        string     `gorm:"size:64;not null" json:"type"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	Attempts    int        `gorm:"default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt *time.Time `gorm:"index" json:"delivered_at,omitempty"`
+	// FailedAt 投递失败达到最大次数后不再重试
+	FailedAt *time.Time `gorm:"index" json:"failed_at,omitempty"`
+}
+
+// 审计日志动作
+const (
+	AuditPollCreated    = "poll.created"
+	AuditPollUpdated    = "poll.updated"
+	AuditPollOpened     = "poll.opened"
+	AuditPollClosed     = "poll.closed"
+	AuditPollReset      = "poll.reset"
+	AuditPollSeeded     = "poll.seeded"
+	AuditPollArchived   = "poll.archived"
+	AuditPollRestored   = "poll.restored"
+	AuditPollReconciled = "poll.reconciled"
+	AuditVoteCleared    = "vote.cleared"
+	AuditBallotsIssued  = "ballot.issued"
+	AuditBallotRevoked  = "ballot.revoked"
+	AuditRollImported   = "roll.imported"
+	AuditRollDeleted    = "roll.deleted"
+	// AuditIdentifiersPurged 按保留期限清除投票人标识，不属于单个投票问卷
+	AuditIdentifiersPurged = "identifiers.purged"
+
+	AuditProposalApproved = "proposal.approved"
+	AuditProposalMerged   = "proposal.merged"
+	AuditProposalRejected = "proposal.rejected"
+
+	AuditSurveyCreated = "survey.created"
+	AuditSurveyOpened  = "survey.opened"
+	AuditSurveyClosed  = "survey.closed"
+
+	AuditTemplateCreated    = "template.created"
+	AuditTemplateUpdated    = "template.updated"
+	AuditTemplateDeleted    = "template.deleted"
+	AuditWebhookCreated     = "webhook.created"
+	AuditWebhookUpdated     = "webhook.updated"
+	AuditWebhookDeleted     = "webhook.deleted"
+	AuditWebhookRedelivered = "webhook.redelivered"
+	AuditTokenCreated       = "token.created"
+	AuditTokenRevoked       = "token.revoked"
+)
+
+// AuditLog 只追加的审计日志，每条记录的Hash包含上一条记录的Hash形成哈希链
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Seq       uint64    `gorm:"uniqueIndex;not null" json:"seq"`
+	Action    string    `gorm:"size:64;index;not null" json:"action"`
+	Actor     string    `gorm:"size:128;index" json:"actor"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	RequestID string    `gorm:"size:64" json:"request_id"`
+	PollID    *uint     `gorm:"index" json:"poll_id,omitempty"`
+	Before    string    `gorm:"type:text" json:"before,omitempty"`
+	After     string    `gorm:"type:text" json:"after,omitempty"`
+	PrevHash  string    `gorm:"size:64" json:"prev_hash"`
+	Hash      string    `gorm:"size:64;not null" json:"hash"`
+}
+
+// AuditChainHead 审计哈希链的链头，追加日志时先锁定该行以保证链的顺序
+type AuditChainHead struct {
+	ID       uint   `gorm:"primarykey"`
+	Seq      uint64 `gorm:"not null"`
+	LastHash string `gorm:"size:64"`
+}
+
+// VoteRequest 投票请求结构，按投票方式提供OptionID、OptionIDs或Scores之一
 type VoteRequest struct {
+	// OptionID 单选投票的选项
+	OptionID uint `json:"option_id"`
+	// OptionIDs 赞成投票中赞成的选项，波达计数中按名次从高到低排列的选项
+	OptionIDs []uint `json:"option_ids" binding:"max=100"`
+	// Scores 评分投票中各选项的分数，未评分的选项不计入平均分
+	Scores []ScoreInput `json:"scores" binding:"max=100,dive"`
+	// WriteIn 投给自填选项时必填
+	WriteIn string `json:"write_in" binding:"max=255"`
+	// AccessCode 私有投票问卷的访问码
+	AccessCode string `json:"access_code" binding:"max=64"`
+	// BallotToken 邀请制投票问卷的投票令牌
+	BallotToken string `json:"ballot_token" binding:"max=128"`
+}
+
+// DelegationRequest 委托投票请求结构，PollID和Topic都为空时委托进行中的投票问卷
+type DelegationRequest struct {
+	PollID uint `json:"poll_id"`
+	// Topic 投票问卷的标签，委托带有该标签的所有投票问卷，不区分大小写
+	Topic string `json:"topic" binding:"max=32,excludesall=0x2C"`
+	// Delegate 受托人的投票人标识
+	Delegate string `json:"delegate" binding:"required,max=255"`
+}
+
+// ScoreInput 评分投票中一个选项的分数
+type ScoreInput struct {
+	OptionID uint `json:"option_id" binding:"required"`
+	Score    int  `json:"score" binding:"min=0"`
+}
+
+// ProposeOptionRequest 投票人提议新选项请求结构
+type ProposeOptionRequest struct {
+	Text string `json:"text" binding:"required,max=255"`
+}
+
+// MergeProposalRequest 将提议或自填内容并入已有选项请求结构
+type MergeProposalRequest struct {
 	OptionID uint `json:"option_id" binding:"required"`
 }
 
 // PollResponse 投票问卷响应结构
 type PollResponse struct {
-	Poll        Poll  `json:"poll"`
-	TotalVotes  int   `json:"total_votes"`
-	UserVoted   bool  `json:"user_voted"`
+	Poll Poll `json:"poll"`
+	// TotalVotes 选票数，单选投票中等于各选项票数之和
+	TotalVotes int `json:"total_votes"`
+	// TotalWeight 各选项加权票数之和
+	TotalWeight weight.Weight `json:"total_weight"`
+	UserVoted   bool          `json:"user_voted"`
+	// VotedOption 单选投票中投票人选择的选项
 	VotedOption *uint `json:"voted_option,omitempty"`
+	// Turnout 有投票人名册时按名册统计的投票率
+	Turnout *Turnout `json:"turnout,omitempty"`
+}
+
+// OptionInput 创建或编辑投票问卷时的选项，ID为0表示新增
+type OptionInput struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text" binding:"required,max=255"`
+}
+
+// CreatePollRequest 创建投票问卷请求结构
+type CreatePollRequest struct {
+	Title       string   `json:"title" binding:"required,max=255"`
+	Description string   `json:"description"`
+	Options     []string `json:"options" binding:"required,min=2,dive,required,max=255"`
+	// Tags 标签，不区分大小写，不能包含逗号
+	Tags     []string `json:"tags" binding:"max=10,dive,required,max=32,excludesall=0x2C"`
+	IsActive *bool    `json:"is_active"`
+	// ResultVisibility 为空时默认始终可见
+	ResultVisibility string     `json:"result_visibility"`
+	Rules            *PollRules `json:"rules"`
+	// WriteInOption 非空时追加一个该文本的自填选项
+	WriteInOption  string `json:"write_in_option" binding:"max=255"`
+	AllowProposals bool   `json:"allow_proposals"`
+	// Access 为空时默认公开，private时必须提供AccessCode
+	Access     string `json:"access"`
+	AccessCode string `json:"access_code" binding:"omitempty,min=4,max=64"`
+	// RollID 投票人名册，只有名册中的投票人可以投票
+	RollID *uint `json:"roll_id"`
+	// Mode 投票方式，为空时为单选
+	Mode string `json:"mode"`
+	// MaxScore 评分投票的最高分，为0时为DefaultMaxScore
+	MaxScore int `json:"max_score" binding:"min=0"`
+}
+
+// UpdatePollRequest 编辑投票问卷请求结构，未提供的字段保持不变
+type UpdatePollRequest struct {
+	Title       *string       `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string       `json:"description"`
+	Options     []OptionInput `json:"options" binding:"omitempty,dive"`
+	// Tags 提供时整体替换标签，空数组表示清除
+	Tags             []string `json:"tags" binding:"omitempty,max=10,dive,required,max=32,excludesall=0x2C"`
+	ResultVisibility *string  `json:"result_visibility"`
+	// Rules 提供时整体替换自动关闭规则
+	Rules *PollRules `json:"rules"`
+	// WriteInOption 没有自填选项时追加，已有时修改其文本
+	WriteInOption  *string `json:"write_in_option" binding:"omitempty,min=1,max=255"`
+	AllowProposals *bool   `json:"allow_proposals"`
+	Access         *string `json:"access"`
+	// AccessCode 提供时更换私有投票问卷的访问码，改为private且原来没有访问码时必须提供
+	AccessCode *string `json:"access_code" binding:"omitempty,min=4,max=64"`
+	// RollID 更换投票人名册，为0时取消名册限制
+	RollID *uint `json:"roll_id"`
+}
+
+// IssueBallotsRequest 批量生成投票令牌请求结构，Labels非空时为每个受邀人生成一个，否则生成Count个
+type IssueBallotsRequest struct {
+	Count  int      `json:"count" binding:"min=0,max=10000"`
+	Labels []string `json:"labels" binding:"max=10000,dive,max=255"`
+	// Weight 令牌的投票权重，十进制小数，为空时为1
+	Weight string `json:"weight" binding:"max=32"`
+	// Weights 与Labels一一对应的权重，提供时覆盖Weight
+	Weights []string `json:"weights" binding:"max=10000,dive,max=32"`
+	// BaseURL 投票页面地址，令牌以ballot参数附加在后面，为空时使用请求的地址
+	BaseURL string `json:"base_url" binding:"omitempty,url,max=1024"`
+}
+
+// CreateWebhookRequest 创建webhook订阅请求结构，未提供Secret时自动生成
+type CreateWebhookRequest struct {
+	PollID    *uint    `json:"poll_id"`
+	URL       string   `json:"url" binding:"required,url,max=2048"`
+	Secret    string   `json:"secret" binding:"max=128"`
+	Events    []string `json:"events"`
+	Threshold int      `json:"threshold" binding:"min=0"`
+	Active    *bool    `json:"active"`
+}
+
+// CreateTokenRequest 创建管理接口令牌请求结构
+type CreateTokenRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// UpdateWebhookRequest 编辑webhook订阅请求结构，未提供的字段保持不变
+type UpdateWebhookRequest struct {
+	URL       *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Secret    *string  `json:"secret" binding:"omitempty,min=1,max=128"`
+	Events    []string `json:"events"`
+	Threshold *int     `json:"threshold" binding:"omitempty,min=0"`
+	Active    *bool    `json:"active"`
+}
+
+// TemplateRequest 创建或编辑投票问卷模板请求结构
+type TemplateRequest struct {
+	Name              string     `json:"name" binding:"required,max=128"`
+	Title             string     `json:"title" binding:"required,max=255"`
+	Description       string     `json:"description"`
+	Options           []string   `json:"options" binding:"required,min=2,dive,required,max=255"`
+	ResultVisibility  string     `json:"result_visibility"`
+	Rules             *PollRules `json:"rules"`
+	CloseAfterMinutes int        `json:"close_after_minutes" binding:"min=0"`
+}
+
+// InstantiateTemplateRequest 根据模板创建投票问卷请求结构，Variables用于填充自定义模板变量
+type InstantiateTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+	IsActive  *bool             `json:"is_active"`
+}
+
+// ClonePollRequest 复制投票问卷请求结构，未提供Title时沿用原标题
+type ClonePollRequest struct {
+	Title    *string `json:"title" binding:"omitempty,min=1,max=255"`
+	IsActive *bool   `json:"is_active"`
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"gorm.io/driver/sqlite"
@@ -230,3 +232,62 @@ func TestPollResponse(t *testing.T) {
 		t.Errorf("期望投票选项ID 2, 得到 %v", response.VotedOption)
 	}
 }
+
+func TestResultsVisible(t *testing.T) {
+	cases := []struct {
+		visibility string
+		active     bool
+		voted      bool
+		admin      bool
+		expected   bool
+	}{
+		{VisibilityAlways, true, false, false, true},
+		{"", true, false, false, true},
+		{VisibilityAfterVote, true, false, false, false},
+		{VisibilityAfterVote, true, true, false, true},
+		{VisibilityAfterVote, true, false, true, true},
+		{VisibilityAfterVote, false, false, false, true},
+		{VisibilityAfterClose, true, true, true, false},
+		{VisibilityAfterClose, false, false, false, true},
+		{VisibilityAdminOnly, true, true, false, false},
+		{VisibilityAdminOnly, false, false, false, false},
+		{VisibilityAdminOnly, true, false, true, true},
+	}
+
+	for _, tc := range cases {
+		poll := Poll{ResultVisibility: tc.visibility, IsActive: tc.active}
+		if got := poll.ResultsVisible(tc.voted, tc.admin); got != tc.expected {
+			t.Errorf("%+v: 期望 %v, 得到 %v", tc, tc.expected, got)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	tags := NormalizeTags([]string{" Team ", "team", "", "Q3"})
+	if !tags.Equal(Tags{"team", "q3"}) {
+		t.Errorf("期望 [team q3], 得到 %v", tags)
+	}
+
+	db := setupTestDB()
+	db.AutoMigrate(&Poll{})
+	poll := Poll{Title: "标签", Tags: tags}
+	db.Create(&poll)
+
+	var stored string
+	db.Raw("SELECT tags FROM polls WHERE id = ?", poll.ID).Scan(&stored)
+	if stored != ",team,q3," {
+		t.Errorf("标签应以逗号包围保存, 得到 %q", stored)
+	}
+
+	var loaded Poll
+	db.First(&loaded, poll.ID)
+	if !loaded.Tags.Equal(tags) {
+		t.Errorf("期望 %v, 得到 %v", tags, loaded.Tags)
+	}
+
+	// 没有标签时输出空数组
+	data, _ := json.Marshal(Poll{})
+	if !strings.Contains(string(data), `"tags":[]`) {
+		t.Errorf("没有标签时应输出空数组: %s", data)
+	}
+}
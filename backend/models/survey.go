@@ -0,0 +1,130 @@
+package models
+
+import "time"
+
+// 调查问卷的问题类型
+const (
+	QuestionSingleChoice   = "single_choice"
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionRating         = "rating"
+	QuestionText           = "text"
+)
+
+// 调查问卷的限制
+const (
+	MaxSurveyQuestions = 100
+	MaxQuestionChoices = 50
+	MaxRatingScale     = 10
+	MaxTextAnswer      = 2000
+	MaxBranchRules     = 50
+)
+
+// BranchRule 问题的跳转规则，回答满足条件时跳到GoTo指定的问题，中间的问题不再显示
+//
+// 选择题按Choice判断是否选中该选项，评分题按MinRating、MaxRating判断分值是否在范围内（包含两端）。
+// 规则按顺序匹配，都不满足或未作答时进入下一个问题。
+type BranchRule struct {
+	Choice    *int `json:"choice,omitempty"`
+	MinRating *int `json:"min_rating,omitempty"`
+	MaxRating *int `json:"max_rating,omitempty"`
+	// GoTo 跳转到的问题序号（position），只能向后跳转；0表示结束问卷
+	GoTo int `json:"goto" binding:"min=0"`
+}
+
+// Survey 由多个按顺序排列的问题组成的调查问卷，投票人一次提交所有问题的答案
+type Survey struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	IsActive    bool       `gorm:"default:true" json:"is_active"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+	// Owner 创建者，与投票问卷相同
+	Owner     string           `gorm:"size:128;index" json:"owner,omitempty"`
+	Questions []SurveyQuestion `gorm:"foreignKey:SurveyID" json:"questions"`
+}
+
+// SurveyQuestion 调查问卷中的一个问题，选择题的答案以Choices中的下标保存
+type SurveyQuestion struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	SurveyID uint   `gorm:"not null;index" json:"survey_id"`
+	Position int    `gorm:"not null" json:"position"`
+	Type     string `gorm:"size:32;not null" json:"type"`
+	Title    string `gorm:"size:255;not null" json:"title"`
+	Required bool   `gorm:"default:false" json:"required"`
+	// Choices 单选题和多选题的选项
+	Choices []string `gorm:"type:text;serializer:json" json:"choices,omitempty"`
+	// MaxChoices 多选题最多可选的项数，0为不限
+	MaxChoices int `gorm:"default:0" json:"max_choices,omitempty"`
+	// MinRating、MaxRating 评分题的分值范围
+	MinRating int `gorm:"default:0" json:"min_rating,omitempty"`
+	MaxRating int `gorm:"default:0" json:"max_rating,omitempty"`
+	// Branches 回答该问题后的跳转规则
+	Branches []BranchRule `gorm:"type:text;serializer:json" json:"branches,omitempty"`
+}
+
+// SurveyResponse 投票人对调查问卷的一次完整作答，每个投票人只能提交一次
+type SurveyResponse struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	SurveyID  uint      `gorm:"not null;index" json:"survey_id"`
+	UserIP    string    `gorm:"size:255" json:"-"`
+	// 隐私模式下只保存投票人标识的HMAC，与投票记录相同
+	VoterHash  string         `gorm:"size:64;index" json:"-"`
+	VoterKeyID string         `gorm:"size:16" json:"-"`
+	Answers    []SurveyAnswer `gorm:"foreignKey:ResponseID" json:"answers"`
+}
+
+// SurveyAnswer 对单个问题的回答，未作答的可选问题不保存
+type SurveyAnswer struct {
+	ID         uint `gorm:"primarykey" json:"id"`
+	ResponseID uint `gorm:"not null;index" json:"response_id"`
+	QuestionID uint `gorm:"not null;index" json:"question_id"`
+	// Choices 选择题选中的选项下标，按升序保存
+	Choices []int  `gorm:"type:text;serializer:json" json:"choices,omitempty"`
+	Rating  *int   `json:"rating,omitempty"`
+	Text    string `gorm:"type:text" json:"text,omitempty"`
+}
+
+// SurveyView 投票人看到的调查问卷
+type SurveyView struct {
+	Survey    Survey `json:"survey"`
+	Responded bool   `json:"responded"`
+}
+
+// SurveyQuestionInput 创建调查问卷时的问题
+type SurveyQuestionInput struct {
+	Type       string   `json:"type" binding:"required,oneof=single_choice multiple_choice rating text"`
+	Title      string   `json:"title" binding:"required,max=255"`
+	Required   bool     `json:"required"`
+	Choices    []string `json:"choices" binding:"omitempty,max=50,dive,required,max=255"`
+	MaxChoices int      `json:"max_choices" binding:"min=0"`
+	// MinRating、MaxRating 评分题的分值范围，都为0时默认1到5
+	MinRating int `json:"min_rating" binding:"min=0"`
+	MaxRating int `json:"max_rating" binding:"min=0"`
+	// Branches 跳转规则，只用于选择题和评分题
+	Branches []BranchRule `json:"branches" binding:"omitempty,max=50,dive"`
+}
+
+// CreateSurveyRequest 创建调查问卷请求结构
+type CreateSurveyRequest struct {
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description"`
+	// IsActive 为空时默认开启
+	IsActive  *bool                 `json:"is_active"`
+	Questions []SurveyQuestionInput `json:"questions" binding:"required,min=1,max=100,dive"`
+}
+
+// SurveyAnswerInput 单个问题的回答，按问题类型填写Choices、Rating或Text
+type SurveyAnswerInput struct {
+	QuestionID uint   `json:"question_id" binding:"required"`
+	Choices    []int  `json:"choices"`
+	Rating     *int   `json:"rating"`
+	Text       string `json:"text" binding:"max=2000"`
+}
+
+// SubmitSurveyRequest 提交调查问卷请求结构，包含所有问题的回答
+type SubmitSurveyRequest struct {
+	Answers []SurveyAnswerInput `json:"answers" binding:"dive"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 标签的数量和长度限制
+const (
+	MaxTags      = 10
+	MaxTagLength = 32
+)
+
+// Tags 投票问卷的标签，以 ",a,b," 的形式保存在一列中，按单个标签筛选时使用 LIKE '%,a,%'
+type Tags []string
+
+// NormalizeTags 去除首尾空白、转为小写并去重，保持原有顺序，空标签被忽略
+func NormalizeTags(tags []string) Tags {
+	normalized := Tags{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// Equal 判断两组标签是否相同，顺序敏感
+func (t Tags) Equal(other Tags) bool {
+	if len(t) != len(other) {
+		return false
+	}
+	for i := range t {
+		if t[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GormDataType 以字符串列保存
+func (Tags) GormDataType() string {
+	return "string"
+}
+
+// Value 实现driver.Valuer，没有标签时保存为空字符串
+func (t Tags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	return "," + strings.Join(t, ",") + ",", nil
+}
+
+// Scan 实现sql.Scanner
+func (t *Tags) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported tags value: %T", value)
+	}
+
+	*t = Tags{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON 没有标签时输出空数组而不是null
+func (t Tags) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
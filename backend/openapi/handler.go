@@ -0,0 +1,60 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// UIPath 接口文档页面的路径，Swagger UI的脚本和样式在其下
+const UIPath = "/docs"
+
+//go:embed ui/index.html
+var uiPage []byte
+
+// Docs 输出OpenAPI文档和Swagger UI页面，文档在第一次请求时生成，只序列化一次
+type Docs struct {
+	build  func() *Document
+	once   sync.Once
+	data   []byte
+	assets http.Handler
+}
+
+// NewDocs 创建Docs，build在所有路由注册完成后才会被调用
+func NewDocs(build func() *Document) *Docs {
+	return &Docs{
+		build:  build,
+		assets: http.StripPrefix(UIPath, http.FileServer(http.FS(swaggerfiles.FS))),
+	}
+}
+
+// Spec 输出OpenAPI文档
+func (d *Docs) Spec(c *gin.Context) {
+	d.once.Do(func() {
+		data, err := json.MarshalIndent(d.build(), "", "  ")
+		if err != nil {
+			panic("openapi: failed to encode document: " + err.Error())
+		}
+		d.data = data
+	})
+	c.Data(http.StatusOK, "application/json; charset=utf-8", d.data)
+}
+
+// UI 返回Swagger UI页面，从同源的/openapi.json读取文档
+func (d *Docs) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", uiPage)
+}
+
+// Asset 返回内嵌的Swagger UI脚本和样式，无需访问外网；目录本身返回文档页面
+func (d *Docs) Asset(c *gin.Context) {
+	if name := c.Param("filepath"); name == "/" || strings.HasSuffix(name, "/index.html") {
+		d.UI(c)
+		return
+	}
+	d.assets.ServeHTTP(c.Writer, c.Request)
+}
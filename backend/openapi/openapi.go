@@ -0,0 +1,386 @@
+// Package openapi 根据路由表和请求、响应类型生成OpenAPI 3文档
+//
+// 路径、方法和路径参数取自Gin的路由表，接口说明按处理函数登记（见handlers.OpenAPI），
+// 请求和响应的结构由Go类型通过反射生成，字段名取自json标签，长度、取值范围等约束取自binding标签，保证文档与实现一致。
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"vote-system/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version 生成的文档遵循的OpenAPI版本
+const Version = "3.0.3"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info 文档的基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Tag 接口分组
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem 同一路径下各HTTP方法的接口，键为小写的方法名
+type PathItem map[string]*Operation
+
+// Operation 单个接口
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter 路径、查询或请求头参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 响应
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 请求体或响应体的内容
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components 可复用的结构和认证方式
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 认证方式
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	Description  string `json:"description,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Param 查询参数或请求头的声明
+type Param struct {
+	Name        string
+	Description string
+	// Type 参数类型：string、integer、boolean，为空时为string
+	Type string
+	// Format 例如 date-time
+	Format   string
+	Enum     []string
+	Required bool
+	// Header 为true时是请求头，否则是查询参数
+	Header bool
+}
+
+// Op 接口说明
+type Op struct {
+	// ID 接口的operationId，生成客户端时作为方法名，为空时由处理函数名生成
+	ID          string
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Param
+	// Request 请求体的类型，nil表示没有请求体
+	Request interface{}
+	// RequestTypes 请求体的媒体类型，为空时为application/json；JSON和YAML以外的请求体为字符串
+	RequestTypes []string
+	// OptionalBody 为true时请求体可以为空
+	OptionalBody bool
+	// Status 成功时的状态码，为0时为200
+	Status int
+	// Response 成功响应的类型，nil表示没有响应体
+	Response interface{}
+	// ResponseTypes 成功响应的媒体类型，为空时为application/json；JSON和YAML以外的响应为二进制
+	ResponseTypes []string
+	// Errors 可能返回的错误码，状态码由apierror映射
+	Errors []apierror.Code
+}
+
+// Builder 按路由表添加接口生成文档
+type Builder struct {
+	doc     *Document
+	schemas *schemaRegistry
+	groups  []*Group
+	// ops 按处理函数名登记的接口说明
+	ops map[string]Op
+}
+
+// Group 共享路径前缀、分组和认证方式的一组接口
+type Group struct {
+	b        *Builder
+	prefix   string
+	security []map[string][]string
+	errors   []apierror.Code
+}
+
+// BearerAuth 管理接口使用的认证方式名称
+const BearerAuth = "bearerAuth"
+
+// New 创建文档
+func New(info Info, tags ...Tag) *Builder {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Tags:    tags,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+		},
+	}
+	b := &Builder{doc: doc, schemas: newSchemaRegistry(doc.Components.Schemas), ops: map[string]Op{}}
+	// 所有错误响应使用同一个结构
+	b.schemas.schema(apierror.Response{})
+	return b
+}
+
+// Document 返回生成的文档
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+// Group 创建路径前缀为prefix的接口组
+func (b *Builder) Group(prefix string) *Group {
+	g := &Group{b: b, prefix: prefix}
+	b.groups = append(b.groups, g)
+	return g
+}
+
+// BearerGroup 创建需要Bearer令牌认证的接口组，errors为认证失败时的错误码
+func (b *Builder) BearerGroup(prefix, description string, errors ...apierror.Code) *Group {
+	if b.doc.Components.SecuritySchemes == nil {
+		b.doc.Components.SecuritySchemes = map[string]*SecurityScheme{}
+	}
+	b.doc.Components.SecuritySchemes[BearerAuth] = &SecurityScheme{Type: "http", Scheme: "bearer", Description: description}
+	g := &Group{
+		b:        b,
+		prefix:   prefix,
+		security: []map[string][]string{{BearerAuth: {}}},
+		errors:   errors,
+	}
+	b.groups = append(b.groups, g)
+	return g
+}
+
+// HandlerName 返回处理函数的名称，与gin.RouteInfo.Handler一致；方法值和方法表达式得到相同的名称
+func HandlerName(handler interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// Describe 登记处理函数的接口说明，handler通常为方法表达式，例如 (*PollHandler).GetPoll
+func (b *Builder) Describe(handler interface{}, op Op) {
+	b.ops[HandlerName(handler)] = op
+}
+
+// AddRoutes 按路由表添加接口，认证方式和公共错误码取自路径前缀最长的接口组
+//
+// 返回没有登记说明的路由，这些路由仍写入文档，operationId由处理函数名生成。
+func (b *Builder) AddRoutes(routes gin.RoutesInfo) []gin.RouteInfo {
+	var undescribed []gin.RouteInfo
+	for _, route := range routes {
+		name := strings.TrimSuffix(route.Handler, "-fm")
+		op, ok := b.ops[name]
+		if !ok {
+			undescribed = append(undescribed, route)
+		}
+		if op.ID == "" {
+			op.ID = operationID(name)
+		}
+
+		group := b.root()
+		for _, g := range b.groups {
+			if strings.HasPrefix(route.Path, g.prefix+"/") && len(g.prefix) > len(group.prefix) {
+				group = g
+			}
+		}
+		group.Add(route.Method, strings.TrimPrefix(route.Path, group.prefix), op)
+	}
+	return undescribed
+}
+
+// root 返回没有路径前缀的接口组
+func (b *Builder) root() *Group {
+	for _, g := range b.groups {
+		if g.prefix == "" {
+			return g
+		}
+	}
+	return b.Group("")
+}
+
+// operationID 由处理函数名生成operationId，例如 vote-system/handlers.(*PollHandler).GetPoll 生成 getPoll
+func operationID(handler string) string {
+	name := handler[strings.LastIndex(handler, ".")+1:]
+	if name == "" {
+		return handler
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// ginParam 匹配Gin路径参数 :id 和通配参数 *filepath
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Path 将Gin路径转换为OpenAPI路径，例如 /polls/:id 转换为 /polls/{id}
+func Path(ginPath string) string {
+	return ginParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+// Add 添加接口，path使用Gin的路径写法；通常由AddRoutes按路由表调用
+func (g *Group) Add(method, path string, op Op) {
+	b := g.b
+	full := g.prefix + path
+	operation := &Operation{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Responses:   map[string]*Response{},
+		Security:    g.security,
+	}
+	if op.Tag != "" {
+		operation.Tags = []string{op.Tag}
+	}
+
+	for _, match := range ginParam.FindAllStringSubmatch(full, -1) {
+		// :id 为数据库ID，通配参数为剩余的路径
+		schema := &Schema{Type: "integer", Minimum: float(1)}
+		if strings.HasPrefix(match[0], "*") {
+			schema = &Schema{Type: "string"}
+		}
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+	for _, p := range op.Query {
+		schema := &Schema{Type: p.Type, Format: p.Format}
+		if schema.Type == "" {
+			schema.Type = "string"
+		}
+		for _, v := range p.Enum {
+			schema.Enum = append(schema.Enum, v)
+		}
+		in := "query"
+		if p.Header {
+			in = "header"
+		}
+		operation.Parameters = append(operation.Parameters, Parameter{
+			Name:        p.Name,
+			In:          in,
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      schema,
+		})
+	}
+
+	if op.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: !op.OptionalBody,
+			Content:  b.content(op.Request, op.RequestTypes, &Schema{Type: "string"}),
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		success.Content = b.content(op.Response, op.ResponseTypes, &Schema{Type: "string", Format: "binary"})
+	}
+	operation.Responses[strconv.Itoa(status)] = success
+
+	// 同一状态码的错误码合并到一个响应中
+	codes := map[int][]string{}
+	for _, code := range append(append([]apierror.Code{}, g.errors...), op.Errors...) {
+		codes[code.Status()] = append(codes[code.Status()], string(code))
+	}
+	codes[http.StatusInternalServerError] = append(codes[http.StatusInternalServerError], string(apierror.Internal))
+	for status, list := range codes {
+		sort.Strings(list)
+		list = dedupe(list)
+		operation.Responses[strconv.Itoa(status)] = &Response{
+			Description: "错误码: " + strings.Join(list, ", "),
+			Content: map[string]MediaType{
+				"application/json": {Schema: b.schemas.schema(apierror.Response{})},
+			},
+		}
+	}
+
+	key := Path(full)
+	item, ok := b.doc.Paths[key]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[key] = item
+	}
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// content 生成请求体或响应体的内容，JSON和YAML使用v的结构，其他媒体类型使用fallback
+func (b *Builder) content(v interface{}, types []string, fallback *Schema) map[string]MediaType {
+	if len(types) == 0 {
+		types = []string{"application/json"}
+	}
+	content := map[string]MediaType{}
+	for _, t := range types {
+		if t == "application/json" || t == "application/yaml" {
+			content[t] = MediaType{Schema: b.schemas.schema(v)}
+		} else {
+			content[t] = MediaType{Schema: fallback}
+		}
+	}
+	return content
+}
+
+func dedupe(list []string) []string {
+	out := list[:0]
+	for i, v := range list {
+		if i == 0 || v != list[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"vote-system/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type itemResponse struct {
+	ID       uint          `json:"id"`
+	Name     string        `json:"name"`
+	Note     string        `json:"note,omitempty"`
+	ClosesAt *time.Time    `json:"closes_at"`
+	Parent   *itemResponse `json:"parent,omitempty"`
+	internal string
+}
+
+type createItemRequest struct {
+	Name   string   `json:"name" binding:"required,max=10"`
+	Tags   []string `json:"tags" binding:"required,min=2,dive,required,max=5"`
+	Kind   string   `json:"kind" binding:"omitempty,oneof=a b"`
+	Target string   `json:"target" binding:"url"`
+	Note   string   `json:"note"`
+}
+
+func TestPath(t *testing.T) {
+	if got := Path("/polls/:id/history"); got != "/polls/{id}/history" {
+		t.Errorf("期望 /polls/{id}/history, 得到 %s", got)
+	}
+}
+
+func TestSchema_Response(t *testing.T) {
+	b := New(Info{Title: "test", Version: "1"})
+	ref := b.schemas.schema(itemResponse{})
+	if ref.Ref != "#/components/schemas/itemResponse" {
+		t.Fatalf("具名结构体应通过$ref引用, 得到 %+v", ref)
+	}
+	s := b.doc.Components.Schemas["itemResponse"]
+	if len(s.Properties) != 5 {
+		t.Errorf("期望 5 个字段, 得到 %d", len(s.Properties))
+	}
+	if got := s.Properties["closes_at"]; got.Format != "date-time" || !got.Nullable {
+		t.Errorf("时间指针应为可空的date-time, 得到 %+v", got)
+	}
+	if got := s.Properties["parent"]; got.Ref != ref.Ref {
+		t.Errorf("自引用应指向自身, 得到 %+v", got)
+	}
+	want := []string{"id", "name"}
+	if len(s.Required) != len(want) || s.Required[0] != want[0] || s.Required[1] != want[1] {
+		t.Errorf("期望必填字段 %v, 得到 %v", want, s.Required)
+	}
+}
+
+func TestSchema_RequestBinding(t *testing.T) {
+	b := New(Info{Title: "test", Version: "1"})
+	b.schemas.schema(createItemRequest{})
+	s := b.doc.Components.Schemas["createItemRequest"]
+
+	if len(s.Required) != 2 || s.Required[0] != "name" || s.Required[1] != "tags" {
+		t.Errorf("请求结构体只有binding:\"required\"的字段必填, 得到 %v", s.Required)
+	}
+	if got := s.Properties["name"]; got.MaxLength == nil || *got.MaxLength != 10 {
+		t.Errorf("name 应有 maxLength=10, 得到 %+v", got)
+	}
+	tags := s.Properties["tags"]
+	if tags.MinItems == nil || *tags.MinItems != 2 {
+		t.Errorf("tags 应有 minItems=2, 得到 %+v", tags)
+	}
+	if tags.Items.MinLength == nil || *tags.Items.MinLength != 1 || tags.Items.MaxLength == nil || *tags.Items.MaxLength != 5 {
+		t.Errorf("dive之后的规则应作用于列表元素, 得到 %+v", tags.Items)
+	}
+	if got := s.Properties["kind"]; len(got.Enum) != 2 {
+		t.Errorf("oneof 应转换为enum, 得到 %+v", got)
+	}
+	if got := s.Properties["target"]; got.Format != "uri" {
+		t.Errorf("url 应转换为format=uri, 得到 %+v", got)
+	}
+}
+
+func TestAdd_Responses(t *testing.T) {
+	b := New(Info{Title: "test", Version: "1"})
+	admin := b.BearerGroup("/admin", "", apierror.Unauthorized)
+	admin.Add(http.MethodPost, "/items/:id", Op{
+		ID:       "createItem",
+		Request:  createItemRequest{},
+		Status:   http.StatusCreated,
+		Response: itemResponse{},
+		Errors:   []apierror.Code{apierror.InvalidRequest, apierror.ValidationFailed, apierror.PollNotFound},
+	})
+
+	item, ok := b.Document().Paths["/admin/items/{id}"]
+	if !ok {
+		t.Fatal("路径应转换为OpenAPI写法")
+	}
+	op := (*item)["post"]
+	if len(op.Security) != 1 || len(op.Parameters) != 1 || op.Parameters[0].In != "path" {
+		t.Errorf("认证或路径参数不正确: %+v", op)
+	}
+	for status, desc := range map[string]string{
+		"201": "Created",
+		"400": "错误码: invalid_request, validation_failed",
+		"401": "错误码: unauthorized",
+		"404": "错误码: poll_not_found",
+		"500": "错误码: internal_error",
+	} {
+		resp, ok := op.Responses[status]
+		if !ok || resp.Description != desc {
+			t.Errorf("响应 %s: 期望 %q, 得到 %+v", status, desc, resp)
+		}
+	}
+}
+
+type itemHandler struct{}
+
+func (h *itemHandler) GetItem(c *gin.Context)     {}
+func (h *itemHandler) ListFiles(c *gin.Context)   {}
+func (h *itemHandler) Undescribed(c *gin.Context) {}
+
+func TestAddRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h := &itemHandler{}
+	router.GET("/admin/items/:id", h.GetItem)
+	router.GET("/files/*filepath", h.ListFiles)
+	router.DELETE("/items/:id", h.Undescribed)
+
+	b := New(Info{Title: "test", Version: "1"})
+	b.BearerGroup("/admin", "", apierror.Unauthorized)
+	b.Describe((*itemHandler).GetItem, Op{ID: "getItem", Summary: "获取", Response: itemResponse{}})
+	b.Describe((*itemHandler).ListFiles, Op{Summary: "文件"})
+	undescribed := b.AddRoutes(router.Routes())
+
+	if len(undescribed) != 1 || undescribed[0].Path != "/items/:id" {
+		t.Errorf("期望 /items/:id 没有说明, 得到 %+v", undescribed)
+	}
+	paths := b.Document().Paths
+	if op := (*paths["/admin/items/{id}"])["get"]; op == nil || op.Summary != "获取" || len(op.Security) != 1 {
+		t.Errorf("方法值应匹配方法表达式登记的说明并使用接口组的认证, 得到 %+v", op)
+	}
+	files := (*paths["/files/{filepath}"])["get"]
+	if files == nil || files.OperationID != "listFiles" || files.Parameters[0].Schema.Type != "string" {
+		t.Errorf("通配参数应为字符串, operationId由函数名生成, 得到 %+v", files)
+	}
+	if op := (*paths["/items/{id}"])["delete"]; op == nil || op.OperationID != "undescribed" || len(op.Security) != 0 {
+		t.Errorf("没有说明的路由也应写入文档, 得到 %+v", op)
+	}
+}
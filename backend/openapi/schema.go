@@ -0,0 +1,266 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"vote-system/weight"
+)
+
+// Schema JSON结构描述，只包含生成文档用到的字段
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+	weightType  = reflect.TypeOf(weight.Weight(0))
+)
+
+// schemaRegistry 将Go类型转换为结构描述，具名结构体注册到components中并以$ref引用
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry(schemas map[string]*Schema) *schemaRegistry {
+	return &schemaRegistry{schemas: schemas, names: map[reflect.Type]string{}}
+}
+
+// schema 返回值v的类型对应的结构描述
+func (r *schemaRegistry) schema(v interface{}) *Schema {
+	return r.of(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) of(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{}
+	case weightType:
+		// 权重以十进制字符串表示
+		return &Schema{Type: "string", Format: "decimal"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return r.of(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + r.register(t)}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.of(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
+
+// register 注册具名结构体并返回名称，不同包中的同名类型以包名区分
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := r.schemas[name]; taken {
+		pkg := []rune(path.Base(t.PkgPath()))
+		pkg[0] = unicode.ToUpper(pkg[0])
+		name = string(pkg) + name
+	}
+	r.names[t] = name
+	// 先占位，自引用的结构体可以引用自身
+	r.schemas[name] = &Schema{}
+	*r.schemas[name] = *r.object(t)
+	return name
+}
+
+// object 生成结构体的结构描述
+//
+// 请求结构体（名称以Request结尾或带binding标签）中只有binding:"required"的字段是必填的，
+// 响应结构体中没有omitempty的非指针字段总会返回，也标记为必填，便于生成客户端类型。
+func (r *schemaRegistry) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	request := isRequest(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// 没有json名称的嵌入结构体，字段平铺到外层
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := r.object(ft)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := r.of(field.Type)
+		pointer := field.Type.Kind() == reflect.Ptr
+		if pointer && prop.Ref == "" {
+			prop.Nullable = true
+		}
+
+		binding, hasBinding := field.Tag.Lookup("binding")
+		required := applyBinding(prop, binding)
+		if !request && !hasBinding && !pointer && !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Interface {
+			required = true
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// isRequest 判断是否为请求结构体
+func isRequest(t reflect.Type) bool {
+	if strings.HasSuffix(t.Name(), "Request") {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBinding 将binding标签中的校验规则转换为结构约束，返回字段是否必填
+//
+// dive之后的规则作用于列表元素；通过$ref引用的结构不添加约束。
+func applyBinding(s *Schema, tag string) bool {
+	required := false
+	target := s
+	top := true
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			if target.Items == nil {
+				return required
+			}
+			target = target.Items
+			top = false
+			continue
+		}
+		if target.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "required":
+			if top {
+				required = true
+			} else if target.Type == "string" && target.MinLength == nil {
+				target.MinLength = intPtr(1)
+			}
+		case "min", "max", "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			switch target.Type {
+			case "string":
+				if name != "max" {
+					target.MinLength = intPtr(n)
+				}
+				if name != "min" {
+					target.MaxLength = intPtr(n)
+				}
+			case "array":
+				if name != "max" {
+					target.MinItems = intPtr(n)
+				}
+				if name != "min" {
+					target.MaxItems = intPtr(n)
+				}
+			case "integer", "number":
+				if name != "max" {
+					target.Minimum = float(float64(n))
+				}
+				if name != "min" {
+					target.Maximum = float(float64(n))
+				}
+			}
+		case "url":
+			target.Format = "uri"
+		case "email":
+			target.Format = "email"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, v)
+			}
+		}
+	}
+	return required
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func float(n float64) *float64 {
+	return &n
+}
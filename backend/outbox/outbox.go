@@ -0,0 +1,250 @@
+package outbox
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/roll"
+	"vote-system/survey"
+	"vote-system/tally"
+	"vote-system/websocket"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultInterval 分发器轮询未投递事件的间隔
+	DefaultInterval = time.Second
+	// DefaultMaxAttempts 事件投递失败达到该次数后标记为失败，不再重试
+	DefaultMaxAttempts = 10
+	// batchSize 每轮最多投递的事件数
+	batchSize = 100
+)
+
+// Publisher 事件发布者，返回错误时事件会在下一轮重新投递，直到达到最大尝试次数
+type Publisher interface {
+	Publish(event models.OutboxEvent) error
+}
+
+// Enqueue 在给定事务中写入一条outbox事件
+func Enqueue(tx *gorm.DB, pollID uint, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := models.OutboxEvent{
+		PollID:  pollID,
+		Type:    eventType,
+		Payload: string(data),
+	}
+	return tx.Create(&event).Error
+}
+
+// EnqueueSurvey 在给定事务中写入一条调查问卷的outbox事件
+func EnqueueSurvey(tx *gorm.DB, surveyID uint, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event := models.OutboxEvent{
+		SurveyID: surveyID,
+		Type:     eventType,
+		Payload:  string(data),
+	}
+	return tx.Create(&event).Error
+}
+
+// Dispatcher 轮询outbox表，将已提交的事件至少投递一次给所有Publisher
+type Dispatcher struct {
+	db          *gorm.DB
+	publishers  []Publisher
+	interval    time.Duration
+	wake        chan struct{}
+	MaxAttempts int
+}
+
+// NewDispatcher 创建新的Dispatcher
+func NewDispatcher(db *gorm.DB, publishers ...Publisher) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		publishers:  publishers,
+		interval:    DefaultInterval,
+		wake:        make(chan struct{}, 1),
+		MaxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// Run 运行分发循环，定时或被Notify唤醒时投递未完成的事件
+func (d *Dispatcher) Run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		if err := d.DispatchPending(); err != nil {
+			log.Printf("Error dispatching outbox events: %v", err)
+		}
+	}
+}
+
+// Notify 唤醒分发器立即投递，不会阻塞调用方；nil Dispatcher 上调用是安全的
+func (d *Dispatcher) Notify() {
+	if d == nil {
+		return
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchPending 投递一批未完成的事件，成功后标记为已投递，失败达到MaxAttempts次后标记为失败
+func (d *Dispatcher) DispatchPending() error {
+	var events []models.OutboxEvent
+	if err := d.db.Where("delivered_at IS NULL AND failed_at IS NULL").Order("id").Limit(batchSize).Find(&events).Error; err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := d.publish(event); err != nil {
+			log.Printf("Error publishing outbox event %d (%s): %v", event.ID, event.Type, err)
+			updates := map[string]interface{}{
+				"attempts":   gorm.Expr("attempts + ?", 1),
+				"last_error": err.Error(),
+			}
+			if event.Attempts+1 >= d.MaxAttempts {
+				log.Printf("Giving up on outbox event %d after %d attempts", event.ID, event.Attempts+1)
+				updates["failed_at"] = time.Now()
+			}
+			// 记录失败也出错时停止本轮，否则尝试次数不会增加，事件会一直重试
+			if err := d.db.Model(&event).Updates(updates).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		now := time.Now()
+		if err := d.db.Model(&event).Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + ?", 1),
+			"delivered_at": &now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) publish(event models.OutboxEvent) error {
+	for _, p := range d.publishers {
+		if err := p.Publish(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HubPublisher 将事件转换为WebSocket投票更新广播
+type HubPublisher struct {
+	db     *gorm.DB
+	hub    *websocket.Hub
+	hasher *privacy.Hasher
+}
+
+// NewHubPublisher 创建新的HubPublisher，hasher用于判断客户端是否已投票
+func NewHubPublisher(db *gorm.DB, hub *websocket.Hub, hasher *privacy.Hasher) *HubPublisher {
+	return &HubPublisher{
+		db:     db,
+		hub:    hub,
+		hasher: hasher,
+	}
+}
+
+// Publish 读取事件所属投票问卷的最新数据并按结果可见性广播，关闭事件额外广播关闭结果，有投票人名册时广播投票率
+//
+// 新的提议和被拒绝的提议不改变投票问卷，只通过webhook通知。调查问卷的事件广播按问题汇总的结果。
+func (p *HubPublisher) Publish(event models.OutboxEvent) error {
+	if event.SurveyID != 0 {
+		return p.publishSurvey(event)
+	}
+	if event.Type == models.EventOptionProposed || event.Type == models.EventProposalRejected {
+		return nil
+	}
+
+	var poll models.Poll
+	if err := p.db.Preload("Options").First(&poll, event.PollID).Error; err != nil {
+		return err
+	}
+	// 评分投票和波达计数的平均得分和分布随票数一起广播
+	if err := tally.Fill(p.db, &poll); err != nil {
+		return err
+	}
+
+	// 在广播前按已连接客户端的身份确定可见性，Hub的分发循环中不查询数据库
+	var visible map[websocket.Audience]bool
+	if poll.ResultVisibility != "" && poll.ResultVisibility != models.VisibilityAlways {
+		visible = map[websocket.Audience]bool{}
+		for _, audience := range p.hub.Audiences() {
+			// 只有投票后可见的模式需要查询投票记录
+			voted := poll.ResultVisibility == models.VisibilityAfterVote && p.voted(poll.ID, audience.Voter)
+			visible[audience] = poll.ResultsVisible(voted, audience.Admin)
+		}
+	}
+
+	hidden := poll
+	hidden.Options = append([]models.Option(nil), poll.Options...)
+	hidden.HideResults()
+	p.hub.BroadcastFor("poll_update", poll, hidden, visible)
+
+	// 投票率只包含人数，不受结果可见性限制
+	turnout, err := roll.Count(p.db, poll)
+	if err != nil {
+		return err
+	}
+	if turnout != nil {
+		p.hub.BroadcastFor("turnout_update", turnout, nil, nil)
+	}
+
+	if event.Type == models.EventPollClosed {
+		var outcome map[string]interface{}
+		if err := json.Unmarshal([]byte(event.Payload), &outcome); err != nil {
+			return err
+		}
+		// 结果中包含票数，对看不到结果的客户端只公布关闭原因
+		reason := outcome["reason"]
+		p.hub.BroadcastFor("poll_closed",
+			map[string]interface{}{"poll_id": poll.ID, "reason": reason, "outcome": outcome},
+			map[string]interface{}{"poll_id": poll.ID, "reason": reason},
+			visible)
+	}
+	return nil
+}
+
+// publishSurvey 广播调查问卷的最新汇总结果，自由回答只包含作答人数
+func (p *HubPublisher) publishSurvey(event models.OutboxEvent) error {
+	results, err := survey.Aggregate(p.db, event.SurveyID)
+	if err != nil {
+		return err
+	}
+	p.hub.BroadcastFor("survey_update", results, nil, nil)
+	return nil
+}
+
+// voted 判断投票人是否已在该投票问卷中投票
+func (p *HubPublisher) voted(pollID uint, voter string) bool {
+	if voter == "" {
+		return false
+	}
+	var count int64
+	p.hasher.VoterScope(p.db.Model(&models.Vote{}), voter).Where("poll_id = ?", pollID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,282 @@
+package outbox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/websocket"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.OutboxEvent{})
+	return db
+}
+
+type recordingPublisher struct {
+	events []models.OutboxEvent
+	err    error
+}
+
+func (p *recordingPublisher) Publish(event models.OutboxEvent) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func TestEnqueueRolledBack(t *testing.T) {
+	db := setupTestDB()
+
+	tx := db.Begin()
+	if err := Enqueue(tx, 1, models.EventVoteCast, map[string]uint{"option_id": 2}); err != nil {
+		t.Fatalf("写入事件失败: %v", err)
+	}
+	tx.Rollback()
+
+	var count int64
+	db.Model(&models.OutboxEvent{}).Count(&count)
+	if count != 0 {
+		t.Errorf("回滚后期望0条事件, 得到 %d", count)
+	}
+}
+
+func TestDispatchPending(t *testing.T) {
+	db := setupTestDB()
+
+	tx := db.Begin()
+	Enqueue(tx, 1, models.EventVoteCast, map[string]uint{"option_id": 2})
+	Enqueue(tx, 1, models.EventPollReset, map[string]uint{})
+	tx.Commit()
+
+	publisher := &recordingPublisher{}
+	dispatcher := NewDispatcher(db, publisher)
+
+	if err := dispatcher.DispatchPending(); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("期望投递2条事件, 得到 %d", len(publisher.events))
+	}
+
+	if publisher.events[0].Type != models.EventVoteCast {
+		t.Errorf("期望首个事件 %s, 得到 %s", models.EventVoteCast, publisher.events[0].Type)
+	}
+
+	if publisher.events[0].Payload != `{"option_id":2}` {
+		t.Errorf("事件内容不正确: %s", publisher.events[0].Payload)
+	}
+
+	var pending int64
+	db.Model(&models.OutboxEvent{}).Where("delivered_at IS NULL").Count(&pending)
+	if pending != 0 {
+		t.Errorf("期望所有事件已投递, 仍有 %d 条", pending)
+	}
+
+	// 已投递的事件不应重复投递
+	dispatcher.DispatchPending()
+	if len(publisher.events) != 2 {
+		t.Errorf("已投递事件被重复投递, 共 %d 次", len(publisher.events))
+	}
+}
+
+func TestDispatchPendingRetriesOnFailure(t *testing.T) {
+	db := setupTestDB()
+
+	Enqueue(db, 1, models.EventVoteCast, map[string]uint{"option_id": 2})
+
+	publisher := &recordingPublisher{err: errors.New("unavailable")}
+	dispatcher := NewDispatcher(db, publisher)
+	dispatcher.DispatchPending()
+
+	var event models.OutboxEvent
+	db.First(&event)
+	if event.DeliveredAt != nil {
+		t.Error("投递失败的事件不应标记为已投递")
+	}
+	if event.Attempts != 1 {
+		t.Errorf("期望尝试次数 1, 得到 %d", event.Attempts)
+	}
+	if event.LastError != "unavailable" {
+		t.Errorf("期望记录错误信息, 得到 %q", event.LastError)
+	}
+
+	// 恢复后重新投递
+	publisher.err = nil
+	dispatcher.DispatchPending()
+
+	db.First(&event)
+	if event.DeliveredAt == nil {
+		t.Error("重试成功后事件应标记为已投递")
+	}
+	if len(publisher.events) != 2 {
+		t.Errorf("期望共投递2次, 得到 %d", len(publisher.events))
+	}
+}
+
+func TestDispatchPendingGivesUpAfterMaxAttempts(t *testing.T) {
+	db := setupTestDB()
+
+	Enqueue(db, 1, models.EventVoteCast, map[string]uint{"option_id": 2})
+
+	publisher := &recordingPublisher{err: errors.New("unavailable")}
+	dispatcher := NewDispatcher(db, publisher)
+	dispatcher.MaxAttempts = 3
+	for i := 0; i < 5; i++ {
+		dispatcher.DispatchPending()
+	}
+
+	if len(publisher.events) != 3 {
+		t.Errorf("期望最多投递3次, 得到 %d", len(publisher.events))
+	}
+	var event models.OutboxEvent
+	db.First(&event)
+	if event.FailedAt == nil || event.DeliveredAt != nil || event.Attempts != 3 {
+		t.Errorf("期望事件标记为失败且尝试3次, 得到 %+v", event)
+	}
+
+	// 失败的事件不阻塞后续事件
+	Enqueue(db, 1, models.EventPollReset, map[string]uint{})
+	publisher.err = nil
+	dispatcher.DispatchPending()
+	if len(publisher.events) != 4 || publisher.events[3].Type != models.EventPollReset {
+		t.Errorf("期望只投递新事件, 得到 %d 次", len(publisher.events))
+	}
+}
+
+// failingPublisher 投递失败，并让之后记录失败的写入也失败
+type failingPublisher struct {
+	db *gorm.DB
+}
+
+func (p failingPublisher) Publish(event models.OutboxEvent) error {
+	p.db.Migrator().DropTable(&models.OutboxEvent{})
+	return errors.New("unavailable")
+}
+
+func TestDispatchPendingReturnsRecordError(t *testing.T) {
+	db := setupTestDB()
+
+	Enqueue(db, 1, models.EventVoteCast, map[string]uint{"option_id": 2})
+	if err := NewDispatcher(db, failingPublisher{db: db}).DispatchPending(); err == nil {
+		t.Error("记录失败出错时期望返回错误")
+	}
+}
+
+func TestNotifyNilDispatcher(t *testing.T) {
+	var dispatcher *Dispatcher
+	// 不应panic
+	dispatcher.Notify()
+}
+
+func TestHubPublisherSurvey(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{})
+	sv := models.Survey{Title: "调查", IsActive: true, Questions: []models.SurveyQuestion{
+		{Position: 1, Type: models.QuestionSingleChoice, Title: "选一个", Choices: []string{"甲", "乙"}},
+	}}
+	db.Create(&sv)
+	db.Create(&models.SurveyResponse{SurveyID: sv.ID, Answers: []models.SurveyAnswer{{QuestionID: sv.Questions[0].ID, Choices: []int{1}}}})
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	messages, cancel := hub.Subscribe(websocket.Audience{Voter: "10.0.0.1"})
+	defer cancel()
+
+	EnqueueSurvey(db, sv.ID, models.EventSurveyResponse, map[string]uint{"response_id": 1})
+	if err := NewDispatcher(db, NewHubPublisher(db, hub, nil)).DispatchPending(); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	select {
+	case message := <-messages:
+		if !strings.Contains(string(message), `"type":"survey_update"`) || !strings.Contains(string(message), `"responses":1`) {
+			t.Errorf("期望广播调查问卷的汇总结果, 得到 %s", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("没有收到广播")
+	}
+}
+
+func TestHubPublisherTurnout(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.VoterRoll{}, &models.RollMember{})
+	roll := models.VoterRoll{Name: "董事会", Members: []models.RollMember{{Identifier: "alice"}, {Identifier: "bob"}}}
+	db.Create(&roll)
+	poll := models.Poll{Title: "董事会决议", IsActive: true, RollID: &roll.ID, Options: []models.Option{{Text: "同意"}}}
+	db.Create(&poll)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: poll.Options[0].ID, UserIP: "alice", RollMemberID: &roll.Members[0].ID})
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	messages, cancel := hub.Subscribe(websocket.Audience{Voter: "bob"})
+	defer cancel()
+
+	Enqueue(db, poll.ID, models.EventVoteCast, map[string]uint{"vote_id": 1})
+	if err := NewDispatcher(db, NewHubPublisher(db, hub, nil)).DispatchPending(); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	// 先收到poll_update，之后是投票率
+	for _, want := range []string{`"type":"poll_update"`, `"type":"turnout_update"`} {
+		select {
+		case message := <-messages:
+			if !strings.Contains(string(message), want) {
+				t.Fatalf("期望 %s, 得到 %s", want, message)
+			}
+			if want == `"type":"turnout_update"` && !strings.Contains(string(message), `"eligible":2,"voted":1,"percentage":50`) {
+				t.Errorf("投票率不正确: %s", message)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("没有收到 %s", want)
+		}
+	}
+}
+
+func TestHubPublisherVisibility(t *testing.T) {
+	db := setupTestDB()
+	poll := models.Poll{Title: "投票后公布", IsActive: true, ResultVisibility: models.VisibilityAfterVote, Options: []models.Option{{Text: "同意", VoteCount: 1}}}
+	db.Create(&poll)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: poll.Options[0].ID, UserIP: "alice"})
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	alice, cancelAlice := hub.Subscribe(websocket.Audience{Voter: "alice"})
+	defer cancelAlice()
+	bob, cancelBob := hub.Subscribe(websocket.Audience{Voter: "bob"})
+	defer cancelBob()
+
+	Enqueue(db, poll.ID, models.EventVoteCast, map[string]uint{"vote_id": 1})
+	if err := NewDispatcher(db, NewHubPublisher(db, hub, nil)).DispatchPending(); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	// 已投票的alice看到票数，bob只看到隐藏的结果
+	cases := []struct {
+		voter    string
+		messages <-chan []byte
+		want     string
+	}{
+		{"alice", alice, `"vote_count":1`},
+		{"bob", bob, `"results_hidden":true`},
+	}
+	for _, tc := range cases {
+		select {
+		case message := <-tc.messages:
+			if !strings.Contains(string(message), tc.want) {
+				t.Errorf("%s: 期望 %s, 得到 %s", tc.voter, tc.want, message)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s 没有收到广播", tc.voter)
+		}
+	}
+}
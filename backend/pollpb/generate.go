@@ -0,0 +1,4 @@
+// Package pollpb 由poll.proto生成的gRPC接口代码
+package pollpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative poll.proto
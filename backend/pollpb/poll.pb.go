@@ -0,0 +1,1110 @@
+// 投票问卷的gRPC接口，供内部服务投票和订阅结果
+//
+// 修改后在backend目录执行 go generate ./pollpb 重新生成代码。
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: poll.proto
+
+package pollpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Option struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text      string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	VoteCount int64                  `protobuf:"varint,3,opt,name=vote_count,json=voteCount,proto3" json:"vote_count,omitempty"`
+	// write_in 自填选项，投给该选项时需在VoteRequest.write_in中填写内容
+	WriteIn bool `protobuf:"varint,4,opt,name=write_in,json=writeIn,proto3" json:"write_in,omitempty"`
+	// weighted_votes 按投票人权重累计的票数，十进制字符串，例如 "12.5"
+	WeightedVotes string `protobuf:"bytes,5,opt,name=weighted_votes,json=weightedVotes,proto3" json:"weighted_votes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Option) Reset() {
+	*x = Option{}
+	mi := &file_poll_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Option) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Option) ProtoMessage() {}
+
+func (x *Option) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Option.ProtoReflect.Descriptor instead.
+func (*Option) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Option) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Option) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Option) GetVoteCount() int64 {
+	if x != nil {
+		return x.VoteCount
+	}
+	return 0
+}
+
+func (x *Option) GetWriteIn() bool {
+	if x != nil {
+		return x.WriteIn
+	}
+	return false
+}
+
+func (x *Option) GetWeightedVotes() string {
+	if x != nil {
+		return x.WeightedVotes
+	}
+	return ""
+}
+
+type PollRules struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TargetVotes    int64                  `protobuf:"varint,1,opt,name=target_votes,json=targetVotes,proto3" json:"target_votes,omitempty"`
+	WinPercent     int64                  `protobuf:"varint,2,opt,name=win_percent,json=winPercent,proto3" json:"win_percent,omitempty"`
+	WinMinVotes    int64                  `protobuf:"varint,3,opt,name=win_min_votes,json=winMinVotes,proto3" json:"win_min_votes,omitempty"`
+	EligibleVoters int64                  `protobuf:"varint,4,opt,name=eligible_voters,json=eligibleVoters,proto3" json:"eligible_voters,omitempty"`
+	ClosesAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=closes_at,json=closesAt,proto3" json:"closes_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PollRules) Reset() {
+	*x = PollRules{}
+	mi := &file_poll_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollRules) ProtoMessage() {}
+
+func (x *PollRules) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollRules.ProtoReflect.Descriptor instead.
+func (*PollRules) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PollRules) GetTargetVotes() int64 {
+	if x != nil {
+		return x.TargetVotes
+	}
+	return 0
+}
+
+func (x *PollRules) GetWinPercent() int64 {
+	if x != nil {
+		return x.WinPercent
+	}
+	return 0
+}
+
+func (x *PollRules) GetWinMinVotes() int64 {
+	if x != nil {
+		return x.WinMinVotes
+	}
+	return 0
+}
+
+func (x *PollRules) GetEligibleVoters() int64 {
+	if x != nil {
+		return x.EligibleVoters
+	}
+	return 0
+}
+
+func (x *PollRules) GetClosesAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ClosesAt
+	}
+	return nil
+}
+
+type Poll struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title            string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description      string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	IsActive         bool                   `protobuf:"varint,4,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	ResultVisibility string                 `protobuf:"bytes,5,opt,name=result_visibility,json=resultVisibility,proto3" json:"result_visibility,omitempty"`
+	// results_hidden 为true时选项的vote_count和weighted_votes已被隐藏
+	ResultsHidden  bool                   `protobuf:"varint,6,opt,name=results_hidden,json=resultsHidden,proto3" json:"results_hidden,omitempty"`
+	Options        []*Option              `protobuf:"bytes,7,rep,name=options,proto3" json:"options,omitempty"`
+	Rules          *PollRules             `protobuf:"bytes,8,opt,name=rules,proto3" json:"rules,omitempty"`
+	CloseReason    string                 `protobuf:"bytes,9,opt,name=close_reason,json=closeReason,proto3" json:"close_reason,omitempty"`
+	ClosedAt       *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=closed_at,json=closedAt,proto3" json:"closed_at,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	AllowProposals bool                   `protobuf:"varint,13,opt,name=allow_proposals,json=allowProposals,proto3" json:"allow_proposals,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Poll) Reset() {
+	*x = Poll{}
+	mi := &file_poll_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Poll) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Poll) ProtoMessage() {}
+
+func (x *Poll) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Poll.ProtoReflect.Descriptor instead.
+func (*Poll) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Poll) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Poll) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Poll) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Poll) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *Poll) GetResultVisibility() string {
+	if x != nil {
+		return x.ResultVisibility
+	}
+	return ""
+}
+
+func (x *Poll) GetResultsHidden() bool {
+	if x != nil {
+		return x.ResultsHidden
+	}
+	return false
+}
+
+func (x *Poll) GetOptions() []*Option {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Poll) GetRules() *PollRules {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+func (x *Poll) GetCloseReason() string {
+	if x != nil {
+		return x.CloseReason
+	}
+	return ""
+}
+
+func (x *Poll) GetClosedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ClosedAt
+	}
+	return nil
+}
+
+func (x *Poll) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Poll) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Poll) GetAllowProposals() bool {
+	if x != nil {
+		return x.AllowProposals
+	}
+	return false
+}
+
+type PollView struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Poll       *Poll                  `protobuf:"bytes,1,opt,name=poll,proto3" json:"poll,omitempty"`
+	TotalVotes int64                  `protobuf:"varint,2,opt,name=total_votes,json=totalVotes,proto3" json:"total_votes,omitempty"`
+	UserVoted  bool                   `protobuf:"varint,3,opt,name=user_voted,json=userVoted,proto3" json:"user_voted,omitempty"`
+	// voted_option 投票人选择的选项，未投票时为0
+	VotedOption uint64 `protobuf:"varint,4,opt,name=voted_option,json=votedOption,proto3" json:"voted_option,omitempty"`
+	// turnout 有投票人名册时按名册统计的投票率
+	Turnout *Turnout `protobuf:"bytes,5,opt,name=turnout,proto3" json:"turnout,omitempty"`
+	// total_weight 各选项加权票数之和，十进制字符串
+	TotalWeight   string `protobuf:"bytes,6,opt,name=total_weight,json=totalWeight,proto3" json:"total_weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollView) Reset() {
+	*x = PollView{}
+	mi := &file_poll_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollView) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollView) ProtoMessage() {}
+
+func (x *PollView) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollView.ProtoReflect.Descriptor instead.
+func (*PollView) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PollView) GetPoll() *Poll {
+	if x != nil {
+		return x.Poll
+	}
+	return nil
+}
+
+func (x *PollView) GetTotalVotes() int64 {
+	if x != nil {
+		return x.TotalVotes
+	}
+	return 0
+}
+
+func (x *PollView) GetUserVoted() bool {
+	if x != nil {
+		return x.UserVoted
+	}
+	return false
+}
+
+func (x *PollView) GetVotedOption() uint64 {
+	if x != nil {
+		return x.VotedOption
+	}
+	return 0
+}
+
+func (x *PollView) GetTurnout() *Turnout {
+	if x != nil {
+		return x.Turnout
+	}
+	return nil
+}
+
+func (x *PollView) GetTotalWeight() string {
+	if x != nil {
+		return x.TotalWeight
+	}
+	return ""
+}
+
+type Turnout struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RollId        uint64                 `protobuf:"varint,1,opt,name=roll_id,json=rollId,proto3" json:"roll_id,omitempty"`
+	Eligible      int64                  `protobuf:"varint,2,opt,name=eligible,proto3" json:"eligible,omitempty"`
+	Voted         int64                  `protobuf:"varint,3,opt,name=voted,proto3" json:"voted,omitempty"`
+	Percentage    float64                `protobuf:"fixed64,4,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Turnout) Reset() {
+	*x = Turnout{}
+	mi := &file_poll_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Turnout) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Turnout) ProtoMessage() {}
+
+func (x *Turnout) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Turnout.ProtoReflect.Descriptor instead.
+func (*Turnout) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Turnout) GetRollId() uint64 {
+	if x != nil {
+		return x.RollId
+	}
+	return 0
+}
+
+func (x *Turnout) GetEligible() int64 {
+	if x != nil {
+		return x.Eligible
+	}
+	return 0
+}
+
+func (x *Turnout) GetVoted() int64 {
+	if x != nil {
+		return x.Voted
+	}
+	return 0
+}
+
+func (x *Turnout) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+type GetPollRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// poll_id 为0时为进行中的投票问卷
+	PollId uint64 `protobuf:"varint,1,opt,name=poll_id,json=pollId,proto3" json:"poll_id,omitempty"`
+	// voter 投票人标识，与HTTP接口中的客户端IP对应
+	Voter         string `protobuf:"bytes,2,opt,name=voter,proto3" json:"voter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPollRequest) Reset() {
+	*x = GetPollRequest{}
+	mi := &file_poll_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPollRequest) ProtoMessage() {}
+
+func (x *GetPollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPollRequest.ProtoReflect.Descriptor instead.
+func (*GetPollRequest) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetPollRequest) GetPollId() uint64 {
+	if x != nil {
+		return x.PollId
+	}
+	return 0
+}
+
+func (x *GetPollRequest) GetVoter() string {
+	if x != nil {
+		return x.Voter
+	}
+	return ""
+}
+
+type ListPollsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPollsRequest) Reset() {
+	*x = ListPollsRequest{}
+	mi := &file_poll_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPollsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPollsRequest) ProtoMessage() {}
+
+func (x *ListPollsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPollsRequest.ProtoReflect.Descriptor instead.
+func (*ListPollsRequest) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{6}
+}
+
+type ListPollsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Polls         []*Poll                `protobuf:"bytes,1,rep,name=polls,proto3" json:"polls,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPollsResponse) Reset() {
+	*x = ListPollsResponse{}
+	mi := &file_poll_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPollsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPollsResponse) ProtoMessage() {}
+
+func (x *ListPollsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPollsResponse.ProtoReflect.Descriptor instead.
+func (*ListPollsResponse) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListPollsResponse) GetPolls() []*Poll {
+	if x != nil {
+		return x.Polls
+	}
+	return nil
+}
+
+type VoteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// poll_id 为0时投给进行中的投票问卷
+	PollId   uint64 `protobuf:"varint,1,opt,name=poll_id,json=pollId,proto3" json:"poll_id,omitempty"`
+	OptionId uint64 `protobuf:"varint,2,opt,name=option_id,json=optionId,proto3" json:"option_id,omitempty"`
+	Voter    string `protobuf:"bytes,3,opt,name=voter,proto3" json:"voter,omitempty"`
+	// write_in 投给自填选项时必填
+	WriteIn string `protobuf:"bytes,4,opt,name=write_in,json=writeIn,proto3" json:"write_in,omitempty"`
+	// access_code 私有投票问卷的访问码
+	AccessCode string `protobuf:"bytes,5,opt,name=access_code,json=accessCode,proto3" json:"access_code,omitempty"`
+	// ballot_token 邀请制投票问卷的投票令牌
+	BallotToken   string `protobuf:"bytes,6,opt,name=ballot_token,json=ballotToken,proto3" json:"ballot_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteRequest) Reset() {
+	*x = VoteRequest{}
+	mi := &file_poll_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteRequest) ProtoMessage() {}
+
+func (x *VoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteRequest.ProtoReflect.Descriptor instead.
+func (*VoteRequest) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VoteRequest) GetPollId() uint64 {
+	if x != nil {
+		return x.PollId
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetOptionId() uint64 {
+	if x != nil {
+		return x.OptionId
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetVoter() string {
+	if x != nil {
+		return x.Voter
+	}
+	return ""
+}
+
+func (x *VoteRequest) GetWriteIn() string {
+	if x != nil {
+		return x.WriteIn
+	}
+	return ""
+}
+
+func (x *VoteRequest) GetAccessCode() string {
+	if x != nil {
+		return x.AccessCode
+	}
+	return ""
+}
+
+func (x *VoteRequest) GetBallotToken() string {
+	if x != nil {
+		return x.BallotToken
+	}
+	return ""
+}
+
+type VoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteResponse) Reset() {
+	*x = VoteResponse{}
+	mi := &file_poll_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteResponse) ProtoMessage() {}
+
+func (x *VoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteResponse.ProtoReflect.Descriptor instead.
+func (*VoteResponse) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{9}
+}
+
+type ResetPollRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// poll_id 为0时为进行中的投票问卷
+	PollId        uint64 `protobuf:"varint,1,opt,name=poll_id,json=pollId,proto3" json:"poll_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetPollRequest) Reset() {
+	*x = ResetPollRequest{}
+	mi := &file_poll_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetPollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetPollRequest) ProtoMessage() {}
+
+func (x *ResetPollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetPollRequest.ProtoReflect.Descriptor instead.
+func (*ResetPollRequest) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ResetPollRequest) GetPollId() uint64 {
+	if x != nil {
+		return x.PollId
+	}
+	return 0
+}
+
+type ResetPollResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetPollResponse) Reset() {
+	*x = ResetPollResponse{}
+	mi := &file_poll_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetPollResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetPollResponse) ProtoMessage() {}
+
+func (x *ResetPollResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetPollResponse.ProtoReflect.Descriptor instead.
+func (*ResetPollResponse) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{11}
+}
+
+type WatchPollRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// poll_id 为0时为订阅时进行中的投票问卷
+	PollId uint64 `protobuf:"varint,1,opt,name=poll_id,json=pollId,proto3" json:"poll_id,omitempty"`
+	// voter 按该投票人的身份决定是否隐藏票数
+	Voter         string `protobuf:"bytes,2,opt,name=voter,proto3" json:"voter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPollRequest) Reset() {
+	*x = WatchPollRequest{}
+	mi := &file_poll_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPollRequest) ProtoMessage() {}
+
+func (x *WatchPollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPollRequest.ProtoReflect.Descriptor instead.
+func (*WatchPollRequest) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchPollRequest) GetPollId() uint64 {
+	if x != nil {
+		return x.PollId
+	}
+	return 0
+}
+
+func (x *WatchPollRequest) GetVoter() string {
+	if x != nil {
+		return x.Voter
+	}
+	return ""
+}
+
+type PollEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// type 为snapshot、poll_update、poll_closed或turnout_update
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Poll *Poll  `protobuf:"bytes,2,opt,name=poll,proto3" json:"poll,omitempty"`
+	// close_reason 仅poll_closed事件，取值与Poll.close_reason相同
+	CloseReason string `protobuf:"bytes,3,opt,name=close_reason,json=closeReason,proto3" json:"close_reason,omitempty"`
+	// turnout 仅turnout_update事件
+	Turnout       *Turnout `protobuf:"bytes,4,opt,name=turnout,proto3" json:"turnout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollEvent) Reset() {
+	*x = PollEvent{}
+	mi := &file_poll_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollEvent) ProtoMessage() {}
+
+func (x *PollEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_poll_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollEvent.ProtoReflect.Descriptor instead.
+func (*PollEvent) Descriptor() ([]byte, []int) {
+	return file_poll_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PollEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PollEvent) GetPoll() *Poll {
+	if x != nil {
+		return x.Poll
+	}
+	return nil
+}
+
+func (x *PollEvent) GetCloseReason() string {
+	if x != nil {
+		return x.CloseReason
+	}
+	return ""
+}
+
+func (x *PollEvent) GetTurnout() *Turnout {
+	if x != nil {
+		return x.Turnout
+	}
+	return nil
+}
+
+var File_poll_proto protoreflect.FileDescriptor
+
+const file_poll_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"poll.proto\x12\avote.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8d\x01\n" +
+	"\x06Option\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1d\n" +
+	"\n" +
+	"vote_count\x18\x03 \x01(\x03R\tvoteCount\x12\x19\n" +
+	"\bwrite_in\x18\x04 \x01(\bR\awriteIn\x12%\n" +
+	"\x0eweighted_votes\x18\x05 \x01(\tR\rweightedVotes\"\xd5\x01\n" +
+	"\tPollRules\x12!\n" +
+	"\ftarget_votes\x18\x01 \x01(\x03R\vtargetVotes\x12\x1f\n" +
+	"\vwin_percent\x18\x02 \x01(\x03R\n" +
+	"winPercent\x12\"\n" +
+	"\rwin_min_votes\x18\x03 \x01(\x03R\vwinMinVotes\x12'\n" +
+	"\x0feligible_voters\x18\x04 \x01(\x03R\x0eeligibleVoters\x127\n" +
+	"\tcloses_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bclosesAt\"\x8f\x04\n" +
+	"\x04Poll\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1b\n" +
+	"\tis_active\x18\x04 \x01(\bR\bisActive\x12+\n" +
+	"\x11result_visibility\x18\x05 \x01(\tR\x10resultVisibility\x12%\n" +
+	"\x0eresults_hidden\x18\x06 \x01(\bR\rresultsHidden\x12)\n" +
+	"\aoptions\x18\a \x03(\v2\x0f.vote.v1.OptionR\aoptions\x12(\n" +
+	"\x05rules\x18\b \x01(\v2\x12.vote.v1.PollRulesR\x05rules\x12!\n" +
+	"\fclose_reason\x18\t \x01(\tR\vcloseReason\x127\n" +
+	"\tclosed_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\bclosedAt\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12'\n" +
+	"\x0fallow_proposals\x18\r \x01(\bR\x0eallowProposals\"\xdf\x01\n" +
+	"\bPollView\x12!\n" +
+	"\x04poll\x18\x01 \x01(\v2\r.vote.v1.PollR\x04poll\x12\x1f\n" +
+	"\vtotal_votes\x18\x02 \x01(\x03R\n" +
+	"totalVotes\x12\x1d\n" +
+	"\n" +
+	"user_voted\x18\x03 \x01(\bR\tuserVoted\x12!\n" +
+	"\fvoted_option\x18\x04 \x01(\x04R\vvotedOption\x12*\n" +
+	"\aturnout\x18\x05 \x01(\v2\x10.vote.v1.TurnoutR\aturnout\x12!\n" +
+	"\ftotal_weight\x18\x06 \x01(\tR\vtotalWeight\"t\n" +
+	"\aTurnout\x12\x17\n" +
+	"\aroll_id\x18\x01 \x01(\x04R\x06rollId\x12\x1a\n" +
+	"\beligible\x18\x02 \x01(\x03R\beligible\x12\x14\n" +
+	"\x05voted\x18\x03 \x01(\x03R\x05voted\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x04 \x01(\x01R\n" +
+	"percentage\"?\n" +
+	"\x0eGetPollRequest\x12\x17\n" +
+	"\apoll_id\x18\x01 \x01(\x04R\x06pollId\x12\x14\n" +
+	"\x05voter\x18\x02 \x01(\tR\x05voter\"\x12\n" +
+	"\x10ListPollsRequest\"8\n" +
+	"\x11ListPollsResponse\x12#\n" +
+	"\x05polls\x18\x01 \x03(\v2\r.vote.v1.PollR\x05polls\"\xb8\x01\n" +
+	"\vVoteRequest\x12\x17\n" +
+	"\apoll_id\x18\x01 \x01(\x04R\x06pollId\x12\x1b\n" +
+	"\toption_id\x18\x02 \x01(\x04R\boptionId\x12\x14\n" +
+	"\x05voter\x18\x03 \x01(\tR\x05voter\x12\x19\n" +
+	"\bwrite_in\x18\x04 \x01(\tR\awriteIn\x12\x1f\n" +
+	"\vaccess_code\x18\x05 \x01(\tR\n" +
+	"accessCode\x12!\n" +
+	"\fballot_token\x18\x06 \x01(\tR\vballotToken\"\x0e\n" +
+	"\fVoteResponse\"+\n" +
+	"\x10ResetPollRequest\x12\x17\n" +
+	"\apoll_id\x18\x01 \x01(\x04R\x06pollId\"\x13\n" +
+	"\x11ResetPollResponse\"A\n" +
+	"\x10WatchPollRequest\x12\x17\n" +
+	"\apoll_id\x18\x01 \x01(\x04R\x06pollId\x12\x14\n" +
+	"\x05voter\x18\x02 \x01(\tR\x05voter\"\x91\x01\n" +
+	"\tPollEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12!\n" +
+	"\x04poll\x18\x02 \x01(\v2\r.vote.v1.PollR\x04poll\x12!\n" +
+	"\fclose_reason\x18\x03 \x01(\tR\vcloseReason\x12*\n" +
+	"\aturnout\x18\x04 \x01(\v2\x10.vote.v1.TurnoutR\aturnout2\xbf\x02\n" +
+	"\vPollService\x125\n" +
+	"\aGetPoll\x12\x17.vote.v1.GetPollRequest\x1a\x11.vote.v1.PollView\x12B\n" +
+	"\tListPolls\x12\x19.vote.v1.ListPollsRequest\x1a\x1a.vote.v1.ListPollsResponse\x123\n" +
+	"\x04Vote\x12\x14.vote.v1.VoteRequest\x1a\x15.vote.v1.VoteResponse\x12B\n" +
+	"\tResetPoll\x12\x19.vote.v1.ResetPollRequest\x1a\x1a.vote.v1.ResetPollResponse\x12<\n" +
+	"\tWatchPoll\x12\x19.vote.v1.WatchPollRequest\x1a\x12.vote.v1.PollEvent0\x01B\x14Z\x12vote-system/pollpbb\x06proto3"
+
+var (
+	file_poll_proto_rawDescOnce sync.Once
+	file_poll_proto_rawDescData []byte
+)
+
+func file_poll_proto_rawDescGZIP() []byte {
+	file_poll_proto_rawDescOnce.Do(func() {
+		file_poll_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_poll_proto_rawDesc), len(file_poll_proto_rawDesc)))
+	})
+	return file_poll_proto_rawDescData
+}
+
+var file_poll_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_poll_proto_goTypes = []any{
+	(*Option)(nil),                // 0: vote.v1.Option
+	(*PollRules)(nil),             // 1: vote.v1.PollRules
+	(*Poll)(nil),                  // 2: vote.v1.Poll
+	(*PollView)(nil),              // 3: vote.v1.PollView
+	(*Turnout)(nil),               // 4: vote.v1.Turnout
+	(*GetPollRequest)(nil),        // 5: vote.v1.GetPollRequest
+	(*ListPollsRequest)(nil),      // 6: vote.v1.ListPollsRequest
+	(*ListPollsResponse)(nil),     // 7: vote.v1.ListPollsResponse
+	(*VoteRequest)(nil),           // 8: vote.v1.VoteRequest
+	(*VoteResponse)(nil),          // 9: vote.v1.VoteResponse
+	(*ResetPollRequest)(nil),      // 10: vote.v1.ResetPollRequest
+	(*ResetPollResponse)(nil),     // 11: vote.v1.ResetPollResponse
+	(*WatchPollRequest)(nil),      // 12: vote.v1.WatchPollRequest
+	(*PollEvent)(nil),             // 13: vote.v1.PollEvent
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+}
+var file_poll_proto_depIdxs = []int32{
+	14, // 0: vote.v1.PollRules.closes_at:type_name -> google.protobuf.Timestamp
+	0,  // 1: vote.v1.Poll.options:type_name -> vote.v1.Option
+	1,  // 2: vote.v1.Poll.rules:type_name -> vote.v1.PollRules
+	14, // 3: vote.v1.Poll.closed_at:type_name -> google.protobuf.Timestamp
+	14, // 4: vote.v1.Poll.created_at:type_name -> google.protobuf.Timestamp
+	14, // 5: vote.v1.Poll.updated_at:type_name -> google.protobuf.Timestamp
+	2,  // 6: vote.v1.PollView.poll:type_name -> vote.v1.Poll
+	4,  // 7: vote.v1.PollView.turnout:type_name -> vote.v1.Turnout
+	2,  // 8: vote.v1.ListPollsResponse.polls:type_name -> vote.v1.Poll
+	2,  // 9: vote.v1.PollEvent.poll:type_name -> vote.v1.Poll
+	4,  // 10: vote.v1.PollEvent.turnout:type_name -> vote.v1.Turnout
+	5,  // 11: vote.v1.PollService.GetPoll:input_type -> vote.v1.GetPollRequest
+	6,  // 12: vote.v1.PollService.ListPolls:input_type -> vote.v1.ListPollsRequest
+	8,  // 13: vote.v1.PollService.Vote:input_type -> vote.v1.VoteRequest
+	10, // 14: vote.v1.PollService.ResetPoll:input_type -> vote.v1.ResetPollRequest
+	12, // 15: vote.v1.PollService.WatchPoll:input_type -> vote.v1.WatchPollRequest
+	3,  // 16: vote.v1.PollService.GetPoll:output_type -> vote.v1.PollView
+	7,  // 17: vote.v1.PollService.ListPolls:output_type -> vote.v1.ListPollsResponse
+	9,  // 18: vote.v1.PollService.Vote:output_type -> vote.v1.VoteResponse
+	11, // 19: vote.v1.PollService.ResetPoll:output_type -> vote.v1.ResetPollResponse
+	13, // 20: vote.v1.PollService.WatchPoll:output_type -> vote.v1.PollEvent
+	16, // [16:21] is the sub-list for method output_type
+	11, // [11:16] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_poll_proto_init() }
+func file_poll_proto_init() {
+	if File_poll_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_poll_proto_rawDesc), len(file_poll_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_poll_proto_goTypes,
+		DependencyIndexes: file_poll_proto_depIdxs,
+		MessageInfos:      file_poll_proto_msgTypes,
+	}.Build()
+	File_poll_proto = out.File
+	file_poll_proto_goTypes = nil
+	file_poll_proto_depIdxs = nil
+}
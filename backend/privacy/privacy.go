@@ -0,0 +1,139 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Key 用于计算投票人标识HMAC的密钥
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Hasher 隐私模式下将投票人标识转换为带密钥的HMAC
+//
+// 第一个密钥用于新投票，其余密钥只用于查重，轮换密钥时把新密钥放在最前面即可。
+// nil Hasher 表示未开启隐私模式，标识按原样保存。
+type Hasher struct {
+	keys []Key
+}
+
+// ParseKeys 解析 "id:secret,id:secret" 格式的密钥配置
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(part, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid voter key %q, expected id:secret", part)
+		}
+		if len(id) > 16 {
+			return nil, fmt.Errorf("voter key id %q is longer than 16 characters", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate voter key id %q", id)
+		}
+		seen[id] = true
+
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+
+	return keys, nil
+}
+
+// NewHasher 创建新的Hasher，没有密钥时返回nil
+func NewHasher(keys []Key) *Hasher {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &Hasher{keys: keys}
+}
+
+// Enabled 是否开启了隐私模式
+func (h *Hasher) Enabled() bool {
+	return h != nil
+}
+
+// Identify 使用当前密钥计算标识的HMAC，返回HMAC和密钥ID
+func (h *Hasher) Identify(raw string) (string, string) {
+	key := h.keys[0]
+	return sum(key, raw), key.ID
+}
+
+// Candidates 返回标识在所有密钥下的HMAC，用于跨密钥轮换查重
+func (h *Hasher) Candidates(raw string) []string {
+	candidates := make([]string, 0, len(h.keys))
+	for _, key := range h.keys {
+		candidates = append(candidates, sum(key, raw))
+	}
+	return candidates
+}
+
+// Keys 返回标识可能被保存的形式：原始标识，隐私模式下还有所有密钥的HMAC，兼容开启隐私模式前保存的记录
+func (h *Hasher) Keys(raw string) []string {
+	if !h.Enabled() {
+		return []string{raw}
+	}
+	return append(h.Candidates(raw), raw)
+}
+
+// Pseudonymize 隐私模式下返回标识的HMAC，否则原样返回
+func (h *Hasher) Pseudonymize(raw string) string {
+	if !h.Enabled() {
+		return raw
+	}
+	hash, _ := h.Identify(raw)
+	return hash
+}
+
+// VoterScope 限定查询为该投票人的投票记录，兼容开启隐私模式前保存的原始标识
+func (h *Hasher) VoterScope(db *gorm.DB, raw string) *gorm.DB {
+	if !h.Enabled() {
+		return db.Where("user_ip = ?", raw)
+	}
+	return db.Where("(user_ip = ? OR voter_hash IN ?)", raw, h.Candidates(raw))
+}
+
+var (
+	processHasher     *Hasher
+	processHasherOnce sync.Once
+)
+
+// ExportHasher 返回导出时计算投票人哈希的Hasher
+//
+// 隐私模式下使用当前密钥，导出的哈希与已保存的HMAC一致；否则使用进程启动后随机生成的密钥，
+// 同一进程内的导出可以相互比较，但无法通过枚举IP反推出原始标识。
+func ExportHasher(h *Hasher) *Hasher {
+	if h.Enabled() {
+		return h
+	}
+
+	processHasherOnce.Do(func() {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(fmt.Sprintf("generate export key: %v", err))
+		}
+		processHasher = NewHasher([]Key{{ID: "process", Secret: secret}})
+	})
+	return processHasher
+}
+
+func sum(key Key, raw string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(raw))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,221 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.OptionProposal{}, &models.Survey{}, &models.SurveyResponse{},
+		&models.Delegation{}, &models.AuditLog{}, &models.AuditChainHead{})
+	return db
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys("k2:new-secret, k1:old-secret")
+	if err != nil {
+		t.Fatalf("解析密钥失败: %v", err)
+	}
+
+	if len(keys) != 2 || keys[0].ID != "k2" || string(keys[1].Secret) != "old-secret" {
+		t.Errorf("密钥解析结果不正确: %+v", keys)
+	}
+
+	for _, spec := range []string{"nosecret", ":secret", "k1:a,k1:b", "a-very-long-key-identifier:x"} {
+		if _, err := ParseKeys(spec); err == nil {
+			t.Errorf("期望 %q 解析失败", spec)
+		}
+	}
+
+	if keys, _ := ParseKeys(""); NewHasher(keys) != nil {
+		t.Error("没有密钥时不应开启隐私模式")
+	}
+}
+
+func TestHasherRotation(t *testing.T) {
+	oldHasher := NewHasher([]Key{{ID: "k1", Secret: []byte("old")}})
+	rotated := NewHasher([]Key{{ID: "k2", Secret: []byte("new")}, {ID: "k1", Secret: []byte("old")}})
+
+	oldHash, oldKey := oldHasher.Identify("10.0.0.1")
+	newHash, newKey := rotated.Identify("10.0.0.1")
+
+	if oldKey != "k1" || newKey != "k2" {
+		t.Errorf("密钥ID不正确: %s, %s", oldKey, newKey)
+	}
+
+	if oldHash == newHash || len(newHash) != 64 {
+		t.Errorf("不同密钥应得到不同的HMAC: %s, %s", oldHash, newHash)
+	}
+
+	candidates := rotated.Candidates("10.0.0.1")
+	if len(candidates) != 2 || candidates[0] != newHash || candidates[1] != oldHash {
+		t.Errorf("轮换后应能匹配旧密钥的HMAC: %v", candidates)
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	var disabled *Hasher
+	if disabled.Pseudonymize("10.0.0.1") != "10.0.0.1" {
+		t.Error("未开启隐私模式时应原样返回")
+	}
+
+	hasher := NewHasher([]Key{{ID: "k1", Secret: []byte("s")}})
+	if hasher.Pseudonymize("10.0.0.1") == "10.0.0.1" {
+		t.Error("隐私模式下不应返回原始标识")
+	}
+}
+
+func TestVoterScope(t *testing.T) {
+	db := setupTestDB()
+	oldHasher := NewHasher([]Key{{ID: "k1", Secret: []byte("old")}})
+	hash, keyID := oldHasher.Identify("10.0.0.2")
+
+	db.Create(&models.Vote{PollID: 1, OptionID: 1, UserIP: "10.0.0.1"})
+	db.Create(&models.Vote{PollID: 1, OptionID: 1, VoterHash: hash, VoterKeyID: keyID})
+
+	rotated := NewHasher([]Key{{ID: "k2", Secret: []byte("new")}, {ID: "k1", Secret: []byte("old")}})
+
+	var count int64
+	rotated.VoterScope(db.Model(&models.Vote{}), "10.0.0.2").Count(&count)
+	if count != 1 {
+		t.Errorf("轮换后应找到旧密钥保存的投票, 得到 %d", count)
+	}
+
+	// 开启隐私模式前保存的原始IP仍然可以查重
+	rotated.VoterScope(db.Model(&models.Vote{}), "10.0.0.1").Count(&count)
+	if count != 1 {
+		t.Errorf("应找到原始IP保存的投票, 得到 %d", count)
+	}
+
+	var disabled *Hasher
+	disabled.VoterScope(db.Model(&models.Vote{}), "10.0.0.2").Count(&count)
+	if count != 0 {
+		t.Errorf("未开启隐私模式时不应匹配HMAC, 得到 %d", count)
+	}
+}
+
+func TestPurgeIdentifiers(t *testing.T) {
+	db := setupTestDB()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	longAgo := now.AddDate(0, 0, -40)
+	recently := now.AddDate(0, 0, -5)
+
+	expired := models.Poll{Title: "已过期", IsActive: true}
+	db.Create(&expired)
+	db.Model(&expired).Updates(map[string]interface{}{"is_active": false, "closed_at": longAgo})
+
+	recent := models.Poll{Title: "最近关闭", IsActive: true}
+	db.Create(&recent)
+	db.Model(&recent).Updates(map[string]interface{}{"is_active": false, "closed_at": recently})
+
+	open := models.Poll{Title: "进行中", IsActive: true}
+	db.Create(&open)
+
+	option := models.Option{PollID: expired.ID, Text: "A", VoteCount: 2}
+	db.Create(&option)
+
+	db.Create(&models.Vote{PollID: expired.ID, OptionID: option.ID, UserIP: "10.0.0.1"})
+	member := uint(7)
+	db.Create(&models.Vote{PollID: expired.ID, OptionID: option.ID, VoterHash: "abc", VoterKeyID: "k1", RollMemberID: &member})
+	db.Create(&models.Vote{PollID: recent.ID, OptionID: 2, UserIP: "10.0.0.2", RollMemberID: &member})
+	db.Create(&models.Vote{PollID: open.ID, OptionID: 3, UserIP: "10.0.0.3"})
+	db.Create(&models.OptionProposal{PollID: expired.ID, Kind: models.ProposalKindOption, Text: "B", Key: "b", Status: models.ProposalPending, ProposedBy: "10.0.0.1"})
+	db.Create(&models.OptionProposal{PollID: open.ID, Kind: models.ProposalKindOption, Text: "B", Key: "b", Status: models.ProposalPending, ProposedBy: "10.0.0.3"})
+	db.Create(&models.Delegation{Delegator: "abc", Delegate: "ghi", PollID: expired.ID})
+	db.Create(&models.Delegation{Delegator: "abc", Delegate: "ghi", PollID: recent.ID})
+	db.Create(&models.Delegation{Delegator: "abc", Delegate: "ghi", Topic: "finance"})
+
+	closedSurvey := models.Survey{Title: "已关闭的调查"}
+	db.Create(&closedSurvey)
+	db.Model(&closedSurvey).Updates(map[string]interface{}{"is_active": false, "closed_at": longAgo})
+	openSurvey := models.Survey{Title: "进行中的调查"}
+	db.Create(&openSurvey)
+	db.Create(&models.SurveyResponse{SurveyID: closedSurvey.ID, VoterHash: "def", VoterKeyID: "k1"})
+	db.Create(&models.SurveyResponse{SurveyID: openSurvey.ID, UserIP: "10.0.0.3"})
+
+	purged, err := PurgeIdentifiers(db, 30, now, "cli")
+	if err != nil {
+		t.Fatalf("清除标识失败: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("期望清除2条投票的标识, 得到 %d", purged)
+	}
+
+	var votes []models.Vote
+	db.Where("poll_id = ?", expired.ID).Find(&votes)
+	if len(votes) != 2 {
+		t.Fatalf("清除标识不应删除投票记录, 剩余 %d", len(votes))
+	}
+	for _, vote := range votes {
+		if vote.UserIP != "" || vote.VoterHash != "" || vote.VoterKeyID != "" || vote.RollMemberID != nil {
+			t.Errorf("投票标识未被清除: %+v", vote)
+		}
+	}
+
+	// 未过保留期的投票仍关联名册成员，用于统计投票率
+	var linked int64
+	db.Model(&models.Vote{}).Where("roll_member_id IS NOT NULL").Count(&linked)
+	if linked != 1 {
+		t.Errorf("期望保留 1 条名册关联, 得到 %d", linked)
+	}
+
+	var remaining int64
+	db.Model(&models.Vote{}).Where("user_ip <> ''").Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("未过保留期的投票标识应保留, 得到 %d", remaining)
+	}
+
+	var proposals []models.OptionProposal
+	db.Order("id").Find(&proposals)
+	if len(proposals) != 2 || proposals[0].ProposedBy != "" || proposals[1].ProposedBy == "" {
+		t.Errorf("只应清除过保留期的提议人标识, 得到 %+v", proposals)
+	}
+
+	// 过保留期的投票问卷的委托被删除，按标签的委托保留
+	var delegations []models.Delegation
+	db.Order("id").Find(&delegations)
+	if len(delegations) != 2 || delegations[0].PollID != recent.ID || delegations[1].Topic != "finance" {
+		t.Errorf("只应删除过保留期的投票问卷的委托, 得到 %+v", delegations)
+	}
+
+	var responses []models.SurveyResponse
+	db.Order("id").Find(&responses)
+	if len(responses) != 2 || responses[0].VoterHash != "" || responses[0].VoterKeyID != "" || responses[1].UserIP == "" {
+		t.Errorf("只应清除过保留期的调查问卷作答标识, 得到 %+v", responses)
+	}
+
+	db.First(&option, option.ID)
+	if option.VoteCount != 2 {
+		t.Errorf("清除标识不应影响票数, 得到 %d", option.VoteCount)
+	}
+
+	// 审计日志记录截止时间和各类记录的清除条数
+	var logs []models.AuditLog
+	db.Where("action = ?", models.AuditIdentifiersPurged).Find(&logs)
+	if len(logs) != 1 || logs[0].Actor != "cli" || !strings.Contains(logs[0].After, `"votes":2`) ||
+		!strings.Contains(logs[0].After, `"proposals":1`) || !strings.Contains(logs[0].After, `"delegations":1`) || !strings.Contains(logs[0].After, `"survey_responses":1`) ||
+		!strings.Contains(logs[0].After, `"cutoff":"2024-01-31`) {
+		t.Errorf("期望一条清除标识的审计日志, 得到 %+v", logs)
+	}
+
+	// 没有需要清除的标识时不写入审计日志
+	if purged, err := PurgeIdentifiers(db, 30, now, "system"); purged != 0 || err != nil {
+		t.Errorf("期望没有需要清除的标识, 得到 %d %v", purged, err)
+	}
+	var count int64
+	db.Model(&models.AuditLog{}).Count(&count)
+	if count != 1 {
+		t.Errorf("期望 1 条审计日志, 得到 %d", count)
+	}
+}
@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"log"
+	"time"
+	"vote-system/audit"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// PurgeIdentifiers 清除关闭超过retentionDays天的投票问卷中投票人的标识
+//
+// 只清空投票记录、选项提议和调查问卷作答中的标识字段以及投票记录与名册成员的关联，记录和票数保持不变，
+// 统计结果不受影响，清除后的投票不再计入投票率。这些投票问卷的委托只记录委托人和受托人，直接删除；
+// 按标签的委托对之后的投票问卷仍然有效，不清除。
+// 调查问卷按同样的保留期限清除，返回值只统计投票记录。有标识被清除时在同一事务中以actor写入审计日志。
+func PurgeIdentifiers(db *gorm.DB, retentionDays int, now time.Time, actor string) (int64, error) {
+	cutoff := now.AddDate(0, 0, -retentionDays)
+	var votes int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		closedPolls := tx.Model(&models.Poll{}).Unscoped().
+			Select("id").
+			Where("is_active = ? AND closed_at IS NOT NULL AND closed_at < ?", false, cutoff)
+
+		// 投票记录关联的名册成员同样能识别投票人，一并清除
+		result := tx.Model(&models.Vote{}).Unscoped().
+			Where("poll_id IN (?)", closedPolls).
+			Where("user_ip <> '' OR voter_hash <> '' OR roll_member_id IS NOT NULL").
+			Updates(map[string]interface{}{
+				"user_ip":        "",
+				"voter_hash":     "",
+				"voter_key_id":   "",
+				"roll_member_id": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		votes = result.RowsAffected
+
+		// 提议新选项的投票人同样是标识
+		proposals := tx.Model(&models.OptionProposal{}).
+			Where("poll_id IN (?) AND proposed_by <> ''", closedPolls).
+			Update("proposed_by", "")
+		if proposals.Error != nil {
+			return proposals.Error
+		}
+
+		// 委托在关闭时已计入投票，唯一索引不允许清空标识，直接删除
+		delegations := tx.Where("poll_id IN (?)", closedPolls).Delete(&models.Delegation{})
+		if delegations.Error != nil {
+			return delegations.Error
+		}
+
+		closedSurveys := tx.Model(&models.Survey{}).
+			Select("id").
+			Where("is_active = ? AND closed_at IS NOT NULL AND closed_at < ?", false, cutoff)
+		responses := tx.Model(&models.SurveyResponse{}).
+			Where("survey_id IN (?)", closedSurveys).
+			Where("user_ip <> '' OR voter_hash <> ''").
+			Updates(map[string]interface{}{
+				"user_ip":      "",
+				"voter_hash":   "",
+				"voter_key_id": "",
+			})
+		if responses.Error != nil {
+			return responses.Error
+		}
+
+		if votes == 0 && proposals.RowsAffected == 0 && delegations.RowsAffected == 0 && responses.RowsAffected == 0 {
+			return nil
+		}
+		return audit.Record(tx, audit.Entry{
+			Action: models.AuditIdentifiersPurged,
+			Actor:  actor,
+			After: map[string]interface{}{
+				"retention_days":   retentionDays,
+				"cutoff":           cutoff,
+				"votes":            votes,
+				"proposals":        proposals.RowsAffected,
+				"delegations":      delegations.RowsAffected,
+				"survey_responses": responses.RowsAffected,
+			},
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return votes, nil
+}
+
+// RunRetention 定期执行标识清除，retentionDays为0时不运行
+func RunRetention(db *gorm.DB, retentionDays int, interval time.Duration) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := PurgeIdentifiers(db, retentionDays, time.Now(), "system")
+		if err != nil {
+			log.Printf("Error purging voter identifiers: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged voter identifiers from %d votes", purged)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,198 @@
+// Package roll 投票人名册：从CSV导入、检查投票资格和按名册统计投票率
+//
+// CSV第一行为表头，必须包含identifier列（投票人标识，与身份识别方式得到的标识比较，不区分大小写），
+// name和weight列可选，其他列（例如department）作为投票人属性保存。
+package roll
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// MaxMembers 一个名册最多的投票人数
+const MaxMembers = 100000
+
+// 有特殊含义的列，其他列作为属性
+const (
+	ColumnIdentifier = "identifier"
+	ColumnName       = "name"
+	ColumnWeight     = "weight"
+)
+
+// Normalize 规范化投票人标识，导入和投票时都按规范化后的标识比较
+func Normalize(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// Parse 解析CSV名册，任一行无效时返回InvalidRoll并指出行号和列名
+func Parse(r io.Reader) ([]models.RollMember, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, invalid(1, ColumnIdentifier)
+	}
+	if err != nil {
+		return nil, readError(err)
+	}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		if i == 0 {
+			// Excel导出的UTF-8文件带有BOM
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || len(name) > 64 || seen[name] {
+			return nil, invalid(1, "header")
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen[ColumnIdentifier] {
+		return nil, invalid(1, ColumnIdentifier)
+	}
+
+	members := []models.RollMember{}
+	identifiers := map[string]bool{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, readError(err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(members) == MaxMembers {
+			return nil, invalid(line, "rows")
+		}
+
+		member := models.RollMember{Weight: weight.One}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch column := columns[i]; column {
+			case ColumnIdentifier:
+				member.Identifier = Normalize(value)
+				if member.Identifier == "" || len(member.Identifier) > 255 || identifiers[member.Identifier] {
+					return nil, invalid(line, column)
+				}
+				identifiers[member.Identifier] = true
+			case ColumnName:
+				if len(value) > 255 {
+					return nil, invalid(line, column)
+				}
+				member.Name = value
+			case ColumnWeight:
+				w, err := weight.Parse(value)
+				if err != nil {
+					return nil, invalid(line, column)
+				}
+				member.Weight = w
+			default:
+				if len(value) > 255 {
+					return nil, invalid(line, column)
+				}
+				if value == "" {
+					continue
+				}
+				if member.Attributes == nil {
+					member.Attributes = map[string]string{}
+				}
+				member.Attributes[column] = value
+			}
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// Import 在事务中创建名册和其中的投票人
+func Import(tx *gorm.DB, name string, members []models.RollMember) (models.VoterRoll, error) {
+	roll := models.VoterRoll{Name: name}
+	if err := tx.Create(&roll).Error; err != nil {
+		return roll, err
+	}
+	for i := range members {
+		members[i].RollID = roll.ID
+	}
+	if len(members) > 0 {
+		if err := tx.CreateInBatches(&members, 500).Error; err != nil {
+			return roll, err
+		}
+	}
+	roll.MemberCount = len(members)
+	return roll, nil
+}
+
+// Eligible 查找投票人在投票问卷名册中的记录，投票问卷没有名册时返回nil，不在名册中时返回NotEligible
+func Eligible(db *gorm.DB, poll models.Poll, voter string) (*models.RollMember, error) {
+	if poll.RollID == nil {
+		return nil, nil
+	}
+	identifier := Normalize(voter)
+	if identifier == "" {
+		return nil, apierror.New(apierror.NotEligible)
+	}
+
+	var member models.RollMember
+	if err := db.Where("roll_id = ? AND identifier = ?", *poll.RollID, identifier).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.New(apierror.NotEligible)
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// Count 按名册统计投票问卷的投票率，投票问卷没有名册时返回nil
+//
+// 只统计名册中投票人的投票，更换名册后原名册中投票人的投票不计入。
+func Count(db *gorm.DB, poll models.Poll) (*models.Turnout, error) {
+	if poll.RollID == nil {
+		return nil, nil
+	}
+	turnout := &models.Turnout{PollID: poll.ID, RollID: *poll.RollID}
+
+	var eligible, voted int64
+	if err := db.Model(&models.RollMember{}).Where("roll_id = ?", *poll.RollID).Count(&eligible).Error; err != nil {
+		return nil, err
+	}
+	err := db.Model(&models.Vote{}).
+		Where("poll_id = ? AND roll_member_id IN (?)", poll.ID,
+			db.Model(&models.RollMember{}).Select("id").Where("roll_id = ?", *poll.RollID)).
+		Count(&voted).Error
+	if err != nil {
+		return nil, err
+	}
+
+	turnout.Eligible, turnout.Voted = int(eligible), int(voted)
+	if eligible > 0 {
+		turnout.Percentage = math.Round(float64(voted)*10000/float64(eligible)) / 100
+	}
+	return turnout, nil
+}
+
+// invalid 名册第line行column列无效
+func invalid(line int, column string) error {
+	return apierror.New(apierror.InvalidRoll, "line", strconv.Itoa(line), "column", column)
+}
+
+// readError 转换CSV格式错误，列数与表头不一致等错误指出行号
+func readError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return invalid(parseErr.Line, "row")
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package roll
+
+import (
+	"strings"
+	"testing"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.Vote{}, &models.VoterRoll{}, &models.RollMember{})
+	return db
+}
+
+// expectRollError 检查错误为InvalidRoll并指出了行号和列名
+func expectRollError(t *testing.T, err error, line, column string) {
+	t.Helper()
+	apiErr, ok := apierror.As(err)
+	if !ok || apiErr.Code != apierror.InvalidRoll {
+		t.Fatalf("期望 %s, 得到 %v", apierror.InvalidRoll, err)
+	}
+	want := []string{"line", line, "column", column}
+	if strings.Join(apiErr.Params, ",") != strings.Join(want, ",") {
+		t.Errorf("期望参数 %v, 得到 %v", want, apiErr.Params)
+	}
+}
+
+func TestParse(t *testing.T) {
+	members, err := Parse(strings.NewReader("\ufeffIdentifier,Name,Department,Weight\n" +
+		" Alice@Example.com ,张三,研发,2.50\n" +
+		"bob@example.com,李四,,\n"))
+	if err != nil {
+		t.Fatalf("解析名册失败: %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("期望 2 人, 得到 %d", len(members))
+	}
+	alice, bob := members[0], members[1]
+	if alice.Identifier != "alice@example.com" || alice.Name != "张三" || alice.Weight != 2500000 || alice.Attributes["department"] != "研发" {
+		t.Errorf("第一个投票人不正确: %+v", alice)
+	}
+	if bob.Weight != weight.One || bob.Attributes != nil {
+		t.Errorf("空的权重和属性应使用默认值: %+v", bob)
+	}
+
+	_, err = Parse(strings.NewReader(""))
+	expectRollError(t, err, "1", "identifier")
+	_, err = Parse(strings.NewReader("name,department\n张三,研发\n"))
+	expectRollError(t, err, "1", "identifier")
+	_, err = Parse(strings.NewReader("identifier,name,Name\n"))
+	expectRollError(t, err, "1", "header")
+	_, err = Parse(strings.NewReader("identifier,weight\na,1\nA,2\n"))
+	expectRollError(t, err, "3", "identifier")
+	_, err = Parse(strings.NewReader("identifier,weight\na,1\nb,0\n"))
+	expectRollError(t, err, "3", "weight")
+	_, err = Parse(strings.NewReader("identifier,name\na,张三\nb\n"))
+	expectRollError(t, err, "3", "row")
+}
+
+func TestEligibleAndCount(t *testing.T) {
+	db := setupTestDB(t)
+	members, _ := Parse(strings.NewReader("identifier\nalice\nbob\ncarol\n"))
+	roll, err := Import(db, "董事会", members)
+	if err != nil || roll.MemberCount != 3 {
+		t.Fatalf("导入名册失败: %v", err)
+	}
+
+	open := models.Poll{Title: "公开投票", IsActive: true}
+	db.Create(&open)
+	if member, err := Eligible(db, open, "anyone"); member != nil || err != nil {
+		t.Errorf("没有名册时所有人都可以投票: %v %v", member, err)
+	}
+	if turnout, _ := Count(db, open); turnout != nil {
+		t.Errorf("没有名册时不统计投票率: %+v", turnout)
+	}
+
+	poll := models.Poll{Title: "董事会决议", IsActive: true, RollID: &roll.ID}
+	db.Create(&poll)
+	member, err := Eligible(db, poll, " ALICE ")
+	if err != nil || member == nil || member.Identifier != "alice" {
+		t.Fatalf("名册中的投票人应有投票资格: %v %v", member, err)
+	}
+	for _, voter := range []string{"", "dave"} {
+		_, err := Eligible(db, poll, voter)
+		if apiErr, ok := apierror.As(err); !ok || apiErr.Code != apierror.NotEligible {
+			t.Errorf("%q 期望 %s, 得到 %v", voter, apierror.NotEligible, err)
+		}
+	}
+
+	// 名册外的投票（例如更换名册前的投票）不计入
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: 1, RollMemberID: &member.ID})
+	other := uint(999)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: 1, RollMemberID: &other})
+	turnout, err := Count(db, poll)
+	if err != nil || turnout.Eligible != 3 || turnout.Voted != 1 || turnout.Percentage != 33.33 {
+		t.Errorf("投票率不正确: %+v %v", turnout, err)
+	}
+}
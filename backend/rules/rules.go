@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"errors"
+	"log"
+	"time"
+	"vote-system/audit"
+	"vote-system/delegation"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// Outcome 投票问卷关闭时的结果，随poll_closed事件广播
+type Outcome struct {
+	Reason      string        `json:"reason"`
+	TotalVotes  int           `json:"total_votes"`
+	TotalWeight weight.Weight `json:"total_weight"`
+	// WinnerID 按投票方式领先的选项，见tally.Leader，并列或无人投票时为空
+	WinnerID     *uint         `json:"winner_id"`
+	WinnerText   string        `json:"winner_text,omitempty"`
+	WinnerVotes  int           `json:"winner_votes,omitempty"`
+	WinnerWeight weight.Weight `json:"winner_weight,omitempty"`
+	// WinnerPoints 和 WinnerAverage 评分投票和波达计数中领先选项的得分和平均得分
+	WinnerPoints  weight.Weight `json:"winner_points,omitempty"`
+	WinnerAverage *float64      `json:"winner_average,omitempty"`
+	// DelegatedVotes 关闭时按委托计入的票数，已包含在总票数中
+	DelegatedVotes int `json:"delegated_votes,omitempty"`
+}
+
+// Evaluate 判断投票问卷是否满足自动关闭规则，返回关闭原因
+//
+// 总票数、最少票数和有投票资格的人数按投票人数计算，得票率和领先者按加权票数计算。
+// 单选以外的投票方式只支持截止时间，见models.ModeConflict。
+func Evaluate(poll models.Poll, now time.Time) (string, bool) {
+	rules := poll.Rules
+	if !poll.Plurality() {
+		rules = models.PollRules{ClosesAt: rules.ClosesAt}
+	}
+	t := count(poll.Options)
+
+	if rules.TargetVotes > 0 && t.votes >= rules.TargetVotes {
+		return models.CloseReasonTarget, true
+	}
+
+	if rules.WinPercent > 0 && t.weight > 0 {
+		for _, option := range poll.Options {
+			if option.VoteCount >= rules.WinMinVotes && weight.AtLeast(option.WeightedVotes, t.weight, rules.WinPercent) {
+				return models.CloseReasonThreshold, true
+			}
+		}
+	}
+
+	// 剩余票数全部投给第二名也无法追平领先者；有权重不为1的投票时剩余投票人的权重未知，不做判断
+	if rules.EligibleVoters > 0 && t.votes > 0 && t.unweighted &&
+		t.leader-t.runnerUp > weight.Of(rules.EligibleVoters-t.votes) {
+		return models.CloseReasonUnassailable, true
+	}
+
+	if rules.ClosesAt != nil && !now.Before(*rules.ClosesAt) {
+		return models.CloseReasonDeadline, true
+	}
+
+	return "", false
+}
+
+// totals 投票问卷的统计数据
+type totals struct {
+	votes  int
+	weight weight.Weight
+	// leader 和 runnerUp 最高和第二高的加权票数
+	leader, runnerUp weight.Weight
+	// unweighted 每个选项的加权票数都等于票数，即没有使用权重
+	unweighted bool
+}
+
+// count 统计总票数、总加权票数和最高的两个加权票数
+func count(options []models.Option) totals {
+	t := totals{unweighted: true}
+	for _, option := range options {
+		t.votes += option.VoteCount
+		t.weight += option.WeightedVotes
+		if option.WeightedVotes != weight.Of(option.VoteCount) {
+			t.unweighted = false
+		}
+		switch {
+		case option.WeightedVotes > t.leader:
+			t.leader, t.runnerUp = option.WeightedVotes, t.leader
+		case option.WeightedVotes > t.runnerUp:
+			t.runnerUp = option.WeightedVotes
+		}
+	}
+	return t
+}
+
+// NewOutcome 根据当前票数生成关闭结果
+//
+// 单选以外的投票方式中各选项票数之和不是选票数，调用方需要用tally.Ballots修正总票数。
+func NewOutcome(poll models.Poll, reason string) Outcome {
+	outcome := Outcome{Reason: reason}
+
+	t := count(poll.Options)
+	outcome.TotalVotes, outcome.TotalWeight = t.votes, t.weight
+
+	leader := tally.Leader(poll)
+	if leader == nil {
+		return outcome
+	}
+	id := leader.ID
+	outcome.WinnerID = &id
+	outcome.WinnerText = leader.Text
+	outcome.WinnerVotes = leader.VoteCount
+	outcome.WinnerWeight = leader.WeightedVotes
+	if poll.Mode == models.ModeScore || poll.Mode == models.ModeBorda {
+		average := float64(leader.Points) / float64(leader.WeightedVotes)
+		outcome.WinnerPoints = leader.Points
+		outcome.WinnerAverage = &average
+	}
+	return outcome
+}
+
+// Close 在事务中关闭投票问卷、按委托计入委托人的投票、记录原因并写入poll_closed事件
+//
+// 只有仍处于开启状态时才会关闭，并发关闭时返回false。自动关闭规则按关闭前的直接投票判断。
+// hasher与保存委托时一致，隐私模式下委托只保存标识的HMAC。
+func Close(tx *gorm.DB, poll *models.Poll, hasher *privacy.Hasher, reason string, now time.Time) (Outcome, bool, error) {
+	result := tx.Model(&models.Poll{}).Where("id = ? AND is_active = ?", poll.ID, true).Updates(map[string]interface{}{
+		"is_active":    false,
+		"closed_at":    now,
+		"close_reason": reason,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return Outcome{}, false, result.Error
+	}
+	poll.IsActive = false
+	poll.ClosedAt = &now
+	poll.CloseReason = reason
+
+	delegated, err := delegation.Apply(tx, poll, hasher, now)
+	if err != nil {
+		return Outcome{}, false, err
+	}
+
+	outcome := NewOutcome(*poll, reason)
+	outcome.DelegatedVotes = delegated
+	if !poll.Plurality() {
+		if outcome.TotalVotes, outcome.TotalWeight, err = tally.Ballots(tx, poll.ID); err != nil {
+			return Outcome{}, false, err
+		}
+	}
+	if err := outbox.Enqueue(tx, poll.ID, models.EventPollClosed, outcome); err != nil {
+		return Outcome{}, false, err
+	}
+	return outcome, true, nil
+}
+
+// Apply 检查投票问卷的自动关闭规则，满足时关闭并写入审计日志，返回是否关闭
+func Apply(db *gorm.DB, hasher *privacy.Hasher, pollID uint, now time.Time) (bool, error) {
+	closed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var poll models.Poll
+		if err := tx.Preload("Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).Where("is_active = ?", true).First(&poll, pollID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		reason, ok := Evaluate(poll, now)
+		if !ok {
+			return nil
+		}
+
+		outcome, ok, err := Close(tx, &poll, hasher, reason, now)
+		if err != nil || !ok {
+			return err
+		}
+		closed = true
+
+		return audit.Record(tx, audit.Entry{
+			Action: models.AuditPollClosed,
+			Actor:  "system",
+			PollID: &poll.ID,
+			Before: map[string]interface{}{"is_active": true},
+			After:  map[string]interface{}{"is_active": false, "close_reason": reason, "outcome": outcome},
+		})
+	})
+	return closed, err
+}
+
+// RunDeadlines 定期关闭已到截止时间的投票问卷
+func RunDeadlines(db *gorm.DB, dispatcher *outbox.Dispatcher, hasher *privacy.Hasher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var ids []uint
+		if err := db.Model(&models.Poll{}).Where("is_active = ? AND closes_at <= ?", true, time.Now()).
+			Pluck("id", &ids).Error; err != nil {
+			log.Printf("Error loading expired polls: %v", err)
+			continue
+		}
+
+		for _, id := range ids {
+			closed, err := Apply(db, hasher, id, time.Now())
+			if err != nil {
+				log.Printf("Error closing poll %d: %v", id, err)
+				continue
+			}
+			if closed {
+				dispatcher.Notify()
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{})
+	return db
+}
+
+func pollWithVotes(rules models.PollRules, votes ...int) models.Poll {
+	poll := models.Poll{IsActive: true, Rules: rules}
+	for i, count := range votes {
+		poll.Options = append(poll.Options, models.Option{ID: uint(i + 1), Text: string(rune('A' + i)), VoteCount: count, WeightedVotes: weight.Of(count)})
+	}
+	return poll
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	cases := []struct {
+		name     string
+		poll     models.Poll
+		expected string
+	}{
+		{"无规则", pollWithVotes(models.PollRules{}, 5, 3), ""},
+		{"未达到总票数", pollWithVotes(models.PollRules{TargetVotes: 10}, 5, 4), ""},
+		{"达到总票数", pollWithVotes(models.PollRules{TargetVotes: 10}, 6, 4), models.CloseReasonTarget},
+		{"得票率达标但票数不足", pollWithVotes(models.PollRules{WinPercent: 60, WinMinVotes: 5}, 3, 1), ""},
+		{"得票率和票数均达标", pollWithVotes(models.PollRules{WinPercent: 60, WinMinVotes: 5}, 6, 4), models.CloseReasonThreshold},
+		{"得票率不足", pollWithVotes(models.PollRules{WinPercent: 70}, 6, 4), ""},
+		{"领先仍可被追上", pollWithVotes(models.PollRules{EligibleVoters: 10}, 4, 1), ""},
+		{"领先无法被追上", pollWithVotes(models.PollRules{EligibleVoters: 10}, 6, 1), models.CloseReasonUnassailable},
+		{"全部投完但平票", pollWithVotes(models.PollRules{EligibleVoters: 4}, 2, 2), ""},
+		{"未到截止时间", pollWithVotes(models.PollRules{ClosesAt: &future}, 1), ""},
+		{"已到截止时间", pollWithVotes(models.PollRules{ClosesAt: &past}, 1), models.CloseReasonDeadline},
+	}
+
+	for _, tc := range cases {
+		reason, ok := Evaluate(tc.poll, now)
+		if reason != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("%s: 期望 %q, 得到 %q", tc.name, tc.expected, reason)
+		}
+	}
+}
+
+func TestNewOutcome(t *testing.T) {
+	outcome := NewOutcome(pollWithVotes(models.PollRules{}, 2, 5, 1), models.CloseReasonManual)
+	if outcome.TotalVotes != 8 || outcome.WinnerID == nil || *outcome.WinnerID != 2 || outcome.WinnerVotes != 5 {
+		t.Errorf("结果不正确: %+v", outcome)
+	}
+
+	outcome = NewOutcome(pollWithVotes(models.PollRules{}, 3, 3), models.CloseReasonManual)
+	if outcome.WinnerID != nil {
+		t.Errorf("平票时不应有胜出选项, 得到 %d", *outcome.WinnerID)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	// A有3票，每票权重1；B有1票，权重5
+	poll := pollWithVotes(models.PollRules{WinPercent: 60, EligibleVoters: 5}, 3, 1)
+	poll.Options[1].WeightedVotes = 5 * weight.One
+
+	outcome := NewOutcome(poll, models.CloseReasonManual)
+	if outcome.WinnerID == nil || *outcome.WinnerID != 2 || outcome.WinnerVotes != 1 || outcome.WinnerWeight != 5*weight.One ||
+		outcome.TotalVotes != 4 || outcome.TotalWeight != 8*weight.One {
+		t.Errorf("应按加权票数决定胜出选项: %+v", outcome)
+	}
+
+	// 5/8 = 62.5% 达到得票率
+	if reason, _ := Evaluate(poll, time.Now()); reason != models.CloseReasonThreshold {
+		t.Errorf("期望 %s, 得到 %q", models.CloseReasonThreshold, reason)
+	}
+
+	// 按人数领先无法被追上，但剩余投票人的权重未知
+	poll.Rules.WinPercent = 0
+	poll.Options[0].VoteCount, poll.Options[0].WeightedVotes = 4, 4*weight.One
+	poll.Options[1].WeightedVotes = weight.One / 2
+	if reason, ok := Evaluate(poll, time.Now()); ok {
+		t.Errorf("有权重时不应按剩余人数判断领先, 得到 %q", reason)
+	}
+}
+
+func TestApply(t *testing.T) {
+	db := setupTestDB()
+
+	poll := models.Poll{Title: "测试投票", IsActive: true, Rules: models.PollRules{TargetVotes: 3}}
+	db.Create(&poll)
+	option := models.Option{PollID: poll.ID, Text: "选项1", VoteCount: 2, WeightedVotes: weight.Of(2)}
+	db.Create(&option)
+
+	closed, err := Apply(db, nil, poll.ID, time.Now())
+	if err != nil || closed {
+		t.Fatalf("未满足规则时不应关闭: %v, %v", closed, err)
+	}
+
+	db.Model(&option).Updates(map[string]interface{}{"vote_count": 3, "weighted_votes": weight.Of(3)})
+	closed, err = Apply(db, nil, poll.ID, time.Now())
+	if err != nil || !closed {
+		t.Fatalf("满足规则时应关闭: %v, %v", closed, err)
+	}
+
+	db.First(&poll, poll.ID)
+	if poll.IsActive || poll.ClosedAt == nil || poll.CloseReason != models.CloseReasonTarget {
+		t.Errorf("关闭状态不正确: %+v", poll)
+	}
+
+	var event models.OutboxEvent
+	if err := db.Where("type = ?", models.EventPollClosed).First(&event).Error; err != nil {
+		t.Fatalf("期望写入poll_closed事件: %v", err)
+	}
+	var outcome Outcome
+	json.Unmarshal([]byte(event.Payload), &outcome)
+	if outcome.Reason != models.CloseReasonTarget || outcome.WinnerID == nil || *outcome.WinnerID != option.ID {
+		t.Errorf("事件结果不正确: %+v", outcome)
+	}
+
+	var audits int64
+	db.Model(&models.AuditLog{}).Where("action = ? AND actor = ?", models.AuditPollClosed, "system").Count(&audits)
+	if audits != 1 {
+		t.Errorf("期望1条审计记录, 得到 %d", audits)
+	}
+
+	// 已关闭的投票问卷不会重复关闭
+	closed, _ = Apply(db, nil, poll.ID, time.Now())
+	if closed {
+		t.Error("已关闭的投票问卷不应再次关闭")
+	}
+}
@@ -0,0 +1,175 @@
+// Package search 投票问卷标题、描述和选项文本的全文检索
+//
+// MySQL使用带ngram分词器的FULLTEXT索引；SQLite使用trigram分词的FTS5虚拟表poll_search，
+// 由触发器与polls、options表保持同步。go-sqlite3默认不包含FTS5，需要以 -tags sqlite_fts5 编译，
+// 索引不可用或关键词短于分词长度时按LIKE匹配，结果相同，只是无法利用索引。
+package search
+
+import (
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLite全文检索表名
+const ftsTable = "poll_search"
+
+// MySQL全文索引名
+const (
+	pollIndex   = "idx_polls_fulltext"
+	optionIndex = "idx_options_fulltext"
+)
+
+// 全文索引能匹配的最短关键词（字符数），MySQL为默认的ngram_token_size
+const (
+	minTrigram = 3
+	minNgram   = 2
+)
+
+// sqliteSchema 创建poll_search及同步触发器，rowid为投票问卷ID
+var sqliteSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS poll_search USING fts5(title, description, options, tokenize = 'trigram')`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_polls_insert AFTER INSERT ON polls BEGIN
+		INSERT INTO poll_search(rowid, title, description, options) VALUES (new.id, new.title, new.description, '');
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_polls_update AFTER UPDATE OF title, description ON polls BEGIN
+		UPDATE poll_search SET title = new.title, description = new.description WHERE rowid = new.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_polls_delete AFTER DELETE ON polls BEGIN
+		DELETE FROM poll_search WHERE rowid = old.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_options_insert AFTER INSERT ON options BEGIN
+		UPDATE poll_search SET options = ` + optionText("new.poll_id") + ` WHERE rowid = new.poll_id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_options_update AFTER UPDATE OF text, deleted_at ON options BEGIN
+		UPDATE poll_search SET options = ` + optionText("new.poll_id") + ` WHERE rowid = new.poll_id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS poll_search_options_delete AFTER DELETE ON options BEGIN
+		UPDATE poll_search SET options = ` + optionText("old.poll_id") + ` WHERE rowid = old.poll_id;
+	END`,
+}
+
+// optionText 拼接投票问卷未删除选项文本的子查询
+func optionText(pollID string) string {
+	return `COALESCE((SELECT group_concat(text, ' ') FROM options WHERE poll_id = ` + pollID + ` AND deleted_at IS NULL), '')`
+}
+
+// Migrate 创建全文索引，需在polls、options表迁移之后调用
+//
+// SQLite未编译FTS5时只记录日志，检索按LIKE匹配。
+func Migrate(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return migrateMySQL(db)
+	case "sqlite":
+		return migrateSQLite(db)
+	}
+	return nil
+}
+
+func migrateMySQL(db *gorm.DB) error {
+	indexes := []struct{ table, name, columns string }{
+		{"polls", pollIndex, "title, description"},
+		{"options", optionIndex, "text"},
+	}
+	for _, index := range indexes {
+		if db.Migrator().HasIndex(index.table, index.name) {
+			continue
+		}
+		if err := db.Exec("ALTER TABLE " + index.table + " ADD FULLTEXT INDEX " + index.name +
+			" (" + index.columns + ") WITH PARSER ngram").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateSQLite(db *gorm.DB) error {
+	// 未编译FTS5属于预期情况，不输出GORM的错误日志
+	probe := db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+	if err := probe.Exec(sqliteSchema[0]).Error; err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			log.Printf("SQLite FTS5 unavailable, poll search falls back to LIKE (build with -tags sqlite_fts5): %v", err)
+			return nil
+		}
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range sqliteSchema[1:] {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		// 每次启动重建索引内容，覆盖触发器创建之前写入的数据
+		if err := tx.Exec("DELETE FROM poll_search").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO poll_search(rowid, title, description, options)
+			SELECT id, title, description, ` + optionText("polls.id") + ` FROM polls`).Error
+	})
+}
+
+// indexed 返回当前数据库可用的全文索引能匹配的最短关键词，0表示没有全文索引
+func indexed(db *gorm.DB) int {
+	switch db.Dialector.Name() {
+	case "mysql":
+		if db.Migrator().HasIndex("polls", pollIndex) && db.Migrator().HasIndex("options", optionIndex) {
+			return minNgram
+		}
+	case "sqlite":
+		if db.Migrator().HasTable(ftsTable) {
+			return minTrigram
+		}
+	}
+	return 0
+}
+
+// Terms 将检索词按空白拆分为关键词，去掉双引号，所有关键词都需匹配
+func Terms(query string) []string {
+	return strings.Fields(strings.ReplaceAll(query, `"`, " "))
+}
+
+// Match 返回筛选polls表的scope，标题、描述或任一未删除选项的文本包含每个关键词的投票问卷才会保留
+func Match(query string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		terms := Terms(query)
+		if len(terms) == 0 {
+			return db
+		}
+
+		min := indexed(db.Session(&gorm.Session{NewDB: true}))
+		for _, term := range terms {
+			if min == 0 || utf8.RuneCountInString(term) < min {
+				db = like(db, term)
+				continue
+			}
+			switch db.Dialector.Name() {
+			case "mysql":
+				phrase := `"` + term + `"`
+				db = db.Where("(MATCH(polls.title, polls.description) AGAINST(? IN BOOLEAN MODE)"+
+					" OR polls.id IN (SELECT poll_id FROM options WHERE deleted_at IS NULL AND MATCH(text) AGAINST(? IN BOOLEAN MODE)))",
+					phrase, phrase)
+			case "sqlite":
+				db = db.Where("polls.id IN (SELECT rowid FROM poll_search WHERE poll_search MATCH ?)", `"`+term+`"`)
+			}
+		}
+		return db
+	}
+}
+
+// like 按LIKE匹配单个关键词，大小写是否敏感取决于数据库的排序规则
+func like(db *gorm.DB, term string) *gorm.DB {
+	pattern := "%" + EscapeLike(term) + "%"
+	return db.Where("(polls.title LIKE ? ESCAPE '!' OR polls.description LIKE ? ESCAPE '!'"+
+		" OR EXISTS (SELECT 1 FROM options WHERE options.poll_id = polls.id AND options.deleted_at IS NULL AND options.text LIKE ? ESCAPE '!'))",
+		pattern, pattern, pattern)
+}
+
+// EscapeLike 转义LIKE中的通配符，需配合 ESCAPE '!' 使用
+func EscapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"sort"
+	"testing"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupDB 迁移表结构并创建全文索引，未以 -tags sqlite_fts5 编译时检索按LIKE匹配
+func setupDB(t *testing.T, polls ...models.Poll) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	// 内存数据库每个连接各自独立，虚拟表和触发器需要在同一个连接上
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	db.AutoMigrate(&models.Poll{}, &models.Option{})
+
+	// 迁移前已有的数据由Migrate补建索引
+	for i := range polls {
+		db.Create(&polls[i])
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("创建全文索引失败: %v", err)
+	}
+	t.Logf("全文索引最短关键词: %d", indexed(db))
+	return db
+}
+
+// titles 返回匹配检索词的投票问卷标题
+func titles(t *testing.T, db *gorm.DB, query string) []string {
+	t.Helper()
+	var polls []models.Poll
+	if err := db.Model(&models.Poll{}).Scopes(Match(query)).Find(&polls).Error; err != nil {
+		t.Fatalf("检索 %q 失败: %v", query, err)
+	}
+	result := []string{}
+	for _, poll := range polls {
+		result = append(result, poll.Title)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMatch(t *testing.T) {
+	db := setupDB(t,
+		models.Poll{Title: "最喜欢的编程语言", Description: "后端团队调查", Options: []models.Option{{Text: "Rust"}, {Text: "Go"}}},
+		models.Poll{Title: "午餐吃什么", Description: "100% 匿名", Options: []models.Option{{Text: "面条"}, {Text: "米饭"}}},
+	)
+	db.Create(&models.Poll{Title: "Frontend framework", Description: "Pick one", Options: []models.Option{{Text: "React"}, {Text: "Svelte"}}})
+
+	tests := []struct {
+		query  string
+		expect []string
+	}{
+		{"", []string{"Frontend framework", "午餐吃什么", "最喜欢的编程语言"}},
+		{"rust", []string{"最喜欢的编程语言"}},
+		{"Go", []string{"最喜欢的编程语言"}},
+		{"svelte", []string{"Frontend framework"}},
+		{"午餐吃", []string{"午餐吃什么"}},
+		{"编程语言 团队", []string{"最喜欢的编程语言"}},
+		{"编程语言 svelte", []string{}},
+		{`"pick one"`, []string{"Frontend framework"}},
+		{"%", []string{"午餐吃什么"}},
+		{"_", []string{}},
+	}
+	for _, tt := range tests {
+		if got := titles(t, db, tt.query); !equal(got, tt.expect) {
+			t.Errorf("检索 %q: 期望 %v, 得到 %v", tt.query, tt.expect, got)
+		}
+	}
+}
+
+func TestMatchFollowsUpdates(t *testing.T) {
+	db := setupDB(t)
+	poll := models.Poll{Title: "团建地点", Options: []models.Option{{Text: "Beach"}, {Text: "Mountain"}}}
+	db.Create(&poll)
+
+	db.Model(&poll.Options[0]).Update("text", "Lakeside")
+	if got := titles(t, db, "beach"); len(got) != 0 {
+		t.Errorf("修改后的选项文本不应再匹配, 得到 %v", got)
+	}
+	if got := titles(t, db, "lakeside"); len(got) != 1 {
+		t.Errorf("应匹配新的选项文本, 得到 %v", got)
+	}
+
+	db.Delete(&poll.Options[1])
+	if got := titles(t, db, "mountain"); len(got) != 0 {
+		t.Errorf("已删除的选项不应匹配, 得到 %v", got)
+	}
+
+	db.Model(&poll).Update("title", "年会地点")
+	if got := titles(t, db, "年会地点"); len(got) != 1 {
+		t.Errorf("应匹配新的标题, 得到 %v", got)
+	}
+	if got := titles(t, db, "团建地点"); len(got) != 0 {
+		t.Errorf("旧标题不应再匹配, 得到 %v", got)
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms(`  "foo bar"  baz `)
+	if !equal(got, []string{"foo", "bar", "baz"}) {
+		t.Errorf("期望 [foo bar baz], 得到 %v", got)
+	}
+}
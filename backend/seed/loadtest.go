@@ -0,0 +1,149 @@
+package seed
+
+import (
+	"fmt"
+	"time"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/stats"
+
+	"gorm.io/gorm"
+)
+
+// loadTestSlug 压测投票问卷的slug，用于判断是否已初始化
+const loadTestSlug = "load-test"
+
+// batchSize 批量插入的行数
+const batchSize = 1000
+
+// LoadTest 压测数据集：一个包含大量选项和投票记录的投票问卷
+type LoadTest struct {
+	Options int
+	Votes   int
+	// Span 投票时间在最近Span内均匀分布，用于投票趋势
+	Span time.Duration
+}
+
+// DefaultLoadTest load-test数据集的规模
+var DefaultLoadTest = LoadTest{Options: 2000, Votes: 20000, Span: 24 * time.Hour}
+
+// Run 创建压测投票问卷，已存在时跳过
+//
+// 已有开启的投票问卷时压测投票问卷保持关闭，保证只有一个开启的投票问卷。
+func (l LoadTest) Run(db *gorm.DB, hasher *privacy.Hasher) (*Result, error) {
+	result := &Result{Fixture: FixtureLoadTest}
+
+	var count int64
+	if err := db.Unscoped().Model(&models.Poll{}).Where("slug = ?", loadTestSlug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		result.Skipped = 1
+		return result, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.Poll{}).Where("is_active = ?", true).Count(&active).Error; err != nil {
+			return err
+		}
+
+		slug := loadTestSlug
+		poll := models.Poll{
+			Slug:        &slug,
+			Title:       "压力测试",
+			Description: fmt.Sprintf("%d个选项、%d条投票记录", l.Options, l.Votes),
+			IsActive:    true,
+		}
+		if err := tx.Create(&poll).Error; err != nil {
+			return err
+		}
+		// IsActive带default标签，零值会被替换为默认值，需要单独更新
+		if active > 0 {
+			poll.IsActive = false
+			if err := tx.Model(&poll).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		options := make([]models.Option, l.Options)
+		for i := range options {
+			options[i] = models.Option{PollID: poll.ID, Text: fmt.Sprintf("选项 %04d", i+1)}
+		}
+		if err := tx.CreateInBatches(options, batchSize).Error; err != nil {
+			return err
+		}
+
+		if err := l.insertVotes(tx, poll.ID, options, hasher); err != nil {
+			return err
+		}
+		// 票数和汇总数据由投票记录统一计算
+		if _, err := stats.Reconcile(tx, poll.ID); err != nil {
+			return err
+		}
+
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollCreated, map[string]string{"title": poll.Title}); err != nil {
+			return err
+		}
+		pollID := poll.ID
+		return audit.Record(tx, audit.Entry{
+			Action: models.AuditPollSeeded,
+			Actor:  "system",
+			PollID: &pollID,
+			After: map[string]interface{}{
+				"fixture": FixtureLoadTest,
+				"title":   poll.Title,
+				"options": l.Options,
+				"votes":   l.Votes,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Created = 1
+	result.Votes = l.Votes
+	return result, nil
+}
+
+// insertVotes 批量插入投票记录，前面的选项得票更多，每条记录使用不同的投票人标识
+func (l LoadTest) insertVotes(tx *gorm.DB, pollID uint, options []models.Option, hasher *privacy.Hasher) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	batch := make([]models.Vote, 0, batchSize)
+	for i := 0; i < l.Votes; i++ {
+		// 平方分布使票数集中在前面的选项
+		r := float64((i*7919)%l.Votes) / float64(l.Votes)
+		option := options[int(r*r*float64(len(options)))]
+
+		vote := models.Vote{
+			PollID:    pollID,
+			OptionID:  option.ID,
+			CreatedAt: now.Add(-l.Span + time.Duration(i)*l.Span/time.Duration(l.Votes)),
+		}
+		voter := fmt.Sprintf("10.%d.%d.%d", (i>>16)&255, (i>>8)&255, i&255)
+		if hasher.Enabled() {
+			vote.VoterHash, vote.VoterKeyID = hasher.Identify(voter)
+		} else {
+			vote.UserIP = voter
+		}
+
+		batch = append(batch, vote)
+		if len(batch) == batchSize {
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return tx.Create(&batch).Error
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package seed
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"vote-system/audit"
+	"vote-system/manifest"
+	"vote-system/privacy"
+
+	"gorm.io/gorm"
+)
+
+// 内置的数据集，其余名称按文件路径读取YAML或JSON文档
+const (
+	FixtureEmpty    = "empty"
+	FixtureDemo     = "demo"
+	FixtureLoadTest = "load-test"
+)
+
+// ErrProduction 生产模式下不接受来自配置的数据集
+var ErrProduction = errors.New("seeding from configuration is disabled in production, pass --seed explicitly")
+
+//go:embed demo.yaml
+var demoDocument []byte
+
+// Result 初始化数据的结果
+type Result struct {
+	Fixture string `json:"fixture"`
+	// Created 新建的投票问卷数，已存在的投票问卷计入Skipped
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+	Votes   int `json:"votes"`
+}
+
+// Select 决定要使用的数据集，命令行参数优先于配置，生产模式下只接受命令行参数
+//
+// 返回空字符串表示不初始化数据。
+func Select(flagValue, configValue string, production bool) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if configValue != "" && production {
+		return "", ErrProduction
+	}
+	return configValue, nil
+}
+
+// Run 按名称或文件路径初始化数据，已存在的投票问卷（按slug匹配）不会被修改，重复执行是安全的
+func Run(db *gorm.DB, fixture string, hasher *privacy.Hasher) (*Result, error) {
+	switch fixture {
+	case FixtureEmpty:
+		return &Result{Fixture: fixture}, nil
+	case FixtureDemo:
+		return applyDocument(db, fixture, demoDocument)
+	case FixtureLoadTest:
+		return DefaultLoadTest.Run(db, hasher)
+	}
+
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("unknown fixture %q: %w", fixture, err)
+	}
+	return applyDocument(db, fixture, data)
+}
+
+// applyDocument 以种子模式应用文档
+func applyDocument(db *gorm.DB, fixture string, data []byte) (*Result, error) {
+	doc, err := manifest.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := manifest.Apply(db, doc, manifest.ApplyOptions{
+		Audit: audit.Entry{Actor: "system"},
+		Seed:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Fixture: fixture}
+	for _, change := range plan.Changes {
+		if change.Action == manifest.ActionCreate {
+			result.Created++
+		} else {
+			result.Skipped++
+		}
+	}
+	return result, nil
+}
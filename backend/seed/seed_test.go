@@ -0,0 +1,174 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/stats"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.VoteRollup{}, &models.VoterRoll{}, &models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{})
+	return db
+}
+
+func TestSelect(t *testing.T) {
+	cases := []struct {
+		flag, config string
+		production   bool
+		expected     string
+		err          bool
+	}{
+		{"", "", false, "", false},
+		{"", "", true, "", false},
+		{"", "demo", false, "demo", false},
+		{"", "demo", true, "", true},
+		{"load-test", "demo", true, "load-test", false},
+	}
+	for _, tc := range cases {
+		got, err := Select(tc.flag, tc.config, tc.production)
+		if got != tc.expected || (err != nil) != tc.err {
+			t.Errorf("Select(%q, %q, %v): 期望 %q/%v, 得到 %q/%v", tc.flag, tc.config, tc.production, tc.expected, tc.err, got, err)
+		}
+	}
+}
+
+func TestRun_Demo(t *testing.T) {
+	db := setupTestDB()
+
+	result, err := Run(db, FixtureDemo, nil)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	if result.Created != 2 {
+		t.Errorf("期望创建2个投票问卷, 得到 %+v", result)
+	}
+
+	var poll models.Poll
+	db.Preload("Options").Where("slug = ?", "favorite-language").First(&poll)
+	if poll.Title != "您最喜欢的编程语言是什么？" || !poll.IsActive || len(poll.Options) != 5 {
+		t.Errorf("demo投票问卷不正确: %+v", poll)
+	}
+	// 只有一个开启的投票问卷
+	var active int64
+	db.Model(&models.Poll{}).Where("is_active = ?", true).Count(&active)
+	if active != 1 {
+		t.Errorf("期望1个开启的投票问卷, 得到 %d", active)
+	}
+
+	// 重复执行不会重复创建，也不会覆盖修改
+	db.Model(&poll).Update("title", "已修改")
+	result, err = Run(db, FixtureDemo, nil)
+	if err != nil || result.Created != 0 || result.Skipped != 2 {
+		t.Errorf("重复执行期望跳过2个, 得到 %+v %v", result, err)
+	}
+	db.First(&poll, poll.ID)
+	if poll.Title != "已修改" {
+		t.Errorf("重复执行不应修改已有投票问卷, 得到 %q", poll.Title)
+	}
+
+	var logs []models.AuditLog
+	db.Where("action = ?", models.AuditPollSeeded).Find(&logs)
+	if len(logs) != 2 || logs[0].Actor != "system" {
+		t.Errorf("期望2条system的poll.seeded审计日志, 得到 %d", len(logs))
+	}
+}
+
+func TestRun_EmptyAndFile(t *testing.T) {
+	db := setupTestDB()
+
+	if _, err := Run(db, FixtureEmpty, nil); err != nil {
+		t.Fatalf("empty数据集失败: %v", err)
+	}
+	var count int64
+	db.Model(&models.Poll{}).Count(&count)
+	if count != 0 {
+		t.Errorf("empty数据集不应创建投票问卷, 得到 %d", count)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.yaml")
+	os.WriteFile(path, []byte("polls: [{slug: standup, title: 站会时间, options: [早上, 下午]}]"), 0o644)
+	result, err := Run(db, path, nil)
+	if err != nil || result.Created != 1 {
+		t.Fatalf("文件数据集期望创建1个, 得到 %+v %v", result, err)
+	}
+
+	if _, err := Run(db, "no-such-fixture", nil); err == nil {
+		t.Error("不存在的数据集应返回错误")
+	}
+}
+
+func TestLoadTest(t *testing.T) {
+	db := setupTestDB()
+	hasher := privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("secret")}})
+
+	fixture := LoadTest{Options: 30, Votes: 2500, Span: time.Hour}
+	result, err := fixture.Run(db, hasher)
+	if err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	if result.Created != 1 || result.Votes != 2500 {
+		t.Errorf("结果不正确: %+v", result)
+	}
+
+	var poll models.Poll
+	db.Preload("Options").Where("slug = ?", "load-test").First(&poll)
+	if len(poll.Options) != 30 {
+		t.Fatalf("期望30个选项, 得到 %d", len(poll.Options))
+	}
+	total := 0
+	for _, option := range poll.Options {
+		total += option.VoteCount
+	}
+	if total != 2500 || poll.Options[0].VoteCount <= poll.Options[29].VoteCount {
+		t.Errorf("票数分布不正确: 总数 %d, 首个 %d, 末个 %d", total, poll.Options[0].VoteCount, poll.Options[29].VoteCount)
+	}
+	if drift, _ := stats.FindDrift(db, poll.ID); len(drift) != 0 {
+		t.Errorf("票数应与投票记录一致: %+v", drift)
+	}
+
+	// 隐私模式下只保存投票人标识的HMAC
+	var plain int64
+	db.Model(&models.Vote{}).Where("user_ip <> ''").Count(&plain)
+	if plain != 0 {
+		t.Errorf("隐私模式下不应保存原始标识, 得到 %d 条", plain)
+	}
+
+	if !poll.IsActive {
+		t.Error("没有其他开启的投票问卷时压测投票问卷应开启")
+	}
+
+	result, err = fixture.Run(db, hasher)
+	if err != nil || result.Created != 0 || result.Skipped != 1 {
+		t.Errorf("重复执行期望跳过, 得到 %+v %v", result, err)
+	}
+}
+
+func TestLoadTestAfterDemo(t *testing.T) {
+	db := setupTestDB()
+
+	if _, err := Run(db, FixtureDemo, nil); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+	if _, err := (LoadTest{Options: 3, Votes: 10, Span: time.Hour}).Run(db, nil); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	var active []models.Poll
+	db.Where("is_active = ?", true).Find(&active)
+	if len(active) != 1 || *active[0].Slug != "favorite-language" {
+		t.Errorf("期望只有demo的第一个投票问卷开启, 得到 %+v", active)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// Credentials 投票人为私有或邀请制投票问卷提供的凭证
+type Credentials struct {
+	AccessCode  string
+	BallotToken string
+}
+
+// checkAccess 按投票问卷的访问方式检查凭证，邀请制投票问卷返回校验通过的令牌
+func (s *PollService) checkAccess(poll models.Poll, creds Credentials) (*models.BallotToken, error) {
+	switch poll.Access {
+	case models.AccessPrivate:
+		if !ballot.CheckCode(poll, creds.AccessCode) {
+			return nil, apierror.New(apierror.AccessCodeInvalid)
+		}
+	case models.AccessInvite:
+		token, err := ballot.Verify(s.db, poll, creds.BallotToken)
+		if errors.Is(err, ballot.ErrInvalid) {
+			return nil, apierror.New(apierror.BallotInvalid)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if token.Used {
+			return nil, apierror.New(apierror.BallotUsed)
+		}
+		return token, nil
+	}
+	return nil, nil
+}
+
+// IssueBallots 为投票问卷的每个受邀人生成一个投票令牌并记录审计日志，令牌明文只在返回值中出现
+//
+// weights与labels一一对应，为使用令牌投票时的权重。
+func (s *PollService) IssueBallots(pollID uint, labels []string, weights []weight.Weight, entry audit.Entry) ([]ballot.Issued, error) {
+	poll, err := s.Load(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	var issued []ballot.Issued
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if issued, err = ballot.Issue(tx, &poll, labels, weights); err != nil {
+			return err
+		}
+		var total weight.Weight
+		for _, w := range weights {
+			total += w
+		}
+		return audit.Record(tx, pollEntry(entry, models.AuditBallotsIssued, poll.ID, nil, map[string]interface{}{
+			"count": len(issued), "access": poll.Access, "total_weight": total,
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Ballots 列出投票问卷的投票令牌（不含明文）和投票率
+func (s *PollService) Ballots(pollID uint) ([]models.BallotToken, ballot.Turnout, error) {
+	poll, err := s.Load(pollID)
+	if err != nil {
+		return nil, ballot.Turnout{}, err
+	}
+
+	tokens := []models.BallotToken{}
+	if err := s.db.Where("poll_id = ?", poll.ID).Order("id").Find(&tokens).Error; err != nil {
+		return nil, ballot.Turnout{}, err
+	}
+	turnout, err := ballot.Count(s.db, poll.ID)
+	return tokens, turnout, err
+}
+
+// RevokeBallot 吊销投票问卷中未使用的投票令牌，已使用的令牌返回BallotUsed
+func (s *PollService) RevokeBallot(pollID, ballotID uint, entry audit.Entry) (models.BallotToken, error) {
+	var token models.BallotToken
+	if err := s.db.Where("id = ? AND poll_id = ?", ballotID, pollID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return token, apierror.New(apierror.BallotNotFound)
+		}
+		return token, err
+	}
+
+	if token.RevokedAt != nil {
+		return token, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ballot.Revoke(tx, &token, time.Now()); err != nil {
+			if errors.Is(err, ballot.ErrUsed) {
+				return apierror.New(apierror.BallotUsed)
+			}
+			return err
+		}
+		return audit.Record(tx, pollEntry(entry, models.AuditBallotRevoked, pollID,
+			map[string]interface{}{"ballot_id": token.ID, "label": token.Label, "prefix": token.Prefix}, nil))
+	})
+	return token, err
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/models"
+	"vote-system/privacy"
+	"vote-system/weight"
+)
+
+func TestVotePrivatePoll(t *testing.T) {
+	s, db := setupService(t)
+	poll := models.Poll{Title: "部门聚餐", IsActive: true, Access: models.AccessPrivate, AccessCodeHash: ballot.Hash("dinner"),
+		Options: []models.Option{{Text: "火锅"}, {Text: "烧烤"}}}
+	db.Create(&poll)
+	option := poll.Options[0].ID
+
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{}), apierror.AccessCodeInvalid)
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{AccessCode: "lunch"}), apierror.AccessCodeInvalid)
+	if err := s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{AccessCode: "dinner"}); err != nil {
+		t.Fatalf("访问码正确时投票失败: %v", err)
+	}
+	// 访问码相同，仍按投票人标识防止重复投票
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{AccessCode: "dinner"}), apierror.AlreadyVoted)
+}
+
+func TestVoteInvitePoll(t *testing.T) {
+	s, db := setupService(t)
+	s.hasher = privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("s")}})
+	poll := models.Poll{Title: "理事会选举", IsActive: true, Access: models.AccessInvite,
+		Options: []models.Option{{Text: "甲"}, {Text: "乙"}}}
+	db.Create(&poll)
+	option := poll.Options[1].ID
+
+	issued, err := s.IssueBallots(poll.ID, []string{"张三", "李四", "王五"},
+		[]weight.Weight{weight.One, 2500000, weight.One}, audit.Entry{Actor: "admin"})
+	if err != nil || len(issued) != 3 {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{}), apierror.BallotInvalid)
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{BallotToken: "vbt_forged.0000"}), apierror.BallotInvalid)
+
+	// 同一IP的两个受邀人都可以投票，每个令牌只能使用一次
+	for _, item := range issued[:2] {
+		if err := s.Vote(poll.ID, option, "10.0.0.1", "", Credentials{BallotToken: item.Token}); err != nil {
+			t.Fatalf("使用令牌投票失败: %v", err)
+		}
+	}
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.2", "", Credentials{BallotToken: issued[0].Token}), apierror.BallotUsed)
+
+	var votes []models.Vote
+	db.Where("poll_id = ?", poll.ID).Find(&votes)
+	if len(votes) != 2 {
+		t.Fatalf("期望2票, 得到 %d", len(votes))
+	}
+	for _, vote := range votes {
+		if vote.UserIP != "" || vote.VoterHash != "" {
+			t.Errorf("邀请制投票问卷的投票记录不应保存投票人标识: %+v", vote)
+		}
+	}
+	// 投票的权重来自令牌
+	var voted models.Option
+	db.First(&voted, option)
+	if voted.VoteCount != 2 || voted.WeightedVotes != 3500000 {
+		t.Errorf("期望2票、加权3.5票, 得到 %d %s", voted.VoteCount, voted.WeightedVotes)
+	}
+
+	_, err = s.RevokeBallot(poll.ID, issued[0].ID, audit.Entry{})
+	expectCode(t, err, apierror.BallotUsed)
+	if _, err := s.RevokeBallot(poll.ID, issued[2].ID, audit.Entry{}); err != nil {
+		t.Fatalf("吊销令牌失败: %v", err)
+	}
+	expectCode(t, s.Vote(poll.ID, option, "10.0.0.3", "", Credentials{BallotToken: issued[2].Token}), apierror.BallotInvalid)
+	_, err = s.RevokeBallot(poll.ID+1, issued[2].ID, audit.Entry{})
+	expectCode(t, err, apierror.BallotNotFound)
+
+	tokens, turnout, err := s.Ballots(poll.ID)
+	if err != nil || len(tokens) != 3 || turnout.Used != 2 || turnout.Revoked != 1 || turnout.Percentage != 100 {
+		t.Errorf("投票率不正确: %+v %v", turnout, err)
+	}
+
+	var logs int64
+	db.Model(&models.AuditLog{}).Where("action IN ?", []string{models.AuditBallotsIssued, models.AuditBallotRevoked}).Count(&logs)
+	if logs != 2 {
+		t.Errorf("期望2条审计日志, 得到 %d", logs)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/delegation"
+	"vote-system/models"
+	"vote-system/roll"
+
+	"gorm.io/gorm"
+)
+
+// Delegate 创建或替换投票人的委托，req中的PollID和Topic都为空时委托进行中的投票问卷
+//
+// 委托某个投票问卷时该投票问卷必须开启且有名册，委托人和受托人都必须在名册中；
+// 按标签委托时不检查名册，关闭投票问卷时不在名册中的投票人不计入。
+// 隐私模式下委托人和受托人只保存标识的HMAC，返回的委托中同样是HMAC。
+func (s *PollService) Delegate(voter string, req models.DelegationRequest) (models.Delegation, error) {
+	delegator, delegate := roll.Normalize(voter), roll.Normalize(req.Delegate)
+	d := models.Delegation{
+		Delegator: s.hasher.Pseudonymize(delegator),
+		Delegate:  s.hasher.Pseudonymize(delegate),
+		Topic:     strings.ToLower(strings.TrimSpace(req.Topic)),
+	}
+
+	if d.Topic == "" {
+		poll, err := s.delegationPoll(req.PollID)
+		if err != nil {
+			return d, err
+		}
+		if !poll.IsActive {
+			return d, apierror.New(apierror.PollClosed)
+		}
+		if _, err := roll.Eligible(s.db, poll, voter); err != nil {
+			return d, err
+		}
+		if _, err := roll.Eligible(s.db, poll, delegate); err != nil {
+			if e, ok := apierror.As(err); ok && e.Code == apierror.NotEligible {
+				return d, apierror.New(apierror.InvalidParameter, "name", "delegate")
+			}
+			return d, err
+		}
+		d.PollID = poll.ID
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 轮换密钥或开启隐私模式前保存的同一范围的委托由新的委托替换
+		if s.hasher.Enabled() {
+			if err := tx.Where("delegator IN ? AND delegator <> ? AND poll_id = ? AND topic = ?",
+				s.hasher.Keys(delegator), d.Delegator, d.PollID, d.Topic).Delete(&models.Delegation{}).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		d, err = delegation.Set(tx, d)
+		if errors.Is(err, delegation.ErrCycle) {
+			return apierror.New(apierror.DelegationCycle, "delegate", delegate)
+		}
+		return err
+	})
+	return d, err
+}
+
+// RemoveDelegation 删除投票人在投票问卷或标签上的委托，topic为空时为pollID的投票问卷，pollID也为0时为进行中的投票问卷
+func (s *PollService) RemoveDelegation(voter string, pollID uint, topic string) error {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if topic == "" {
+		poll, err := s.target(pollID)
+		if err != nil {
+			return err
+		}
+		pollID = poll.ID
+	} else {
+		pollID = 0
+	}
+
+	result := s.db.Where("delegator IN ? AND poll_id = ? AND topic = ?", s.hasher.Keys(roll.Normalize(voter)), pollID, topic).
+		Delete(&models.Delegation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apierror.New(apierror.DelegationNotFound)
+	}
+	return nil
+}
+
+// DelegationChain 返回投票人在投票问卷中实际生效的委托链，pollID为0时为进行中的投票问卷
+func (s *PollService) DelegationChain(pollID uint, voter string) (delegation.Chain, error) {
+	poll, err := s.delegationPoll(pollID)
+	if err != nil {
+		return delegation.Chain{}, err
+	}
+	member, err := roll.Eligible(s.db, poll, voter)
+	if err != nil {
+		return delegation.Chain{}, err
+	}
+	return delegation.For(s.db, poll, s.hasher, member.Identifier)
+}
+
+// Delegations 返回投票问卷名册中每个投票人的委托链
+func (s *PollService) Delegations(pollID uint) ([]delegation.Chain, error) {
+	poll, err := s.delegationPoll(pollID)
+	if err != nil {
+		return nil, err
+	}
+	return delegation.Resolve(s.db, poll, s.hasher)
+}
+
+// delegationPoll 读取可以使用委托的投票问卷，没有名册时返回RollNotFound
+func (s *PollService) delegationPoll(pollID uint) (models.Poll, error) {
+	poll, err := s.target(pollID)
+	if err != nil {
+		return poll, err
+	}
+	if poll.RollID == nil {
+		return poll, apierror.New(apierror.RollNotFound)
+	}
+	return poll, nil
+}
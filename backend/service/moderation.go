@@ -0,0 +1,346 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/stats"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// MaxPendingProposals 每个投票人在一个投票问卷中待审核的提议上限
+const MaxPendingProposals = 3
+
+// normalizeText 去掉首尾空白并合并连续空白
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// proposalKey 归并提议使用的内容，不区分大小写
+func proposalKey(text string) string {
+	return strings.ToLower(text)
+}
+
+// writeInProposal 返回自填内容对应的审核记录，内容第一次出现时创建并写入outbox事件
+func (s *PollService) writeInProposal(tx *gorm.DB, pollID uint, text, voter string) (models.OptionProposal, error) {
+	proposal := models.OptionProposal{
+		PollID:     pollID,
+		Kind:       models.ProposalKindWriteIn,
+		Text:       text,
+		Key:        proposalKey(text),
+		Status:     models.ProposalPending,
+		ProposedBy: s.hasher.Pseudonymize(voter),
+	}
+	result := tx.Where(models.OptionProposal{PollID: pollID, Kind: proposal.Kind, Key: proposal.Key}).FirstOrCreate(&proposal)
+	if result.Error != nil {
+		return proposal, result.Error
+	}
+	if result.RowsAffected > 0 && proposal.Status == models.ProposalPending {
+		if err := outbox.Enqueue(tx, pollID, models.EventOptionProposed, proposalSummary(proposal)); err != nil {
+			return proposal, err
+		}
+	}
+	return proposal, nil
+}
+
+// Propose 投票人提议新选项，pollID为0时为进行中的投票问卷
+//
+// 与已有选项相同时返回OptionExists；相同内容已被提议时返回已有的记录，created为false。
+func (s *PollService) Propose(pollID uint, text, voter string) (proposal models.OptionProposal, created bool, err error) {
+	poll, err := s.target(pollID)
+	if err != nil {
+		return proposal, false, err
+	}
+	// 波达计数不能增加选项，升级前创建的投票问卷可能仍开启了提议
+	if !poll.AllowProposals || poll.Mode == models.ModeBorda {
+		return proposal, false, apierror.New(apierror.ProposalsDisabled)
+	}
+	if !poll.IsActive {
+		return proposal, false, apierror.New(apierror.PollClosed)
+	}
+	if deadline := poll.Rules.ClosesAt; deadline != nil && !time.Now().Before(*deadline) {
+		s.applyRules(poll.ID)
+		return proposal, false, apierror.New(apierror.PollClosed)
+	}
+
+	text = normalizeText(text)
+	if text == "" {
+		return proposal, false, apierror.New(apierror.InvalidParameter, "name", "text")
+	}
+	key := proposalKey(text)
+	for _, option := range poll.Options {
+		if proposalKey(normalizeText(option.Text)) == key {
+			return proposal, false, apierror.New(apierror.OptionExists)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(models.OptionProposal{PollID: poll.ID, Kind: models.ProposalKindOption, Key: key}).First(&proposal).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		proposedBy := s.hasher.Pseudonymize(voter)
+		var pending int64
+		if err := tx.Model(&models.OptionProposal{}).
+			Where("poll_id = ? AND kind = ? AND status = ? AND proposed_by = ?",
+				poll.ID, models.ProposalKindOption, models.ProposalPending, proposedBy).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending >= MaxPendingProposals {
+			return apierror.New(apierror.TooManyProposals)
+		}
+
+		proposal = models.OptionProposal{
+			PollID:     poll.ID,
+			Kind:       models.ProposalKindOption,
+			Text:       text,
+			Key:        key,
+			Status:     models.ProposalPending,
+			ProposedBy: proposedBy,
+		}
+		if err := tx.Create(&proposal).Error; err != nil {
+			return err
+		}
+		created = true
+		return outbox.Enqueue(tx, poll.ID, models.EventOptionProposed, proposalSummary(proposal))
+	})
+	if err != nil {
+		return models.OptionProposal{}, false, err
+	}
+	if created {
+		s.dispatcher.Notify()
+	}
+	return proposal, created, nil
+}
+
+// Proposals 列出投票问卷的提议和自填内容，status为空时列出全部，按ID排序
+//
+// 票数与AdminView一致，关闭后可见的投票问卷在关闭前不统计。
+func (s *PollService) Proposals(pollID uint, status string) ([]models.OptionProposal, error) {
+	switch status {
+	case "", models.ProposalPending, models.ProposalApproved, models.ProposalMerged, models.ProposalRejected:
+	default:
+		return nil, apierror.New(apierror.InvalidParameter, "name", "status")
+	}
+	poll, err := s.Load(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Where("poll_id = ?", pollID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	proposals := []models.OptionProposal{}
+	if err := query.Order("id").Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	if !poll.ResultsVisible(false, true) {
+		return proposals, nil
+	}
+
+	var counts []struct {
+		ProposalID uint
+		Votes      int
+	}
+	if err := s.db.Model(&models.Vote{}).Select("proposal_id, COUNT(*) AS votes").
+		Where("poll_id = ? AND proposal_id IS NOT NULL", pollID).
+		Group("proposal_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	votes := map[uint]int{}
+	for _, count := range counts {
+		votes[count.ProposalID] = count.Votes
+	}
+	for i := range proposals {
+		proposals[i].Votes = votes[proposals[i].ID]
+	}
+	return proposals, nil
+}
+
+// ApproveProposal 将提议作为新选项加入投票问卷，自填内容的投票移到新选项
+func (s *PollService) ApproveProposal(pollID, proposalID uint, entry audit.Entry) (models.OptionProposal, error) {
+	return s.resolve(pollID, proposalID, entry, func(tx *gorm.DB, poll models.Poll, proposal *models.OptionProposal) (string, error) {
+		if poll.Mode == models.ModeBorda {
+			return "", apierror.New(apierror.ProposalsDisabled)
+		}
+		for _, option := range poll.Options {
+			if proposalKey(normalizeText(option.Text)) == proposal.Key {
+				return "", apierror.New(apierror.OptionExists)
+			}
+		}
+		option := models.Option{PollID: poll.ID, Text: proposal.Text}
+		if err := tx.Create(&option).Error; err != nil {
+			return "", err
+		}
+		proposal.Status = models.ProposalApproved
+		proposal.OptionID = &option.ID
+		return models.AuditProposalApproved, nil
+	})
+}
+
+// MergeProposal 将提议并入投票问卷中已有的选项，自填内容的投票移到该选项
+func (s *PollService) MergeProposal(pollID, proposalID, optionID uint, entry audit.Entry) (models.OptionProposal, error) {
+	return s.resolve(pollID, proposalID, entry, func(tx *gorm.DB, poll models.Poll, proposal *models.OptionProposal) (string, error) {
+		var target *models.Option
+		for i := range poll.Options {
+			if poll.Options[i].ID == optionID && !poll.Options[i].WriteIn {
+				target = &poll.Options[i]
+			}
+		}
+		if target == nil {
+			return "", apierror.New(apierror.InvalidOption)
+		}
+		proposal.Status = models.ProposalMerged
+		proposal.OptionID = &target.ID
+		return models.AuditProposalMerged, nil
+	})
+}
+
+// RejectProposal 拒绝提议，自填内容的投票保留在自填选项中
+func (s *PollService) RejectProposal(pollID, proposalID uint, entry audit.Entry) (models.OptionProposal, error) {
+	return s.resolve(pollID, proposalID, entry, func(tx *gorm.DB, poll models.Poll, proposal *models.OptionProposal) (string, error) {
+		proposal.Status = models.ProposalRejected
+		return models.AuditProposalRejected, nil
+	})
+}
+
+// resolveEvents 审核结果对应的outbox事件
+var resolveEvents = map[string]string{
+	models.ProposalApproved: models.EventOptionApproved,
+	models.ProposalMerged:   models.EventProposalMerged,
+	models.ProposalRejected: models.EventProposalRejected,
+}
+
+// resolve 在一个事务中审核待审核的提议，apply设置新的状态和目标选项并返回审计动作
+func (s *PollService) resolve(pollID, proposalID uint, entry audit.Entry,
+	apply func(tx *gorm.DB, poll models.Poll, proposal *models.OptionProposal) (string, error)) (models.OptionProposal, error) {
+	poll, err := s.Load(pollID)
+	if err != nil {
+		return models.OptionProposal{}, err
+	}
+
+	var proposal models.OptionProposal
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND poll_id = ?", proposalID, pollID).First(&proposal).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.New(apierror.ProposalNotFound)
+			}
+			return err
+		}
+		if proposal.Status != models.ProposalPending {
+			return apierror.New(apierror.ProposalResolved)
+		}
+		before := proposal
+
+		action, err := apply(tx, poll, &proposal)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		proposal.ResolvedBy = entry.Actor
+		proposal.ResolvedAt = &now
+
+		// 只更新仍待审核的记录，并发审核时后提交的一方失败
+		result := tx.Model(&models.OptionProposal{}).
+			Where("id = ? AND status = ?", proposal.ID, models.ProposalPending).
+			Updates(map[string]interface{}{
+				"status":      proposal.Status,
+				"option_id":   proposal.OptionID,
+				"resolved_by": proposal.ResolvedBy,
+				"resolved_at": proposal.ResolvedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apierror.New(apierror.ProposalResolved)
+		}
+
+		moved := int64(0)
+		if proposal.OptionID != nil {
+			if moved, err = moveVotes(tx, poll.ID, proposal.ID, *proposal.OptionID); err != nil {
+				return err
+			}
+		}
+
+		payload := proposalSummary(proposal)
+		payload["moved_votes"] = moved
+		if err := outbox.Enqueue(tx, poll.ID, resolveEvents[proposal.Status], payload); err != nil {
+			return err
+		}
+		return audit.Record(tx, pollEntry(entry, action, poll.ID, proposalSummary(before), proposalSummary(proposal)))
+	})
+	if err != nil {
+		return models.OptionProposal{}, err
+	}
+	s.dispatcher.Notify()
+	return proposal, nil
+}
+
+// moveVotes 将填写该内容的投票移到目标选项，同步选项票数、加权票数和汇总数据，返回移动的票数
+func moveVotes(tx *gorm.DB, pollID, proposalID, optionID uint) (int64, error) {
+	var sources []struct {
+		OptionID uint
+		Votes    int64
+		Weight   weight.Weight
+	}
+	if err := tx.Model(&models.Vote{}).Select("option_id, COUNT(*) AS votes, SUM(weight) AS weight").
+		Where("proposal_id = ? AND option_id <> ?", proposalID, optionID).
+		Group("option_id").Scan(&sources).Error; err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	var totalWeight weight.Weight
+	for _, source := range sources {
+		if err := tx.Model(&models.Option{}).Where("id = ?", source.OptionID).Updates(map[string]interface{}{
+			"vote_count":     gorm.Expr("vote_count - ?", source.Votes),
+			"weighted_votes": gorm.Expr("weighted_votes - ?", source.Weight),
+		}).Error; err != nil {
+			return 0, err
+		}
+		total += source.Votes
+		var err error
+		if totalWeight, err = totalWeight.Add(source.Weight); err != nil {
+			return 0, apierror.New(apierror.TallyOverflow)
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Model(&models.Vote{}).Where("proposal_id = ? AND option_id <> ?", proposalID, optionID).
+		Update("option_id", optionID).Error; err != nil {
+		return 0, err
+	}
+	if err := tally.Increment(tx, optionID, int(total), totalWeight, 0); err != nil {
+		return 0, err
+	}
+	return total, stats.RebuildRollups(tx, pollID)
+}
+
+// proposalSummary 提议相关outbox事件和审计日志中记录的字段
+func proposalSummary(proposal models.OptionProposal) map[string]interface{} {
+	summary := map[string]interface{}{
+		"proposal_id": proposal.ID,
+		"kind":        proposal.Kind,
+		"text":        proposal.Text,
+		"status":      proposal.Status,
+	}
+	if proposal.OptionID != nil {
+		summary["option_id"] = *proposal.OptionID
+	}
+	return summary
+}
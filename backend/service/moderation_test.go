@@ -0,0 +1,190 @@
+package service
+
+import (
+	"testing"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/stats"
+
+	"gorm.io/gorm"
+)
+
+// writeInPoll 创建进行中、带自填选项“其他”并允许提议的投票问卷
+func writeInPoll(db *gorm.DB) models.Poll {
+	poll := models.Poll{Title: "团建去哪", IsActive: true, AllowProposals: true, Options: []models.Option{
+		{Text: "海边"}, {Text: "爬山"}, {Text: "其他", WriteIn: true},
+	}}
+	db.Create(&poll)
+	return poll
+}
+
+func expectCode(t *testing.T, err error, code apierror.Code) {
+	t.Helper()
+	if e, ok := apierror.As(err); !ok || e.Code != code {
+		t.Errorf("期望 %s, 得到 %v", code, err)
+	}
+}
+
+// counts 返回各选项的票数
+func counts(db *gorm.DB, pollID uint) map[uint]int {
+	var options []models.Option
+	db.Where("poll_id = ?", pollID).Find(&options)
+	result := map[uint]int{}
+	for _, option := range options {
+		result[option.ID] = option.VoteCount
+	}
+	return result
+}
+
+func TestVoteWriteIn(t *testing.T) {
+	s, db := setupService(t)
+	poll := writeInPoll(db)
+	other := poll.Options[2].ID
+
+	expectCode(t, s.Vote(poll.ID, other, "10.0.0.1", "  ", Credentials{}), apierror.WriteInRequired)
+	expectCode(t, s.Vote(poll.ID, poll.Options[0].ID, "10.0.0.1", "湖边", Credentials{}), apierror.WriteInNotAllowed)
+
+	// 相同内容不区分大小写和多余空白，归并到同一条审核记录
+	votes := map[string]string{"10.0.0.1": "Lake  side", "10.0.0.2": "lake side", "10.0.0.3": "露营"}
+	for _, voter := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if err := s.Vote(poll.ID, other, voter, votes[voter], Credentials{}); err != nil {
+			t.Fatalf("投票失败: %v", err)
+		}
+	}
+	proposals, err := s.Proposals(poll.ID, models.ProposalPending)
+	if err != nil || len(proposals) != 2 {
+		t.Fatalf("期望2条待审核的自填内容, 得到 %+v %v", proposals, err)
+	}
+	if proposals[0].Text != "Lake side" || proposals[0].Kind != models.ProposalKindWriteIn || proposals[0].Votes != 2 {
+		t.Errorf("自填内容归并不正确: %+v", proposals[0])
+	}
+	var vote models.Vote
+	db.Where("user_ip = ?", "10.0.0.2").First(&vote)
+	if vote.WriteIn != "lake side" || vote.ProposalID == nil || *vote.ProposalID != proposals[0].ID {
+		t.Errorf("投票应保存填写的内容和审核记录: %+v", vote)
+	}
+	if got := counts(db, poll.ID)[other]; got != 3 {
+		t.Errorf("审核前票数计入自填选项, 期望 3, 得到 %d", got)
+	}
+}
+
+func TestApproveWriteInMovesVotes(t *testing.T) {
+	s, db := setupService(t)
+	poll := writeInPoll(db)
+	other := poll.Options[2].ID
+	s.Vote(poll.ID, other, "10.0.0.1", "湖边", Credentials{})
+	s.Vote(poll.ID, other, "10.0.0.2", "湖边", Credentials{})
+	s.Vote(poll.ID, other, "10.0.0.3", "露营", Credentials{})
+	proposals, _ := s.Proposals(poll.ID, "")
+
+	approved, err := s.ApproveProposal(poll.ID, proposals[0].ID, audit.Entry{Actor: "admin:alice"})
+	if err != nil {
+		t.Fatalf("批准失败: %v", err)
+	}
+	if approved.Status != models.ProposalApproved || approved.OptionID == nil || approved.ResolvedBy != "admin:alice" {
+		t.Fatalf("审核结果不正确: %+v", approved)
+	}
+	lake := *approved.OptionID
+	got := counts(db, poll.ID)
+	if got[lake] != 2 || got[other] != 1 {
+		t.Errorf("批准后投票应移到新选项: %v", got)
+	}
+	if drift, _ := stats.FindDrift(db, poll.ID); len(drift) != 0 {
+		t.Errorf("票数和汇总数据应与投票记录一致: %+v", drift)
+	}
+
+	// 之后相同内容的投票直接计入新选项
+	s.Vote(poll.ID, other, "10.0.0.4", "湖边", Credentials{})
+	if got := counts(db, poll.ID)[lake]; got != 3 {
+		t.Errorf("已批准内容的新投票应计入新选项, 得到 %d", got)
+	}
+
+	_, err = s.ApproveProposal(poll.ID, proposals[0].ID, audit.Entry{})
+	expectCode(t, err, apierror.ProposalResolved)
+
+	var events int64
+	db.Model(&models.OutboxEvent{}).Where("type = ?", models.EventOptionApproved).Count(&events)
+	var logs int64
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditProposalApproved).Count(&logs)
+	if events != 1 || logs != 1 {
+		t.Errorf("批准应写入1条事件和1条审计日志, 得到 %d %d", events, logs)
+	}
+}
+
+func TestMergeAndRejectProposal(t *testing.T) {
+	s, db := setupService(t)
+	poll := writeInPoll(db)
+	beach, other := poll.Options[0].ID, poll.Options[2].ID
+	s.Vote(poll.ID, other, "10.0.0.1", "沙滩", Credentials{})
+	s.Vote(poll.ID, other, "10.0.0.2", "不去", Credentials{})
+	proposals, _ := s.Proposals(poll.ID, "")
+
+	// 只能并入该投票问卷中的普通选项
+	_, err := s.MergeProposal(poll.ID, proposals[0].ID, other, audit.Entry{})
+	expectCode(t, err, apierror.InvalidOption)
+
+	merged, err := s.MergeProposal(poll.ID, proposals[0].ID, beach, audit.Entry{})
+	if err != nil || merged.Status != models.ProposalMerged || *merged.OptionID != beach {
+		t.Fatalf("合并失败: %+v %v", merged, err)
+	}
+	if _, err := s.RejectProposal(poll.ID, proposals[1].ID, audit.Entry{}); err != nil {
+		t.Fatalf("拒绝失败: %v", err)
+	}
+	got := counts(db, poll.ID)
+	if got[beach] != 1 || got[other] != 1 {
+		t.Errorf("合并的投票应移到目标选项, 拒绝的保留在自填选项: %v", got)
+	}
+
+	_, err = s.RejectProposal(poll.ID, 999, audit.Entry{})
+	expectCode(t, err, apierror.ProposalNotFound)
+}
+
+func TestPropose(t *testing.T) {
+	s, db := setupService(t)
+	poll := writeInPoll(db)
+
+	proposal, created, err := s.Propose(poll.ID, " 湖边 ", "10.0.0.1")
+	if err != nil || !created || proposal.Text != "湖边" || proposal.Kind != models.ProposalKindOption {
+		t.Fatalf("提议失败: %+v %v", proposal, err)
+	}
+	again, created, err := s.Propose(poll.ID, "湖边", "10.0.0.2")
+	if err != nil || created || again.ID != proposal.ID {
+		t.Errorf("相同内容应返回已有的提议: %+v %v", again, err)
+	}
+	_, _, err = s.Propose(poll.ID, "海边", "10.0.0.1")
+	expectCode(t, err, apierror.OptionExists)
+
+	for _, text := range []string{"古镇", "温泉"} {
+		s.Propose(poll.ID, text, "10.0.0.1")
+	}
+	_, _, err = s.Propose(poll.ID, "滑雪", "10.0.0.1")
+	expectCode(t, err, apierror.TooManyProposals)
+
+	// 批准后新选项加入投票问卷，可以投票
+	approved, err := s.ApproveProposal(poll.ID, proposal.ID, audit.Entry{})
+	if err != nil {
+		t.Fatalf("批准失败: %v", err)
+	}
+	if err := s.Vote(poll.ID, *approved.OptionID, "10.0.0.5", "", Credentials{}); err != nil {
+		t.Errorf("应能投给批准的选项: %v", err)
+	}
+	_, _, err = s.Propose(poll.ID, "湖边", "10.0.0.3")
+	expectCode(t, err, apierror.OptionExists)
+
+	closed := createPoll(db, models.Poll{Title: "已关闭", AllowProposals: true}, 0, 0)
+	_, _, err = s.Propose(closed.ID, "X", "10.0.0.1")
+	expectCode(t, err, apierror.PollClosed)
+	disabled := createPoll(db, models.Poll{Title: "不接受提议", IsActive: true}, 0, 0)
+	_, _, err = s.Propose(disabled.ID, "X", "10.0.0.1")
+	expectCode(t, err, apierror.ProposalsDisabled)
+
+	// 波达计数不能增加选项，之前开启的提议既不能提交也不能批准
+	borda := createPoll(db, models.Poll{Title: "波达计数", IsActive: true, Mode: models.ModeBorda, AllowProposals: true}, 0, 0)
+	_, _, err = s.Propose(borda.ID, "X", "10.0.0.1")
+	expectCode(t, err, apierror.ProposalsDisabled)
+	pending := models.OptionProposal{PollID: borda.ID, Kind: models.ProposalKindOption, Text: "X", Key: "x", Status: models.ProposalPending}
+	db.Create(&pending)
+	_, err = s.ApproveProposal(borda.ID, pending.ID, audit.Entry{})
+	expectCode(t, err, apierror.ProposalsDisabled)
+}
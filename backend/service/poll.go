@@ -0,0 +1,455 @@
+// Package service 投票问卷的业务逻辑，HTTP和gRPC接口共用
+//
+// 重复投票、投票问卷状态等规则只在这里检查，接口层只负责解析请求和转换响应。
+// 业务错误以 *apierror.Error 返回，其他错误为服务器内部错误。
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/ballot"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/roll"
+	"vote-system/rules"
+	"vote-system/stats"
+	"vote-system/tally"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// PollService 投票问卷的查询、投票和重置
+type PollService struct {
+	db         *gorm.DB
+	dispatcher *outbox.Dispatcher
+	hasher     *privacy.Hasher
+}
+
+// NewPollService 创建PollService，hasher为nil时投票人标识按原样保存，dispatcher为nil时不立即广播
+func NewPollService(db *gorm.DB, dispatcher *outbox.Dispatcher, hasher *privacy.Hasher) *PollService {
+	return &PollService{
+		db:         db,
+		dispatcher: dispatcher,
+		hasher:     hasher,
+	}
+}
+
+// Load 读取投票问卷（含选项，按ID排序）
+func (s *PollService) Load(pollID uint) (models.Poll, error) {
+	var poll models.Poll
+	if err := s.db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&poll, pollID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return poll, apierror.New(apierror.PollNotFound)
+		}
+		return poll, err
+	}
+	return poll, nil
+}
+
+// Active 读取进行中的投票问卷（含选项）
+func (s *PollService) Active() (models.Poll, error) {
+	var poll models.Poll
+	if err := s.db.Where("is_active = ?", true).Preload("Options").First(&poll).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return poll, apierror.New(apierror.NoActivePoll)
+		}
+		return poll, err
+	}
+	return poll, nil
+}
+
+// target 读取投票人操作的投票问卷，pollID为0时为进行中的投票问卷
+func (s *PollService) target(pollID uint) (models.Poll, error) {
+	if pollID == 0 {
+		return s.Active()
+	}
+	return s.Load(pollID)
+}
+
+// View 按投票人的身份返回投票问卷和统计数据，pollID为0时为进行中的投票问卷
+func (s *PollService) View(pollID uint, voter string) (models.PollResponse, error) {
+	poll, err := s.target(pollID)
+	if err != nil {
+		return models.PollResponse{}, err
+	}
+
+	// 计算总票数和总加权票数，单选以外的投票方式中一张选票计入多个选项，按选票统计
+	totalVotes := 0
+	var totalWeight weight.Weight
+	if poll.Plurality() {
+		for _, option := range poll.Options {
+			totalVotes += option.VoteCount
+			totalWeight += option.WeightedVotes
+		}
+	} else {
+		if totalVotes, totalWeight, err = tally.Ballots(s.db, poll.ID); err != nil {
+			return models.PollResponse{}, err
+		}
+		if err := tally.Fill(s.db, &poll); err != nil {
+			return models.PollResponse{}, err
+		}
+	}
+
+	// 检查用户是否已投票
+	var vote models.Vote
+	userVoted := false
+	var votedOption *uint
+
+	if err := s.hasher.VoterScope(s.db, voter).Where("poll_id = ?", poll.ID).First(&vote).Error; err == nil {
+		userVoted = true
+		if vote.OptionID != 0 {
+			votedOption = &vote.OptionID
+		}
+	}
+
+	// 按结果可见性隐藏票数
+	if !poll.ResultsVisible(userVoted, false) {
+		poll.HideResults()
+		totalVotes, totalWeight = 0, 0
+	}
+
+	turnout, err := roll.Count(s.db, poll)
+	if err != nil {
+		return models.PollResponse{}, err
+	}
+
+	return models.PollResponse{
+		Poll:        poll,
+		TotalVotes:  totalVotes,
+		TotalWeight: totalWeight,
+		UserVoted:   userVoted,
+		VotedOption: votedOption,
+		Turnout:     turnout,
+	}, nil
+}
+
+// List 按管理员身份列出所有投票问卷
+func (s *PollService) List() ([]models.Poll, error) {
+	var polls []models.Poll
+	if err := s.db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("id").Find(&polls).Error; err != nil {
+		return nil, err
+	}
+	for i := range polls {
+		AdminView(&polls[i])
+	}
+	return polls, nil
+}
+
+// Vote 记录投票人对选项的单选投票，见Cast
+func (s *PollService) Vote(pollID, optionID uint, voter, writeIn string, creds Credentials) error {
+	return s.Cast(pollID, tally.Ballot{OptionID: optionID}, voter, writeIn, creds)
+}
+
+// Cast 记录投票人的选票，pollID为0时投给进行中的投票问卷
+//
+// 选票按投票问卷的投票方式校验，见tally.Marks。
+// 投给自填选项时writeIn必填，相同内容的投票归并到同一条审核记录；该内容已批准或合并时直接计入对应选项。
+// 私有投票问卷需要creds中的访问码；邀请制投票问卷需要投票令牌，按令牌而不是投票人标识防止重复投票。
+// 有投票人名册时只有名册中的投票人可以投票，按名册中的标识查重。
+// 投票的权重来自名册或投票令牌，其他情况为1。
+func (s *PollService) Cast(pollID uint, b tally.Ballot, voter, writeIn string, creds Credentials) error {
+	var poll models.Poll
+	if pollID == 0 {
+		if err := s.db.Where("is_active = ?", true).First(&poll).Error; err != nil {
+			return apierror.New(apierror.NoActivePoll)
+		}
+	} else {
+		if err := s.db.First(&poll, pollID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.New(apierror.PollNotFound)
+			}
+			return err
+		}
+		if !poll.IsActive {
+			return apierror.New(apierror.PollClosed)
+		}
+	}
+
+	// 已过截止时间但尚未被定时任务关闭
+	if deadline := poll.Rules.ClosesAt; deadline != nil && !time.Now().Before(*deadline) {
+		s.applyRules(poll.ID)
+		return apierror.New(apierror.PollClosed)
+	}
+
+	token, err := s.checkAccess(poll, creds)
+	if err != nil {
+		return err
+	}
+	member, err := roll.Eligible(s.db, poll, voter)
+	if err != nil {
+		return err
+	}
+	if member != nil {
+		voter = member.Identifier
+	}
+	voteWeight := weight.One
+	switch {
+	case member != nil:
+		voteWeight = member.Weight
+	case token != nil:
+		voteWeight = token.Weight
+	}
+
+	// 检查选票和选项是否存在
+	if !poll.Plurality() {
+		if err := s.db.Where("poll_id = ?", poll.ID).Order("id").Find(&poll.Options).Error; err != nil {
+			return err
+		}
+	}
+	marks, err := tally.Marks(poll, b)
+	if err != nil {
+		return err
+	}
+	var option models.Option
+	if poll.Plurality() {
+		if err := s.db.Where("id = ? AND poll_id = ?", b.OptionID, poll.ID).First(&option).Error; err != nil {
+			return apierror.New(apierror.InvalidOption)
+		}
+	}
+	writeIn = normalizeText(writeIn)
+	if option.WriteIn && writeIn == "" {
+		return apierror.New(apierror.WriteInRequired)
+	}
+	if !option.WriteIn && writeIn != "" {
+		return apierror.New(apierror.WriteInNotAllowed)
+	}
+
+	// 检查用户是否已投票，邀请制投票问卷在事务中检查令牌
+	if token == nil {
+		var existingVote models.Vote
+		if err := s.hasher.VoterScope(s.db, voter).Where("poll_id = ?", poll.ID).First(&existingVote).Error; err == nil {
+			return apierror.New(apierror.AlreadyVoted)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 创建投票记录
+		vote := models.Vote{
+			PollID:   poll.ID,
+			OptionID: option.ID,
+			UserIP:   voter,
+			Weight:   voteWeight,
+		}
+		if token != nil {
+			// 投票记录不保存投票人标识，避免与令牌的受邀人对应
+			vote.UserIP = ""
+			if err := ballot.Use(tx, token); err != nil {
+				if errors.Is(err, ballot.ErrUsed) {
+					return apierror.New(apierror.BallotUsed)
+				}
+				return err
+			}
+		} else if s.hasher.Enabled() {
+			vote.UserIP = ""
+			vote.VoterHash, vote.VoterKeyID = s.hasher.Identify(voter)
+		}
+		// 名册关联用于统计投票率和关闭时解析委托，隐私模式下同样保存，按保留期限随标识一起清除
+		if member != nil {
+			vote.RollMemberID = &member.ID
+		}
+		if option.WriteIn {
+			proposal, err := s.writeInProposal(tx, poll.ID, writeIn, voter)
+			if err != nil {
+				return err
+			}
+			vote.WriteIn = writeIn
+			vote.ProposalID = &proposal.ID
+			if proposal.OptionID != nil && (proposal.Status == models.ProposalApproved || proposal.Status == models.ProposalMerged) {
+				vote.OptionID = *proposal.OptionID
+				option = models.Option{ID: *proposal.OptionID}
+			}
+		}
+		if err := tx.Create(&vote).Error; err != nil {
+			return err
+		}
+
+		payload := map[string]interface{}{"vote_id": vote.ID, "option_id": option.ID}
+		// 邀请制投票问卷的权重来自受邀人的令牌，事件中不包含，避免据此对应受邀人和选项
+		if token == nil {
+			payload["weight"] = vote.Weight
+		}
+		var totalVotes int
+		if marks == nil {
+			// 增加选项投票数和加权票数
+			if err := tally.Increment(tx, option.ID, 1, vote.Weight, 0); err != nil {
+				return err
+			}
+
+			// 更新按分钟的汇总数据
+			if err := stats.IncrementRollup(tx, vote); err != nil {
+				return err
+			}
+
+			// 投票后的总票数，供webhook判断是否达到阈值
+			if err := tx.Model(&models.Option{}).Where("poll_id = ?", poll.ID).
+				Select("COALESCE(SUM(vote_count), 0)").Scan(&totalVotes).Error; err != nil {
+				return err
+			}
+		} else {
+			// 保存选票标记，增加各选项的票数、加权票数和得分
+			if err := tally.Record(tx, vote, marks); err != nil {
+				return err
+			}
+			optionIDs := make([]uint, 0, len(marks))
+			for _, mark := range marks {
+				optionIDs = append(optionIDs, mark.OptionID)
+				marked := vote
+				marked.OptionID = mark.OptionID
+				if err := stats.IncrementRollup(tx, marked); err != nil {
+					return err
+				}
+			}
+			payload["option_ids"] = optionIDs
+
+			ballots, _, err := tally.Ballots(tx, poll.ID)
+			if err != nil {
+				return err
+			}
+			totalVotes = ballots
+		}
+		payload["total_votes"] = totalVotes
+
+		// 写入outbox事件，提交后由分发器广播
+		return outbox.Enqueue(tx, poll.ID, models.EventVoteCast, payload)
+	})
+	if err != nil {
+		return err
+	}
+	s.dispatcher.Notify()
+
+	// 投票已提交，规则检查失败不影响本次投票
+	s.applyRules(poll.ID)
+	return nil
+}
+
+// ClearVote 清除投票人在进行中的投票问卷中的投票，entry提供审计日志的操作人、IP和请求ID
+func (s *PollService) ClearVote(voter string, entry audit.Entry) error {
+	var poll models.Poll
+	if err := s.db.Where("is_active = ?", true).First(&poll).Error; err != nil {
+		return apierror.New(apierror.NoActivePoll)
+	}
+
+	// 查找用户的投票记录
+	var vote models.Vote
+	if err := s.hasher.VoterScope(s.db, voter).Where("poll_id = ?", poll.ID).First(&vote).Error; err != nil {
+		return apierror.New(apierror.VoteNotFound)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		before := map[string]interface{}{"vote_id": vote.ID, "option_id": vote.OptionID}
+		if vote.OptionID != 0 {
+			// 减少选项的投票数和加权票数
+			if err := tx.Model(&models.Option{}).Where("id = ?", vote.OptionID).Updates(map[string]interface{}{
+				"vote_count":     gorm.Expr("vote_count - ?", 1),
+				"weighted_votes": gorm.Expr("weighted_votes - ?", vote.Weight),
+			}).Error; err != nil {
+				return err
+			}
+			if err := stats.DecrementRollup(tx, vote); err != nil {
+				return err
+			}
+		} else {
+			// 删除选票标记，减少各选项的票数、加权票数和得分
+			marks, err := tally.Remove(tx, []uint{vote.ID})
+			if err != nil {
+				return err
+			}
+			optionIDs := make([]uint, 0, len(marks))
+			for _, mark := range marks {
+				optionIDs = append(optionIDs, mark.OptionID)
+				marked := vote
+				marked.OptionID = mark.OptionID
+				if err := stats.DecrementRollup(tx, marked); err != nil {
+					return err
+				}
+			}
+			before["option_ids"] = optionIDs
+		}
+
+		// 删除投票记录
+		if err := tx.Delete(&vote).Error; err != nil {
+			return err
+		}
+
+		if err := outbox.Enqueue(tx, poll.ID, models.EventVoteCleared, before); err != nil {
+			return err
+		}
+		return audit.Record(tx, pollEntry(entry, models.AuditVoteCleared, poll.ID, before, nil))
+	})
+	if err != nil {
+		return err
+	}
+	s.dispatcher.Notify()
+	return nil
+}
+
+// Reset 在一个事务中清除投票问卷的所有投票、汇总数据并记录审计日志
+func (s *PollService) Reset(poll models.Poll, entry audit.Entry) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 删除所有投票记录
+		if err := tx.Where("poll_id = ?", poll.ID).Delete(&models.Vote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("poll_id = ?", poll.ID).Delete(&models.VoteMark{}).Error; err != nil {
+			return err
+		}
+
+		// 重置所有选项的投票数、加权票数和得分
+		if err := tx.Model(&models.Option{}).Where("poll_id = ?", poll.ID).Updates(map[string]interface{}{
+			"vote_count": 0, "weighted_votes": 0, "points": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := stats.ClearRollups(tx, poll.ID); err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(tx, poll.ID, models.EventPollReset, map[string]interface{}{}); err != nil {
+			return err
+		}
+		return audit.Record(tx, pollEntry(entry, models.AuditPollReset, poll.ID,
+			audit.PollSummary(poll), map[string]interface{}{"total_votes": 0}))
+	})
+	if err != nil {
+		return err
+	}
+	s.dispatcher.Notify()
+	return nil
+}
+
+// applyRules 检查自动关闭规则，关闭后通知分发器广播结果
+func (s *PollService) applyRules(pollID uint) {
+	closed, err := rules.Apply(s.db, s.hasher, pollID, time.Now())
+	if err != nil {
+		log.Printf("Error applying close rules to poll %d: %v", pollID, err)
+		return
+	}
+	if closed {
+		s.dispatcher.Notify()
+	}
+}
+
+// AdminView 按管理员身份处理结果可见性，关闭后可见的投票问卷在关闭前对管理员也隐藏票数
+func AdminView(poll *models.Poll) {
+	if !poll.ResultsVisible(false, true) {
+		poll.HideResults()
+	}
+}
+
+// pollEntry 在调用方提供的审计事件上填写动作和变更内容
+func pollEntry(entry audit.Entry, action string, pollID uint, before, after interface{}) audit.Entry {
+	entry.Action = action
+	entry.PollID = &pollID
+	entry.Before = before
+	entry.After = after
+	return entry
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/roll"
+
+	"gorm.io/gorm"
+)
+
+// ImportRoll 导入投票人名册并记录审计日志，members由roll.Parse解析
+func (s *PollService) ImportRoll(name string, members []models.RollMember, entry audit.Entry) (models.VoterRoll, error) {
+	var imported models.VoterRoll
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if imported, err = roll.Import(tx, name, members); err != nil {
+			return err
+		}
+		entry.Action = models.AuditRollImported
+		entry.After = map[string]interface{}{"roll_id": imported.ID, "name": imported.Name, "members": imported.MemberCount}
+		return audit.Record(tx, entry)
+	})
+	return imported, err
+}
+
+// Rolls 列出所有投票人名册及其人数，不含名册中的投票人
+func (s *PollService) Rolls() ([]models.VoterRoll, error) {
+	rolls := []models.VoterRoll{}
+	if err := s.db.Order("id").Find(&rolls).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		RollID uint
+		Count  int
+	}
+	if err := s.db.Model(&models.RollMember{}).Select("roll_id, COUNT(*) AS count").
+		Group("roll_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	members := map[uint]int{}
+	for _, row := range counts {
+		members[row.RollID] = row.Count
+	}
+	for i := range rolls {
+		rolls[i].MemberCount = members[rolls[i].ID]
+	}
+	return rolls, nil
+}
+
+// Roll 读取投票人名册及其中的投票人
+func (s *PollService) Roll(rollID uint) (models.VoterRoll, error) {
+	var result models.VoterRoll
+	if err := s.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&result, rollID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, apierror.New(apierror.RollNotFound)
+		}
+		return result, err
+	}
+	result.MemberCount = len(result.Members)
+	return result, nil
+}
+
+// DeleteRoll 删除投票人名册，仍有投票问卷（包括已归档的）使用时返回RollInUse
+func (s *PollService) DeleteRoll(rollID uint, entry audit.Entry) error {
+	var existing models.VoterRoll
+	if err := s.db.First(&existing, rollID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierror.New(apierror.RollNotFound)
+		}
+		return err
+	}
+
+	var polls int64
+	if err := s.db.Unscoped().Model(&models.Poll{}).Where("roll_id = ?", rollID).Count(&polls).Error; err != nil {
+		return err
+	}
+	if polls > 0 {
+		return apierror.New(apierror.RollInUse)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("roll_id = ?", rollID).Delete(&models.RollMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return err
+		}
+		entry.Action = models.AuditRollDeleted
+		entry.Before = map[string]interface{}{"roll_id": existing.ID, "name": existing.Name}
+		return audit.Record(tx, entry)
+	})
+}
+
+// Turnout 按名册统计投票问卷的投票率，投票问卷没有名册时返回nil
+func (s *PollService) Turnout(pollID uint) (*models.Turnout, error) {
+	poll, err := s.Load(pollID)
+	if err != nil {
+		return nil, err
+	}
+	return roll.Count(s.db, poll)
+}
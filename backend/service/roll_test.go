@@ -0,0 +1,77 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/roll"
+	"vote-system/weight"
+)
+
+func TestVoteWithRoll(t *testing.T) {
+	s, db := setupService(t)
+	members, err := roll.Parse(strings.NewReader("identifier,department,weight\nalice@example.com,研发,2\nbob@example.com,市场,\n"))
+	if err != nil {
+		t.Fatalf("解析名册失败: %v", err)
+	}
+	imported, err := s.ImportRoll("董事会", members, audit.Entry{Actor: "admin"})
+	if err != nil || imported.MemberCount != 2 {
+		t.Fatalf("导入名册失败: %v", err)
+	}
+	var logs int64
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditRollImported).Count(&logs)
+	if logs != 1 {
+		t.Errorf("导入名册应记录审计日志, 得到 %d 条", logs)
+	}
+
+	poll := models.Poll{Title: "董事会决议", IsActive: true, RollID: &imported.ID, Options: []models.Option{{Text: "同意"}, {Text: "反对"}}}
+	db.Create(&poll)
+	option := poll.Options[0].ID
+
+	expectCode(t, s.Vote(poll.ID, option, "carol@example.com", "", Credentials{}), apierror.NotEligible)
+	if err := s.Vote(poll.ID, option, "Alice@Example.com", "", Credentials{}); err != nil {
+		t.Fatalf("名册中的投票人投票失败: %v", err)
+	}
+	// 按名册中的标识查重，大小写不同也视为同一投票人
+	expectCode(t, s.Vote(poll.ID, option, "alice@example.com", "", Credentials{}), apierror.AlreadyVoted)
+
+	var vote models.Vote
+	db.Where("poll_id = ?", poll.ID).First(&vote)
+	if vote.RollMemberID == nil || vote.UserIP != "alice@example.com" {
+		t.Errorf("投票记录应关联名册中的投票人: %+v", vote)
+	}
+
+	if vote.Weight != 2*weight.One {
+		t.Errorf("投票的权重应来自名册, 得到 %s", vote.Weight)
+	}
+
+	if err := s.Vote(poll.ID, poll.Options[1].ID, "bob@example.com", "", Credentials{}); err != nil {
+		t.Fatalf("名册中的投票人投票失败: %v", err)
+	}
+	view, err := s.View(poll.ID, "alice@example.com")
+	if err != nil || !view.UserVoted || view.Turnout == nil || view.Turnout.Eligible != 2 || view.Turnout.Voted != 2 || view.Turnout.Percentage != 100 {
+		t.Errorf("投票率不正确: %+v %v", view.Turnout, err)
+	}
+	if view.TotalVotes != 2 || view.TotalWeight != 3*weight.One || view.Poll.Options[0].WeightedVotes != 2*weight.One {
+		t.Errorf("加权票数不正确: %d %s %+v", view.TotalVotes, view.TotalWeight, view.Poll.Options)
+	}
+
+	// 清除投票时按投票的权重扣减
+	if err := s.ClearVote("alice@example.com", audit.Entry{}); err != nil {
+		t.Fatalf("清除投票失败: %v", err)
+	}
+	view, _ = s.View(poll.ID, "alice@example.com")
+	if view.TotalVotes != 1 || view.TotalWeight != weight.One {
+		t.Errorf("清除投票后加权票数不正确: %d %s", view.TotalVotes, view.TotalWeight)
+	}
+
+	rolls, err := s.Rolls()
+	if err != nil || len(rolls) != 1 || rolls[0].MemberCount != 2 {
+		t.Errorf("名册列表不正确: %+v %v", rolls, err)
+	}
+	expectCode(t, s.DeleteRoll(imported.ID, audit.Entry{Actor: "admin"}), apierror.RollInUse)
+	_, err = s.Roll(imported.ID + 1)
+	expectCode(t, err, apierror.RollNotFound)
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/search"
+
+	"gorm.io/gorm"
+)
+
+// 投票问卷列表的排序字段
+const (
+	SortCreatedAt = "created_at"
+	SortVotes     = "votes"
+	SortClosesAt  = "closes_at"
+)
+
+// 投票问卷列表的状态筛选
+const (
+	StateActive = "active"
+	StateClosed = "closed"
+)
+
+// 每页条数的默认值和上限
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// votesExpr 按管理员视角计算的总票数，关闭后可见的投票问卷在进行中按0计，避免通过排序推断被隐藏的结果
+const votesExpr = "(CASE WHEN polls.result_visibility = '" + models.VisibilityAfterClose + "' AND polls.is_active THEN 0 ELSE " +
+	"(SELECT COALESCE(SUM(vote_count), 0) FROM options WHERE options.poll_id = polls.id AND options.deleted_at IS NULL) END)"
+
+// PollFilter 投票问卷列表的筛选、排序和分页条件，零值表示不筛选
+type PollFilter struct {
+	// State active 或 closed
+	State string
+	// Tag 包含该标签，不区分大小写
+	Tag string
+	// Owner 创建者
+	Owner string
+	// CreatedFrom、CreatedTo 创建时间范围，左闭右开
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Query 全文检索标题、描述和选项文本，多个关键词需同时匹配
+	Query string
+	// Sort 排序字段，默认created_at
+	Sort string
+	// Order asc 或 desc，默认created_at和votes为desc，closes_at为asc
+	Order string
+	// Limit 每页条数，0为DefaultPageSize
+	Limit int
+	// Cursor 上一页返回的NextCursor
+	Cursor string
+}
+
+// PollPage 一页投票问卷
+type PollPage struct {
+	Polls []models.Poll
+	// NextCursor 下一页的游标，没有更多数据时为空
+	NextCursor string
+}
+
+// cursor 游标记录上一页最后一条的排序值和ID，排序条件不同的游标无效
+type cursor struct {
+	Sort  string          `json:"s"`
+	Order string          `json:"o"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// Search 按条件分页列出投票问卷（管理员视角），使用keyset分页，翻页期间新增的投票问卷不会导致重复或遗漏
+func (s *PollService) Search(f PollFilter) (PollPage, error) {
+	if f.Sort == "" {
+		f.Sort = SortCreatedAt
+	}
+	if f.Order == "" {
+		f.Order = "desc"
+		if f.Sort == SortClosesAt {
+			f.Order = "asc"
+		}
+	}
+	if f.Sort != SortCreatedAt && f.Sort != SortVotes && f.Sort != SortClosesAt {
+		return PollPage{}, apierror.New(apierror.InvalidParameter, "name", "sort")
+	}
+	if f.Order != "asc" && f.Order != "desc" {
+		return PollPage{}, apierror.New(apierror.InvalidParameter, "name", "order")
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return PollPage{}, apierror.New(apierror.InvalidParameter, "name", "limit")
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+		return PollPage{}, apierror.New(apierror.InvalidTimeRange)
+	}
+
+	query := s.db.Model(&models.Poll{})
+	switch f.State {
+	case "":
+	case StateActive:
+		query = query.Where("polls.is_active = ?", true)
+	case StateClosed:
+		query = query.Where("polls.is_active = ?", false)
+	default:
+		return PollPage{}, apierror.New(apierror.InvalidParameter, "name", "state")
+	}
+	if tag := strings.ToLower(strings.TrimSpace(f.Tag)); tag != "" {
+		query = query.Where("polls.tags LIKE ? ESCAPE '!'", "%,"+search.EscapeLike(tag)+",%")
+	}
+	if f.Owner != "" {
+		query = query.Where("polls.owner = ?", f.Owner)
+	}
+	// 创建时间按本地时区保存，SQLite按字符串比较，需统一时区
+	if f.CreatedFrom != nil {
+		query = query.Where("polls.created_at >= ?", f.CreatedFrom.Local())
+	}
+	if f.CreatedTo != nil {
+		query = query.Where("polls.created_at < ?", f.CreatedTo.Local())
+	}
+	query = query.Scopes(search.Match(f.Query))
+
+	if f.Cursor != "" {
+		var err error
+		if query, err = after(query, f, f.Cursor); err != nil {
+			return PollPage{}, err
+		}
+	}
+
+	dir := strings.ToUpper(f.Order)
+	switch f.Sort {
+	case SortCreatedAt:
+		query = query.Order("polls.created_at " + dir)
+	case SortVotes:
+		query = query.Order(votesExpr + " " + dir)
+	case SortClosesAt:
+		// 没有截止时间视为无穷远，升序时排在最后
+		if dir == "ASC" {
+			query = query.Order("polls.closes_at IS NULL").Order("polls.closes_at")
+		} else {
+			query = query.Order("polls.closes_at IS NULL DESC").Order("polls.closes_at DESC")
+		}
+	}
+	query = query.Order("polls.id " + dir)
+
+	var polls []models.Poll
+	if err := query.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Limit(f.Limit + 1).Find(&polls).Error; err != nil {
+		return PollPage{}, err
+	}
+
+	page := PollPage{Polls: polls}
+	if len(polls) > f.Limit {
+		page.Polls = polls[:f.Limit]
+	}
+	for i := range page.Polls {
+		AdminView(&page.Polls[i])
+	}
+	if len(polls) > f.Limit {
+		page.NextCursor = encodeCursor(f, page.Polls[f.Limit-1])
+	}
+	return page, nil
+}
+
+// sortValue 投票问卷在排序字段上的取值，与排序使用的SQL表达式一致
+func sortValue(sort string, poll models.Poll) interface{} {
+	switch sort {
+	case SortVotes:
+		total := 0
+		for _, option := range poll.Options {
+			total += option.VoteCount
+		}
+		return total
+	case SortClosesAt:
+		return poll.Rules.ClosesAt
+	}
+	return poll.CreatedAt
+}
+
+func encodeCursor(f PollFilter, last models.Poll) string {
+	value, _ := json.Marshal(sortValue(f.Sort, last))
+	data, _ := json.Marshal(cursor{Sort: f.Sort, Order: f.Order, Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after 只保留排在游标之后的投票问卷
+func after(query *gorm.DB, f PollFilter, token string) (*gorm.DB, error) {
+	invalid := apierror.New(apierror.InvalidParameter, "name", "cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != f.Sort || c.Order != f.Order || c.ID == 0 {
+		return nil, invalid
+	}
+
+	cmp := ">"
+	if f.Order == "desc" {
+		cmp = "<"
+	}
+	keyset := func(expr string, value interface{}) *gorm.DB {
+		return query.Where("("+expr+" "+cmp+" ? OR ("+expr+" = ? AND polls.id "+cmp+" ?))", value, value, c.ID)
+	}
+
+	switch f.Sort {
+	case SortCreatedAt:
+		var value time.Time
+		if err := json.Unmarshal(c.Value, &value); err != nil {
+			return nil, invalid
+		}
+		return keyset("polls.created_at", value), nil
+	case SortVotes:
+		var value int
+		if err := json.Unmarshal(c.Value, &value); err != nil {
+			return nil, invalid
+		}
+		return keyset(votesExpr, value), nil
+	}
+
+	var value *time.Time
+	if err := json.Unmarshal(c.Value, &value); err != nil {
+		return nil, invalid
+	}
+	switch {
+	case value == nil && f.Order == "asc":
+		return query.Where("polls.closes_at IS NULL AND polls.id > ?", c.ID), nil
+	case value == nil:
+		return query.Where("(polls.closes_at IS NOT NULL OR polls.id < ?)", c.ID), nil
+	case f.Order == "asc":
+		return query.Where("(polls.closes_at IS NULL OR polls.closes_at > ? OR (polls.closes_at = ? AND polls.id > ?))", *value, *value, c.ID), nil
+	}
+	return query.Where("polls.closes_at IS NOT NULL AND (polls.closes_at < ? OR (polls.closes_at = ? AND polls.id < ?))", *value, *value, c.ID), nil
+}
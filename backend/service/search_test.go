@@ -0,0 +1,201 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"vote-system/apierror"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupService(t *testing.T) (*PollService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.OptionProposal{}, &models.BallotToken{}, &models.VoterRoll{}, &models.RollMember{}, &models.Delegation{}, &models.VoteRollup{},
+		&models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{},
+		&models.OutboxEvent{}, &models.AuditLog{}, &models.AuditChainHead{})
+	return NewPollService(db, nil, nil), db
+}
+
+// createPoll 创建投票问卷并设置各选项票数
+func createPoll(db *gorm.DB, poll models.Poll, votes ...int) models.Poll {
+	for i, count := range votes {
+		poll.Options = append(poll.Options, models.Option{Text: string(rune('A' + i)), VoteCount: count})
+	}
+	active := poll.IsActive
+	db.Create(&poll)
+	// IsActive带default标签，Create后需要单独更新
+	if !active {
+		db.Model(&poll).Update("is_active", false)
+	}
+	return poll
+}
+
+// collect 沿游标读取所有分页，返回投票问卷ID和页数
+func collect(t *testing.T, s *PollService, f PollFilter) ([]uint, int) {
+	t.Helper()
+	var ids []uint
+	pages := 0
+	for {
+		page, err := s.Search(f)
+		if err != nil {
+			t.Fatalf("查询失败: %v", err)
+		}
+		pages++
+		for _, poll := range page.Polls {
+			ids = append(ids, poll.ID)
+		}
+		if page.NextCursor == "" {
+			return ids, pages
+		}
+		f.Cursor = page.NextCursor
+	}
+}
+
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSearchPagination(t *testing.T) {
+	s, db := setupService(t)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		// 前两个创建时间相同，按ID区分先后
+		created := base.Add(time.Duration(i/2*2) * time.Hour)
+		poll := createPoll(db, models.Poll{Title: "p", IsActive: true, CreatedAt: created}, 1, 1)
+		ids = append(ids, poll.ID)
+	}
+
+	got, pages := collect(t, s, PollFilter{Limit: 2})
+	expect := []uint{ids[4], ids[3], ids[2], ids[1], ids[0]}
+	if !sameIDs(got, expect) || pages != 3 {
+		t.Errorf("默认按创建时间倒序: 期望 %v 共3页, 得到 %v 共%d页", expect, got, pages)
+	}
+
+	got, _ = collect(t, s, PollFilter{Order: "asc", Limit: 2})
+	if !sameIDs(got, ids) {
+		t.Errorf("按创建时间正序: 期望 %v, 得到 %v", ids, got)
+	}
+
+	// 翻页期间新建的投票问卷不会导致重复
+	page, _ := s.Search(PollFilter{Limit: 2})
+	createPoll(db, models.Poll{Title: "new", IsActive: true}, 0, 0)
+	next, err := s.Search(PollFilter{Limit: 2, Cursor: page.NextCursor})
+	if err != nil || next.Polls[0].ID != ids[2] {
+		t.Errorf("第二页应从 %d 开始, 得到 %+v %v", ids[2], next.Polls, err)
+	}
+}
+
+func TestSearchSortVotes(t *testing.T) {
+	s, db := setupService(t)
+	low := createPoll(db, models.Poll{Title: "low", IsActive: true}, 1, 0)
+	high := createPoll(db, models.Poll{Title: "high", IsActive: true}, 5, 4)
+	// 进行中且关闭后才公开结果的投票问卷按0票排序，不泄露结果
+	sealed := createPoll(db, models.Poll{Title: "sealed", IsActive: true, ResultVisibility: models.VisibilityAfterClose}, 50, 50)
+	closed := createPoll(db, models.Poll{Title: "closed", IsActive: false, ResultVisibility: models.VisibilityAfterClose}, 3, 0)
+
+	got, _ := collect(t, s, PollFilter{Sort: SortVotes, Limit: 1})
+	expect := []uint{high.ID, closed.ID, low.ID, sealed.ID}
+	if !sameIDs(got, expect) {
+		t.Errorf("按票数倒序: 期望 %v, 得到 %v", expect, got)
+	}
+
+	page, _ := s.Search(PollFilter{Sort: SortVotes, Order: "asc", Limit: 1})
+	if page.Polls[0].ID != sealed.ID || !page.Polls[0].ResultsHidden {
+		t.Errorf("进行中的隐藏结果应按0票排在最前且票数隐藏, 得到 %+v", page.Polls[0])
+	}
+}
+
+func TestSearchSortClosesAt(t *testing.T) {
+	s, db := setupService(t)
+	soon := time.Now().Add(time.Hour)
+	later := time.Now().Add(48 * time.Hour)
+	none1 := createPoll(db, models.Poll{Title: "none1", IsActive: true}, 0, 0)
+	b := createPoll(db, models.Poll{Title: "later", IsActive: true, Rules: models.PollRules{ClosesAt: &later}}, 0, 0)
+	none2 := createPoll(db, models.Poll{Title: "none2", IsActive: true}, 0, 0)
+	a := createPoll(db, models.Poll{Title: "soon", IsActive: true, Rules: models.PollRules{ClosesAt: &soon}}, 0, 0)
+
+	got, _ := collect(t, s, PollFilter{Sort: SortClosesAt, Limit: 1})
+	expect := []uint{a.ID, b.ID, none1.ID, none2.ID}
+	if !sameIDs(got, expect) {
+		t.Errorf("按截止时间正序，没有截止时间的排在最后: 期望 %v, 得到 %v", expect, got)
+	}
+
+	got, _ = collect(t, s, PollFilter{Sort: SortClosesAt, Order: "desc", Limit: 1})
+	expect = []uint{none2.ID, none1.ID, b.ID, a.ID}
+	if !sameIDs(got, expect) {
+		t.Errorf("按截止时间倒序: 期望 %v, 得到 %v", expect, got)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	s, db := setupService(t)
+	old := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	a := createPoll(db, models.Poll{Title: "团建地点", IsActive: true, Owner: "admin", Tags: models.Tags{"team", "q3"}, CreatedAt: old}, 0, 0)
+	b := createPoll(db, models.Poll{Title: "午餐", IsActive: false, Owner: "token:ci", Tags: models.Tags{"food"}}, 0, 0)
+	c := createPoll(db, models.Poll{Title: "Team lunch", IsActive: true, Owner: "token:ci", Tags: models.Tags{"teams"}}, 0, 0)
+
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter PollFilter
+		expect []uint
+	}{
+		{"state=active", PollFilter{State: StateActive, Order: "asc"}, []uint{a.ID, c.ID}},
+		{"state=closed", PollFilter{State: StateClosed}, []uint{b.ID}},
+		{"tag", PollFilter{Tag: "TEAM"}, []uint{a.ID}},
+		{"owner", PollFilter{Owner: "token:ci", Order: "asc"}, []uint{b.ID, c.ID}},
+		{"created_to", PollFilter{CreatedTo: &cutoff}, []uint{a.ID}},
+		{"created_from", PollFilter{CreatedFrom: &cutoff, Order: "asc"}, []uint{b.ID, c.ID}},
+		{"q", PollFilter{Query: "lunch", State: StateActive}, []uint{c.ID}},
+	}
+	for _, tt := range tests {
+		got, _ := collect(t, s, tt.filter)
+		if !sameIDs(got, tt.expect) {
+			t.Errorf("%s: 期望 %v, 得到 %v", tt.name, tt.expect, got)
+		}
+	}
+}
+
+func TestSearchInvalid(t *testing.T) {
+	s, db := setupService(t)
+	createPoll(db, models.Poll{Title: "a", IsActive: true}, 0, 0)
+	createPoll(db, models.Poll{Title: "b", IsActive: true}, 0, 0)
+	page, _ := s.Search(PollFilter{Limit: 1})
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+	tests := []struct {
+		filter PollFilter
+		code   apierror.Code
+	}{
+		{PollFilter{State: "archived"}, apierror.InvalidParameter},
+		{PollFilter{Sort: "title"}, apierror.InvalidParameter},
+		{PollFilter{Order: "up"}, apierror.InvalidParameter},
+		{PollFilter{Limit: MaxPageSize + 1}, apierror.InvalidParameter},
+		{PollFilter{Cursor: "not-a-cursor"}, apierror.InvalidParameter},
+		// 游标只能用于生成它的排序条件
+		{PollFilter{Sort: SortVotes, Cursor: page.NextCursor}, apierror.InvalidParameter},
+		{PollFilter{CreatedFrom: &from, CreatedTo: &to}, apierror.InvalidTimeRange},
+	}
+	for _, tt := range tests {
+		_, err := s.Search(tt.filter)
+		if e, ok := apierror.As(err); !ok || e.Code != tt.code {
+			t.Errorf("%+v: 期望 %s, 得到 %v", tt.filter, tt.code, err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"errors"
+	"time"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/outbox"
+	"vote-system/privacy"
+	"vote-system/survey"
+
+	"gorm.io/gorm"
+)
+
+// SurveyService 调查问卷的创建、作答和统计
+type SurveyService struct {
+	db         *gorm.DB
+	dispatcher *outbox.Dispatcher
+	hasher     *privacy.Hasher
+}
+
+// NewSurveyService 创建SurveyService，hasher和dispatcher的含义与NewPollService相同
+func NewSurveyService(db *gorm.DB, dispatcher *outbox.Dispatcher, hasher *privacy.Hasher) *SurveyService {
+	return &SurveyService{
+		db:         db,
+		dispatcher: dispatcher,
+		hasher:     hasher,
+	}
+}
+
+// Load 读取调查问卷（含问题，按顺序排列）
+func (s *SurveyService) Load(surveyID uint) (models.Survey, error) {
+	sv, err := survey.Load(s.db, surveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return sv, apierror.New(apierror.SurveyNotFound)
+	}
+	return sv, err
+}
+
+// List 列出所有调查问卷，按ID排序
+func (s *SurveyService) List() ([]models.Survey, error) {
+	surveys := []models.Survey{}
+	if err := s.db.Preload("Questions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Order("id").Find(&surveys).Error; err != nil {
+		return nil, err
+	}
+	return surveys, nil
+}
+
+// Create 校验问题并在一个事务中创建调查问卷、写入事件和审计日志，创建者为entry的操作人
+func (s *SurveyService) Create(req models.CreateSurveyRequest, entry audit.Entry) (models.Survey, error) {
+	questions, err := survey.BuildQuestions(req.Questions)
+	if err != nil {
+		return models.Survey{}, err
+	}
+	active := req.IsActive == nil || *req.IsActive
+	sv := models.Survey{
+		Title:       req.Title,
+		Description: req.Description,
+		IsActive:    active,
+		Owner:       entry.Actor,
+		Questions:   questions,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sv).Error; err != nil {
+			return err
+		}
+		// IsActive带default标签，零值会被替换为默认值，需要单独更新
+		if !active {
+			if err := tx.Model(&sv).Update("is_active", false).Error; err != nil {
+				return err
+			}
+			sv.IsActive = false
+		}
+		if err := outbox.EnqueueSurvey(tx, sv.ID, models.EventSurveyCreated, map[string]interface{}{"title": sv.Title}); err != nil {
+			return err
+		}
+		return audit.Record(tx, surveyEntry(entry, models.AuditSurveyCreated, nil, surveySummary(sv)))
+	})
+	if err != nil {
+		return models.Survey{}, err
+	}
+	s.dispatcher.Notify()
+	return sv, nil
+}
+
+// SetState 开启或关闭调查问卷，已处于请求的状态时返回SurveyUnchanged
+func (s *SurveyService) SetState(surveyID uint, active bool, entry audit.Entry) (models.Survey, error) {
+	sv, err := s.Load(surveyID)
+	if err != nil {
+		return sv, err
+	}
+	if sv.IsActive == active {
+		return sv, apierror.New(apierror.SurveyUnchanged)
+	}
+
+	action, eventType := models.AuditSurveyOpened, models.EventSurveyOpened
+	var closedAt *time.Time
+	if !active {
+		now := time.Now()
+		action, eventType, closedAt = models.AuditSurveyClosed, models.EventSurveyClosed, &now
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 只更新仍处于原状态的记录，并发请求时后提交的一方失败
+		result := tx.Model(&models.Survey{}).Where("id = ? AND is_active = ?", sv.ID, !active).
+			Updates(map[string]interface{}{"is_active": active, "closed_at": closedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apierror.New(apierror.SurveyUnchanged)
+		}
+		if err := outbox.EnqueueSurvey(tx, sv.ID, eventType, map[string]interface{}{}); err != nil {
+			return err
+		}
+		return audit.Record(tx, surveyEntry(entry, action,
+			map[string]interface{}{"survey_id": sv.ID, "is_active": !active},
+			map[string]interface{}{"survey_id": sv.ID, "is_active": active}))
+	})
+	if err != nil {
+		return sv, err
+	}
+	s.dispatcher.Notify()
+	sv.IsActive, sv.ClosedAt = active, closedAt
+	return sv, nil
+}
+
+// View 返回投票人看到的调查问卷，Responded表示该投票人是否已提交
+func (s *SurveyService) View(surveyID uint, voter string) (models.SurveyView, error) {
+	sv, err := s.Load(surveyID)
+	if err != nil {
+		return models.SurveyView{}, err
+	}
+	responded, err := s.responded(sv.ID, voter)
+	if err != nil {
+		return models.SurveyView{}, err
+	}
+	return models.SurveyView{Survey: sv, Responded: responded}, nil
+}
+
+// responded 判断投票人是否已提交该调查问卷
+func (s *SurveyService) responded(surveyID uint, voter string) (bool, error) {
+	var count int64
+	err := s.hasher.VoterScope(s.db.Model(&models.SurveyResponse{}), voter).
+		Where("survey_id = ?", surveyID).Count(&count).Error
+	return count > 0, err
+}
+
+// Submit 在一个事务中保存投票人对所有问题的回答，任一回答无效时不保存任何回答
+func (s *SurveyService) Submit(surveyID uint, answers []models.SurveyAnswerInput, voter string) (models.SurveyResponse, error) {
+	sv, err := s.Load(surveyID)
+	if err != nil {
+		return models.SurveyResponse{}, err
+	}
+	if !sv.IsActive {
+		return models.SurveyResponse{}, apierror.New(apierror.SurveyClosed)
+	}
+
+	validated, err := survey.ValidateAnswers(sv, answers)
+	if err != nil {
+		return models.SurveyResponse{}, err
+	}
+
+	responded, err := s.responded(sv.ID, voter)
+	if err != nil {
+		return models.SurveyResponse{}, err
+	}
+	if responded {
+		return models.SurveyResponse{}, apierror.New(apierror.AlreadyResponded)
+	}
+
+	response := models.SurveyResponse{
+		SurveyID: sv.ID,
+		UserIP:   voter,
+		Answers:  validated,
+	}
+	if s.hasher.Enabled() {
+		response.UserIP = ""
+		response.VoterHash, response.VoterKeyID = s.hasher.Identify(voter)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&response).Error; err != nil {
+			return err
+		}
+
+		var total int64
+		if err := tx.Model(&models.SurveyResponse{}).Where("survey_id = ?", sv.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		return outbox.EnqueueSurvey(tx, sv.ID, models.EventSurveyResponse, map[string]interface{}{
+			"response_id": response.ID, "total_responses": total,
+		})
+	})
+	if err != nil {
+		return models.SurveyResponse{}, err
+	}
+	s.dispatcher.Notify()
+	return response, nil
+}
+
+// Next 按部分作答返回下一个要显示的问题，前端不需要重复实现跳转规则
+func (s *SurveyService) Next(surveyID uint, answers []models.SurveyAnswerInput) (*survey.Progress, error) {
+	sv, err := s.Load(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	return survey.Next(sv, answers)
+}
+
+// Results 按问题汇总调查问卷的回答
+func (s *SurveyService) Results(surveyID uint) (*survey.Results, error) {
+	results, err := survey.Aggregate(s.db, surveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apierror.New(apierror.SurveyNotFound)
+	}
+	return results, err
+}
+
+// Crosstab 按分组依据交叉分析问题的回答，人数过少的单元格已隐藏
+func (s *SurveyService) Crosstab(surveyID, questionID uint, pivot string) (*survey.Crosstab, error) {
+	table, err := survey.BuildCrosstab(s.db, surveyID, questionID, pivot)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apierror.New(apierror.SurveyNotFound)
+	}
+	return table, err
+}
+
+// surveyEntry 在调用方提供的审计事件上填写动作和变更内容，调查问卷不属于任何投票问卷
+func surveyEntry(entry audit.Entry, action string, before, after interface{}) audit.Entry {
+	entry.Action = action
+	entry.PollID = nil
+	entry.Before = before
+	entry.After = after
+	return entry
+}
+
+// surveySummary 审计日志中记录的调查问卷摘要
+func surveySummary(sv models.Survey) map[string]interface{} {
+	questions := make([]map[string]interface{}, 0, len(sv.Questions))
+	for _, question := range sv.Questions {
+		summary := map[string]interface{}{
+			"id":       question.ID,
+			"type":     question.Type,
+			"title":    question.Title,
+			"required": question.Required,
+		}
+		if len(question.Branches) > 0 {
+			summary["branches"] = question.Branches
+		}
+		questions = append(questions, summary)
+	}
+	return map[string]interface{}{
+		"survey_id": sv.ID,
+		"title":     sv.Title,
+		"is_active": sv.IsActive,
+		"questions": questions,
+	}
+}
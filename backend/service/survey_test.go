@@ -0,0 +1,132 @@
+package service
+
+import (
+	"testing"
+	"vote-system/apierror"
+	"vote-system/audit"
+	"vote-system/models"
+	"vote-system/privacy"
+)
+
+func surveyRequest() models.CreateSurveyRequest {
+	return models.CreateSurveyRequest{
+		Title: "迭代回顾",
+		Questions: []models.SurveyQuestionInput{
+			{Type: models.QuestionSingleChoice, Title: "本次迭代顺利吗", Required: true, Choices: []string{"顺利", "一般", "不顺利"}},
+			{Type: models.QuestionRating, Title: "打分", Required: true},
+			{Type: models.QuestionText, Title: "补充"},
+		},
+	}
+}
+
+func TestCreateSurvey(t *testing.T) {
+	_, db := setupService(t)
+	s := NewSurveyService(db, nil, nil)
+
+	inactive := false
+	req := surveyRequest()
+	req.IsActive = &inactive
+	sv, err := s.Create(req, audit.Entry{Actor: "admin:alice"})
+	if err != nil {
+		t.Fatalf("创建调查问卷失败: %v", err)
+	}
+	if sv.IsActive || sv.Owner != "admin:alice" || len(sv.Questions) != 3 {
+		t.Errorf("调查问卷不正确: %+v", sv)
+	}
+	loaded, _ := s.Load(sv.ID)
+	if loaded.IsActive {
+		t.Error("is_active=false 应保存为关闭")
+	}
+
+	var events, logs int64
+	db.Model(&models.OutboxEvent{}).Where("survey_id = ? AND type = ?", sv.ID, models.EventSurveyCreated).Count(&events)
+	db.Model(&models.AuditLog{}).Where("action = ?", models.AuditSurveyCreated).Count(&logs)
+	if events != 1 || logs != 1 {
+		t.Errorf("期望1条事件和1条审计日志, 得到 %d %d", events, logs)
+	}
+
+	req = surveyRequest()
+	req.Questions[0].Choices = []string{"只有一项"}
+	_, err = s.Create(req, audit.Entry{})
+	expectCode(t, err, apierror.InvalidQuestion)
+
+	if _, err := s.SetState(sv.ID, false, audit.Entry{}); err == nil {
+		t.Error("关闭已关闭的调查问卷应失败")
+	}
+	opened, err := s.SetState(sv.ID, true, audit.Entry{})
+	if err != nil || !opened.IsActive || opened.ClosedAt != nil {
+		t.Errorf("开启调查问卷失败: %+v %v", opened, err)
+	}
+}
+
+func TestSubmitSurvey(t *testing.T) {
+	_, db := setupService(t)
+	s := NewSurveyService(db, nil, privacy.NewHasher([]privacy.Key{{ID: "k1", Secret: []byte("s")}}))
+	sv, err := s.Create(surveyRequest(), audit.Entry{})
+	if err != nil {
+		t.Fatalf("创建调查问卷失败: %v", err)
+	}
+	choice, rating, text := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID
+	four := 4
+
+	// 任一回答无效时整份作答都不保存
+	_, err = s.Submit(sv.ID, []models.SurveyAnswerInput{
+		{QuestionID: choice, Choices: []int{0}},
+		{QuestionID: rating, Rating: &four},
+		{QuestionID: text, Choices: []int{0}},
+	}, "10.0.0.1")
+	expectCode(t, err, apierror.InvalidAnswer)
+	_, err = s.Submit(sv.ID, []models.SurveyAnswerInput{{QuestionID: choice, Choices: []int{0}}}, "10.0.0.1")
+	expectCode(t, err, apierror.AnswerRequired)
+
+	var responses, answers int64
+	db.Model(&models.SurveyResponse{}).Count(&responses)
+	db.Model(&models.SurveyAnswer{}).Count(&answers)
+	if responses != 0 || answers != 0 {
+		t.Fatalf("校验失败时不应保存回答, 得到 %d份作答 %d条回答", responses, answers)
+	}
+
+	response, err := s.Submit(sv.ID, []models.SurveyAnswerInput{
+		{QuestionID: choice, Choices: []int{0}},
+		{QuestionID: rating, Rating: &four},
+	}, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("提交失败: %v", err)
+	}
+	if len(response.Answers) != 2 || response.UserIP != "" || response.VoterHash == "" {
+		t.Errorf("隐私模式下应只保存标识的HMAC: %+v", response)
+	}
+
+	view, _ := s.View(sv.ID, "10.0.0.1")
+	if !view.Responded {
+		t.Error("提交后Responded应为true")
+	}
+	_, err = s.Submit(sv.ID, []models.SurveyAnswerInput{
+		{QuestionID: choice, Choices: []int{1}},
+		{QuestionID: rating, Rating: &four},
+	}, "10.0.0.1")
+	expectCode(t, err, apierror.AlreadyResponded)
+
+	var events int64
+	db.Model(&models.OutboxEvent{}).Where("survey_id = ? AND type = ?", sv.ID, models.EventSurveyResponse).Count(&events)
+	if events != 1 {
+		t.Errorf("期望1条作答事件, 得到 %d", events)
+	}
+
+	results, err := s.Results(sv.ID)
+	if err != nil || results.Responses != 1 || results.Questions[0].Choices[0].Count != 1 {
+		t.Errorf("汇总结果不正确: %+v %v", results, err)
+	}
+
+	if _, err := s.SetState(sv.ID, false, audit.Entry{}); err != nil {
+		t.Fatalf("关闭调查问卷失败: %v", err)
+	}
+	_, err = s.Submit(sv.ID, []models.SurveyAnswerInput{
+		{QuestionID: choice, Choices: []int{1}},
+		{QuestionID: rating, Rating: &four},
+	}, "10.0.0.2")
+	expectCode(t, err, apierror.SurveyClosed)
+
+	_, err = s.Results(sv.ID + 1)
+	expectCode(t, err, apierror.SurveyNotFound)
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// Drift 选项上记录的票数、加权票数或得分与投票记录不一致
+type Drift struct {
+	OptionID     uint          `json:"option_id"`
+	Text         string        `json:"text"`
+	Stored       int           `json:"stored"`
+	Actual       int           `json:"actual"`
+	StoredWeight weight.Weight `json:"stored_weight"`
+	ActualWeight weight.Weight `json:"actual_weight"`
+	StoredPoints weight.Weight `json:"stored_points"`
+	ActualPoints weight.Weight `json:"actual_points"`
+}
+
+// FindDrift 按投票记录和选票标记重新计数，返回票数、加权票数或得分不一致的选项
+func FindDrift(db *gorm.DB, pollID uint) ([]Drift, error) {
+	var options []models.Option
+	if err := db.Where("poll_id = ?", pollID).Order("id").Find(&options).Error; err != nil {
+		return nil, err
+	}
+
+	type count struct {
+		OptionID uint
+		Count    int
+		Weight   weight.Weight
+		Points   weight.Weight
+	}
+	var votes, marks []count
+	if err := db.Model(&models.Vote{}).Select("option_id, COUNT(*) AS count, SUM(weight) AS weight").
+		Where("poll_id = ? AND option_id <> 0", pollID).Group("option_id").Scan(&votes).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.VoteMark{}).Select("option_id, COUNT(*) AS count, SUM(weight) AS weight, SUM(points * weight) AS points").
+		Where("poll_id = ?", pollID).Group("option_id").Scan(&marks).Error; err != nil {
+		return nil, err
+	}
+	actual := map[uint]count{}
+	for _, row := range append(votes, marks...) {
+		a := actual[row.OptionID]
+		a.Count += row.Count
+		a.Weight += row.Weight
+		a.Points += row.Points
+		actual[row.OptionID] = a
+	}
+
+	drift := []Drift{}
+	for _, option := range options {
+		a := actual[option.ID]
+		if option.VoteCount != a.Count || option.WeightedVotes != a.Weight || option.Points != a.Points {
+			drift = append(drift, Drift{
+				OptionID:     option.ID,
+				Text:         option.Text,
+				Stored:       option.VoteCount,
+				Actual:       a.Count,
+				StoredWeight: option.WeightedVotes,
+				ActualWeight: a.Weight,
+				StoredPoints: option.Points,
+				ActualPoints: a.Points,
+			})
+		}
+	}
+	return drift, nil
+}
+
+// Reconcile 按投票记录和选票标记修正选项票数、加权票数和得分并重建汇总数据，返回修正前不一致的选项
+func Reconcile(tx *gorm.DB, pollID uint) ([]Drift, error) {
+	drift, err := FindDrift(tx, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range drift {
+		if err := tx.Model(&models.Option{}).Where("id = ?", d.OptionID).Updates(map[string]interface{}{
+			"vote_count": d.Actual, "weighted_votes": d.ActualWeight, "points": d.ActualPoints,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := RebuildRollups(tx, pollID); err != nil {
+		return nil, err
+	}
+	return drift, nil
+}
+
+// BackfillWeights 升级前的选项没有加权票数，按每票权重为1补齐
+func BackfillWeights(db *gorm.DB) error {
+	return db.Model(&models.Option{}).Where("weighted_votes = 0 AND vote_count <> 0").
+		Update("weighted_votes", gorm.Expr("vote_count * ?", weight.Scale)).Error
+}
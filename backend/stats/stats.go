@@ -0,0 +1,234 @@
+package stats
+
+import (
+	"errors"
+	"sort"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 支持的时间粒度
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// MaxBuckets 单次查询允许返回的最大时间桶数量
+const MaxBuckets = 5000
+
+// ErrTooManyBuckets 查询范围相对粒度过大
+var ErrTooManyBuckets = errors.New("too many buckets for the requested range, use a coarser granularity")
+
+// Point 某个时间桶内的票数及截至该桶的累计票数
+type Point struct {
+	Start      time.Time `json:"start"`
+	Votes      int       `json:"votes"`
+	Cumulative int       `json:"cumulative"`
+}
+
+// Series 单个选项的时间序列
+type Series struct {
+	OptionID uint    `json:"option_id"`
+	Text     string  `json:"text"`
+	Points   []Point `json:"points"`
+}
+
+// History 投票问卷在一段时间内的投票趋势
+type History struct {
+	PollID      uint      `json:"poll_id"`
+	Granularity string    `json:"granularity"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Series      []Series  `json:"series"`
+}
+
+// ValidGranularity 判断是否为支持的时间粒度
+func ValidGranularity(granularity string) bool {
+	return step(granularity) > 0
+}
+
+func step(granularity string) time.Duration {
+	switch granularity {
+	case GranularityMinute:
+		return time.Minute
+	case GranularityHour:
+		return time.Hour
+	case GranularityDay:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// bucketStart 将时间按粒度截断到所在时间桶的起点（UTC）
+func bucketStart(t time.Time, granularity string) time.Time {
+	return t.UTC().Truncate(step(granularity))
+}
+
+// IncrementRollup 在事务中为投票所在的分钟桶加一
+func IncrementRollup(tx *gorm.DB, vote models.Vote) error {
+	rollup := models.VoteRollup{
+		PollID:      vote.PollID,
+		OptionID:    vote.OptionID,
+		BucketStart: bucketStart(vote.CreatedAt, GranularityMinute),
+		Votes:       1,
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "poll_id"}, {Name: "option_id"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"votes": gorm.Expr("votes + ?", 1)}),
+	}).Create(&rollup).Error
+}
+
+// DecrementRollup 在事务中为被撤销投票所在的分钟桶减一
+func DecrementRollup(tx *gorm.DB, vote models.Vote) error {
+	return tx.Model(&models.VoteRollup{}).
+		Where("poll_id = ? AND option_id = ? AND bucket_start = ? AND votes > 0",
+			vote.PollID, vote.OptionID, bucketStart(vote.CreatedAt, GranularityMinute)).
+		Update("votes", gorm.Expr("votes - ?", 1)).Error
+}
+
+// ClearRollups 在事务中删除投票问卷的所有汇总数据
+func ClearRollups(tx *gorm.DB, pollID uint) error {
+	return tx.Where("poll_id = ?", pollID).Delete(&models.VoteRollup{}).Error
+}
+
+// RebuildRollups 根据投票记录重建投票问卷的汇总数据
+func RebuildRollups(db *gorm.DB, pollID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ClearRollups(tx, pollID); err != nil {
+			return err
+		}
+
+		type key struct {
+			optionID uint
+			start    int64
+		}
+		counts := map[key]int{}
+		// 单选投票按投票记录计数，其他投票方式按选票标记计数
+		for _, q := range []*gorm.DB{
+			tx.Model(&models.Vote{}).Select("option_id, created_at").Where("poll_id = ? AND option_id <> 0", pollID),
+			tx.Model(&models.VoteMark{}).Select("option_id, created_at").Where("poll_id = ?", pollID),
+		} {
+			rows, err := q.Rows()
+			if err != nil {
+				return err
+			}
+			for rows.Next() {
+				var optionID uint
+				var createdAt time.Time
+				if err := rows.Scan(&optionID, &createdAt); err != nil {
+					rows.Close()
+					return err
+				}
+				counts[key{optionID, bucketStart(createdAt, GranularityMinute).Unix()}]++
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		}
+
+		if len(counts) == 0 {
+			return nil
+		}
+
+		rollups := make([]models.VoteRollup, 0, len(counts))
+		for k, n := range counts {
+			rollups = append(rollups, models.VoteRollup{
+				PollID:      pollID,
+				OptionID:    k.optionID,
+				BucketStart: time.Unix(k.start, 0).UTC(),
+				Votes:       n,
+			})
+		}
+		return tx.CreateInBatches(rollups, 500).Error
+	})
+}
+
+// BackfillRollups 为已有投票但尚无汇总数据的投票问卷补建汇总数据
+func BackfillRollups(db *gorm.DB) error {
+	var pollIDs []uint
+	err := db.Model(&models.Vote{}).
+		Where("poll_id NOT IN (?)", db.Model(&models.VoteRollup{}).Select("poll_id")).
+		Distinct("poll_id").Pluck("poll_id", &pollIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, pollID := range pollIDs {
+		if err := RebuildRollups(db, pollID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadHistory 按粒度汇总[from, to)范围内各选项的票数，并计算累计票数
+func LoadHistory(db *gorm.DB, poll models.Poll, granularity string, from, to time.Time) (*History, error) {
+	to = to.UTC()
+	first := bucketStart(from, granularity)
+	last := bucketStart(to, granularity)
+	if !last.Before(first) && int(last.Sub(first)/step(granularity))+1 > MaxBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	history := &History{
+		PollID:      poll.ID,
+		Granularity: granularity,
+		From:        first,
+		To:          to,
+	}
+
+	// 范围之前的票数作为累计起点
+	type baseline struct {
+		OptionID uint
+		Total    int
+	}
+	var baselines []baseline
+	if err := db.Model(&models.VoteRollup{}).
+		Select("option_id, SUM(votes) AS total").
+		Where("poll_id = ? AND bucket_start < ?", poll.ID, first).
+		Group("option_id").
+		Scan(&baselines).Error; err != nil {
+		return nil, err
+	}
+	cumulative := map[uint]int{}
+	for _, b := range baselines {
+		cumulative[b.OptionID] = b.Total
+	}
+
+	var rollups []models.VoteRollup
+	if err := db.Where("poll_id = ? AND bucket_start >= ? AND bucket_start < ?", poll.ID, first, to).
+		Order("bucket_start").
+		Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+
+	perBucket := map[uint]map[int64]int{}
+	for _, r := range rollups {
+		if perBucket[r.OptionID] == nil {
+			perBucket[r.OptionID] = map[int64]int{}
+		}
+		perBucket[r.OptionID][bucketStart(r.BucketStart, granularity).Unix()] += r.Votes
+	}
+
+	options := append([]models.Option(nil), poll.Options...)
+	sort.Slice(options, func(i, j int) bool { return options[i].ID < options[j].ID })
+
+	for _, option := range options {
+		series := Series{OptionID: option.ID, Text: option.Text, Points: []Point{}}
+		total := cumulative[option.ID]
+		for t := first; t.Before(to); t = t.Add(step(granularity)) {
+			votes := perBucket[option.ID][t.Unix()]
+			total += votes
+			series.Points = append(series.Points, Point{Start: t, Votes: votes, Cumulative: total})
+		}
+		history.Series = append(history.Series, series)
+	}
+
+	return history, nil
+}
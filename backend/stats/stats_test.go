@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"testing"
+	"time"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{}, &models.VoteRollup{})
+	return db
+}
+
+func setupTestPoll(db *gorm.DB) (models.Poll, []models.Option) {
+	poll := models.Poll{Title: "测试投票", IsActive: true}
+	db.Create(&poll)
+
+	options := []models.Option{
+		{PollID: poll.ID, Text: "Go"},
+		{PollID: poll.ID, Text: "Python"},
+	}
+	for i := range options {
+		db.Create(&options[i])
+	}
+	poll.Options = options
+	return poll, options
+}
+
+func castVote(t *testing.T, db *gorm.DB, pollID, optionID uint, at time.Time) models.Vote {
+	vote := models.Vote{PollID: pollID, OptionID: optionID, CreatedAt: at}
+	if err := db.Create(&vote).Error; err != nil {
+		t.Fatalf("创建投票失败: %v", err)
+	}
+	if err := IncrementRollup(db, vote); err != nil {
+		t.Fatalf("更新汇总失败: %v", err)
+	}
+	return vote
+}
+
+func TestIncrementAndDecrementRollup(t *testing.T) {
+	db := setupTestDB()
+	poll, options := setupTestPoll(db)
+
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	castVote(t, db, poll.ID, options[0].ID, base.Add(5*time.Second))
+	vote := castVote(t, db, poll.ID, options[0].ID, base.Add(30*time.Second))
+
+	var rollups []models.VoteRollup
+	db.Find(&rollups)
+	if len(rollups) != 1 || rollups[0].Votes != 2 {
+		t.Fatalf("同一分钟内的投票应汇总到一个桶: %+v", rollups)
+	}
+
+	if err := DecrementRollup(db, vote); err != nil {
+		t.Fatalf("减少汇总失败: %v", err)
+	}
+	db.First(&rollups[0])
+	if rollups[0].Votes != 1 {
+		t.Errorf("期望汇总票数 1, 得到 %d", rollups[0].Votes)
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	db := setupTestDB()
+	poll, options := setupTestPoll(db)
+
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	castVote(t, db, poll.ID, options[0].ID, base.Add(-2*time.Hour)) // 范围之前
+	castVote(t, db, poll.ID, options[0].ID, base.Add(10*time.Minute))
+	castVote(t, db, poll.ID, options[0].ID, base.Add(20*time.Minute))
+	castVote(t, db, poll.ID, options[1].ID, base.Add(2*time.Hour+5*time.Minute))
+
+	history, err := LoadHistory(db, poll, GranularityHour, base, base.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("查询趋势失败: %v", err)
+	}
+
+	if len(history.Series) != 2 {
+		t.Fatalf("期望2个选项序列, 得到 %d", len(history.Series))
+	}
+
+	goPoints := history.Series[0].Points
+	if len(goPoints) != 3 {
+		t.Fatalf("期望3个时间桶, 得到 %d", len(goPoints))
+	}
+
+	if goPoints[0].Votes != 2 || goPoints[0].Cumulative != 3 {
+		t.Errorf("第一个桶不正确: %+v", goPoints[0])
+	}
+
+	if goPoints[2].Votes != 0 || goPoints[2].Cumulative != 3 {
+		t.Errorf("空桶应保持累计票数: %+v", goPoints[2])
+	}
+
+	pyPoints := history.Series[1].Points
+	if pyPoints[2].Votes != 1 || pyPoints[2].Cumulative != 1 {
+		t.Errorf("Python第三个桶不正确: %+v", pyPoints[2])
+	}
+}
+
+func TestLoadHistoryTooManyBuckets(t *testing.T) {
+	db := setupTestDB()
+	poll, _ := setupTestPoll(db)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := LoadHistory(db, poll, GranularityMinute, from, from.AddDate(0, 1, 0)); err != ErrTooManyBuckets {
+		t.Errorf("期望 ErrTooManyBuckets, 得到 %v", err)
+	}
+}
+
+func TestRebuildAndBackfillRollups(t *testing.T) {
+	db := setupTestDB()
+	poll, options := setupTestPoll(db)
+
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[0].ID, CreatedAt: base})
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[0].ID, CreatedAt: base.Add(10 * time.Second)})
+	db.Create(&models.Vote{PollID: poll.ID, OptionID: options[1].ID, CreatedAt: base.Add(3 * time.Minute)})
+
+	if err := BackfillRollups(db); err != nil {
+		t.Fatalf("补建汇总失败: %v", err)
+	}
+
+	var total int64
+	db.Model(&models.VoteRollup{}).Select("SUM(votes)").Scan(&total)
+	if total != 3 {
+		t.Errorf("期望汇总总票数 3, 得到 %d", total)
+	}
+
+	var count int64
+	db.Model(&models.VoteRollup{}).Count(&count)
+	if count != 2 {
+		t.Errorf("期望2个汇总桶, 得到 %d", count)
+	}
+
+	// 已有汇总的投票问卷不会被重复补建
+	if err := BackfillRollups(db); err != nil {
+		t.Fatalf("补建汇总失败: %v", err)
+	}
+	db.Model(&models.VoteRollup{}).Select("SUM(votes)").Scan(&total)
+	if total != 3 {
+		t.Errorf("重复补建后期望汇总总票数 3, 得到 %d", total)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	db := setupTestDB()
+	poll, options := setupTestPoll(db)
+
+	now := time.Now()
+	castVote(t, db, poll.ID, options[0].ID, now)
+	castVote(t, db, poll.ID, options[0].ID, now)
+	// 模拟计数与投票记录不一致
+	db.Model(&options[0]).Update("vote_count", 5)
+	db.Model(&options[1]).Update("vote_count", 0)
+
+	drift, err := FindDrift(db, poll.ID)
+	if err != nil {
+		t.Fatalf("检查失败: %v", err)
+	}
+	if len(drift) != 1 || drift[0].OptionID != options[0].ID || drift[0].Stored != 5 || drift[0].Actual != 2 {
+		t.Fatalf("不一致的选项不正确: %+v", drift)
+	}
+
+	if _, err := Reconcile(db, poll.ID); err != nil {
+		t.Fatalf("修正失败: %v", err)
+	}
+	var option models.Option
+	db.First(&option, options[0].ID)
+	if option.VoteCount != 2 || option.WeightedVotes != weight.Of(2) {
+		t.Errorf("期望修正后票数 2, 得到 %d (%s)", option.VoteCount, option.WeightedVotes)
+	}
+	if drift, _ := FindDrift(db, poll.ID); len(drift) != 0 {
+		t.Errorf("修正后不应再有不一致, 得到 %+v", drift)
+	}
+
+	// 票数一致但加权票数不一致
+	db.Model(&models.Vote{}).Where("option_id = ?", options[0].ID).Update("weight", 2500000)
+	drift, _ = FindDrift(db, poll.ID)
+	if len(drift) != 1 || drift[0].Stored != drift[0].Actual || drift[0].ActualWeight != 5*weight.One {
+		t.Errorf("应检查出加权票数不一致: %+v", drift)
+	}
+}
+
+func TestBackfillWeights(t *testing.T) {
+	db := setupTestDB()
+	_, options := setupTestPoll(db)
+	db.Model(&options[0]).Update("vote_count", 3)
+	db.Model(&options[1]).Updates(map[string]interface{}{"vote_count": 1, "weighted_votes": 2500000})
+
+	if err := BackfillWeights(db); err != nil {
+		t.Fatalf("补齐失败: %v", err)
+	}
+	var stored []models.Option
+	db.Order("id").Find(&stored)
+	if stored[0].WeightedVotes != weight.Of(3) || stored[1].WeightedVotes != 2500000 {
+		t.Errorf("补齐后的加权票数不正确: %s %s", stored[0].WeightedVotes, stored[1].WeightedVotes)
+	}
+}
@@ -0,0 +1,384 @@
+package survey
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// MinCellCount 交叉表中人数大于0且小于该值的单元格不公开
+const MinCellCount = 5
+
+// 交叉分析的分组依据，格式为 "类型:值"
+const (
+	PivotQuestion = "question"
+)
+
+// CrosstabCategory 交叉表的一行或一列，Total为该组中两题都作答的人数，为空表示已隐藏
+type CrosstabCategory struct {
+	Label string `json:"label"`
+	Total *int   `json:"total"`
+}
+
+// CrosstabCell 交叉表的单元格，人数过少被隐藏时各字段都为空
+type CrosstabCell struct {
+	Count            *int     `json:"count"`
+	RowPercentage    *float64 `json:"row_percentage"`
+	ColumnPercentage *float64 `json:"column_percentage"`
+}
+
+// ChiSquare 独立性卡方检验，按隐藏前的完整数据计算
+type ChiSquare struct {
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	PValue           float64 `json:"p_value"`
+	// SmallExpected 期望频数小于5的单元格数，超过单元格总数的20%时检验结果不可靠
+	SmallExpected int `json:"small_expected"`
+}
+
+// Crosstab 一个问题按分组依据交叉分析的列联表，行为分组，列为问题的选项或分值
+type Crosstab struct {
+	SurveyID   uint               `json:"survey_id"`
+	QuestionID uint               `json:"question_id"`
+	Question   string             `json:"question"`
+	Pivot      string             `json:"pivot"`
+	PivotTitle string             `json:"pivot_title"`
+	Rows       []CrosstabCategory `json:"rows"`
+	Columns    []CrosstabCategory `json:"columns"`
+	Cells      [][]CrosstabCell   `json:"cells"`
+	// Responses 两题都作答的人数，多选题的一份作答可以计入多个单元格
+	Responses    int        `json:"responses"`
+	MinCellCount int        `json:"min_cell_count"`
+	Suppressed   int        `json:"suppressed"`
+	ChiSquare    *ChiSquare `json:"chi_square,omitempty"`
+}
+
+// axis 交叉表的一个维度：各组的名称和每份作答所属的组
+type axis struct {
+	title   string
+	labels  []string
+	members map[uint][]int
+}
+
+// BuildCrosstab 按pivot对问题questionID的回答做交叉分析，pivot为 "question:问题ID"
+//
+// 问题和分组依据都只能是选择题或评分题，无效时返回InvalidParameter；调查问卷不存在时返回gorm.ErrRecordNotFound。
+func BuildCrosstab(db *gorm.DB, surveyID, questionID uint, pivot string) (*Crosstab, error) {
+	survey, err := Load(db, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	question, ok := categorical(survey, questionID)
+	if !ok {
+		return nil, apierror.New(apierror.InvalidParameter, "name", "question")
+	}
+
+	kind, value, _ := strings.Cut(pivot, ":")
+	var rows *axis
+	switch kind {
+	case PivotQuestion:
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || uint(id) == question.ID {
+			return nil, apierror.New(apierror.InvalidParameter, "name", "pivot")
+		}
+		pivotQuestion, ok := categorical(survey, uint(id))
+		if !ok {
+			return nil, apierror.New(apierror.InvalidParameter, "name", "pivot")
+		}
+		if rows, err = questionAxis(db, pivotQuestion); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, apierror.New(apierror.InvalidParameter, "name", "pivot")
+	}
+
+	columns, err := questionAxis(db, question)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([][]int, len(rows.labels))
+	for i := range counts {
+		counts[i] = make([]int, len(columns.labels))
+	}
+	rowTotals := make([]int, len(rows.labels))
+	columnTotals := make([]int, len(columns.labels))
+	responses := 0
+	for responseID, rowIndexes := range rows.members {
+		columnIndexes, ok := columns.members[responseID]
+		if !ok {
+			continue
+		}
+		responses++
+		for _, r := range rowIndexes {
+			rowTotals[r]++
+			for _, c := range columnIndexes {
+				counts[r][c]++
+			}
+		}
+		for _, c := range columnIndexes {
+			columnTotals[c]++
+		}
+	}
+
+	table := &Crosstab{
+		SurveyID:     survey.ID,
+		QuestionID:   question.ID,
+		Question:     question.Title,
+		Pivot:        pivot,
+		PivotTitle:   rows.title,
+		Responses:    responses,
+		MinCellCount: MinCellCount,
+		ChiSquare:    chiSquare(counts),
+	}
+
+	hidden, hiddenRows, hiddenColumns := suppress(counts)
+	for i, label := range rows.labels {
+		table.Rows = append(table.Rows, category(label, rowTotals[i], hiddenRows[i]))
+	}
+	for j, label := range columns.labels {
+		table.Columns = append(table.Columns, category(label, columnTotals[j], hiddenColumns[j]))
+	}
+	for i := range counts {
+		cells := make([]CrosstabCell, len(counts[i]))
+		for j, count := range counts[i] {
+			if hidden[i][j] {
+				table.Suppressed++
+				continue
+			}
+			count := count
+			rowPercentage := percentage(count, rowTotals[i])
+			columnPercentage := percentage(count, columnTotals[j])
+			cells[j] = CrosstabCell{Count: &count, RowPercentage: &rowPercentage, ColumnPercentage: &columnPercentage}
+		}
+		table.Cells = append(table.Cells, cells)
+	}
+	return table, nil
+}
+
+// categorical 查找可以交叉分析的问题（选择题或评分题）
+func categorical(survey models.Survey, questionID uint) (models.SurveyQuestion, bool) {
+	for _, question := range survey.Questions {
+		if question.ID == questionID {
+			return question, question.Type != models.QuestionText
+		}
+	}
+	return models.SurveyQuestion{}, false
+}
+
+// questionAxis 以问题的选项或分值分组，多选题的一份作答属于多个组
+func questionAxis(db *gorm.DB, question models.SurveyQuestion) (*axis, error) {
+	result := &axis{title: question.Title, labels: question.Choices, members: map[uint][]int{}}
+	if question.Type == models.QuestionRating {
+		result.labels = nil
+		for rating := question.MinRating; rating <= question.MaxRating; rating++ {
+			result.labels = append(result.labels, strconv.Itoa(rating))
+		}
+	}
+
+	var answers []models.SurveyAnswer
+	if err := db.Where("question_id = ?", question.ID).Find(&answers).Error; err != nil {
+		return nil, err
+	}
+	for _, answer := range answers {
+		var indexes []int
+		for _, choice := range answer.Choices {
+			if choice >= 0 && choice < len(result.labels) {
+				indexes = append(indexes, choice)
+			}
+		}
+		if answer.Rating != nil {
+			if offset := *answer.Rating - question.MinRating; offset >= 0 && offset < len(result.labels) {
+				indexes = append(indexes, offset)
+			}
+		}
+		if len(indexes) > 0 {
+			result.members[answer.ResponseID] = indexes
+		}
+	}
+	return result, nil
+}
+
+// category 生成交叉表的行或列，hidden为true时不公开人数
+func category(label string, total int, hidden bool) CrosstabCategory {
+	if hidden {
+		return CrosstabCategory{Label: label}
+	}
+	return CrosstabCategory{Label: label, Total: &total}
+}
+
+// suppress 计算需要隐藏的单元格和合计
+//
+// 人数大于0且小于MinCellCount的单元格首先隐藏。为了不能用合计减去其他单元格反推出被隐藏的人数，
+// 只有一个单元格被隐藏的行或列再隐藏其中人数最少的另一个非零单元格，没有其他非零单元格时隐藏该行或列的合计。
+func suppress(counts [][]int) (hidden [][]bool, hiddenRows, hiddenColumns []bool) {
+	rows := len(counts)
+	columns := 0
+	if rows > 0 {
+		columns = len(counts[0])
+	}
+	hidden = make([][]bool, rows)
+	for i := range counts {
+		hidden[i] = make([]bool, columns)
+		for j, count := range counts[i] {
+			hidden[i][j] = count > 0 && count < MinCellCount
+		}
+	}
+	hiddenRows = make([]bool, rows)
+	hiddenColumns = make([]bool, columns)
+
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < rows; i++ {
+			cells := make([]*bool, columns)
+			values := make([]int, columns)
+			for j := 0; j < columns; j++ {
+				cells[j], values[j] = &hidden[i][j], counts[i][j]
+			}
+			if secondary(cells, values, &hiddenRows[i]) {
+				changed = true
+			}
+		}
+		for j := 0; j < columns; j++ {
+			cells := make([]*bool, rows)
+			values := make([]int, rows)
+			for i := 0; i < rows; i++ {
+				cells[i], values[i] = &hidden[i][j], counts[i][j]
+			}
+			if secondary(cells, values, &hiddenColumns[j]) {
+				changed = true
+			}
+		}
+	}
+	return hidden, hiddenRows, hiddenColumns
+}
+
+// secondary 一行或一列中只有一个单元格被隐藏时，再隐藏人数最少的另一个非零单元格，没有时隐藏合计
+func secondary(cells []*bool, values []int, total *bool) bool {
+	count := 0
+	for _, cell := range cells {
+		if *cell {
+			count++
+		}
+	}
+	if count != 1 || *total {
+		return false
+	}
+
+	pick := -1
+	for j, cell := range cells {
+		if !*cell && values[j] > 0 && (pick < 0 || values[j] < values[pick]) {
+			pick = j
+		}
+	}
+	if pick < 0 {
+		*total = true
+	} else {
+		*cells[pick] = true
+	}
+	return true
+}
+
+// chiSquare 计算独立性卡方检验，合计为0的行和列不参与计算，自由度为0时返回nil
+func chiSquare(counts [][]int) *ChiSquare {
+	var rowTotals, columnTotals []float64
+	var rows []int
+	var columns []int
+	total := 0.0
+	for i := range counts {
+		sum := 0
+		for _, count := range counts[i] {
+			sum += count
+		}
+		if sum > 0 {
+			rows = append(rows, i)
+			rowTotals = append(rowTotals, float64(sum))
+			total += float64(sum)
+		}
+	}
+	if len(counts) > 0 {
+		for j := range counts[0] {
+			sum := 0
+			for i := range counts {
+				sum += counts[i][j]
+			}
+			if sum > 0 {
+				columns = append(columns, j)
+				columnTotals = append(columnTotals, float64(sum))
+			}
+		}
+	}
+
+	df := (len(rows) - 1) * (len(columns) - 1)
+	if df <= 0 {
+		return nil
+	}
+	result := &ChiSquare{DegreesOfFreedom: df}
+	statistic := 0.0
+	for a, i := range rows {
+		for b, j := range columns {
+			expected := rowTotals[a] * columnTotals[b] / total
+			if expected < 5 {
+				result.SmallExpected++
+			}
+			diff := float64(counts[i][j]) - expected
+			statistic += diff * diff / expected
+		}
+	}
+	result.Statistic = math.Round(statistic*10000) / 10000
+	result.PValue = math.Round(chiSquareP(statistic, df)*10000) / 10000
+	return result
+}
+
+// chiSquareP 卡方分布的上尾概率，即正则化上不完全伽马函数Q(df/2, x/2)
+func chiSquareP(x float64, df int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	a, x := float64(df)/2, x/2
+	lg, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		// 级数展开计算P(a, x)
+		sum, term := 1/a, 1/a
+		for n := 1; n < 500; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+
+	// 连分式（Lentz算法）计算Q(a, x)
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 500; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
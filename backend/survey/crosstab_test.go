@@ -0,0 +1,143 @@
+package survey
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"vote-system/apierror"
+	"vote-system/models"
+)
+
+func TestChiSquareP(t *testing.T) {
+	cases := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		{3.841, 1, 0.050014},
+		{5.991, 2, 0.050012},
+		{9.488, 4, 0.049994},
+		{3, 10, 0.981424},
+		{0, 3, 1},
+	}
+	for _, tc := range cases {
+		if got := chiSquareP(tc.x, tc.df); math.Abs(got-tc.want) > 1e-5 {
+			t.Errorf("chiSquareP(%v, %d): 期望 %v, 得到 %v", tc.x, tc.df, tc.want, got)
+		}
+	}
+}
+
+func TestChiSquare(t *testing.T) {
+	result := chiSquare([][]int{{10, 20}, {30, 40}})
+	if result == nil || result.DegreesOfFreedom != 1 || result.Statistic != 0.7937 || result.PValue != 0.373 {
+		t.Errorf("卡方检验结果不正确: %+v", result)
+	}
+
+	// 合计为0的行和列不参与计算
+	result = chiSquare([][]int{{10, 0, 20}, {0, 0, 0}, {30, 0, 40}})
+	if result == nil || result.DegreesOfFreedom != 1 || result.Statistic != 0.7937 {
+		t.Errorf("应忽略合计为0的行和列: %+v", result)
+	}
+
+	if result := chiSquare([][]int{{10, 20}, {0, 0}}); result != nil {
+		t.Errorf("自由度为0时不应返回检验结果: %+v", result)
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	// 第2行第1列人数过少被隐藏，同一行和同一列各需要再隐藏一个单元格
+	hidden, rows, columns := suppress([][]int{
+		{20, 30, 40},
+		{3, 10, 12},
+		{18, 6, 0},
+	})
+	want := [][]bool{
+		{false, false, false},
+		{true, true, false},
+		{true, true, false},
+	}
+	for i := range want {
+		for j := range want[i] {
+			if hidden[i][j] != want[i][j] {
+				t.Errorf("单元格(%d, %d): 期望隐藏=%v, 得到 %v", i, j, want[i][j], hidden[i][j])
+			}
+		}
+	}
+	for _, total := range append(rows, columns...) {
+		if total {
+			t.Error("有其他非零单元格时不应隐藏合计")
+		}
+	}
+
+	// 行或列中没有其他非零单元格时只能隐藏合计
+	hidden, rows, columns = suppress([][]int{{2, 0}, {8, 9}})
+	if !hidden[0][0] || !hidden[1][0] || !hidden[1][1] || !rows[0] || rows[1] || columns[0] || !columns[1] {
+		t.Errorf("隐藏结果不正确: %v %v %v", hidden, rows, columns)
+	}
+}
+
+func TestBuildCrosstab(t *testing.T) {
+	db := setupTestDB()
+	sv := createSurvey(t, db)
+	language, editor, rating, text := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID, sv.Questions[3].ID
+
+	// 10人用Go打5分，6人用Rust打4分，2人用Python打5分
+	respond := func(choice, score int, editors []int) {
+		db.Create(&models.SurveyResponse{SurveyID: sv.ID, Answers: []models.SurveyAnswer{
+			{QuestionID: language, Choices: []int{choice}},
+			{QuestionID: editor, Choices: editors},
+			{QuestionID: rating, Rating: intPtr(score)},
+		}})
+	}
+	for i := 0; i < 10; i++ {
+		respond(0, 5, []int{0, 1})
+	}
+	for i := 0; i < 6; i++ {
+		respond(1, 4, []int{1})
+	}
+	for i := 0; i < 2; i++ {
+		respond(2, 5, []int{2})
+	}
+
+	table, err := BuildCrosstab(db, sv.ID, rating, "question:"+strconv.Itoa(int(language)))
+	if err != nil {
+		t.Fatalf("交叉分析失败: %v", err)
+	}
+	if table.Responses != 18 || len(table.Rows) != 3 || len(table.Columns) != 5 || table.PivotTitle != "常用的语言" {
+		t.Fatalf("交叉表结构不正确: %+v", table)
+	}
+
+	rust := table.Cells[1][3]
+	if rust.Count == nil || *rust.Count != 6 || *rust.RowPercentage != 100 || *rust.ColumnPercentage != 100 {
+		t.Errorf("Rust打4分的单元格不正确: %+v", rust)
+	}
+	// Python打5分只有2人被隐藏，Go行没有其他非零单元格，只能隐藏Go打5分和两行的合计
+	if table.Cells[2][4].Count != nil || table.Cells[0][4].Count != nil || table.Suppressed != 2 {
+		t.Errorf("人数过少的单元格应被隐藏: %+v", table.Cells)
+	}
+	if table.Rows[0].Total != nil || table.Rows[2].Total != nil || *table.Rows[1].Total != 6 || *table.Columns[4].Total != 12 {
+		t.Errorf("合计不正确: %+v %+v", table.Rows, table.Columns)
+	}
+	if table.Cells[0][0].Count == nil || *table.Cells[0][0].Count != 0 {
+		t.Errorf("人数为0的单元格不应隐藏: %+v", table.Cells[0][0])
+	}
+	if table.ChiSquare == nil || table.ChiSquare.DegreesOfFreedom != 2 || table.ChiSquare.PValue >= 0.05 {
+		t.Errorf("语言和评分应显著相关: %+v", table.ChiSquare)
+	}
+
+	// 多选题的一份作答计入多个单元格，合计为人数
+	table, err = BuildCrosstab(db, sv.ID, editor, "question:"+strconv.Itoa(int(language)))
+	if err != nil {
+		t.Fatalf("交叉分析多选题失败: %v", err)
+	}
+	if *table.Rows[0].Total != 10 || *table.Cells[0][0].Count != 10 || *table.Cells[0][1].Count != 10 || *table.Columns[1].Total != 16 {
+		t.Errorf("多选题交叉表不正确: %+v %+v", table.Rows, table.Cells)
+	}
+
+	for _, pivot := range []string{"", "question:x", "question:" + strconv.Itoa(int(text)), "question:" + strconv.Itoa(int(rating)), "department:dev"} {
+		_, err := BuildCrosstab(db, sv.ID, rating, pivot)
+		expectCode(t, err, apierror.InvalidParameter)
+	}
+	_, err = BuildCrosstab(db, sv.ID, text, "question:"+strconv.Itoa(int(language)))
+	expectCode(t, err, apierror.InvalidParameter)
+}
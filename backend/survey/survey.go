@@ -0,0 +1,488 @@
+// Package survey 调查问卷的问题校验、跳转规则、回答校验和按问题汇总的统计
+package survey
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"vote-system/apierror"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+)
+
+// 评分题未指定范围时的默认分值
+const (
+	DefaultMinRating = 1
+	DefaultMaxRating = 5
+)
+
+// BuildQuestions 校验创建请求中的问题并按顺序编号，错误为InvalidQuestion，position从1开始
+func BuildQuestions(inputs []models.SurveyQuestionInput) ([]models.SurveyQuestion, error) {
+	questions := make([]models.SurveyQuestion, 0, len(inputs))
+	for i, input := range inputs {
+		invalid := apierror.New(apierror.InvalidQuestion, "position", strconv.Itoa(i+1))
+		question := models.SurveyQuestion{
+			Position: i + 1,
+			Type:     input.Type,
+			Title:    strings.TrimSpace(input.Title),
+			Required: input.Required,
+		}
+		if question.Title == "" {
+			return nil, invalid
+		}
+
+		switch input.Type {
+		case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+			if input.MinRating != 0 || input.MaxRating != 0 {
+				return nil, invalid
+			}
+			if len(input.Choices) < 2 || len(input.Choices) > models.MaxQuestionChoices {
+				return nil, invalid
+			}
+			seen := map[string]bool{}
+			for _, choice := range input.Choices {
+				choice = strings.TrimSpace(choice)
+				if choice == "" || seen[choice] {
+					return nil, invalid
+				}
+				seen[choice] = true
+				question.Choices = append(question.Choices, choice)
+			}
+			if input.Type == models.QuestionSingleChoice && input.MaxChoices != 0 {
+				return nil, invalid
+			}
+			if input.MaxChoices > len(input.Choices) {
+				return nil, invalid
+			}
+			question.MaxChoices = input.MaxChoices
+		case models.QuestionRating:
+			if len(input.Choices) > 0 || input.MaxChoices != 0 {
+				return nil, invalid
+			}
+			question.MinRating, question.MaxRating = input.MinRating, input.MaxRating
+			if question.MinRating == 0 && question.MaxRating == 0 {
+				question.MinRating, question.MaxRating = DefaultMinRating, DefaultMaxRating
+			}
+			if question.MinRating >= question.MaxRating || question.MaxRating > models.MaxRatingScale {
+				return nil, invalid
+			}
+		case models.QuestionText:
+			if len(input.Choices) > 0 || input.MaxChoices != 0 || input.MinRating != 0 || input.MaxRating != 0 {
+				return nil, invalid
+			}
+		default:
+			return nil, invalid
+		}
+		if !validBranches(question, input.Branches, len(inputs)) {
+			return nil, invalid
+		}
+		question.Branches = input.Branches
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+// validBranches 校验跳转规则：条件与问题类型相符，只能跳到后面的问题或结束问卷
+func validBranches(question models.SurveyQuestion, rules []models.BranchRule, total int) bool {
+	if len(rules) > models.MaxBranchRules {
+		return false
+	}
+	for _, rule := range rules {
+		if rule.GoTo != 0 && (rule.GoTo <= question.Position || rule.GoTo > total) {
+			return false
+		}
+		switch question.Type {
+		case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+			if rule.Choice == nil || *rule.Choice < 0 || *rule.Choice >= len(question.Choices) ||
+				rule.MinRating != nil || rule.MaxRating != nil {
+				return false
+			}
+		case models.QuestionRating:
+			if rule.Choice != nil || (rule.MinRating == nil && rule.MaxRating == nil) {
+				return false
+			}
+			low, high := question.MinRating, question.MaxRating
+			if rule.MinRating != nil {
+				low = *rule.MinRating
+			}
+			if rule.MaxRating != nil {
+				high = *rule.MaxRating
+			}
+			if low < question.MinRating || high > question.MaxRating || low > high {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateAnswers 按问卷的问题和跳转规则校验完整的回答，返回按问题顺序排列的回答
+//
+// 空的回答视为未作答；作答路径上的必答题未作答时返回AnswerRequired，回答了被跳过的问题时返回QuestionHidden，
+// 回答不符合问题类型时返回InvalidAnswer。
+func ValidateAnswers(survey models.Survey, inputs []models.SurveyAnswerInput) ([]models.SurveyAnswer, error) {
+	answered, err := parseAnswers(survey, inputs)
+	if err != nil {
+		return nil, err
+	}
+	path := route(survey, answered)
+	if err := checkPath(survey, answered, path, len(path)); err != nil {
+		return nil, err
+	}
+
+	var answers []models.SurveyAnswer
+	for _, i := range path {
+		if answer, ok := answered[survey.Questions[i].ID]; ok {
+			answers = append(answers, answer)
+		}
+	}
+	return answers, nil
+}
+
+// Progress 部分作答的进度
+type Progress struct {
+	// Next 下一个要显示的问题，已到达问卷结尾时为空
+	Next *models.SurveyQuestion `json:"next"`
+	Done bool                   `json:"done"`
+	// Path 到Next为止的作答路径上的问题ID，按显示顺序排列
+	Path []uint `json:"path"`
+}
+
+// Next 按部分作答计算下一个要显示的问题，下一个问题为最后一个已作答问题在路径上的后继
+//
+// 已作答部分按ValidateAnswers的规则校验，之后的问题不要求作答。
+func Next(survey models.Survey, inputs []models.SurveyAnswerInput) (*Progress, error) {
+	answered, err := parseAnswers(survey, inputs)
+	if err != nil {
+		return nil, err
+	}
+	path := route(survey, answered)
+
+	// 最后一个已作答问题之后的第一个问题
+	cut := 0
+	for j, i := range path {
+		if _, ok := answered[survey.Questions[i].ID]; ok {
+			cut = j + 1
+		}
+	}
+	if err := checkPath(survey, answered, path, cut); err != nil {
+		return nil, err
+	}
+
+	progress := &Progress{Path: []uint{}, Done: cut == len(path)}
+	for _, i := range path[:cut] {
+		progress.Path = append(progress.Path, survey.Questions[i].ID)
+	}
+	if !progress.Done {
+		next := survey.Questions[path[cut]]
+		progress.Next = &next
+		progress.Path = append(progress.Path, next.ID)
+	}
+	return progress, nil
+}
+
+// parseAnswers 校验每个非空回答是否符合问题类型，按问题ID返回
+func parseAnswers(survey models.Survey, inputs []models.SurveyAnswerInput) (map[uint]models.SurveyAnswer, error) {
+	questions := map[uint]models.SurveyQuestion{}
+	for _, question := range survey.Questions {
+		questions[question.ID] = question
+	}
+
+	seen := map[uint]bool{}
+	answered := map[uint]models.SurveyAnswer{}
+	for _, input := range inputs {
+		question, ok := questions[input.QuestionID]
+		// 重复的回答和不属于该问卷的问题
+		if !ok || seen[input.QuestionID] {
+			return nil, invalidAnswer(input.QuestionID)
+		}
+		seen[input.QuestionID] = true
+		if empty(input) {
+			continue
+		}
+
+		answer, err := validateAnswer(question, input)
+		if err != nil {
+			return nil, err
+		}
+		answered[question.ID] = answer
+	}
+	return answered, nil
+}
+
+// route 按跳转规则计算作答路径，返回路径上问题在survey.Questions中的下标
+func route(survey models.Survey, answered map[uint]models.SurveyAnswer) []int {
+	positions := map[int]int{}
+	for i, question := range survey.Questions {
+		positions[question.Position] = i
+	}
+
+	var path []int
+	for i := 0; i < len(survey.Questions); {
+		path = append(path, i)
+		question := survey.Questions[i]
+		next := i + 1
+		if answer, ok := answered[question.ID]; ok {
+			if goTo, ok := branch(question, answer); ok {
+				if goTo == 0 {
+					break
+				}
+				// 跳转规则在创建时已保证只向后跳转
+				if target, ok := positions[goTo]; ok && target > i {
+					next = target
+				}
+			}
+		}
+		i = next
+	}
+	return path
+}
+
+// branch 返回第一条满足条件的跳转规则的目标
+func branch(question models.SurveyQuestion, answer models.SurveyAnswer) (int, bool) {
+	for _, rule := range question.Branches {
+		if rule.Choice != nil {
+			for _, choice := range answer.Choices {
+				if choice == *rule.Choice {
+					return rule.GoTo, true
+				}
+			}
+			continue
+		}
+		if answer.Rating == nil {
+			continue
+		}
+		if (rule.MinRating == nil || *answer.Rating >= *rule.MinRating) &&
+			(rule.MaxRating == nil || *answer.Rating <= *rule.MaxRating) {
+			return rule.GoTo, true
+		}
+	}
+	return 0, false
+}
+
+// checkPath 检查路径前limit个问题中的必答题都已作答，并且没有回答路径之外的问题
+func checkPath(survey models.Survey, answered map[uint]models.SurveyAnswer, path []int, limit int) error {
+	visible := map[uint]bool{}
+	for j, i := range path {
+		question := survey.Questions[i]
+		visible[question.ID] = true
+		if _, ok := answered[question.ID]; !ok && question.Required && j < limit {
+			return apierror.New(apierror.AnswerRequired, "question_id", strconv.FormatUint(uint64(question.ID), 10))
+		}
+	}
+	// 按问题顺序报告第一个被跳过却作答的问题
+	for _, question := range survey.Questions {
+		if _, ok := answered[question.ID]; ok && !visible[question.ID] {
+			return apierror.New(apierror.QuestionHidden, "question_id", strconv.FormatUint(uint64(question.ID), 10))
+		}
+	}
+	return nil
+}
+
+// empty 判断回答是否为空
+func empty(input models.SurveyAnswerInput) bool {
+	return len(input.Choices) == 0 && input.Rating == nil && strings.TrimSpace(input.Text) == ""
+}
+
+func invalidAnswer(questionID uint) error {
+	return apierror.New(apierror.InvalidAnswer, "question_id", strconv.FormatUint(uint64(questionID), 10))
+}
+
+// validateAnswer 校验单个问题的回答，选择题的选项下标去重后升序保存
+func validateAnswer(question models.SurveyQuestion, input models.SurveyAnswerInput) (models.SurveyAnswer, error) {
+	answer := models.SurveyAnswer{QuestionID: question.ID}
+	invalid := invalidAnswer(question.ID)
+
+	switch question.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		if input.Rating != nil || input.Text != "" {
+			return answer, invalid
+		}
+		seen := map[int]bool{}
+		for _, choice := range input.Choices {
+			if choice < 0 || choice >= len(question.Choices) || seen[choice] {
+				return answer, invalid
+			}
+			seen[choice] = true
+			answer.Choices = append(answer.Choices, choice)
+		}
+		if question.Type == models.QuestionSingleChoice && len(answer.Choices) != 1 {
+			return answer, invalid
+		}
+		if question.MaxChoices > 0 && len(answer.Choices) > question.MaxChoices {
+			return answer, invalid
+		}
+		sort.Ints(answer.Choices)
+	case models.QuestionRating:
+		if len(input.Choices) > 0 || input.Text != "" || input.Rating == nil {
+			return answer, invalid
+		}
+		if *input.Rating < question.MinRating || *input.Rating > question.MaxRating {
+			return answer, invalid
+		}
+		rating := *input.Rating
+		answer.Rating = &rating
+	case models.QuestionText:
+		if len(input.Choices) > 0 || input.Rating != nil {
+			return answer, invalid
+		}
+		answer.Text = strings.TrimSpace(input.Text)
+		if len(answer.Text) > models.MaxTextAnswer {
+			return answer, invalid
+		}
+	default:
+		return answer, invalid
+	}
+	return answer, nil
+}
+
+// ChoiceResult 选择题单个选项的统计，百分比相对于该题的作答人数
+type ChoiceResult struct {
+	Index      int     `json:"index"`
+	Text       string  `json:"text"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// RatingCount 评分题单个分值的人数
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+// QuestionResult 单个问题的统计，自由回答只统计作答人数
+type QuestionResult struct {
+	QuestionID uint   `json:"question_id"`
+	Position   int    `json:"position"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Answered   int    `json:"answered"`
+	Skipped    int    `json:"skipped"`
+	// Choices 选择题各选项的统计
+	Choices []ChoiceResult `json:"choices,omitempty"`
+	// Average、Distribution 评分题的平均分（保留两位小数）和各分值的人数
+	Average      *float64      `json:"average,omitempty"`
+	Distribution []RatingCount `json:"distribution,omitempty"`
+}
+
+// Results 调查问卷按问题汇总的统计结果
+type Results struct {
+	SurveyID  uint             `json:"survey_id"`
+	Title     string           `json:"title"`
+	IsActive  bool             `json:"is_active"`
+	Responses int              `json:"responses"`
+	Questions []QuestionResult `json:"questions"`
+}
+
+// Load 读取调查问卷，问题按顺序排列；不存在时返回gorm.ErrRecordNotFound
+func Load(db *gorm.DB, surveyID uint) (models.Survey, error) {
+	var survey models.Survey
+	err := db.Preload("Questions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).First(&survey, surveyID).Error
+	return survey, err
+}
+
+// Aggregate 统计调查问卷每个问题的回答
+func Aggregate(db *gorm.DB, surveyID uint) (*Results, error) {
+	survey, err := Load(db, surveyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses int64
+	if err := db.Model(&models.SurveyResponse{}).Where("survey_id = ?", surveyID).Count(&responses).Error; err != nil {
+		return nil, err
+	}
+
+	results := &Results{
+		SurveyID:  survey.ID,
+		Title:     survey.Title,
+		IsActive:  survey.IsActive,
+		Responses: int(responses),
+		Questions: []QuestionResult{},
+	}
+	index := map[uint]int{}
+	ratingSums := make([]int, len(survey.Questions))
+	for i, question := range survey.Questions {
+		index[question.ID] = i
+		result := QuestionResult{
+			QuestionID: question.ID,
+			Position:   question.Position,
+			Type:       question.Type,
+			Title:      question.Title,
+		}
+		for j, choice := range question.Choices {
+			result.Choices = append(result.Choices, ChoiceResult{Index: j, Text: choice})
+		}
+		if question.Type == models.QuestionRating {
+			for rating := question.MinRating; rating <= question.MaxRating; rating++ {
+				result.Distribution = append(result.Distribution, RatingCount{Rating: rating})
+			}
+		}
+		results.Questions = append(results.Questions, result)
+	}
+
+	// 逐条读取回答，选择题的选项以JSON保存无法在SQL中分组
+	rows, err := db.Model(&models.SurveyAnswer{}).
+		Joins("JOIN survey_responses ON survey_responses.id = survey_answers.response_id").
+		Where("survey_responses.survey_id = ?", surveyID).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var answer models.SurveyAnswer
+		if err := db.ScanRows(rows, &answer); err != nil {
+			return nil, err
+		}
+		i, ok := index[answer.QuestionID]
+		if !ok {
+			continue
+		}
+		result := &results.Questions[i]
+		result.Answered++
+		for _, choice := range answer.Choices {
+			if choice >= 0 && choice < len(result.Choices) {
+				result.Choices[choice].Count++
+			}
+		}
+		if answer.Rating != nil {
+			question := survey.Questions[i]
+			if offset := *answer.Rating - question.MinRating; offset >= 0 && offset < len(result.Distribution) {
+				result.Distribution[offset].Count++
+			}
+			ratingSums[i] += *answer.Rating
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range results.Questions {
+		result := &results.Questions[i]
+		result.Skipped = results.Responses - result.Answered
+		for j := range result.Choices {
+			result.Choices[j].Percentage = percentage(result.Choices[j].Count, result.Answered)
+		}
+		if result.Type == models.QuestionRating && result.Answered > 0 {
+			average := math.Round(float64(ratingSums[i])*100/float64(result.Answered)) / 100
+			result.Average = &average
+		}
+	}
+	return results, nil
+}
+
+// percentage 计算百分比并保留两位小数
+func percentage(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*10000/float64(total)) / 100
+}
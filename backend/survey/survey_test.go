@@ -0,0 +1,344 @@
+package survey
+
+import (
+	"testing"
+	"vote-system/apierror"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Survey{}, &models.SurveyQuestion{}, &models.SurveyResponse{}, &models.SurveyAnswer{})
+	return db
+}
+
+func expectCode(t *testing.T, err error, code apierror.Code) {
+	t.Helper()
+	if e, ok := apierror.As(err); !ok || e.Code != code {
+		t.Errorf("期望 %s, 得到 %v", code, err)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// sampleInputs 四种类型的问题各一个，前两个必答
+func sampleInputs() []models.SurveyQuestionInput {
+	return []models.SurveyQuestionInput{
+		{Type: models.QuestionSingleChoice, Title: "常用的语言", Required: true, Choices: []string{"Go", "Rust", "Python"}},
+		{Type: models.QuestionMultipleChoice, Title: "使用的编辑器", Required: true, Choices: []string{"Vim", "VS Code", "GoLand"}, MaxChoices: 2},
+		{Type: models.QuestionRating, Title: "满意度"},
+		{Type: models.QuestionText, Title: "其他建议"},
+	}
+}
+
+// createSurvey 创建包含sampleInputs问题的调查问卷
+func createSurvey(t *testing.T, db *gorm.DB) models.Survey {
+	t.Helper()
+	questions, err := BuildQuestions(sampleInputs())
+	if err != nil {
+		t.Fatalf("创建问题失败: %v", err)
+	}
+	sv := models.Survey{Title: "开发者调查", IsActive: true, Questions: questions}
+	db.Create(&sv)
+	sv, err = Load(db, sv.ID)
+	if err != nil {
+		t.Fatalf("读取调查问卷失败: %v", err)
+	}
+	return sv
+}
+
+func TestBuildQuestions(t *testing.T) {
+	questions, err := BuildQuestions(sampleInputs())
+	if err != nil {
+		t.Fatalf("创建问题失败: %v", err)
+	}
+	if len(questions) != 4 || questions[0].Position != 1 || questions[3].Position != 4 {
+		t.Errorf("问题顺序不正确: %+v", questions)
+	}
+	if questions[2].MinRating != DefaultMinRating || questions[2].MaxRating != DefaultMaxRating {
+		t.Errorf("评分题期望默认范围1到5, 得到 %d到%d", questions[2].MinRating, questions[2].MaxRating)
+	}
+
+	invalid := []models.SurveyQuestionInput{
+		{Type: models.QuestionSingleChoice, Title: "只有一个选项", Choices: []string{"是"}},
+		{Type: models.QuestionSingleChoice, Title: "重复选项", Choices: []string{"是", " 是 "}},
+		{Type: models.QuestionSingleChoice, Title: "单选限制项数", Choices: []string{"是", "否"}, MaxChoices: 1},
+		{Type: models.QuestionMultipleChoice, Title: "项数超过选项", Choices: []string{"是", "否"}, MaxChoices: 3},
+		{Type: models.QuestionRating, Title: "范围颠倒", MinRating: 5, MaxRating: 1},
+		{Type: models.QuestionRating, Title: "范围过大", MinRating: 1, MaxRating: 100},
+		{Type: models.QuestionText, Title: "自由回答带选项", Choices: []string{"是", "否"}},
+		{Type: models.QuestionText, Title: "   "},
+	}
+	for _, input := range invalid {
+		inputs := append(sampleInputs(), input)
+		_, err := BuildQuestions(inputs)
+		expectCode(t, err, apierror.InvalidQuestion)
+		if e, ok := apierror.As(err); ok && (len(e.Params) != 2 || e.Params[1] != "5") {
+			t.Errorf("%s: 期望指出第5个问题, 得到 %v", input.Title, e.Params)
+		}
+	}
+}
+
+func TestValidateAnswers(t *testing.T) {
+	db := setupTestDB()
+	sv := createSurvey(t, db)
+	single, multiple, rating, text := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID, sv.Questions[3].ID
+
+	answers, err := ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: text, Text: "  继续保持  "},
+		{QuestionID: multiple, Choices: []int{2, 0}},
+		{QuestionID: single, Choices: []int{1}},
+		{QuestionID: rating},
+	})
+	if err != nil {
+		t.Fatalf("校验回答失败: %v", err)
+	}
+	// 空的评分视为未作答，回答按问题顺序排列
+	if len(answers) != 3 || answers[0].QuestionID != single || answers[2].QuestionID != text {
+		t.Fatalf("回答顺序不正确: %+v", answers)
+	}
+	if answers[1].Choices[0] != 0 || answers[1].Choices[1] != 2 || answers[2].Text != "继续保持" {
+		t.Errorf("回答未规范化: %+v", answers)
+	}
+
+	base := []models.SurveyAnswerInput{{QuestionID: single, Choices: []int{0}}, {QuestionID: multiple, Choices: []int{0}}}
+	cases := []struct {
+		name   string
+		answer models.SurveyAnswerInput
+		code   apierror.Code
+	}{
+		{"单选多项", models.SurveyAnswerInput{QuestionID: single, Choices: []int{0, 1}}, apierror.InvalidAnswer},
+		{"选项越界", models.SurveyAnswerInput{QuestionID: multiple, Choices: []int{3}}, apierror.InvalidAnswer},
+		{"超过可选项数", models.SurveyAnswerInput{QuestionID: multiple, Choices: []int{0, 1, 2}}, apierror.InvalidAnswer},
+		{"重复选项", models.SurveyAnswerInput{QuestionID: multiple, Choices: []int{1, 1}}, apierror.InvalidAnswer},
+		{"评分越界", models.SurveyAnswerInput{QuestionID: rating, Rating: intPtr(6)}, apierror.InvalidAnswer},
+		{"评分题填文本", models.SurveyAnswerInput{QuestionID: rating, Text: "五星"}, apierror.InvalidAnswer},
+		{"文本题选选项", models.SurveyAnswerInput{QuestionID: text, Choices: []int{0}}, apierror.InvalidAnswer},
+		{"其他问卷的问题", models.SurveyAnswerInput{QuestionID: text + 100, Text: "?"}, apierror.InvalidAnswer},
+	}
+	for _, tc := range cases {
+		// 用该用例的回答替换基础回答中同一问题的回答
+		var inputs []models.SurveyAnswerInput
+		for _, input := range base {
+			if input.QuestionID != tc.answer.QuestionID {
+				inputs = append(inputs, input)
+			}
+		}
+		_, err := ValidateAnswers(sv, append(inputs, tc.answer))
+		if e, ok := apierror.As(err); !ok || e.Code != tc.code {
+			t.Errorf("%s: 期望 %s, 得到 %v", tc.name, tc.code, err)
+		}
+	}
+
+	_, err = ValidateAnswers(sv, append(base, models.SurveyAnswerInput{QuestionID: single, Choices: []int{1}}))
+	expectCode(t, err, apierror.InvalidAnswer)
+
+	_, err = ValidateAnswers(sv, []models.SurveyAnswerInput{{QuestionID: single, Choices: []int{0}}, {QuestionID: multiple}})
+	expectCode(t, err, apierror.AnswerRequired)
+}
+
+func TestAggregate(t *testing.T) {
+	db := setupTestDB()
+	sv := createSurvey(t, db)
+	single, multiple, rating, text := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID, sv.Questions[3].ID
+
+	responses := [][]models.SurveyAnswer{
+		{{QuestionID: single, Choices: []int{0}}, {QuestionID: multiple, Choices: []int{0, 1}}, {QuestionID: rating, Rating: intPtr(5)}, {QuestionID: text, Text: "很好"}},
+		{{QuestionID: single, Choices: []int{0}}, {QuestionID: multiple, Choices: []int{1}}, {QuestionID: rating, Rating: intPtr(4)}},
+		{{QuestionID: single, Choices: []int{2}}, {QuestionID: multiple, Choices: []int{1, 2}}},
+	}
+	for _, answers := range responses {
+		db.Create(&models.SurveyResponse{SurveyID: sv.ID, Answers: answers})
+	}
+
+	results, err := Aggregate(db, sv.ID)
+	if err != nil {
+		t.Fatalf("汇总失败: %v", err)
+	}
+	if results.Responses != 3 || len(results.Questions) != 4 {
+		t.Fatalf("期望3份作答4个问题, 得到 %+v", results)
+	}
+
+	q := results.Questions[0]
+	if q.Answered != 3 || q.Choices[0].Count != 2 || q.Choices[0].Percentage != 66.67 || q.Choices[1].Count != 0 {
+		t.Errorf("单选题统计不正确: %+v", q)
+	}
+	q = results.Questions[1]
+	if q.Choices[1].Count != 3 || q.Choices[1].Percentage != 100 || q.Choices[2].Count != 1 {
+		t.Errorf("多选题统计不正确: %+v", q)
+	}
+	q = results.Questions[2]
+	if q.Answered != 2 || q.Skipped != 1 || q.Average == nil || *q.Average != 4.5 {
+		t.Errorf("评分题统计不正确: %+v", q)
+	}
+	if len(q.Distribution) != 5 || q.Distribution[3].Count != 1 || q.Distribution[4].Count != 1 {
+		t.Errorf("评分分布不正确: %+v", q.Distribution)
+	}
+	q = results.Questions[3]
+	if q.Answered != 1 || q.Skipped != 2 || len(q.Choices) != 0 {
+		t.Errorf("自由回答只统计作答人数: %+v", q)
+	}
+
+	if _, err := Aggregate(db, sv.ID+1); err != gorm.ErrRecordNotFound {
+		t.Errorf("不存在的调查问卷期望ErrRecordNotFound, 得到 %v", err)
+	}
+}
+
+// onboardingSurvey 入职调查：第1题选“否”时跳到第4题，第2题评分不超过2时结束问卷
+func onboardingSurvey(t *testing.T, db *gorm.DB) models.Survey {
+	t.Helper()
+	no, low := 1, 2
+	questions, err := BuildQuestions([]models.SurveyQuestionInput{
+		{Type: models.QuestionSingleChoice, Title: "用过类似产品吗", Required: true, Choices: []string{"是", "否"},
+			Branches: []models.BranchRule{{Choice: &no, GoTo: 4}}},
+		{Type: models.QuestionRating, Title: "之前的产品打几分", Required: true,
+			Branches: []models.BranchRule{{MaxRating: &low, GoTo: 0}}},
+		{Type: models.QuestionText, Title: "之前的产品哪里好", Required: true},
+		{Type: models.QuestionText, Title: "对我们有什么期待"},
+	})
+	if err != nil {
+		t.Fatalf("创建问题失败: %v", err)
+	}
+	sv := models.Survey{Title: "入职调查", IsActive: true, Questions: questions}
+	db.Create(&sv)
+	sv, _ = Load(db, sv.ID)
+	return sv
+}
+
+func TestBuildQuestionsBranches(t *testing.T) {
+	one, three, six := 1, 3, 6
+	invalid := []models.BranchRule{
+		{Choice: &three, GoTo: 3},  // 选项越界
+		{Choice: &one, GoTo: 1},    // 向前跳转
+		{Choice: &one, GoTo: 5},    // 超出问题数
+		{MinRating: &one, GoTo: 3}, // 选择题使用评分条件
+		{GoTo: 3},                  // 缺少条件
+	}
+	for _, rule := range invalid {
+		inputs := sampleInputs()
+		inputs[0].Branches = []models.BranchRule{rule}
+		_, err := BuildQuestions(inputs)
+		expectCode(t, err, apierror.InvalidQuestion)
+	}
+
+	inputs := sampleInputs()
+	inputs[2].Branches = []models.BranchRule{{MinRating: &six, GoTo: 4}}
+	_, err := BuildQuestions(inputs)
+	expectCode(t, err, apierror.InvalidQuestion)
+
+	inputs[2].Branches = []models.BranchRule{{MinRating: &three, GoTo: 0}}
+	inputs[3].Branches = []models.BranchRule{{Choice: &one, GoTo: 0}}
+	_, err = BuildQuestions(inputs)
+	expectCode(t, err, apierror.InvalidQuestion)
+
+	inputs[3].Branches = nil
+	if _, err := BuildQuestions(inputs); err != nil {
+		t.Errorf("评分题的有效跳转规则不应报错: %v", err)
+	}
+}
+
+func TestValidateAnswersBranches(t *testing.T) {
+	db := setupTestDB()
+	sv := onboardingSurvey(t, db)
+	q1, q2, q3, q4 := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID, sv.Questions[3].ID
+	one, four := 1, 4
+
+	// 选“否”跳过第2、3题，它们虽然必答也不需要作答
+	answers, err := ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{1}}, {QuestionID: q4, Text: "好用"},
+	})
+	if err != nil || len(answers) != 2 {
+		t.Errorf("跳过的必答题不应要求作答: %+v %v", answers, err)
+	}
+
+	_, err = ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{1}}, {QuestionID: q2, Rating: &four},
+	})
+	expectCode(t, err, apierror.QuestionHidden)
+
+	// 选“是”时第2、3题可见且必答
+	_, err = ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &four},
+	})
+	expectCode(t, err, apierror.AnswerRequired)
+
+	// 评分不超过2时结束问卷，之后的问题都被跳过
+	_, err = ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &one}, {QuestionID: q4, Text: "?"},
+	})
+	expectCode(t, err, apierror.QuestionHidden)
+	answers, err = ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &one},
+	})
+	if err != nil || len(answers) != 2 {
+		t.Errorf("提前结束的问卷应通过校验: %+v %v", answers, err)
+	}
+
+	answers, err = ValidateAnswers(sv, []models.SurveyAnswerInput{
+		{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &four}, {QuestionID: q3, Text: "稳定"},
+	})
+	if err != nil || len(answers) != 3 {
+		t.Errorf("完整路径应通过校验: %+v %v", answers, err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	db := setupTestDB()
+	sv := onboardingSurvey(t, db)
+	q1, q2, q3, q4 := sv.Questions[0].ID, sv.Questions[1].ID, sv.Questions[2].ID, sv.Questions[3].ID
+	one, four := 1, 4
+
+	cases := []struct {
+		name    string
+		answers []models.SurveyAnswerInput
+		next    uint
+		path    []uint
+	}{
+		{"尚未作答", nil, q1, []uint{q1}},
+		{"选是", []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{0}}}, q2, []uint{q1, q2}},
+		{"选否跳到第4题", []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{1}}}, q4, []uint{q1, q4}},
+		{"评分较高", []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &four}}, q3, []uint{q1, q2, q3}},
+		{"评分较低结束", []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{0}}, {QuestionID: q2, Rating: &one}}, 0, []uint{q1, q2}},
+		{"已到结尾", []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{1}}, {QuestionID: q4, Text: "好用"}}, 0, []uint{q1, q4}},
+	}
+	for _, tc := range cases {
+		progress, err := Next(sv, tc.answers)
+		if err != nil {
+			t.Errorf("%s: 计算下一题失败: %v", tc.name, err)
+			continue
+		}
+		if tc.next == 0 {
+			if !progress.Done || progress.Next != nil {
+				t.Errorf("%s: 期望已到结尾, 得到 %+v", tc.name, progress)
+			}
+		} else if progress.Done || progress.Next == nil || progress.Next.ID != tc.next {
+			t.Errorf("%s: 期望下一题 %d, 得到 %+v", tc.name, tc.next, progress)
+		}
+		if len(progress.Path) != len(tc.path) {
+			t.Errorf("%s: 期望路径 %v, 得到 %v", tc.name, tc.path, progress.Path)
+			continue
+		}
+		for i := range tc.path {
+			if progress.Path[i] != tc.path[i] {
+				t.Errorf("%s: 期望路径 %v, 得到 %v", tc.name, tc.path, progress.Path)
+				break
+			}
+		}
+	}
+
+	// 已作答部分之前的必答题未作答、回答了被跳过的问题都应报错
+	_, err := Next(sv, []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{0}}, {QuestionID: q3, Text: "稳定"}})
+	expectCode(t, err, apierror.AnswerRequired)
+	_, err = Next(sv, []models.SurveyAnswerInput{{QuestionID: q1, Choices: []int{1}}, {QuestionID: q3, Text: "稳定"}})
+	expectCode(t, err, apierror.QuestionHidden)
+}
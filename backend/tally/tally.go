@@ -0,0 +1,323 @@
+// Package tally 单选以外的投票方式：赞成投票、评分投票和波达计数的选票校验、计分和结果统计
+//
+// 这些投票方式的一张选票包含多个选项，投票记录的OptionID为0，选票上的每个选项保存为一条VoteMark。
+// 选项的VoteCount为标记该选项的选票数，WeightedVotes为这些选票的权重之和，
+// Points为各选票得分乘以权重之和：赞成投票每个选项1分，评分投票为分数，
+// 波达计数中排在第k名的选项得到“选项数-k”分，未排名的选项不得分。
+package tally
+
+import (
+	"math"
+	"math/big"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/gorm"
+)
+
+// Ballot 投票人提交的选票，按投票方式只使用其中一个字段
+type Ballot struct {
+	// OptionID 单选投票的选项
+	OptionID uint
+	// OptionIDs 赞成的选项，或波达计数中按名次从高到低排列的选项
+	OptionIDs []uint
+	// Scores 评分投票中各选项的分数
+	Scores []models.ScoreInput
+}
+
+// Empty 判断选票是否没有选择任何选项
+func (b Ballot) Empty() bool {
+	return b.OptionID == 0 && len(b.OptionIDs) == 0 && len(b.Scores) == 0
+}
+
+// Marks 按投票问卷的投票方式校验选票，返回选票上各选项的标记；单选投票返回nil
+//
+// 选票使用了不属于该投票方式的字段、选项重复或分数超出范围时返回InvalidVote，选项不存在时返回InvalidOption。
+// 返回的标记还没有填写VoteID、PollID、Weight和CreatedAt。
+func Marks(poll models.Poll, b Ballot) ([]models.VoteMark, error) {
+	mode := poll.Mode
+	if poll.Plurality() {
+		mode = models.ModePlurality
+	}
+	invalid := func(field string) error {
+		return apierror.New(apierror.InvalidVote, "field", field, "mode", mode)
+	}
+
+	switch {
+	case mode == models.ModePlurality:
+		if len(b.OptionIDs) > 0 {
+			return nil, invalid("option_ids")
+		}
+		if len(b.Scores) > 0 {
+			return nil, invalid("scores")
+		}
+		return nil, nil
+	case b.OptionID != 0:
+		return nil, invalid("option_id")
+	case mode == models.ModeScore && len(b.OptionIDs) > 0:
+		return nil, invalid("option_ids")
+	case mode != models.ModeScore && len(b.Scores) > 0:
+		return nil, invalid("scores")
+	case len(b.OptionIDs) == 0 && len(b.Scores) == 0:
+		if mode == models.ModeScore {
+			return nil, invalid("scores")
+		}
+		return nil, invalid("option_ids")
+	}
+
+	valid := map[uint]bool{}
+	for _, option := range poll.Options {
+		valid[option.ID] = !option.WriteIn
+	}
+	seen := map[uint]bool{}
+	check := func(optionID uint, field string) error {
+		if !valid[optionID] {
+			return apierror.New(apierror.InvalidOption)
+		}
+		if seen[optionID] {
+			return invalid(field)
+		}
+		seen[optionID] = true
+		return nil
+	}
+
+	var marks []models.VoteMark
+	switch mode {
+	case models.ModeApproval:
+		for _, id := range b.OptionIDs {
+			if err := check(id, "option_ids"); err != nil {
+				return nil, err
+			}
+			marks = append(marks, models.VoteMark{OptionID: id, Value: 1, Points: 1})
+		}
+	case models.ModeBorda:
+		for i, id := range b.OptionIDs {
+			if err := check(id, "option_ids"); err != nil {
+				return nil, err
+			}
+			marks = append(marks, models.VoteMark{OptionID: id, Value: i + 1, Points: len(poll.Options) - i - 1})
+		}
+	case models.ModeScore:
+		for _, score := range b.Scores {
+			if err := check(score.OptionID, "scores"); err != nil {
+				return nil, err
+			}
+			if score.Score < 0 || score.Score > poll.MaxScore {
+				return nil, invalid("scores")
+			}
+			marks = append(marks, models.VoteMark{OptionID: score.OptionID, Value: score.Score, Points: score.Score})
+		}
+	}
+	return marks, nil
+}
+
+// Record 在事务中保存投票记录的选票标记并增加各选项的票数、加权票数和得分
+func Record(tx *gorm.DB, vote models.Vote, marks []models.VoteMark) error {
+	if len(marks) == 0 {
+		return nil
+	}
+	for i := range marks {
+		marks[i].ID = 0
+		marks[i].VoteID = vote.ID
+		marks[i].PollID = vote.PollID
+		marks[i].Weight = vote.Weight
+		marks[i].CreatedAt = vote.CreatedAt
+	}
+	if err := tx.Create(&marks).Error; err != nil {
+		return err
+	}
+	return Adjust(tx, marks, 1)
+}
+
+// Remove 在事务中删除投票记录的选票标记并减少各选项的票数、加权票数和得分，返回删除的标记
+func Remove(tx *gorm.DB, voteIDs []uint) ([]models.VoteMark, error) {
+	if len(voteIDs) == 0 {
+		return nil, nil
+	}
+	var marks []models.VoteMark
+	if err := tx.Where("vote_id IN ?", voteIDs).Order("id").Find(&marks).Error; err != nil {
+		return nil, err
+	}
+	if len(marks) == 0 {
+		return nil, nil
+	}
+	if err := tx.Where("vote_id IN ?", voteIDs).Delete(&models.VoteMark{}).Error; err != nil {
+		return nil, err
+	}
+	return marks, Adjust(tx, marks, -1)
+}
+
+// Adjust 按选票标记增加（sign为1）或减少（sign为-1）各选项的票数、加权票数和得分，溢出时返回TallyOverflow
+func Adjust(tx *gorm.DB, marks []models.VoteMark, sign int) error {
+	type total struct {
+		votes          int
+		weight, points weight.Weight
+	}
+	totals := map[uint]*total{}
+	var order []uint
+	for _, mark := range marks {
+		t, ok := totals[mark.OptionID]
+		if !ok {
+			t = &total{}
+			totals[mark.OptionID] = t
+			order = append(order, mark.OptionID)
+		}
+		points, err := Points(mark)
+		if err != nil {
+			return err
+		}
+		t.votes++
+		if t.weight, err = t.weight.Add(mark.Weight); err != nil {
+			return apierror.New(apierror.TallyOverflow)
+		}
+		if t.points, err = t.points.Add(points); err != nil {
+			return apierror.New(apierror.TallyOverflow)
+		}
+	}
+
+	for _, optionID := range order {
+		t := totals[optionID]
+		if err := Increment(tx, optionID, sign*t.votes, weight.Weight(sign)*t.weight, weight.Weight(sign)*t.points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Increment 在事务中按增量更新选项的票数、加权票数和得分
+//
+// 增加后加权票数或得分超出int64的范围时返回TallyOverflow，调用方回滚事务后选项保持不变。
+// 数据库中的整数溢出不一定报错（SQLite转为浮点数），因此在更新条件中先确认范围。
+func Increment(tx *gorm.DB, optionID uint, votes int, weighted, points weight.Weight) error {
+	query := tx.Model(&models.Option{}).Where("id = ?", optionID)
+	if weighted > 0 {
+		query = query.Where("weighted_votes <= ?", math.MaxInt64-int64(weighted))
+	}
+	if points > 0 {
+		query = query.Where("points <= ?", math.MaxInt64-int64(points))
+	}
+	updates := map[string]interface{}{
+		"vote_count":     gorm.Expr("vote_count + ?", votes),
+		"weighted_votes": gorm.Expr("weighted_votes + ?", weighted),
+	}
+	if points != 0 {
+		updates["points"] = gorm.Expr("points + ?", points)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if (weighted > 0 || points > 0) && result.RowsAffected == 0 {
+		return apierror.New(apierror.TallyOverflow)
+	}
+	return nil
+}
+
+// Points 返回标记按选票权重计算的得分，超出int64的范围时返回TallyOverflow
+func Points(mark models.VoteMark) (weight.Weight, error) {
+	points, err := mark.Weight.Mul(mark.Points)
+	if err != nil {
+		return 0, apierror.New(apierror.TallyOverflow)
+	}
+	return points, nil
+}
+
+// Fill 为评分投票和波达计数的选项计算平均得分和分布，其他投票方式不做处理
+//
+// 评分投票的分布按分数统计选票数，波达计数按名次统计。
+func Fill(db *gorm.DB, poll *models.Poll) error {
+	if poll.Mode != models.ModeScore && poll.Mode != models.ModeBorda {
+		return nil
+	}
+
+	var rows []struct {
+		OptionID uint
+		Value    int
+		Count    int
+	}
+	if err := db.Model(&models.VoteMark{}).Select("option_id, value, COUNT(*) AS count").
+		Where("poll_id = ?", poll.ID).Group("option_id, value").Scan(&rows).Error; err != nil {
+		return err
+	}
+	distribution := map[uint]map[int]int{}
+	for _, row := range rows {
+		if distribution[row.OptionID] == nil {
+			distribution[row.OptionID] = map[int]int{}
+		}
+		distribution[row.OptionID][row.Value] = row.Count
+	}
+
+	for i := range poll.Options {
+		option := &poll.Options[i]
+		option.Distribution = distribution[option.ID]
+		if option.WeightedVotes > 0 {
+			average := float64(option.Points) / float64(option.WeightedVotes)
+			option.Average = &average
+		}
+	}
+	return nil
+}
+
+// Ballots 统计投票问卷的选票数和选票权重之和
+func Ballots(db *gorm.DB, pollID uint) (int, weight.Weight, error) {
+	var row struct {
+		Count  int
+		Weight weight.Weight
+	}
+	err := db.Model(&models.Vote{}).Select("COUNT(*) AS count, COALESCE(SUM(weight), 0) AS weight").
+		Where("poll_id = ?", pollID).Scan(&row).Error
+	return row.Count, row.Weight, err
+}
+
+// Leader 按投票方式返回领先的选项，并列或无人投票时返回nil
+//
+// 单选和赞成投票按加权票数排名，波达计数按得分，评分投票按平均分（没有人评分的选项不参与排名）。
+func Leader(poll models.Poll) *models.Option {
+	var leader *models.Option
+	tied := false
+	for i := range poll.Options {
+		option := &poll.Options[i]
+		if option.WeightedVotes == 0 {
+			continue
+		}
+		if leader == nil {
+			leader = option
+			continue
+		}
+		switch c := compare(poll.Mode, *option, *leader); {
+		case c > 0:
+			leader, tied = option, false
+		case c == 0:
+			tied = true
+		}
+	}
+	if tied || (leader != nil && poll.Mode == models.ModeBorda && leader.Points == 0) {
+		return nil
+	}
+	return leader
+}
+
+// compare 按投票方式比较两个选项，a领先时返回正数
+func compare(mode string, a, b models.Option) int {
+	switch mode {
+	case models.ModeBorda:
+		return cmp(int64(a.Points), int64(b.Points))
+	case models.ModeScore:
+		// a.Points/a.WeightedVotes 与 b.Points/b.WeightedVotes 交叉相乘比较，避免浮点误差和溢出
+		left := new(big.Int).Mul(big.NewInt(int64(a.Points)), big.NewInt(int64(b.WeightedVotes)))
+		right := new(big.Int).Mul(big.NewInt(int64(b.Points)), big.NewInt(int64(a.WeightedVotes)))
+		return left.Cmp(right)
+	}
+	return cmp(int64(a.WeightedVotes), int64(b.WeightedVotes))
+}
+
+func cmp(a, b int64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package tally
+
+import (
+	"math"
+	"testing"
+	"vote-system/apierror"
+	"vote-system/models"
+	"vote-system/weight"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func modePoll(mode string) models.Poll {
+	poll := models.Poll{Mode: mode, Options: []models.Option{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4, WriteIn: true}}}
+	if mode == models.ModeScore {
+		poll.MaxScore = 10
+	}
+	return poll
+}
+
+func expectCode(t *testing.T, name string, err error, code apierror.Code) {
+	t.Helper()
+	e, ok := apierror.As(err)
+	if !ok || e.Code != code {
+		t.Errorf("%s: 期望 %s, 得到 %v", name, code, err)
+	}
+}
+
+func TestMarks(t *testing.T) {
+	// 单选投票不使用选票标记，升级前没有投票方式的投票问卷视为单选
+	marks, err := Marks(models.Poll{}, Ballot{OptionID: 1})
+	if marks != nil || err != nil {
+		t.Errorf("单选投票期望没有标记, 得到 %v %v", marks, err)
+	}
+	_, err = Marks(models.Poll{Mode: models.ModePlurality}, Ballot{OptionIDs: []uint{1}})
+	expectCode(t, "plurality option_ids", err, apierror.InvalidVote)
+
+	invalid := []struct {
+		name string
+		mode string
+		b    Ballot
+		code apierror.Code
+	}{
+		{"approval option_id", models.ModeApproval, Ballot{OptionID: 1}, apierror.InvalidVote},
+		{"approval empty", models.ModeApproval, Ballot{}, apierror.InvalidVote},
+		{"approval duplicate", models.ModeApproval, Ballot{OptionIDs: []uint{1, 1}}, apierror.InvalidVote},
+		{"approval write-in", models.ModeApproval, Ballot{OptionIDs: []uint{4}}, apierror.InvalidOption},
+		{"borda unknown", models.ModeBorda, Ballot{OptionIDs: []uint{1, 9}}, apierror.InvalidOption},
+		{"borda scores", models.ModeBorda, Ballot{Scores: []models.ScoreInput{{OptionID: 1}}}, apierror.InvalidVote},
+		{"score option_ids", models.ModeScore, Ballot{OptionIDs: []uint{1}}, apierror.InvalidVote},
+		{"score too high", models.ModeScore, Ballot{Scores: []models.ScoreInput{{OptionID: 1, Score: 11}}}, apierror.InvalidVote},
+	}
+	for _, tc := range invalid {
+		_, err := Marks(modePoll(tc.mode), tc.b)
+		expectCode(t, tc.name, err, tc.code)
+	}
+
+	// 波达计数：第1名得 选项数-1 分
+	marks, err = Marks(modePoll(models.ModeBorda), Ballot{OptionIDs: []uint{3, 1}})
+	if err != nil || len(marks) != 2 || marks[0].Value != 1 || marks[0].Points != 3 || marks[1].Points != 2 {
+		t.Errorf("波达计数的标记不正确: %+v %v", marks, err)
+	}
+	marks, err = Marks(modePoll(models.ModeScore), Ballot{Scores: []models.ScoreInput{{OptionID: 2, Score: 0}, {OptionID: 1, Score: 10}}})
+	if err != nil || len(marks) != 2 || marks[0].Points != 0 || marks[1].Value != 10 {
+		t.Errorf("评分投票的标记不正确: %+v %v", marks, err)
+	}
+}
+
+func TestLeader(t *testing.T) {
+	option := func(id uint, votes int, points int) models.Option {
+		return models.Option{ID: id, VoteCount: votes, WeightedVotes: weight.Of(votes), Points: weight.Of(points)}
+	}
+
+	// 评分投票按平均分：选项1平均 4.5，选项2得分更多但平均 3
+	poll := models.Poll{Mode: models.ModeScore, Options: []models.Option{option(1, 2, 9), option(2, 4, 12), option(3, 0, 0)}}
+	if leader := Leader(poll); leader == nil || leader.ID != 1 {
+		t.Errorf("期望选项1领先, 得到 %+v", leader)
+	}
+
+	// 波达计数按得分
+	poll.Mode = models.ModeBorda
+	if leader := Leader(poll); leader == nil || leader.ID != 2 {
+		t.Errorf("期望选项2领先, 得到 %+v", leader)
+	}
+
+	// 赞成投票按加权票数，并列时没有领先者
+	poll = models.Poll{Mode: models.ModeApproval, Options: []models.Option{option(1, 3, 3), option(2, 3, 3)}}
+	if leader := Leader(poll); leader != nil {
+		t.Errorf("并列时期望没有领先者, 得到 %+v", leader)
+	}
+	if leader := Leader(models.Poll{Options: []models.Option{option(1, 0, 0)}}); leader != nil {
+		t.Errorf("无人投票时期望没有领先者, 得到 %+v", leader)
+	}
+}
+
+func TestRecordOverflow(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	db.AutoMigrate(&models.Poll{}, &models.Option{}, &models.Vote{}, &models.VoteMark{})
+	poll := models.Poll{Title: "评分", IsActive: true, Mode: models.ModeScore, MaxScore: 100, Options: []models.Option{{Text: "甲"}}}
+	db.Create(&poll)
+	option := poll.Options[0]
+
+	// 最大权重的满分选票可以计入
+	max, _ := weight.Parse("999999999.999999")
+	vote := models.Vote{PollID: poll.ID, Weight: max}
+	db.Create(&vote)
+	marks := []models.VoteMark{{OptionID: option.ID, Value: 100, Points: 100}}
+	if err := Record(db, vote, marks); err != nil {
+		t.Fatalf("保存选票标记失败: %v", err)
+	}
+
+	// 得分之和超出int64时拒绝，选项保持不变
+	full := weight.Weight(math.MaxInt64) - max*50
+	db.Model(&option).Update("points", full)
+	other := models.Vote{PollID: poll.ID, Weight: max}
+	db.Create(&other)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return Record(tx, other, []models.VoteMark{{OptionID: option.ID, Value: 100, Points: 100}})
+	})
+	expectCode(t, "points overflow", err, apierror.TallyOverflow)
+	db.First(&option, option.ID)
+	if option.VoteCount != 1 || option.Points != full || option.WeightedVotes != max {
+		t.Errorf("溢出时选项不应改变, 得到 %+v", option)
+	}
+
+	// 单个标记的得分超出int64时同样拒绝
+	_, err = Points(models.VoteMark{Points: 100, Weight: weight.Weight(1 << 60)})
+	expectCode(t, "mark overflow", err, apierror.TallyOverflow)
+
+	// 撤销投票时减少得分不检查范围
+	if _, err := Remove(db, []uint{vote.ID}); err != nil {
+		t.Errorf("撤销投票失败: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"vote-system/models"
+)
+
+// variablePattern 匹配 {{name}} 形式的模板变量，变量名两侧允许空格
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// UndefinedVariableError 模板中使用了未定义的变量
+type UndefinedVariableError struct {
+	Name string
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return "undefined template variable: " + e.Name
+}
+
+// Builtins 返回内置模板变量：date、time、year、month、week
+func Builtins(now time.Time) map[string]string {
+	year, week := now.ISOWeek()
+	return map[string]string{
+		"date":  now.Format("2006-01-02"),
+		"time":  now.Format("15:04"),
+		"year":  strconv.Itoa(now.Year()),
+		"month": now.Format("01"),
+		"week":  fmt.Sprintf("%d-W%02d", year, week),
+	}
+}
+
+// Render 替换文本中的模板变量，存在未定义的变量时返回错误
+func Render(text string, vars map[string]string) (string, error) {
+	var missing string
+	result := variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return match
+		}
+		return value
+	})
+
+	if missing != "" {
+		return "", &UndefinedVariableError{Name: missing}
+	}
+	return result, nil
+}
+
+// Instantiate 根据模板生成投票问卷，自定义变量可以覆盖内置变量
+func Instantiate(tpl models.PollTemplate, custom map[string]string, now time.Time) (models.Poll, error) {
+	vars := Builtins(now)
+	for name, value := range custom {
+		vars[name] = value
+	}
+
+	title, err := Render(tpl.Title, vars)
+	if err != nil {
+		return models.Poll{}, err
+	}
+	description, err := Render(tpl.Description, vars)
+	if err != nil {
+		return models.Poll{}, err
+	}
+
+	poll := models.Poll{
+		Title:            title,
+		Description:      description,
+		ResultVisibility: tpl.ResultVisibility,
+		Rules:            tpl.Rules,
+	}
+	poll.Rules.ClosesAt = nil
+	if tpl.CloseAfterMinutes > 0 {
+		closesAt := now.Add(time.Duration(tpl.CloseAfterMinutes) * time.Minute)
+		poll.Rules.ClosesAt = &closesAt
+	}
+
+	for _, text := range tpl.Options {
+		rendered, err := Render(text, vars)
+		if err != nil {
+			return models.Poll{}, err
+		}
+		poll.Options = append(poll.Options, models.Option{Text: rendered})
+	}
+
+	return poll, nil
+}
+
+// Clone 复制投票问卷的标题、描述、选项和设置，不包含票数和关闭状态
+func Clone(source models.Poll) models.Poll {
+	poll := models.Poll{
+		Title:            source.Title,
+		Description:      source.Description,
+		Tags:             append(models.Tags{}, source.Tags...),
+		ResultVisibility: source.ResultVisibility,
+		Rules:            source.Rules,
+		AllowProposals:   source.AllowProposals,
+		Access:           source.Access,
+		AccessCodeHash:   source.AccessCodeHash,
+		RollID:           source.RollID,
+		Mode:             source.Mode,
+		MaxScore:         source.MaxScore,
+	}
+	for _, option := range source.Options {
+		poll.Options = append(poll.Options, models.Option{Text: option.Text, WriteIn: option.WriteIn})
+	}
+	return poll
+}
@@ -0,0 +1,77 @@
+package templates
+
+import (
+	"testing"
+	"time"
+	"vote-system/models"
+)
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{"date": "2024-06-07", "team": "后端组"}
+
+	got, err := Render("{{team}} 周会 {{ date }}", vars)
+	if err != nil {
+		t.Fatalf("渲染失败: %v", err)
+	}
+	if got != "后端组 周会 2024-06-07" {
+		t.Errorf("期望 %q, 得到 %q", "后端组 周会 2024-06-07", got)
+	}
+
+	if _, err := Render("{{unknown}}", vars); err == nil {
+		t.Error("未定义的变量应返回错误")
+	}
+
+	// 不符合变量格式的文本保持不变
+	if got, _ := Render("{{ }} {x}", vars); got != "{{ }} {x}" {
+		t.Errorf("期望原样保留, 得到 %q", got)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	now := time.Date(2024, 12, 30, 9, 5, 0, 0, time.UTC)
+	vars := Builtins(now)
+
+	expected := map[string]string{
+		"date":  "2024-12-30",
+		"time":  "09:05",
+		"year":  "2024",
+		"month": "12",
+		"week":  "2025-W01",
+	}
+	for name, want := range expected {
+		if vars[name] != want {
+			t.Errorf("%s: 期望 %q, 得到 %q", name, want, vars[name])
+		}
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	now := time.Date(2024, 6, 7, 12, 0, 0, 0, time.UTC)
+	tpl := models.PollTemplate{
+		Title:             "{{date}} 午餐",
+		Description:       "{{team}}",
+		Options:           []string{"面", "{{place}}"},
+		ResultVisibility:  models.VisibilityAfterVote,
+		Rules:             models.PollRules{TargetVotes: 10},
+		CloseAfterMinutes: 90,
+	}
+
+	poll, err := Instantiate(tpl, map[string]string{"team": "A组", "place": "食堂"}, now)
+	if err != nil {
+		t.Fatalf("实例化失败: %v", err)
+	}
+
+	if poll.Title != "2024-06-07 午餐" || poll.Description != "A组" {
+		t.Errorf("标题或描述不正确: %q %q", poll.Title, poll.Description)
+	}
+	if len(poll.Options) != 2 || poll.Options[1].Text != "食堂" {
+		t.Errorf("选项不正确: %+v", poll.Options)
+	}
+	if poll.Rules.TargetVotes != 10 || poll.Rules.ClosesAt == nil || !poll.Rules.ClosesAt.Equal(now.Add(90*time.Minute)) {
+		t.Errorf("规则不正确: %+v", poll.Rules)
+	}
+
+	if _, err := Instantiate(tpl, nil, now); err == nil {
+		t.Error("缺少自定义变量时应返回错误")
+	}
+}
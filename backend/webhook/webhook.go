@@ -0,0 +1,380 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultMaxAttempts 投递失败超过该次数后进入死信状态
+	DefaultMaxAttempts = 8
+	// DefaultBaseBackoff 第一次重试的等待时间，之后每次翻倍
+	DefaultBaseBackoff = 10 * time.Second
+	// maxBackoff 重试等待时间上限
+	maxBackoff = time.Hour
+	// pollInterval 投递循环检查待投递记录的间隔
+	pollInterval = time.Second
+	batchSize    = 50
+)
+
+// 投递请求头
+const (
+	HeaderEvent     = "X-Vote-Event"
+	HeaderDelivery  = "X-Vote-Delivery"
+	HeaderTimestamp = "X-Vote-Timestamp"
+	HeaderSignature = "X-Vote-Signature"
+)
+
+// Body webhook请求体
+type Body struct {
+	EventID   uint            `json:"event_id"`
+	Type      string          `json:"type"`
+	PollID    uint            `json:"poll_id"`
+	SurveyID  uint            `json:"survey_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ValidEvents 校验逗号分隔的事件列表
+func ValidEvents(events string) bool {
+	for _, e := range splitEvents(events) {
+		known := false
+		for _, w := range models.WebhookEvents {
+			if e == w {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+func splitEvents(events string) []string {
+	var list []string
+	for _, e := range strings.Split(events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// subscribed 判断订阅是否包含该事件类型
+func subscribed(hook models.Webhook, eventType string) bool {
+	list := splitEvents(hook.Events)
+	if len(list) == 0 {
+		return true
+	}
+	for _, e := range list {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publisher 将outbox事件展开为每个订阅的投递记录，实现outbox.Publisher
+type Publisher struct {
+	db *gorm.DB
+}
+
+// NewPublisher 创建新的Publisher
+func NewPublisher(db *gorm.DB) *Publisher {
+	return &Publisher{db: db}
+}
+
+// Publish 为匹配的订阅创建投递记录；同一事件重复发布时不会重复创建
+//
+// 订阅方按未投票的公众对待：投票问卷的结果对其不可见时，事件中去掉选项和票数，也不发送阈值事件。
+// 阈值事件在总票数首次达到阈值时发送一次，重置投票后可以再次发送。
+func (p *Publisher) Publish(event models.OutboxEvent) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		// 重置投票后票数从零开始，清除已发送阈值事件的标记
+		if event.Type == models.EventPollReset {
+			if err := tx.Where("poll_id = ?", event.PollID).Delete(&models.WebhookThreshold{}).Error; err != nil {
+				return err
+			}
+		}
+
+		var hooks []models.Webhook
+		if err := tx.Where("active = ? AND (poll_id IS NULL OR poll_id = ?)", true, event.PollID).Find(&hooks).Error; err != nil {
+			return err
+		}
+		if len(hooks) == 0 {
+			return nil
+		}
+
+		hidden, err := resultsHidden(tx, event)
+		if err != nil {
+			return err
+		}
+		payload := json.RawMessage(event.Payload)
+		if hidden {
+			if payload, err = redact(event); err != nil {
+				return err
+			}
+		}
+
+		var data struct {
+			TotalVotes *int `json:"total_votes"`
+		}
+		json.Unmarshal([]byte(event.Payload), &data)
+
+		var deliveries []models.WebhookDelivery
+		for _, hook := range hooks {
+			if subscribed(hook, event.Type) {
+				delivery, err := newDelivery(hook, event, event.Type, payload)
+				if err != nil {
+					return err
+				}
+				deliveries = append(deliveries, delivery)
+			}
+
+			// 总票数达到订阅的阈值；合并或关闭时的委托计票可能跳过阈值本身，因此比较是否已达到
+			if event.SurveyID != 0 || hidden || hook.Threshold <= 0 || data.TotalVotes == nil ||
+				*data.TotalVotes < hook.Threshold || !subscribed(hook, models.EventThresholdCrossed) {
+				continue
+			}
+			marked := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.WebhookThreshold{WebhookID: hook.ID, PollID: event.PollID})
+			if marked.Error != nil {
+				return marked.Error
+			}
+			if marked.RowsAffected == 0 {
+				continue
+			}
+			payload, _ := json.Marshal(map[string]int{"threshold": hook.Threshold, "total_votes": *data.TotalVotes})
+			delivery, err := newDelivery(hook, event, models.EventThresholdCrossed, payload)
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+	})
+}
+
+// resultsHidden 判断事件所属投票问卷的结果是否对订阅方隐藏，调查问卷的事件不包含结果，找不到投票问卷时按隐藏处理
+func resultsHidden(db *gorm.DB, event models.OutboxEvent) (bool, error) {
+	if event.SurveyID != 0 {
+		return false, nil
+	}
+
+	var poll models.Poll
+	err := db.Unscoped().Select("id", "is_active", "result_visibility").First(&poll, event.PollID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !poll.ResultsVisible(false, false), nil
+}
+
+// redact 去掉事件中的选项和票数：投票和撤销投票只保留事件本身，关闭事件只保留关闭原因
+func redact(event models.OutboxEvent) (json.RawMessage, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(event.Payload), &data); err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case models.EventVoteCast, models.EventVoteCleared:
+		data = map[string]interface{}{}
+	case models.EventPollClosed:
+		data = map[string]interface{}{"reason": data["reason"]}
+	default:
+		delete(data, "total_votes")
+		delete(data, "moved_votes")
+	}
+	return json.Marshal(data)
+}
+
+func newDelivery(hook models.Webhook, event models.OutboxEvent, eventType string, data json.RawMessage) (models.WebhookDelivery, error) {
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+
+	body, err := json.Marshal(Body{
+		EventID:   event.ID,
+		Type:      eventType,
+		PollID:    event.PollID,
+		SurveyID:  event.SurveyID,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		EventID:       event.ID,
+		EventType:     eventType,
+		Payload:       string(body),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// Worker 发送待投递的webhook，失败时按指数退避重试
+type Worker struct {
+	db          *gorm.DB
+	client      *http.Client
+	MaxAttempts int
+	BaseBackoff time.Duration
+}
+
+// NewWorker 创建新的Worker
+func NewWorker(db *gorm.DB) *Worker {
+	return &Worker{
+		db:          db,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: DefaultMaxAttempts,
+		BaseBackoff: DefaultBaseBackoff,
+	}
+}
+
+// Run 运行投递循环
+func (w *Worker) Run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := w.DeliverDue(time.Now()); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+	}
+}
+
+// DeliverDue 投递所有到期的待投递记录
+func (w *Worker) DeliverDue(now time.Time) error {
+	var deliveries []models.WebhookDelivery
+	if err := w.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("id").Limit(batchSize).Find(&deliveries).Error; err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		var hook models.Webhook
+		if err := w.db.First(&hook, delivery.WebhookID).Error; err != nil {
+			// 订阅已删除，直接转入死信
+			w.db.Model(&delivery).Updates(map[string]interface{}{
+				"status":     models.DeliveryDead,
+				"last_error": "webhook not found",
+			})
+			continue
+		}
+
+		statusCode, err := w.send(hook, delivery, now)
+		w.record(delivery, statusCode, err, now)
+	}
+
+	return nil
+}
+
+func (w *Worker) send(hook models.Webhook, delivery models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vote-system-webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// record 记录投递结果，失败时计算下一次重试时间
+func (w *Worker) record(delivery models.WebhookDelivery, statusCode int, err error, now time.Time) {
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":         attempts,
+		"last_status_code": statusCode,
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.DeliveryDelivered
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+	case attempts >= w.MaxAttempts:
+		updates["status"] = models.DeliveryDead
+		updates["last_error"] = err.Error()
+	default:
+		updates["next_attempt_at"] = now.Add(w.backoff(attempts))
+		updates["last_error"] = err.Error()
+	}
+
+	if err := w.db.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("Error recording webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// backoff 返回第attempts次失败后的等待时间
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// Redeliver 将投递记录重新置为待投递，用于手动重试死信
+func Redeliver(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	return db.Model(delivery).Updates(map[string]interface{}{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	}).Error
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"vote-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// 自动迁移测试表
+	db.AutoMigrate(&models.Poll{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookThreshold{}, &models.OutboxEvent{})
+	db.Create(&models.Poll{Title: "结果公开", IsActive: true})
+	db.Create(&models.Poll{Title: "另一个", IsActive: true})
+	return db
+}
+
+func TestPublishMatchesSubscriptions(t *testing.T) {
+	db := setupTestDB()
+
+	pollID, otherPollID := uint(1), uint(2)
+	db.Create(&models.Webhook{URL: "http://a", Secret: "s"})
+	db.Create(&models.Webhook{URL: "http://b", Secret: "s", PollID: &pollID, Events: "vote_cast"})
+	db.Create(&models.Webhook{URL: "http://c", Secret: "s", PollID: &otherPollID})
+	db.Create(&models.Webhook{URL: "http://d", Secret: "s", Events: "poll_reset"})
+
+	event := models.OutboxEvent{ID: 7, PollID: pollID, Type: models.EventVoteCast, Payload: `{"option_id":1}`}
+	publisher := NewPublisher(db)
+	if err := publisher.Publish(event); err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	// 重复发布不应产生重复投递
+	if err := publisher.Publish(event); err != nil {
+		t.Fatalf("重复发布失败: %v", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	db.Order("webhook_id").Find(&deliveries)
+	if len(deliveries) != 2 {
+		t.Fatalf("期望2条投递记录, 得到 %d", len(deliveries))
+	}
+	if deliveries[0].WebhookID != 1 || deliveries[1].WebhookID != 2 {
+		t.Errorf("投递到了错误的订阅: %+v", deliveries)
+	}
+}
+
+func TestPublishThreshold(t *testing.T) {
+	db := setupTestDB()
+
+	db.Create(&models.Webhook{URL: "http://a", Secret: "s", Events: "threshold_crossed", Threshold: 3})
+
+	publisher := NewPublisher(db)
+	publisher.Publish(models.OutboxEvent{ID: 1, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":2}`})
+	publisher.Publish(models.OutboxEvent{ID: 2, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":3}`})
+	publisher.Publish(models.OutboxEvent{ID: 3, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":4}`})
+
+	var deliveries []models.WebhookDelivery
+	db.Find(&deliveries)
+	if len(deliveries) != 1 {
+		t.Fatalf("期望1条投递记录, 得到 %d", len(deliveries))
+	}
+	if deliveries[0].EventType != models.EventThresholdCrossed || deliveries[0].EventID != 2 {
+		t.Errorf("期望第2个事件触发阈值, 得到 %+v", deliveries[0])
+	}
+
+	// 重置后再次达到阈值时重新发送，撤销投票后回到阈值不重复发送
+	publisher.Publish(models.OutboxEvent{ID: 4, PollID: 1, Type: models.EventPollReset, Payload: `{}`})
+	publisher.Publish(models.OutboxEvent{ID: 5, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":3}`})
+	publisher.Publish(models.OutboxEvent{ID: 6, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":3}`})
+
+	deliveries = nil
+	db.Order("id").Find(&deliveries)
+	if len(deliveries) != 2 || deliveries[1].EventID != 5 {
+		t.Errorf("期望重置后第5个事件再次触发阈值, 得到 %+v", deliveries)
+	}
+}
+
+func TestPublishThresholdSkippedCount(t *testing.T) {
+	db := setupTestDB()
+
+	db.Create(&models.Webhook{URL: "http://a", Secret: "s", Events: "threshold_crossed", Threshold: 3})
+
+	// 关闭时计入委托票，总票数从2直接变为5
+	publisher := NewPublisher(db)
+	publisher.Publish(models.OutboxEvent{ID: 1, PollID: 1, Type: models.EventVoteCast, Payload: `{"total_votes":2}`})
+	publisher.Publish(models.OutboxEvent{ID: 2, PollID: 1, Type: models.EventPollClosed, Payload: `{"reason":"manual","total_votes":5}`})
+
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery).Error; err != nil {
+		t.Fatalf("跳过阈值时也应触发阈值事件: %v", err)
+	}
+	if delivery.EventType != models.EventThresholdCrossed || !strings.Contains(delivery.Payload, `"total_votes":5`) {
+		t.Errorf("期望阈值事件包含总票数5, 得到 %+v", delivery)
+	}
+}
+
+func TestPublishRedactsHiddenResults(t *testing.T) {
+	db := setupTestDB()
+
+	poll := models.Poll{Title: "关闭后公布", IsActive: true, ResultVisibility: models.VisibilityAfterClose}
+	db.Create(&poll)
+	db.Create(&models.Webhook{URL: "http://a", Secret: "s", Threshold: 1})
+
+	publisher := NewPublisher(db)
+	publisher.Publish(models.OutboxEvent{ID: 1, PollID: poll.ID, Type: models.EventVoteCast, Payload: `{"vote_id":5,"option_id":2,"option_ids":[2,3],"weight":"1","total_votes":1}`})
+	publisher.Publish(models.OutboxEvent{ID: 2, PollID: poll.ID, Type: models.EventProposalMerged, Payload: `{"proposal_id":1,"option_id":2,"moved_votes":3}`})
+
+	var deliveries []models.WebhookDelivery
+	db.Order("id").Find(&deliveries)
+	if len(deliveries) != 2 {
+		t.Fatalf("结果隐藏时不应发送阈值事件, 得到 %d 条投递记录", len(deliveries))
+	}
+	if !strings.Contains(deliveries[0].Payload, `"data":{}`) {
+		t.Errorf("投票事件不应包含选项和票数: %s", deliveries[0].Payload)
+	}
+	if strings.Contains(deliveries[1].Payload, "moved_votes") || !strings.Contains(deliveries[1].Payload, `"proposal_id":1`) {
+		t.Errorf("提议事件只应去掉票数: %s", deliveries[1].Payload)
+	}
+
+	// 关闭后结果可见，关闭事件包含完整结果
+	db.Model(&poll).Update("is_active", false)
+	publisher.Publish(models.OutboxEvent{ID: 3, PollID: poll.ID, Type: models.EventPollClosed, Payload: `{"reason":"manual","total_votes":1}`})
+	var closed models.WebhookDelivery
+	db.Where("event_id = ?", 3).First(&closed)
+	if !strings.Contains(closed.Payload, `"total_votes":1`) {
+		t.Errorf("关闭后应包含票数: %s", closed.Payload)
+	}
+
+	// 仅管理员可见的投票问卷关闭后也只公布关闭原因
+	db.Model(&poll).Update("result_visibility", models.VisibilityAdminOnly)
+	publisher.Publish(models.OutboxEvent{ID: 4, PollID: poll.ID, Type: models.EventPollClosed, Payload: `{"reason":"manual","total_votes":1}`})
+	var sealed models.WebhookDelivery
+	db.Where("event_id = ?", 4).First(&sealed)
+	if !strings.Contains(sealed.Payload, `"data":{"reason":"manual"}`) {
+		t.Errorf("期望只包含关闭原因: %s", sealed.Payload)
+	}
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	db := setupTestDB()
+
+	var gotSignature, gotTimestamp, gotEvent string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderSignature)
+		gotTimestamp = r.Header.Get(HeaderTimestamp)
+		gotEvent = r.Header.Get(HeaderEvent)
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	db.Create(&models.Webhook{URL: server.URL, Secret: "topsecret"})
+	NewPublisher(db).Publish(models.OutboxEvent{ID: 1, PollID: 1, Type: models.EventPollOpened, Payload: `{}`})
+
+	now := time.Now()
+	if err := NewWorker(db).DeliverDue(now); err != nil {
+		t.Fatalf("投递失败: %v", err)
+	}
+
+	if gotEvent != models.EventPollOpened {
+		t.Errorf("期望事件头 %s, 得到 %s", models.EventPollOpened, gotEvent)
+	}
+	if want := Sign("topsecret", gotTimestamp, gotBody); gotSignature != want {
+		t.Errorf("签名不匹配: 期望 %s, 得到 %s", want, gotSignature)
+	}
+
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 || delivery.LastStatusCode != 200 {
+		t.Errorf("期望投递成功, 得到 %+v", delivery)
+	}
+}
+
+func TestDeliverRetriesThenDeadLetters(t *testing.T) {
+	db := setupTestDB()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	db.Create(&models.Webhook{URL: server.URL, Secret: "s"})
+	NewPublisher(db).Publish(models.OutboxEvent{ID: 1, PollID: 1, Type: models.EventPollReset, Payload: `{}`})
+
+	worker := NewWorker(db)
+	worker.MaxAttempts = 3
+	worker.BaseBackoff = time.Minute
+
+	now := time.Now()
+	worker.DeliverDue(now)
+
+	var delivery models.WebhookDelivery
+	db.First(&delivery)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("期望等待重试, 得到 %+v", delivery)
+	}
+	if !delivery.NextAttemptAt.After(now.Add(59 * time.Second)) {
+		t.Errorf("期望约1分钟后重试, 得到 %v", delivery.NextAttemptAt)
+	}
+
+	// 未到重试时间不投递
+	worker.DeliverDue(now.Add(30 * time.Second))
+	db.First(&delivery)
+	if delivery.Attempts != 1 {
+		t.Errorf("未到重试时间不应投递, 得到 %d 次", delivery.Attempts)
+	}
+
+	worker.DeliverDue(now.Add(time.Minute))
+	worker.DeliverDue(now.Add(time.Hour))
+	db.First(&delivery)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != 3 {
+		t.Errorf("期望进入死信, 得到 %+v", delivery)
+	}
+	if delivery.LastStatusCode != 500 {
+		t.Errorf("期望记录状态码500, 得到 %d", delivery.LastStatusCode)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	worker := &Worker{BaseBackoff: 10 * time.Second}
+
+	cases := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		20: maxBackoff,
+	}
+	for attempts, want := range cases {
+		if got := worker.backoff(attempts); got != want {
+			t.Errorf("第%d次失败后期望等待 %v, 得到 %v", attempts, want, got)
+		}
+	}
+}
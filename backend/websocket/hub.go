@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/websocket"
 )
@@ -14,19 +15,34 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Client 表示一个WebSocket客户端
+// Audience 客户端的身份，用于按结果可见性决定推送内容
+type Audience struct {
+	Voter string // 投票人标识，即客户端IP
+	Admin bool   // 是否携带了管理令牌
+}
+
+// Client 表示一个WebSocket客户端，conn为nil时是通过Subscribe订阅的客户端
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte // 服务器向客户端发送消息通道
+	hub      *Hub
+	conn     *websocket.Conn
+	send     chan []byte // 服务器向客户端发送消息通道
+	audience Audience
+}
+
+// broadcastMessage 待广播的消息，visible为nil时所有客户端收到full，否则只有visible中为true的客户端收到full，其余收到hidden
+type broadcastMessage struct {
+	full    []byte
+	hidden  []byte
+	visible map[Audience]bool
 }
 
 // Hub 管理所有客户端连接
 type Hub struct {
-	clients    map[*Client]bool // 客户端hash表
-	broadcast  chan []byte      // 广播消息给所有客户端
-	register   chan *Client     // 注册客户端
-	unregister chan *Client     // 注销客户端
+	clients    map[*Client]bool       // 客户端hash表
+	broadcast  chan *broadcastMessage // 广播消息给所有客户端
+	register   chan *Client           // 注册客户端
+	unregister chan *Client           // 注销客户端
+	audiences  chan chan []Audience   // 查询已连接客户端的身份
 }
 
 // Message WebSocket消息结构
@@ -39,13 +55,14 @@ type Message struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan *broadcastMessage),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		audiences:  make(chan chan []Audience),
 	}
 }
 
-// Run 运行Hub
+// Run 运行Hub，只在内存中分发消息，可见性由广播方事先确定
 func (h *Hub) Run() {
 	for {
 		select {
@@ -60,8 +77,25 @@ func (h *Hub) Run() {
 				log.Printf("Client disconnected. Total clients: %d", len(h.clients))
 			}
 
-		case message := <-h.broadcast:
+		case reply := <-h.audiences:
+			seen := map[Audience]bool{}
+			audiences := make([]Audience, 0, len(h.clients))
+			for client := range h.clients {
+				if !seen[client.audience] {
+					seen[client.audience] = true
+					audiences = append(audiences, client.audience)
+				}
+			}
+			reply <- audiences
+
+		case broadcast := <-h.broadcast:
 			for client := range h.clients {
+				message := broadcast.full
+				// 查询身份之后才连接的客户端不在visible中，收到hidden
+				if broadcast.visible != nil && !broadcast.visible[client.audience] {
+					message = broadcast.hidden
+				}
+
 				select {
 				case client.send <- message:
 				default:
@@ -75,22 +109,54 @@ func (h *Hub) Run() {
 
 // BroadcastPollUpdate 广播投票更新
 func (h *Hub) BroadcastPollUpdate(data interface{}) {
-	message := Message{
-		Type: "poll_update",
-		Data: data,
-	}
+	h.BroadcastFor("poll_update", data, nil, nil)
+}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Error marshaling poll update: %v", err)
+// Audiences 返回当前已连接客户端的身份，同一身份的多个连接只返回一次，供广播前确定可见性
+func (h *Hub) Audiences() []Audience {
+	reply := make(chan []Audience, 1)
+	h.audiences <- reply
+	return <-reply
+}
+
+// BroadcastFor 按客户端身份广播消息，visible中不为true的客户端收到hidden，visible为nil时所有客户端收到full
+func (h *Hub) BroadcastFor(msgType string, full, hidden interface{}, visible map[Audience]bool) {
+	message := &broadcastMessage{visible: visible}
+
+	var err error
+	if message.full, err = json.Marshal(Message{Type: msgType, Data: full}); err != nil {
+		log.Printf("Error marshaling %s message: %v", msgType, err)
 		return
 	}
+	if visible != nil {
+		if message.hidden, err = json.Marshal(Message{Type: msgType, Data: hidden}); err != nil {
+			log.Printf("Error marshaling %s message: %v", msgType, err)
+			return
+		}
+	}
 
-	h.broadcast <- jsonData
+	h.broadcast <- message
+}
+
+// Subscribe 不经过WebSocket连接订阅广播，收到的消息与WebSocket客户端相同，供gRPC等其他推送方式使用
+//
+// 消费过慢时Hub会关闭返回的通道；不再需要时必须调用cancel释放订阅，重复调用是安全的。
+func (h *Hub) Subscribe(audience Audience) (messages <-chan []byte, cancel func()) {
+	client := &Client{
+		hub:      h,
+		send:     make(chan []byte, 256),
+		audience: audience,
+	}
+	h.register <- client
+
+	var once sync.Once
+	return client.send, func() {
+		once.Do(func() { h.unregister <- client })
+	}
 }
 
 // ServeWS 处理WebSocket连接
-func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
+func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, audience Audience) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -98,9 +164,10 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan []byte, 256),
+		audience: audience,
 	}
 
 	client.hub.register <- client
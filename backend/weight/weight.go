@@ -0,0 +1,151 @@
+// Package weight 投票权重
+//
+// 权重为正的十进制小数，最多9位整数和6位小数。为了避免浮点数的舍入误差，
+// 权重以百万分之一为单位保存为整数，求和也在整数上进行；JSON中以十进制字符串表示，例如 "2.5"。
+package weight
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Weight 以百万分之一为单位的权重
+type Weight int64
+
+// Scale 1的权重对应的整数值
+const Scale = 1000000
+
+// Decimals 权重最多的小数位数
+const Decimals = 6
+
+// One 默认权重
+const One Weight = Scale
+
+// pattern 投票人权重的十进制表示，最多9位整数和6位小数
+//
+// 单个权重保存为整数后小于1e15，但约九千张最大权重的选票之和、或较大的权重乘以评分投票的分数和波达计数的名次分
+// 就可能超出int64，累加票数和得分时应使用Add和Mul检查溢出。
+var pattern = regexp.MustCompile(`^[0-9]{1,9}(\.[0-9]{1,6})?$`)
+
+// decimalPattern 任意权重（包括总和与差值）的十进制表示
+var decimalPattern = regexp.MustCompile(`^-?[0-9]{1,13}(\.[0-9]{1,6})?$`)
+
+// ErrInvalid 权重不是正的十进制小数或超出范围
+var ErrInvalid = errors.New("weight must be a positive decimal with at most 9 integer and 6 fractional digits")
+
+// ErrOverflow 权重之和或乘积超出int64的范围
+var ErrOverflow = errors.New("weight total overflows int64")
+
+// Parse 解析十进制表示的权重，空字符串为One，0和负数无效
+func Parse(raw string) (Weight, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return One, nil
+	}
+	if !pattern.MatchString(raw) {
+		return 0, ErrInvalid
+	}
+	w, err := decode(raw)
+	if err != nil || w == 0 {
+		return 0, ErrInvalid
+	}
+	return w, nil
+}
+
+// decode 把十进制表示转换为整数，不检查投票人权重的范围
+func decode(raw string) (Weight, error) {
+	whole, fraction, _ := strings.Cut(raw, ".")
+	fraction += strings.Repeat("0", Decimals-len(fraction))
+	w, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	return Weight(w), nil
+}
+
+// Of 返回n票、每票权重为1时的总权重
+func Of(n int) Weight {
+	return Weight(n) * One
+}
+
+// Add 返回w与other之和，超出int64的范围时返回ErrOverflow
+func (w Weight) Add(other Weight) (Weight, error) {
+	sum := w + other
+	if (other > 0 && sum < w) || (other < 0 && sum > w) {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// Mul 返回w的n倍，超出int64的范围时返回ErrOverflow
+func (w Weight) Mul(n int) (Weight, error) {
+	product := new(big.Int).Mul(big.NewInt(int64(w)), big.NewInt(int64(n)))
+	if !product.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return Weight(product.Int64()), nil
+}
+
+// String 返回去掉多余0的十进制表示，例如 "2.5"、"3"
+func (w Weight) String() string {
+	sign := ""
+	v := int64(w)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	whole := strconv.FormatInt(v/Scale, 10)
+	fraction := strings.TrimRight(strconv.FormatInt(Scale+v%Scale, 10)[1:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+// MarshalJSON 以十进制字符串输出，避免客户端按浮点数解析时损失精度
+func (w Weight) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+// UnmarshalJSON 接受十进制字符串或JSON数字，数字按原文解析而不经过浮点数
+//
+// 总和等数据可以为0或超出投票人权重的范围，校验投票人的权重应使用Parse。
+func (w *Weight) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+	if !decimalPattern.MatchString(raw) {
+		return ErrInvalid
+	}
+	parsed, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// AtLeast 判断part是否至少占total的percent%，用大整数比较避免乘法溢出
+func AtLeast(part, total Weight, percent int) bool {
+	left := new(big.Int).Mul(big.NewInt(int64(part)), big.NewInt(100))
+	right := new(big.Int).Mul(big.NewInt(int64(total)), big.NewInt(int64(percent)))
+	return left.Cmp(right) >= 0
+}
+
+// Percentage 计算part占total的百分比并保留两位小数，只用于展示
+func Percentage(part, total Weight) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
@@ -0,0 +1,86 @@
+package weight
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Weight{"": One, "2": 2 * One, "02.50": 2500000, "0.125": 125000, "1.000000": One, "0.000001": 1, "999999999.999999": 999999999999999}
+	for raw, want := range cases {
+		if got, err := Parse(raw); err != nil || got != want {
+			t.Errorf("Parse(%q): 期望 %d, 得到 %d %v", raw, want, got, err)
+		}
+	}
+	for _, raw := range []string{"0", "0.0", "-1", "1e3", "1.", ".5", "1.2345678", "1,5", "1234567890", "abc"} {
+		if _, err := Parse(raw); err == nil {
+			t.Errorf("期望 %q 无效", raw)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := map[Weight]string{One: "1", 2500000: "2.5", 125000: "0.125", 1: "0.000001", 0: "0", -1500000: "-1.5"}
+	for w, want := range cases {
+		if got := w.String(); got != want {
+			t.Errorf("%d: 期望 %s, 得到 %s", int64(w), want, got)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	// 0.1 + 0.2 按浮点数求和不等于 0.3
+	var values []Weight
+	if err := json.Unmarshal([]byte(`["0.1", 0.2]`), &values); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	sum := values[0] + values[1]
+	data, _ := json.Marshal(sum)
+	if string(data) != `"0.3"` {
+		t.Errorf("期望 \"0.3\", 得到 %s", data)
+	}
+	// 总和可以为0或超出投票人权重的范围
+	if err := json.Unmarshal([]byte(`["0", "-1.5", "1234567890123.5"]`), &values); err != nil ||
+		values[0] != 0 || values[1] != -1500000 || values[2] != 1234567890123500000 {
+		t.Errorf("解析总和失败: %v %v", values, err)
+	}
+	if err := json.Unmarshal([]byte(`"1e3"`), &values[0]); err == nil {
+		t.Error("期望科学计数法无效")
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	if !AtLeast(5*One, 10*One, 50) || AtLeast(4999999, 10*One, 50) {
+		t.Error("百分比比较不正确")
+	}
+	// 乘以100会超出int64的范围
+	big := Weight(1 << 60)
+	if !AtLeast(big, big, 100) {
+		t.Error("大权重的比较不应溢出")
+	}
+	if got := Percentage(One, 3*One); got != 33.33 {
+		t.Errorf("期望 33.33, 得到 %v", got)
+	}
+}
+
+func TestAddMul(t *testing.T) {
+	if sum, err := (2 * One).Add(One); sum != 3*One || err != nil {
+		t.Errorf("期望 3, 得到 %s %v", sum, err)
+	}
+	if _, err := Weight(math.MaxInt64).Add(1); err != ErrOverflow {
+		t.Errorf("期望 ErrOverflow, 得到 %v", err)
+	}
+	if _, err := Weight(math.MinInt64).Add(-1); err != ErrOverflow {
+		t.Errorf("期望 ErrOverflow, 得到 %v", err)
+	}
+
+	// 最大的投票人权重乘以评分投票的最高分仍在范围内，再大就会溢出
+	max, _ := Parse("999999999.999999")
+	if product, err := max.Mul(100); err != nil || product != max*100 {
+		t.Errorf("期望 %s, 得到 %s %v", max*100, product, err)
+	}
+	if _, err := max.Mul(10000); err != ErrOverflow {
+		t.Errorf("期望 ErrOverflow, 得到 %v", err)
+	}
+}
@@ -48,17 +48,4 @@ CREATE TABLE IF NOT EXISTS votes (
     FOREIGN KEY (option_id) REFERENCES options(id) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='投票记录表';
 
--- 插入示例数据
-INSERT INTO polls (title, description, is_active) VALUES 
-('您最喜欢的编程语言是什么？', '请选择您最喜欢的编程语言', TRUE);
-
--- 获取刚插入的投票ID
-SET @poll_id = LAST_INSERT_ID();
-
--- 插入选项
-INSERT INTO options (poll_id, text, vote_count) VALUES 
-(@poll_id, 'Go', 0),
-(@poll_id, 'Python', 0),
-(@poll_id, 'JavaScript', 0),
-(@poll_id, 'Java', 0),
-(@poll_id, 'TypeScript', 0); 
\ No newline at end of file
+-- 示例数据不在此处写入，开发环境通过 APP_ENV=development SEED=demo 或 --seed demo 显式初始化
@@ -29,8 +29,8 @@ echo "📦 安装后端依赖..."
 cd backend
 go mod tidy
 
-echo "🔧 启动后端服务 (端口 8080)..."
-go run main.go &
+echo "🔧 启动后端服务 (端口 8080)，使用 demo 数据集..."
+APP_ENV=development SEED=demo go run . &
 BACKEND_PID=$!
 
 cd ../frontend